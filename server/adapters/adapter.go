@@ -27,6 +27,11 @@ type SQLAdapter interface {
 	ReplaceTable(originalTable, replacementTable string, dropOldTable bool) error
 }
 
+//Querier is implemented by adapters which are able to run arbitrary read queries (e.g. for lookup enrichment)
+type Querier interface {
+	Query(query string) ([]map[string]interface{}, error)
+}
+
 //Adapter is an adapter for all destinations
 type Adapter interface {
 	io.Closer
@@ -49,6 +54,51 @@ func (sp *SqlParams) commonTruncate(statement string) error {
 	return nil
 }
 
+//commonQuery executes query and returns all rows as maps column name -> value
+func (sp *SqlParams) commonQuery(query string) ([]map[string]interface{}, error) {
+	sp.queryLogger.LogQuery(query)
+
+	rows, err := sp.dataSource.QueryContext(sp.ctx, query)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			//some drivers (e.g. MySQL) return text values as bytes
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func mapError(err error) error {
 	if notExistRegexp.MatchString(err.Error()) {
 		return ErrTableNotExist
@@ -12,6 +12,8 @@ import (
 
 const (
 	amplitudeDefaultAPIURL = "https://api2.amplitude.com/2/httpapi"
+	//amplitudeMaxBatchSize is a max events count in one HTTP API request
+	amplitudeMaxBatchSize = 2000
 )
 
 //AmplitudeRequest is a dto for sending requests to Amplitude
@@ -24,6 +26,10 @@ type AmplitudeRequest struct {
 type AmplitudeResponse struct {
 	Code  int    `json:"code"`
 	Error string `json:"error"`
+
+	//indices of invalid events: field name -> events indices. The whole request is rejected if there are invalid events
+	EventsWithInvalidFields map[string][]int `json:"events_with_invalid_fields,omitempty"`
+	EventsWithMissingFields map[string][]int `json:"events_with_missing_fields,omitempty"`
 }
 
 //AmplitudeRequestFactory is a factory for building Amplitude HTTP requests from input events
@@ -59,13 +65,77 @@ func (arf *AmplitudeRequestFactory) Create(object map[string]interface{}) (*Requ
 	}, nil
 }
 
+//BatchKey returns key of requests with the same URL and headers
+func (arf *AmplitudeRequestFactory) BatchKey(req *Request) string {
+	return requestBatchKey(req)
+}
+
+//MaxBatchSize returns Amplitude HTTP API limit of events in one request
+func (arf *AmplitudeRequestFactory) MaxBatchSize() int {
+	return amplitudeMaxBatchSize
+}
+
+//CreateBatch returns request with events of all requests. Every request must contain exactly one event
+func (arf *AmplitudeRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	batch := AmplitudeRequest{APIKey: arf.apiKey, Events: make([]map[string]interface{}, 0, len(requests))}
+	for _, req := range requests {
+		amplitudeRequest := &AmplitudeRequest{}
+		if err := json.Unmarshal(req.Body, amplitudeRequest); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling amplitude request: %v", err)
+		}
+		if len(amplitudeRequest.Events) != 1 {
+			return nil, fmt.Errorf("amplitude request must contain one event: %d", len(amplitudeRequest.Events))
+		}
+		batch.Events = append(batch.Events, amplitudeRequest.Events[0])
+	}
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling amplitude batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns errors of invalid events. Amplitude rejects the whole request if it contains invalid events
+func (arf *AmplitudeRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	if response.StatusCode != http.StatusBadRequest {
+		return nil
+	}
+
+	amplitudeResponse := &AmplitudeResponse{}
+	if err := json.Unmarshal(response.Body, amplitudeResponse); err != nil {
+		return nil
+	}
+
+	itemErrors := map[int]error{}
+	addErrors := func(fields map[string][]int, description string) {
+		for field, indices := range fields {
+			for _, index := range indices {
+				if index >= 0 && index < batchSize {
+					itemErrors[index] = fmt.Errorf("amplitude event has %s field [%s]: %s", description, field, amplitudeResponse.Error)
+				}
+			}
+		}
+	}
+	addErrors(amplitudeResponse.EventsWithInvalidFields, "invalid")
+	addErrors(amplitudeResponse.EventsWithMissingFields, "missing")
+
+	return &BatchResult{ItemErrors: itemErrors, RetryOthers: true}
+}
+
 func (arf *AmplitudeRequestFactory) Close() {
 }
 
 //AmplitudeConfig is a dto for parsing Amplitude configuration
 type AmplitudeConfig struct {
-	APIKey   string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
-	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	APIKey   string           `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	Endpoint string           `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Batch    *HTTPBatchConfig `mapstructure:"batch" json:"batch,omitempty" yaml:"batch,omitempty"`
 }
 
 //Validate returns err if invalid
@@ -77,7 +147,7 @@ func (ac *AmplitudeConfig) Validate() error {
 		return errors.New("'api_key' is required parameter")
 	}
 
-	return nil
+	return ac.Batch.Validate()
 }
 
 //Amplitude is an adapter for sending HTTP requests to Amplitude
@@ -95,6 +165,7 @@ func NewAmplitude(config *AmplitudeConfig, httpAdapterConfiguration *HTTPAdapter
 	}
 
 	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
 	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
 	if err != nil {
 		return nil, err
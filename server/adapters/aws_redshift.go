@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/uuid"
 	_ "github.com/lib/pq"
 	"strings"
+	"time"
 )
 
 const (
@@ -46,6 +47,8 @@ var (
 		typing.FLOAT64:   "double precision",
 		typing.TIMESTAMP: "timestamp",
 		typing.BOOL:      "boolean",
+		typing.JSON:      "character varying(65535)",
+		typing.ARRAY:     "character varying(65535)",
 		typing.UNKNOWN:   "character varying(65535)",
 	}
 )
@@ -57,6 +60,25 @@ type AwsRedshift struct {
 	s3Config        *S3Config
 }
 
+//DeleteExpired deletes rows which are older than expiredBefore
+func (ar *AwsRedshift) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if err := requireRetentionTable(rule); err != nil {
+		return nil, err
+	}
+
+	p := ar.dataSourceProxy
+	sqlParams := &SqlParams{ctx: p.ctx, dataSource: p.dataSource, queryLogger: p.queryLogger}
+	rows, err := deleteExpiredRows(sqlParams,
+		fmt.Sprintf(countExpiredTemplate, p.config.Schema, rule.Table, rule.Field),
+		fmt.Sprintf(deleteExpiredTemplate, p.config.Schema, rule.Table, rule.Field),
+		expiredBefore.UTC(), dryRun, &ErrorPayload{Schema: p.config.Schema, Table: rule.Table})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore, Rows: rows}, nil
+}
+
 //NewAwsRedshift returns configured AwsRedshift adapter instance
 func NewAwsRedshift(ctx context.Context, dsConfig *DataSourceConfig, s3Config *S3Config,
 	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*AwsRedshift, error) {
@@ -423,6 +445,11 @@ func (ar *AwsRedshift) Truncate(tableName string) error {
 	return ar.dataSourceProxy.Truncate(tableName)
 }
 
+//Query executes read query uses underlying postgres datasource
+func (ar *AwsRedshift) Query(query string) ([]map[string]interface{}, error) {
+	return ar.dataSourceProxy.Query(query)
+}
+
 //DropTable drops table in transaction uses underlying postgres datasource
 func (ar *AwsRedshift) DropTable(table *Table) error {
 	return ar.dataSourceProxy.DropTable(table)
@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,22 +10,41 @@ import (
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/schema"
-	"math"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 )
 
 const (
 	deleteBigQueryTemplate   = "DELETE FROM `%s.%s.%s` WHERE %s"
 	truncateBigQueryTemplate = "TRUNCATE TABLE `%s.%s.%s`"
-
-	rowsLimitPerInsertOperation = 500
+	countExpiredBQTemplate   = "SELECT COUNT(*) FROM `%s.%s.%s` WHERE `%s` < @expired_before"
+	deleteExpiredBQTemplate  = "DELETE FROM `%s.%s.%s` WHERE `%s` < @expired_before"
+	mergeBigQueryTemplate    = "MERGE `%s.%s.%s` T USING `%s.%s.%s` S ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)"
+
+	//stagingTableExpiration is a time after which BigQuery removes staging table if it hasn't been dropped
+	stagingTableExpiration = 24 * time.Hour
+	//loadJobMinInterval is a min interval between load jobs into the same table
+	//BigQuery allows 1500 load jobs per table per day (failed jobs are also counted)
+	loadJobMinInterval = time.Minute
+
+	//bigQueryJSONFieldType is a native JSON type (isn't declared in the client library version)
+	bigQueryJSONFieldType = "JSON"
+	//bigQueryRepeatedPrefix is used in column types of REPEATED (array) fields e.g. REPEATED STRING
+	bigQueryRepeatedPrefix = "REPEATED "
+
+	//BigQueryMaxClusteringFields is a max count of table clustering columns
+	BigQueryMaxClusteringFields = 4
 )
 
 var (
@@ -35,6 +55,8 @@ var (
 		typing.FLOAT64:   string(bigquery.FloatFieldType),
 		typing.TIMESTAMP: string(bigquery.TimestampFieldType),
 		typing.BOOL:      string(bigquery.BooleanFieldType),
+		typing.JSON:      bigQueryJSONFieldType,
+		typing.ARRAY:     bigQueryRepeatedPrefix + string(bigquery.StringFieldType),
 		typing.UNKNOWN:   string(bigquery.StringFieldType),
 	}
 )
@@ -46,6 +68,7 @@ type BigQuery struct {
 	config      *GoogleConfig
 	queryLogger *logging.QueryLogger
 	sqlTypes    typing.SQLTypes
+	loadJobs    *loadJobsThrottle
 }
 
 // NewBigQuery return configured BigQuery adapter instance
@@ -63,16 +86,29 @@ func NewBigQuery(ctx context.Context, config *GoogleConfig, queryLogger *logging
 		return nil, fmt.Errorf("Error creating BigQuery client: %v", err)
 	}
 
-	return &BigQuery{ctx: ctx, client: client, config: config, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToBigQueryString)}, nil
+	return &BigQuery{ctx: ctx, client: client, config: config, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToBigQueryString),
+		loadJobs: newLoadJobsThrottle(loadJobMinInterval)}, nil
 }
 
 // Copy transfers data from google cloud storage file to google BigQuery table as one batch
 func (bq *BigQuery) Copy(fileKey, tableName string) error {
+	if err := bq.loadJobs.wait(bq.ctx, tableName); err != nil {
+		return err
+	}
+
 	table := bq.client.Dataset(bq.config.Dataset).Table(tableName)
+	return bq.runLoader(bq.gcsLoader(table, fileKey), tableName)
+}
 
+// gcsLoader returns loader of newline delimited JSON google cloud storage file into the table
+func (bq *BigQuery) gcsLoader(table *bigquery.Table, fileKey string) *bigquery.Loader {
 	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, fileKey))
 	gcsRef.SourceFormat = bigquery.JSON
-	loader := table.LoaderFrom(gcsRef)
+	return table.LoaderFrom(gcsRef)
+}
+
+// runLoader runs load job into existing table and waits its completion
+func (bq *BigQuery) runLoader(loader *bigquery.Loader, tableName string) error {
 	loader.CreateDisposition = bigquery.CreateNever
 
 	job, err := loader.Run(bq.ctx)
@@ -119,7 +155,7 @@ func (bq *BigQuery) Insert(insertContext *InsertContext) error {
 	if insertContext.eventContext != nil {
 		return bq.insertSingle(insertContext.eventContext)
 	} else {
-		return bq.insertBatch(insertContext.table, insertContext.objects)
+		return bq.insertBatch(insertContext.table, insertContext.objects, insertContext.merge)
 	}
 }
 
@@ -145,7 +181,11 @@ func (bq *BigQuery) GetTableSchema(tableName string) (*Table, error) {
 	}
 
 	for _, field := range meta.Schema {
-		table.Columns[field.Name] = typing.SQLColumn{Type: string(field.Type)}
+		fieldType := string(field.Type)
+		if field.Repeated {
+			fieldType = bigQueryRepeatedPrefix + fieldType
+		}
+		table.Columns[field.Name] = typing.SQLColumn{Type: fieldType}
 	}
 
 	return table, nil
@@ -174,28 +214,24 @@ func (bq *BigQuery) CreateTable(table *Table) error {
 	bqSchema := bigquery.Schema{}
 	for _, columnName := range table.SortedColumnNames() {
 		column := table.Columns[columnName]
-		bigQueryType := bigquery.FieldType(strings.ToUpper(column.DDLType()))
+		bigQueryType := column.DDLType()
 		sqlType, ok := bq.sqlTypes[columnName]
 		if ok {
-			bigQueryType = bigquery.FieldType(strings.ToUpper(sqlType.DDLType()))
+			bigQueryType = sqlType.DDLType()
 		}
-		bqSchema = append(bqSchema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+		bqSchema = append(bqSchema, bigQueryFieldSchema(columnName, bigQueryType))
 	}
 	bq.logQuery("Creating table for schema: ", bqSchema, true)
 	tableMetaData := bigquery.TableMetadata{Name: table.Name, Schema: bqSchema}
 	if table.Partition.Field != "" && table.Partition.Granularity != schema.ALL {
-		var partitioningType bigquery.TimePartitioningType
-		switch table.Partition.Granularity {
-		case schema.DAY:
-		case schema.WEEK:
-			partitioningType = bigquery.DayPartitioningType
-		case schema.MONTH:
-		case schema.QUARTER:
-			partitioningType = bigquery.MonthPartitioningType
-		case schema.YEAR:
-			partitioningType = bigquery.YearPartitioningType
+		tableMetaData.TimePartitioning = &bigquery.TimePartitioning{
+			Field:      table.Partition.Field,
+			Type:       bigQueryPartitioningType(table.Partition.Granularity),
+			Expiration: table.PartitionExpiration,
 		}
-		tableMetaData.TimePartitioning = &bigquery.TimePartitioning{Field: table.Partition.Field, Type: partitioningType}
+	}
+	if clusteringFields := bq.existingClusteringFields(table); len(clusteringFields) > 0 {
+		tableMetaData.Clustering = &bigquery.Clustering{Fields: clusteringFields}
 	}
 	if err := bqTable.Create(bq.ctx, &tableMetaData); err != nil {
 		schemaJson, _ := bqSchema.ToJSONFields()
@@ -212,6 +248,19 @@ func (bq *BigQuery) CreateTable(table *Table) error {
 	return nil
 }
 
+//existingClusteringFields returns table clustering fields if all of them exist in the table
+//otherwise returns nil: BigQuery doesn't create tables clustered by nonexistent columns
+func (bq *BigQuery) existingClusteringFields(table *Table) []string {
+	for _, field := range table.ClusteringFields {
+		if _, ok := table.Columns[field]; !ok {
+			logging.Warnf("[%s] Table %s is created without clustering: column %s doesn't exist", bq.destinationID(), table.Name, field)
+			return nil
+		}
+	}
+
+	return table.ClusteringFields
+}
+
 // CreateDataset creates google BigQuery Dataset if doesn't exist
 func (bq *BigQuery) CreateDataset(dataset string) error {
 	bqDataset := bq.client.Dataset(dataset)
@@ -252,12 +301,12 @@ func (bq *BigQuery) PatchTableSchema(patchSchema *Table) error {
 
 	for _, columnName := range patchSchema.SortedColumnNames() {
 		column := patchSchema.Columns[columnName]
-		bigQueryType := bigquery.FieldType(strings.ToUpper(column.DDLType()))
+		bigQueryType := column.DDLType()
 		sqlType, ok := bq.sqlTypes[columnName]
 		if ok {
-			bigQueryType = bigquery.FieldType(strings.ToUpper(sqlType.DDLType()))
+			bigQueryType = sqlType.DDLType()
 		}
-		metadata.Schema = append(metadata.Schema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+		metadata.Schema = append(metadata.Schema, bigQueryFieldSchema(columnName, bigQueryType))
 	}
 	updateReq := bigquery.TableMetadataToUpdate{Schema: metadata.Schema}
 	bq.logQuery("Patch update request: ", updateReq, true)
@@ -301,6 +350,21 @@ func (bq *BigQuery) DeletePartition(tableName string, datePartiton *base.DatePar
 	return nil
 }
 
+//bigQueryPartitioningType returns the closest BigQuery time partitioning type
+//BigQuery supports only HOUR, DAY, MONTH and YEAR partitions
+func bigQueryPartitioningType(g schema.Granularity) bigquery.TimePartitioningType {
+	switch g {
+	case schema.HOUR:
+		return bigquery.HourPartitioningType
+	case schema.MONTH, schema.QUARTER:
+		return bigquery.MonthPartitioningType
+	case schema.YEAR:
+		return bigquery.YearPartitioningType
+	default:
+		return bigquery.DayPartitioningType
+	}
+}
+
 func GranularityToPartitionIds(g schema.Granularity, t time.Time) []string {
 	t = g.Lower(t)
 	switch g {
@@ -335,7 +399,7 @@ func (bq *BigQuery) insertSingle(eventContext *EventContext) error {
 	inserter := bq.client.Dataset(bq.config.Dataset).Table(eventContext.Table.Name).Inserter()
 	bq.logQuery(fmt.Sprintf("Inserting values to table %s: ", eventContext.Table.Name), eventContext.ProcessedEvent, false)
 
-	if err := bq.insertItems(inserter, []*BQItem{{values: eventContext.ProcessedEvent}}); err != nil {
+	if err := bq.insertItems(inserter, []*BQItem{{values: eventContext.ProcessedEvent, columns: eventContext.Table.Columns}}); err != nil {
 		return errorj.ExecuteInsertError.Wrap(err, "failed to execute single insert").
 			WithProperty(errorj.DBInfo, &ErrorPayload{
 				Dataset:         bq.config.Dataset,
@@ -349,50 +413,205 @@ func (bq *BigQuery) insertSingle(eventContext *EventContext) error {
 	return nil
 }
 
-// insertBatch streams data into BQ using stream API
-// 1 insert = max 500 rows
-func (bq *BigQuery) insertBatch(table *Table, objects []map[string]interface{}) error {
-	inserter := bq.client.Dataset(bq.config.Dataset).Table(table.Name).Inserter()
-	bq.logQuery(fmt.Sprintf("Inserting [%d] values to table %s using BigQuery Streaming API with chunks [%d]: ", len(objects), table.Name, rowsLimitPerInsertOperation), objects, false)
-
-	items := make([]*BQItem, 0, rowsLimitPerInsertOperation)
-	operation := 0
-	operations := int(math.Max(1, float64(len(objects))/float64(rowsLimitPerInsertOperation)))
-	for _, object := range objects {
-		if len(items) > rowsLimitPerInsertOperation {
-			operation++
-			if err := bq.insertItems(inserter, items); err != nil {
-				return errorj.ExecuteInsertInBatchError.Wrap(err, "failed to execute middle insert %d of %d in batch", operation, operations).
-					WithProperty(errorj.DBInfo, &ErrorPayload{
-						Dataset: bq.config.Dataset,
-						Bucket:  bq.config.Bucket,
-						Project: bq.config.Project,
-						Table:   table.Name,
-					})
-			}
+// insertBatch loads data into BQ with a load job from in-memory newline delimited JSON payload
+// load jobs are free (unlike streaming inserts) and loaded rows are available for DML right after the job is done
+// if merge is true and table has primary keys, data is merged with the MERGE statement via staging table
+func (bq *BigQuery) insertBatch(table *Table, objects []map[string]interface{}, merge bool) error {
+	payload, err := bq.LoadPayload(table, objects, merge)
+	if err != nil {
+		return errorj.ExecuteInsertInBatchError.Wrap(err, "failed to build load job payload").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:      bq.config.Dataset,
+				Project:      bq.config.Project,
+				Table:        table.Name,
+				TotalObjects: len(objects),
+			})
+	}
+
+	bq.queryLogger.LogQuery(fmt.Sprintf("Loading [%d] values to table %s using BigQuery load job", len(objects), table.Name))
+	return bq.loadWithMerge(table, merge, func(bqTable *bigquery.Table) *bigquery.Loader {
+		source := bigquery.NewReaderSource(bytes.NewReader(payload))
+		source.SourceFormat = bigquery.JSON
+		return bqTable.LoaderFrom(source)
+	})
+}
+
+// LoadPayload returns newline delimited JSON payload for load jobs
+// if payload is merged and table has primary keys only the last object with the same primary key values is kept (MERGE fails on duplicates)
+func (bq *BigQuery) LoadPayload(table *Table, objects []map[string]interface{}, merge bool) ([]byte, error) {
+	if merge && len(table.PKFields) > 0 {
+		objects = lastObjectsByPrimaryKey(table, objects)
+	}
 
-			items = make([]*BQItem, 0, rowsLimitPerInsertOperation)
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	for _, object := range objects {
+		row := make(map[string]interface{}, len(object))
+		for name, value := range object {
+			row[name] = bigQueryLoadValue(table.Columns[name], value)
+		}
+		if err := encoder.Encode(row); err != nil {
+			return nil, err
 		}
+	}
+
+	return buf.Bytes(), nil
+}
 
-		items = append(items, &BQItem{values: object})
+// CopyWithMerge transfers data from google cloud storage file to google BigQuery table
+// if table has primary keys, the file is loaded into the staging table and merged into the table
+func (bq *BigQuery) CopyWithMerge(fileKey string, table *Table) error {
+	return bq.loadWithMerge(table, true, func(bqTable *bigquery.Table) *bigquery.Loader {
+		return bq.gcsLoader(bqTable, fileKey)
+	})
+}
+
+// loadWithMerge runs loader into the table or (if merge is true and there are primary keys)
+// into the staging table with the same schema and merges staging table into the table
+// load job isn't started earlier than loadJobMinInterval after the previous load job into the table
+func (bq *BigQuery) loadWithMerge(table *Table, merge bool, loaderFunc func(bqTable *bigquery.Table) *bigquery.Loader) error {
+	if err := bq.loadJobs.wait(bq.ctx, table.Name); err != nil {
+		return err
 	}
 
-	if len(items) > 0 {
-		operation++
-		if err := bq.insertItems(inserter, items); err != nil {
-			return errorj.ExecuteInsertInBatchError.Wrap(err, "failed to execute last insert %d of %d in batch", operation, operations).
-				WithProperty(errorj.DBInfo, &ErrorPayload{
-					Dataset: bq.config.Dataset,
-					Bucket:  bq.config.Bucket,
-					Project: bq.config.Project,
-					Table:   table.Name,
-				})
+	dataset := bq.client.Dataset(bq.config.Dataset)
+	if !merge || len(table.PKFields) == 0 {
+		return bq.runLoader(loaderFunc(dataset.Table(table.Name)), table.Name)
+	}
+
+	metadata, err := dataset.Table(table.Name).Metadata(bq.ctx)
+	if err != nil {
+		return errorj.GetTableError.Wrap(err, "failed to get table metadata").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset: bq.config.Dataset,
+				Project: bq.config.Project,
+				Table:   table.Name,
+			})
+	}
+
+	stagingTableName := fmt.Sprintf("jitsu_tmp_%s_%s", table.Name, uuid.NewLettersNumbers()[:5])
+	stagingTable := dataset.Table(stagingTableName)
+	//staging table is removed by BigQuery even if it wasn't dropped because of an error
+	stagingMetadata := &bigquery.TableMetadata{Name: stagingTableName, Schema: metadata.Schema, ExpirationTime: timestamp.Now().Add(stagingTableExpiration)}
+	bq.logQuery("Creating staging table for schema: ", metadata.Schema, true)
+	if err := stagingTable.Create(bq.ctx, stagingMetadata); err != nil {
+		return errorj.CreateTableError.Wrap(err, "failed to create staging table").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset: bq.config.Dataset,
+				Project: bq.config.Project,
+				Table:   stagingTableName,
+			})
+	}
+
+	defer func() {
+		if err := stagingTable.Delete(bq.ctx); err != nil && !isNotFoundErr(err) {
+			logging.Warnf("[%s] Error dropping BigQuery staging table %s: %v", bq.destinationID(), stagingTableName, err)
 		}
+	}()
+
+	if err := bq.runLoader(loaderFunc(stagingTable), stagingTableName); err != nil {
+		return err
+	}
+
+	query := bq.mergeQuery(table, stagingTableName)
+	bq.queryLogger.LogQuery(query)
+	if err := bq.runQuery(query); err != nil {
+		return errorj.BulkMergeError.Wrap(err, "failed to merge staging table").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:     bq.config.Dataset,
+				Project:     bq.config.Project,
+				Table:       table.Name,
+				PrimaryKeys: table.GetPKFields(),
+				Statement:   query,
+			})
 	}
 
 	return nil
 }
 
+// loadJobsThrottle delays load jobs into the same table so they aren't started more often than minInterval
+type loadJobsThrottle struct {
+	mutex       sync.Mutex
+	minInterval time.Duration
+	//nextJobs is table name -> time when the next load job into the table is allowed
+	nextJobs map[string]time.Time
+}
+
+func newLoadJobsThrottle(minInterval time.Duration) *loadJobsThrottle {
+	return &loadJobsThrottle{minInterval: minInterval, nextJobs: map[string]time.Time{}}
+}
+
+// wait blocks until load job into the table is allowed. Concurrent load jobs into the same table are queued
+// returns err if context is done
+func (ljt *loadJobsThrottle) wait(ctx context.Context, tableName string) error {
+	if ljt == nil {
+		return nil
+	}
+
+	ljt.mutex.Lock()
+	now := timestamp.Now()
+	for name, next := range ljt.nextJobs {
+		if next.Before(now) {
+			delete(ljt.nextJobs, name)
+		}
+	}
+	start := now
+	if next, ok := ljt.nextJobs[tableName]; ok && next.After(now) {
+		start = next
+	}
+	ljt.nextJobs[tableName] = start.Add(ljt.minInterval)
+	ljt.mutex.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	logging.Debugf("BigQuery load job into %s is delayed for %s (min interval between load jobs into the same table is %s)", tableName, delay, ljt.minInterval)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mergeQuery returns MERGE statement which upserts rows from staging table into the table by primary keys
+func (bq *BigQuery) mergeQuery(table *Table, stagingTableName string) string {
+	pkFields := table.GetPKFields()
+	sort.Strings(pkFields)
+
+	var onConditions, updateSet, columns, values []string
+	for _, pkField := range pkFields {
+		onConditions = append(onConditions, fmt.Sprintf("T.`%s` = S.`%s`", pkField, pkField))
+	}
+	for _, name := range table.SortedColumnNames() {
+		updateSet = append(updateSet, fmt.Sprintf("`%s` = S.`%s`", name, name))
+		columns = append(columns, fmt.Sprintf("`%s`", name))
+		values = append(values, fmt.Sprintf("S.`%s`", name))
+	}
+
+	return fmt.Sprintf(mergeBigQueryTemplate, bq.config.Project, bq.config.Dataset, table.Name, bq.config.Project, bq.config.Dataset, stagingTableName,
+		strings.Join(onConditions, " AND "), strings.Join(updateSet, ", "), strings.Join(columns, ", "), strings.Join(values, ", "))
+}
+
+// runQuery runs query job and waits its completion
+func (bq *BigQuery) runQuery(query string) error {
+	job, err := bq.client.Query(query).Run(bq.ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := job.Wait(bq.ctx)
+	if err != nil {
+		return err
+	}
+
+	return status.Err()
+}
+
 // DropTable drops table from BigQuery
 func (bq *BigQuery) DropTable(table *Table) error {
 	bqTable := bq.client.Dataset(bq.config.Dataset).Table(table.Name)
@@ -472,6 +691,45 @@ func (bq *BigQuery) Truncate(tableName string) error {
 	return nil
 }
 
+//Query executes read query and returns result rows
+func (bq *BigQuery) Query(query string) ([]map[string]interface{}, error) {
+	bq.queryLogger.LogQuery(query)
+	rowIterator, err := bq.client.Query(query).Read(bq.ctx)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(err, "failed to execute query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:   bq.config.Dataset,
+				Project:   bq.config.Project,
+				Statement: query,
+			})
+	}
+
+	var result []map[string]interface{}
+	for {
+		values := map[string]bigquery.Value{}
+		err := rowIterator.Next(&values)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errorj.QueryError.Wrap(err, "failed to read query result").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Dataset:   bq.config.Dataset,
+					Project:   bq.config.Project,
+					Statement: query,
+				})
+		}
+
+		row := make(map[string]interface{}, len(values))
+		for name, value := range values {
+			row[name] = value
+		}
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
 func (bq *BigQuery) insertItems(inserter *bigquery.Inserter, items []*BQItem) error {
 	if err := inserter.Put(bq.ctx, items); err != nil {
 		var multiErr error
@@ -523,21 +781,153 @@ func isNotFoundErr(err error) bool {
 	return ok && e.Code == http.StatusNotFound
 }
 
+//bigQueryFieldSchema returns field schema from column type. 'REPEATED ' prefix marks array fields
+func bigQueryFieldSchema(name, columnType string) *bigquery.FieldSchema {
+	columnType = strings.ToUpper(strings.TrimSpace(columnType))
+	repeated := strings.HasPrefix(columnType, bigQueryRepeatedPrefix)
+	if repeated {
+		columnType = strings.TrimSpace(strings.TrimPrefix(columnType, bigQueryRepeatedPrefix))
+	}
+
+	return &bigquery.FieldSchema{Name: name, Type: bigquery.FieldType(columnType), Repeated: repeated}
+}
+
+//bigQueryLoadValue returns value for load job payload
+//arrays are written as is into REPEATED columns, objects are written as is into JSON columns
+//other objects and arrays are serialized into JSON strings
+func bigQueryLoadValue(column typing.SQLColumn, value interface{}) interface{} {
+	columnType := strings.ToUpper(column.Type)
+	if strings.HasPrefix(columnType, bigQueryRepeatedPrefix) {
+		if arr, ok := stringsArray(value); ok {
+			return arr
+		}
+	}
+	if columnType == bigQueryJSONFieldType {
+		return value
+	}
+
+	return jsonValue(value)
+}
+
+//lastObjectsByPrimaryKey returns objects without duplicates by primary key fields. The last object is kept
+func lastObjectsByPrimaryKey(table *Table, objects []map[string]interface{}) []map[string]interface{} {
+	pkFields := table.GetPKFields()
+	indexes := map[string]int{}
+	var result []map[string]interface{}
+	for _, object := range objects {
+		var key strings.Builder
+		for _, pkField := range pkFields {
+			key.WriteString(fmt.Sprint(object[pkField]))
+			key.WriteString("|")
+		}
+		if i, ok := indexes[key.String()]; ok {
+			result[i] = object
+			continue
+		}
+		indexes[key.String()] = len(result)
+		result = append(result, object)
+	}
+
+	return result
+}
+
+func (bq *BigQuery) destinationID() interface{} {
+	return bq.ctx.Value(CtxDestinationId)
+}
+
 // BQItem struct for streaming inserts to BigQuery
 type BQItem struct {
-	values map[string]interface{}
+	values  map[string]interface{}
+	columns Columns
 }
 
+//Save returns row for streaming insert
+//arrays are written as is into REPEATED columns, other objects and arrays are serialized into JSON strings
 func (bqi *BQItem) Save() (row map[string]bigquery.Value, insertID string, err error) {
 	row = map[string]bigquery.Value{}
 
 	for k, v := range bqi.values {
-		row[k] = v
+		if strings.HasPrefix(strings.ToUpper(bqi.columns[k].Type), bigQueryRepeatedPrefix) {
+			if arr, ok := stringsArray(v); ok {
+				row[k] = arr
+				continue
+			}
+		}
+		row[k] = jsonValue(v)
 	}
 
 	return
 }
 
+//DeleteExpired counts (in dry run mode) or deletes rows which are older than expiredBefore with DML statement
+//tables which are created with partitioning retention_days are expired by BigQuery itself
+func (bq *BigQuery) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if err := requireRetentionTable(rule); err != nil {
+		return nil, err
+	}
+
+	parameters := []bigquery.QueryParameter{{Name: "expired_before", Value: expiredBefore.UTC()}}
+	report := &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore}
+	query := fmt.Sprintf(deleteExpiredBQTemplate, bq.config.Project, bq.config.Dataset, rule.Table, rule.Field)
+	if dryRun {
+		query = fmt.Sprintf(countExpiredBQTemplate, bq.config.Project, bq.config.Dataset, rule.Table, rule.Field)
+	}
+	bq.logQuery(query+" with parameters: ", parameters, false)
+
+	q := bq.client.Query(query)
+	q.Parameters = parameters
+	job, err := q.Run(bq.ctx)
+	if err == nil {
+		var status *bigquery.JobStatus
+		status, err = job.Wait(bq.ctx)
+		if err == nil {
+			err = status.Err()
+		}
+		if err == nil {
+			report.Rows, err = bq.expiredRows(job, dryRun)
+		}
+	}
+	if err != nil {
+		return nil, errorj.DeleteFromTableError.Wrap(err, "failed to delete expired rows").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Dataset:   bq.config.Dataset,
+				Project:   bq.config.Project,
+				Table:     rule.Table,
+				Statement: query,
+			})
+	}
+
+	return report, nil
+}
+
+//expiredRows returns COUNT(*) result in dry run mode or amount of deleted rows from DML job statistics
+func (bq *BigQuery) expiredRows(job *bigquery.Job, dryRun bool) (int64, error) {
+	if !dryRun {
+		if statistics, ok := job.LastStatus().Statistics.Details.(*bigquery.QueryStatistics); ok {
+			return statistics.NumDMLAffectedRows, nil
+		}
+		return -1, nil
+	}
+
+	rowIterator, err := job.Read(bq.ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var row []bigquery.Value
+	if err := rowIterator.Next(&row); err != nil {
+		return 0, err
+	}
+	if len(row) == 0 {
+		return 0, nil
+	}
+
+	rows, _ := row[0].(int64)
+	return rows, nil
+}
+
+// Update isn't supported: BigQuery doesn't allow DML statements on rows which are in the streaming buffer
+// users recognition in batch mode merges recognized events via staging tables
 func (bq *BigQuery) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
 	return errors.New("BigQuery doesn't support updates")
 }
@@ -0,0 +1,99 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigQueryMergeQuery(t *testing.T) {
+	bq := &BigQuery{config: &GoogleConfig{Project: "project", Dataset: "dataset"}}
+	table := &Table{
+		Name:     "events",
+		Columns:  Columns{"id": typing.SQLColumn{Type: "INTEGER"}, "email": typing.SQLColumn{Type: "STRING"}, "name": typing.SQLColumn{Type: "STRING"}},
+		PKFields: map[string]bool{"id": true, "email": true},
+	}
+
+	require.Equal(t, "MERGE `project.dataset.events` T USING `project.dataset.jitsu_tmp_events` S ON T.`email` = S.`email` AND T.`id` = S.`id` "+
+		"WHEN MATCHED THEN UPDATE SET `email` = S.`email`, `id` = S.`id`, `name` = S.`name` "+
+		"WHEN NOT MATCHED THEN INSERT (`email`, `id`, `name`) VALUES (S.`email`, S.`id`, S.`name`)",
+		bq.mergeQuery(table, "jitsu_tmp_events"))
+}
+
+func TestBigQueryLoadPayload(t *testing.T) {
+	bq := &BigQuery{config: &GoogleConfig{Project: "project", Dataset: "dataset"}}
+	tests := []struct {
+		name     string
+		table    *Table
+		objects  []map[string]interface{}
+		merge    bool
+		expected string
+	}{
+		{
+			"without primary keys",
+			&Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "INTEGER"}}},
+			[]map[string]interface{}{{"id": 1}, {"id": 1}},
+			true,
+			"{\"id\":1}\n{\"id\":1}\n",
+		},
+		{
+			"last object by primary key is kept",
+			&Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "INTEGER"}, "name": typing.SQLColumn{Type: "STRING"}}, PKFields: map[string]bool{"id": true}},
+			[]map[string]interface{}{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 1, "name": "c"}},
+			true,
+			"{\"id\":1,\"name\":\"c\"}\n{\"id\":2,\"name\":\"b\"}\n",
+		},
+		{
+			"objects aren't deduplicated without merge",
+			&Table{Name: "events", Columns: Columns{"id": typing.SQLColumn{Type: "INTEGER"}, "name": typing.SQLColumn{Type: "STRING"}}, PKFields: map[string]bool{"id": true}},
+			[]map[string]interface{}{{"id": 1, "name": "a"}, {"id": 1, "name": "c"}},
+			false,
+			"{\"id\":1,\"name\":\"a\"}\n{\"id\":1,\"name\":\"c\"}\n",
+		},
+		{
+			"complex values",
+			&Table{Name: "events", Columns: Columns{"tags": typing.SQLColumn{Type: "REPEATED STRING"}, "traits": typing.SQLColumn{Type: "JSON"}, "raw": typing.SQLColumn{Type: "STRING"}}},
+			[]map[string]interface{}{{"tags": []interface{}{"a", 1}, "traits": map[string]interface{}{"k": "v"}, "raw": []interface{}{1}}},
+			false,
+			"{\"raw\":\"[1]\",\"tags\":[\"a\",\"1\"],\"traits\":{\"k\":\"v\"}}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := bq.LoadPayload(tt.table, tt.objects, tt.merge)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(payload))
+		})
+	}
+}
+
+func TestBigQueryLoadJobsThrottle(t *testing.T) {
+	throttle := newLoadJobsThrottle(100 * time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, throttle.wait(context.Background(), "events"))
+	require.NoError(t, throttle.wait(context.Background(), "users"))
+	require.Less(t, int64(time.Since(start)), int64(50*time.Millisecond), "load jobs into different tables mustn't be delayed")
+
+	require.NoError(t, throttle.wait(context.Background(), "events"))
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond), "load job into the same table must be delayed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, throttle.wait(ctx, "events"))
+
+	var nilThrottle *loadJobsThrottle
+	require.NoError(t, nilThrottle.wait(context.Background(), "events"))
+}
+
+func TestBigQueryExistingClusteringFields(t *testing.T) {
+	bq := &BigQuery{ctx: context.Background(), config: &GoogleConfig{Project: "project", Dataset: "dataset"}}
+	columns := Columns{"user_id": typing.SQLColumn{Type: "STRING"}, "event_type": typing.SQLColumn{Type: "STRING"}}
+
+	require.Equal(t, []string{"user_id", "event_type"}, bq.existingClusteringFields(&Table{Name: "events", Columns: columns, ClusteringFields: []string{"user_id", "event_type"}}))
+	require.Nil(t, bq.existingClusteringFields(&Table{Name: "events", Columns: columns, ClusteringFields: []string{"user_id", "url"}}))
+	require.Nil(t, bq.existingClusteringFields(&Table{Name: "events", Columns: columns}))
+}
@@ -0,0 +1,246 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	//brazeMaxBatchSize is a max count of attributes, events and purchases objects (each) in one /users/track request
+	brazeMaxBatchSize = 75
+	//BrazeAnonymousAliasLabel is a label of user aliases which are created for anonymous users
+	BrazeAnonymousAliasLabel = "jitsu_anonymous_id"
+)
+
+//brazeTrackArrays are arrays of /users/track request
+var brazeTrackArrays = []string{"attributes", "events", "purchases"}
+
+//BrazeResponse is a dto for receiving response from Braze
+type BrazeResponse struct {
+	Message string `json:"message"`
+	Errors  []struct {
+		Type       string `json:"type"`
+		InputArray string `json:"input_array"`
+		Index      int    `json:"index"`
+	} `json:"errors,omitempty"`
+}
+
+//BrazeRequestFactory is a factory for building Braze HTTP requests from input events
+//attributes, events and purchases are sent with /users/track API, anonymous users merges (aliases_to_identify) with /users/identify API
+type BrazeRequestFactory struct {
+	apiKey   string
+	endpoint string
+}
+
+//newBrazeRequestFactory returns configured HTTPRequestFactory instance for braze requests
+func newBrazeRequestFactory(config *BrazeConfig) (*BrazeRequestFactory, error) {
+	return &BrazeRequestFactory{apiKey: config.APIKey, endpoint: strings.TrimSuffix(config.Endpoint, "/")}, nil
+}
+
+//Create returns created braze request depends on object type
+func (brf *BrazeRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	if aliases, ok := object["aliases_to_identify"]; ok {
+		return brf.request("/users/identify", map[string]interface{}{"aliases_to_identify": aliases})
+	}
+
+	//other fields (e.g. injected event ID) aren't sent
+	body := map[string]interface{}{}
+	for _, name := range brazeTrackArrays {
+		if items, ok := object[name]; ok && items != nil {
+			body[name] = items
+		}
+	}
+	if len(body) == 0 {
+		return nil, errors.New("braze object must contain 'attributes', 'events', 'purchases' or 'aliases_to_identify' field")
+	}
+
+	return brf.request("/users/track", body)
+}
+
+func (brf *BrazeRequestFactory) request(path string, body map[string]interface{}) (*Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling braze request [%v]: %v", body, err)
+	}
+
+	return &Request{
+		URL:     brf.endpoint + path,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + brf.apiKey, "user-agent": JitsuUserAgent},
+	}, nil
+}
+
+//BatchKey returns key of /users/track requests. Merges aren't batched
+func (brf *BrazeRequestFactory) BatchKey(req *Request) string {
+	if !strings.HasSuffix(req.URL, "/users/track") {
+		return ""
+	}
+
+	return requestBatchKey(req)
+}
+
+//MaxBatchSize returns Braze /users/track limit of objects of each type in one request
+func (brf *BrazeRequestFactory) MaxBatchSize() int {
+	return brazeMaxBatchSize
+}
+
+//CreateBatch returns /users/track request with objects of all requests
+//every request contains one object of a type so arrays don't exceed the limit
+func (brf *BrazeRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	batch := map[string][]json.RawMessage{}
+	for _, req := range requests {
+		trackRequest := map[string][]json.RawMessage{}
+		if err := json.Unmarshal(req.Body, &trackRequest); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling braze request: %v", err)
+		}
+		for name, items := range trackRequest {
+			batch[name] = append(batch[name], items...)
+		}
+	}
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling braze batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns nil: Braze processes valid objects and reports invalid ones by array index
+//which can't be mapped to requests if they contain several objects
+func (brf *BrazeRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	return nil
+}
+
+func (brf *BrazeRequestFactory) Close() {
+}
+
+//BrazeMergeObject returns /users/identify object which merges anonymous user alias profile into the identified user
+//external_id is taken from the object (transformed event of the identified user). Returns nil if the object doesn't have it
+func BrazeMergeObject(anonymousID string, object map[string]interface{}) map[string]interface{} {
+	for _, name := range brazeTrackArrays {
+		items, _ := object[name].([]interface{})
+		for _, item := range items {
+			itemObject, _ := item.(map[string]interface{})
+			if externalID, ok := itemObject["external_id"]; ok && externalID != nil && fmt.Sprint(externalID) != "" {
+				return map[string]interface{}{
+					"aliases_to_identify": []interface{}{map[string]interface{}{
+						"external_id": fmt.Sprint(externalID),
+						"user_alias":  map[string]interface{}{"alias_name": anonymousID, "alias_label": BrazeAnonymousAliasLabel},
+					}},
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+//BrazeConfig is a dto for parsing Braze configuration
+type BrazeConfig struct {
+	//APIKey is a REST API key with users.track and users.identify permissions
+	APIKey string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	//Endpoint is a REST endpoint of the Braze instance (e.g. https://rest.iad-01.braze.com)
+	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	//AppID is an optional app identifier which is added to events
+	AppID string           `mapstructure:"app_id" json:"app_id,omitempty" yaml:"app_id,omitempty"`
+	Batch *HTTPBatchConfig `mapstructure:"batch" json:"batch,omitempty" yaml:"batch,omitempty"`
+}
+
+//Validate returns err if invalid
+func (bc *BrazeConfig) Validate() error {
+	if bc == nil {
+		return errors.New("braze config is required")
+	}
+	if bc.APIKey == "" {
+		return errors.New("'api_key' is required parameter")
+	}
+	if bc.Endpoint == "" {
+		return errors.New("'endpoint' is required parameter")
+	}
+
+	return bc.Batch.Validate()
+}
+
+//Braze is an adapter for sending HTTP requests to Braze
+type Braze struct {
+	AbstractHTTP
+
+	config *BrazeConfig
+}
+
+//NewBraze returns configured Braze adapter instance
+func NewBraze(config *BrazeConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*Braze, error) {
+	httpReqFactory, err := newBrazeRequestFactory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Braze{config: config}
+	b.httpAdapter = httpAdapter
+	return b, nil
+}
+
+//NewTestBraze returns test instance of adapter
+func NewTestBraze(config *BrazeConfig) *Braze {
+	return &Braze{config: config}
+}
+
+//TestAccess sends empty /users/track request to Braze and checks the API key
+func (b *Braze) TestAccess() error {
+	httpReqFactory, err := newBrazeRequestFactory(b.config)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, httpReqFactory.endpoint+"/users/track", bytes.NewBufferString(`{"attributes":[]}`))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Add("Content-Type", "application/json")
+	httpReq.Header.Add("Authorization", "Bearer "+b.config.APIKey)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	//empty request is a bad request but it is authorized
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode >= http.StatusInternalServerError {
+		responseBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("Error reading braze response body: %v", err)
+		}
+
+		response := &BrazeResponse{}
+		if err := json.Unmarshal(responseBody, response); err != nil || response.Message == "" {
+			return fmt.Errorf("error connecting to braze [code=%d]: %s", resp.StatusCode, string(responseBody))
+		}
+		return fmt.Errorf("error connecting to braze [code=%d]: %s", resp.StatusCode, response.Message)
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (b *Braze) Type() string {
+	return "Braze"
+}
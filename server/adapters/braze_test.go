@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrazeBatch(t *testing.T) {
+	config := &BrazeConfig{APIKey: "key", Endpoint: "https://rest.iad-01.braze.com/"}
+	require.NoError(t, config.Validate())
+	require.EqualError(t, (&BrazeConfig{APIKey: "key"}).Validate(), "'endpoint' is required parameter")
+	factory, err := newBrazeRequestFactory(config)
+	require.NoError(t, err)
+
+	first, err := factory.Create(map[string]interface{}{"events": []interface{}{map[string]interface{}{"external_id": "u1", "name": "pageview"}}, "eventn_ctx_event_id": "1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://rest.iad-01.braze.com/users/track", first.URL)
+	require.Equal(t, "Bearer key", first.Headers["Authorization"])
+	require.JSONEq(t, `{"events":[{"external_id":"u1","name":"pageview"}]}`, string(first.Body), "only track arrays must be sent")
+	second, err := factory.Create(map[string]interface{}{"attributes": []interface{}{map[string]interface{}{"external_id": "u1", "plan": "pro"}}})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+
+	merge := BrazeMergeObject("a1", map[string]interface{}{"attributes": []interface{}{map[string]interface{}{"external_id": "u1", "plan": "pro"}}})
+	require.NotNil(t, merge)
+	identify, err := factory.Create(merge)
+	require.NoError(t, err)
+	require.Equal(t, "https://rest.iad-01.braze.com/users/identify", identify.URL)
+	require.JSONEq(t, `{"aliases_to_identify":[{"external_id":"u1","user_alias":{"alias_name":"a1","alias_label":"jitsu_anonymous_id"}}]}`, string(identify.Body))
+	require.Empty(t, factory.BatchKey(identify), "merges can't be batched")
+	require.Nil(t, BrazeMergeObject("a1", map[string]interface{}{"events": []interface{}{map[string]interface{}{"user_alias": map[string]interface{}{"alias_name": "a1"}}}}), "anonymous events can't be merged")
+
+	_, err = factory.Create(map[string]interface{}{"name": "pageview"})
+	require.EqualError(t, err, "braze object must contain 'attributes', 'events', 'purchases' or 'aliases_to_identify' field")
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, first.URL, batch.URL)
+	require.JSONEq(t, `{"events":[{"external_id":"u1","name":"pageview"}],"attributes":[{"external_id":"u1","plan":"pro"}]}`, string(batch.Body))
+}
@@ -9,8 +9,11 @@ import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"io/ioutil"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jitsucom/jitsu/server/errorj"
@@ -32,14 +35,19 @@ const (
 	onClusterCHClauseTemplate = ` ON CLUSTER "%s" `
 	columnCHNullableTemplate  = ` Nullable(%s) `
 
-	createTableCHTemplate              = `CREATE TABLE "%s"."%s" %s (%s) %s %s %s %s`
+	createTableCHTemplate              = `CREATE TABLE "%s"."%s" %s (%s) %s %s %s %s %s`
 	createDistributedTableCHTemplate   = `CREATE TABLE "%s"."dist_%s" %s AS "%s"."%s" ENGINE = Distributed(%s,%s,%s,rand())`
 	dropDistributedTableCHTemplate     = `DROP TABLE IF EXISTS "%s"."dist_%s" %s`
 	alterDistributedTableCHTemplate    = `ALTER TABLE "%s"."dist_%s" %s %s`
 	exchangeDistributedTableCHTemplate = `EXCHANGE TABLES "%s"."dist_%s" AND "%s"."dist_%s"`
 	renameDistributedTableCHTemplate   = `RENAME TABLE "%s"."dist_%s" TO "dist_%s"`
 
+	tableEngineCHQuery                 = `SELECT engine FROM system.tables WHERE database = ? AND name = ?`
 	truncateTableCHTemplate            = `TRUNCATE TABLE IF EXISTS "%s"."%s"`
+	countExpiredCHTemplate             = `SELECT count() FROM "%s"."%s" WHERE "%s" < ?`
+	deleteExpiredCHTemplate            = `ALTER TABLE "%s"."%s" %s DELETE WHERE "%s" < ?`
+	ttlCHTemplate                      = `TTL toDateTime("%s") + INTERVAL %d DAY`
+	chDateTimeLayout                   = "2006-01-02 15:04:05"
 	truncateDistributedTableCHTemplate = `TRUNCATE TABLE IF EXISTS "%s"."dist_%s" %s`
 
 	defaultPartition  = `PARTITION BY (toYYYYMM(_timestamp))`
@@ -48,15 +56,20 @@ const (
 )
 
 var (
+	//SchemaToClickhouse is mapping between JSON types and ClickHouse types
+	//JSON type is experimental in ClickHouse so objects are written into String columns as JSON strings
 	SchemaToClickhouse = map[typing.DataType]string{
 		typing.STRING:    "String",
 		typing.INT64:     "Int64",
 		typing.FLOAT64:   "Float64",
 		typing.TIMESTAMP: "DateTime",
 		typing.BOOL:      "UInt8",
+		typing.JSON:      "String",
+		typing.ARRAY:     "Array(String)",
 		typing.UNKNOWN:   "String",
 	}
 
+
 	defaultValues = map[string]interface{}{
 		"int8":                     0,
 		"int16":                    0,
@@ -76,6 +89,8 @@ var (
 		"uint128":                  0,
 		"uint256":                  0,
 		"string":                   "",
+		"json":                     "{}",
+		"array(string)":            clickhouse.Array([]string{}),
 		"lowcardinality(int8)":     0,
 		"lowcardinality(int16)":    0,
 		"lowcardinality(int32)":    0,
@@ -103,6 +118,8 @@ type ClickHouseConfig struct {
 	TLS      map[string]string `mapstructure:"tls,omitempty" json:"tls,omitempty" yaml:"tls,omitempty"`
 	Cluster  string            `mapstructure:"cluster,omitempty" json:"cluster,omitempty" yaml:"cluster,omitempty"`
 	Engine   *EngineConfig     `mapstructure:"engine,omitempty" json:"engine,omitempty" yaml:"engine,omitempty"`
+	//SSHTunnel is applied to every DSN: ClickHouse node host:port is reached through the SSH server
+	SSHTunnel *SSHTunnelConfig `mapstructure:"ssh_tunnel,omitempty" json:"ssh_tunnel,omitempty" yaml:"ssh_tunnel,omitempty"`
 }
 
 // EngineConfig dto for deserialized clickhouse engine config
@@ -148,6 +165,10 @@ func (chc *ClickHouseConfig) Validate() error {
 		return errors.New("db is required parameter")
 	}
 
+	if err := chc.SSHTunnel.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -162,6 +183,12 @@ type TableStatementFactory struct {
 	primaryKeyClause string
 
 	engineStatementFormat bool
+	rawStatement          bool
+	//ttlClauses is a map of table name -> TTL clause from retention rules
+	ttlClauses map[string]string
+	//defaultSortingKey is true if sorting key isn't configured (order_fields, primary_keys and raw_statement)
+	//in this case tables with primary_key_fields are ordered by them so ReplacingMergeTree deduplicates rows by primary keys
+	defaultSortingKey bool
 }
 
 func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory, error) {
@@ -183,6 +210,7 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 				engineStatement: config.Engine.RawStatement,
 				database:        config.Database,
 				onClusterClause: onClusterClause,
+				rawStatement:    true,
 			}, nil
 		}
 
@@ -216,17 +244,48 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		orderByClause:         orderByClause,
 		primaryKeyClause:      primaryKeyClause,
 		engineStatementFormat: engineStatementFormat,
+		defaultSortingKey:     orderByClause == defaultOrderBy && primaryKeyClause == defaultPrimaryKey,
 	}, nil
 }
 
+// SetRetentionRules adds TTL clause to CREATE TABLE statements of tables with retention rules
+// raw_statement isn't modified
+func (tsf *TableStatementFactory) SetRetentionRules(rules []*RetentionRule) {
+	if tsf.rawStatement {
+		return
+	}
+
+	tsf.ttlClauses = map[string]string{}
+	for _, rule := range rules {
+		if rule.Table != "" {
+			tsf.ttlClauses[rule.Table] = fmt.Sprintf(ttlCHTemplate, rule.Field, rule.Days())
+		}
+	}
+}
+
+// IsReplacingEngine returns true if tables are created with ReplacingMergeTree or ReplicatedReplacingMergeTree engine
+func (tsf TableStatementFactory) IsReplacingEngine() bool {
+	return isReplacingEngine(tsf.engineStatement)
+}
+
 // CreateTableStatement return clickhouse DDL for creating table statement
 func (tsf TableStatementFactory) CreateTableStatement(tableName, columnsClause string) string {
+	return tsf.CreateTableStatementWithPrimaryKeys(tableName, columnsClause, nil)
+}
+
+// CreateTableStatementWithPrimaryKeys return clickhouse DDL for creating table statement
+// if sorting key isn't configured, table is ordered by primary key fields (ReplacingMergeTree deduplicates rows with the same sorting key)
+func (tsf TableStatementFactory) CreateTableStatementWithPrimaryKeys(tableName, columnsClause string, pkFields []string) string {
 	engineStatement := tsf.engineStatement
 	if tsf.engineStatementFormat {
 		engineStatement = fmt.Sprintf(engineStatement, tableName)
 	}
+	orderByClause := tsf.orderByClause
+	if tsf.defaultSortingKey && len(pkFields) > 0 {
+		orderByClause = "ORDER BY (" + strings.Join(pkFields, ",") + ")"
+	}
 	return fmt.Sprintf(createTableCHTemplate, tsf.database, tableName, tsf.onClusterClause, columnsClause, engineStatement,
-		tsf.partitionClause, tsf.orderByClause, tsf.primaryKeyClause)
+		tsf.partitionClause, orderByClause, tsf.primaryKeyClause, tsf.ttlClauses[tableName])
 }
 
 // ClickHouse is adapter for creating,patching (schema or table), inserting data to clickhouse
@@ -235,18 +294,23 @@ type ClickHouse struct {
 	database              string
 	cluster               string
 	dataSource            *sql.DB
+	sshTunnel             *SSHTunnel
 	tableStatementFactory *TableStatementFactory
 	nullableFields        map[string]bool
 	queryLogger           *logging.QueryLogger
 	sqlTypes              typing.SQLTypes
+
+	enginesMutex *sync.RWMutex
+	engines      map[string]string
 }
 
 // NewClickHouse returns configured ClickHouse adapter instance
 func NewClickHouse(ctx context.Context, connectionString, database, cluster string, tlsConfig map[string]string,
-	tableStatementFactory *TableStatementFactory, nullableFields map[string]bool,
+	sshTunnelConfig *SSHTunnelConfig, tableStatementFactory *TableStatementFactory, nullableFields map[string]bool,
 	queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*ClickHouse, error) {
 	connectionString = strings.TrimSpace(connectionString)
 	//configure tls
+	tlsConfigs := map[string]*tls.Config{}
 	if strings.Contains(connectionString, "https://") && tlsConfig != nil {
 		for tlsName, crtPath := range tlsConfig {
 			caCert, err := ioutil.ReadFile(crtPath)
@@ -256,12 +320,33 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 
 			caCertPool := x509.NewCertPool()
 			caCertPool.AppendCertsFromPEM(caCert)
-			if err := clickhouse.RegisterTLSConfig(tlsName, &tls.Config{RootCAs: caCertPool}); err != nil {
+			tlsConfigs[tlsName] = &tls.Config{RootCAs: caCertPool}
+			if err := clickhouse.RegisterTLSConfig(tlsName, tlsConfigs[tlsName]); err != nil {
 				return nil, err
 			}
 		}
 	}
 
+	//open ssh tunnel to the node and connect to the local tunnel address
+	var sshTunnel *SSHTunnel
+	if sshTunnelConfig != nil {
+		host, port, err := clickHouseDsnAddress(connectionString)
+		if err != nil {
+			return nil, err
+		}
+
+		sshTunnel, err = NewSSHTunnel(sshTunnelConfig, host, port)
+		if err != nil {
+			return nil, err
+		}
+
+		connectionString, err = replaceDsnAddress(connectionString, sshTunnel.LocalAddr(), tlsConfigs)
+		if err != nil {
+			sshTunnel.Close()
+			return nil, err
+		}
+	}
+
 	//enrich with ?wait_end_of_query=1 (for waiting on cluster execution result)
 	if strings.Contains(connectionString, "?") {
 		connectionString += "&"
@@ -273,12 +358,14 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 	//connect
 	dataSource, err := sql.Open("clickhouse", connectionString)
 	if err != nil {
+		sshTunnel.Close()
 		return nil, err
 	}
 
 	//keep select 1 and don't use Ping() because chproxy doesn't support /ping endpoint.
 	if _, err := dataSource.Exec("SELECT 1"); err != nil {
 		dataSource.Close()
+		sshTunnel.Close()
 		return nil, err
 	}
 
@@ -287,10 +374,13 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 		database:              database,
 		cluster:               cluster,
 		dataSource:            dataSource,
+		sshTunnel:             sshTunnel,
 		tableStatementFactory: tableStatementFactory,
 		nullableFields:        nullableFields,
 		queryLogger:           queryLogger,
 		sqlTypes:              reformatMappings(sqlTypes, SchemaToClickhouse),
+		enginesMutex:          &sync.RWMutex{},
+		engines:               map[string]string{},
 	}, nil
 }
 
@@ -326,7 +416,9 @@ func (ch *ClickHouse) CreateTable(table *Table) error {
 
 	//sorting columns asc
 	sort.Strings(columnsDDL)
-	statementStr := ch.tableStatementFactory.CreateTableStatement(table.Name, strings.Join(columnsDDL, ","))
+	pkFields := table.GetPKFields()
+	sort.Strings(pkFields)
+	statementStr := ch.tableStatementFactory.CreateTableStatementWithPrimaryKeys(table.Name, strings.Join(columnsDDL, ","), pkFields)
 	ch.queryLogger.LogDDL(statementStr)
 
 	if _, err := ch.dataSource.ExecContext(ch.ctx, statementStr); err != nil {
@@ -506,6 +598,26 @@ func (ch *ClickHouse) Truncate(tableName string) error {
 	return nil
 }
 
+//Query executes read query and returns result rows
+func (ch *ClickHouse) Query(query string) ([]map[string]interface{}, error) {
+	sqlParams := SqlParams{
+		dataSource:  ch.dataSource,
+		queryLogger: ch.queryLogger,
+		ctx:         ch.ctx,
+	}
+	rows, err := sqlParams.commonQuery(query)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(err, "failed to execute query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  ch.database,
+				Cluster:   ch.cluster,
+				Statement: query,
+			})
+	}
+
+	return rows, nil
+}
+
 func (ch *ClickHouse) DropTable(table *Table) error {
 	return ch.dropTable(table, false)
 }
@@ -518,6 +630,7 @@ func (ch *ClickHouse) dropTable(table *Table, ifExists bool) error {
 	}
 	query := fmt.Sprintf(dropTableCHTemplate, ifExs, ch.database, table.Name, ch.getOnClusterClause())
 	ch.queryLogger.LogDDL(query)
+	ch.resetTableEngineInfo(table.Name)
 
 	if _, err := ch.dataSource.ExecContext(ch.ctx, query); err != nil {
 		return errorj.DropError.Wrap(err, "failed to drop table").
@@ -538,6 +651,7 @@ func (ch *ClickHouse) dropTable(table *Table, ifExists bool) error {
 func (ch *ClickHouse) ReplaceTable(originalTable, replacementTable string, dropOldTable bool) error {
 	query := fmt.Sprintf(exchangeTableCHTemplate, ch.database, originalTable, ch.database, replacementTable)
 	ch.queryLogger.LogDDL(query)
+	ch.resetTableEngineInfo(originalTable, replacementTable)
 
 	if _, err := ch.dataSource.ExecContext(ch.ctx, query); err != nil {
 		if mapError(err) == ErrTableNotExist {
@@ -602,7 +716,7 @@ func (ch *ClickHouse) insert(table *Table, objects ...map[string]interface{}) er
 			//append value
 			value, ok := row[column]
 			if ok {
-				valueArgs = append(valueArgs, ch.reformatValue(value))
+				valueArgs = append(valueArgs, ch.reformatValue(value, table.Columns[column].Type))
 			} else {
 				column, _ := table.Columns[column]
 				defaultValue, ok := ch.getDefaultValue(column.Type)
@@ -656,7 +770,50 @@ func (ch *ClickHouse) executeInsert(table *Table, headerWithQuotes []string, pla
 
 // Close underlying sql.DB
 func (ch *ClickHouse) Close() error {
-	return ch.dataSource.Close()
+	err := ch.dataSource.Close()
+	if tunnelErr := ch.sshTunnel.Close(); tunnelErr != nil && err == nil {
+		err = tunnelErr
+	}
+
+	return err
+}
+
+// DeleteExpired submits DELETE mutation for rows which are older than expiredBefore (if there are any)
+// tables which are created with the retention rule have TTL clause and are cleaned up by ClickHouse itself
+func (ch *ClickHouse) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if err := requireRetentionTable(rule); err != nil {
+		return nil, err
+	}
+
+	//count rows in all shards
+	countTable := rule.Table
+	if ch.cluster != "" {
+		countTable = "dist_" + rule.Table
+	}
+
+	sqlParams := &SqlParams{ctx: ch.ctx, dataSource: ch.dataSource, queryLogger: ch.queryLogger}
+	expiredBeforeValue := expiredBefore.UTC().Format(chDateTimeLayout)
+	payload := &ErrorPayload{Database: ch.database, Cluster: ch.cluster, Table: rule.Table}
+	rows, err := countExpiredRows(sqlParams, fmt.Sprintf(countExpiredCHTemplate, ch.database, countTable, rule.Field), expiredBeforeValue, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore, Rows: rows}
+	if dryRun || rows == 0 {
+		return report, nil
+	}
+
+	statement := fmt.Sprintf(deleteExpiredCHTemplate, ch.database, rule.Table, ch.getOnClusterClause(), rule.Field)
+	ch.queryLogger.LogQueryWithValues(statement, []interface{}{expiredBeforeValue})
+	if _, err := ch.dataSource.ExecContext(ch.ctx, statement, expiredBeforeValue); err != nil {
+		payload.Statement = statement
+		payload.Values = []interface{}{expiredBeforeValue}
+		return nil, errorj.DeleteFromTableError.Wrap(err, "failed to delete expired rows").
+			WithProperty(errorj.DBInfo, payload)
+	}
+
+	return report, nil
 }
 
 // return ON CLUSTER name clause or "" if config.cluster is empty
@@ -738,8 +895,10 @@ func (ch *ClickHouse) getDefaultValue(sqlType string) (interface{}, bool) {
 }
 
 // if value is boolean - reformat it [true = 1; false = 0] ClickHouse supports UInt8 instead of boolean
+// if value is an array and column is Array(String) - reformat it into array of strings
+// objects and arrays in other columns (e.g. JSON) are serialized into JSON strings
 // otherwise return value as is
-func (ch *ClickHouse) reformatValue(v interface{}) interface{} {
+func (ch *ClickHouse) reformatValue(v interface{}, sqlType string) interface{} {
 	//reformat boolean
 	booleanValue, ok := v.(bool)
 	if ok {
@@ -750,7 +909,13 @@ func (ch *ClickHouse) reformatValue(v interface{}) interface{} {
 		return 0
 	}
 
-	return v
+	if strings.HasPrefix(strings.ToLower(sqlType), "array(") {
+		if arr, ok := stringsArray(v); ok {
+			return clickhouse.Array(arr)
+		}
+	}
+
+	return jsonValue(v)
 }
 
 func (ch *ClickHouse) destinationId() interface{} {
@@ -769,7 +934,116 @@ func extractStatement(fieldConfigs []FieldConfig) string {
 	return strings.Join(parameters, ",")
 }
 
+// Update replaces one record in ClickHouse
+// only ReplacingMergeTree tables are supported: the object is inserted and the previous row with the same sorting key
+// is removed on background merge (use FINAL modifier in SELECT queries for getting deduplicated rows right away)
+// rows of other engines would be updated with heavy ALTER TABLE UPDATE mutation per event
 func (ch *ClickHouse) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
-	//TODO Add Sign property for CollapcingMergeTree
+	engine, err := ch.getTableEngine(table.Name)
+	if err != nil {
+		return err
+	}
+
+	if !isReplacingEngine(engine) {
+		return errorj.UpdateError.New(fmt.Sprintf("users recognition is supported only for ReplacingMergeTree tables. Table engine: %s", engine)).
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database: ch.database,
+				Cluster:  ch.cluster,
+				Table:    table.Name,
+			})
+	}
+
 	return ch.insert(table, object)
 }
+
+// getTableEngine returns table engine from system.tables. Result is cached
+func (ch *ClickHouse) getTableEngine(tableName string) (string, error) {
+	ch.enginesMutex.RLock()
+	engine, ok := ch.engines[tableName]
+	ch.enginesMutex.RUnlock()
+	if ok {
+		return engine, nil
+	}
+
+	row := ch.dataSource.QueryRowContext(ch.ctx, tableEngineCHQuery, ch.database, tableName)
+	if err := row.Scan(&engine); err != nil {
+		return "", errorj.GetTableError.Wrap(err, "failed to get table engine").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  ch.database,
+				Cluster:   ch.cluster,
+				Table:     tableName,
+				Statement: tableEngineCHQuery,
+			})
+	}
+
+	ch.enginesMutex.Lock()
+	ch.engines[tableName] = engine
+	ch.enginesMutex.Unlock()
+
+	return engine, nil
+}
+
+// resetTableEngineInfo removes cached table engine of dropped or replaced tables
+func (ch *ClickHouse) resetTableEngineInfo(tableNames ...string) {
+	ch.enginesMutex.Lock()
+	for _, tableName := range tableNames {
+		delete(ch.engines, tableName)
+	}
+	ch.enginesMutex.Unlock()
+}
+
+// isReplacingEngine returns true if engine (or engine statement) is ReplacingMergeTree or ReplicatedReplacingMergeTree
+func isReplacingEngine(engine string) bool {
+	return strings.Contains(engine, "ReplacingMergeTree")
+}
+
+// clickHouseDsnAddress returns host and port of ClickHouse HTTP interface from the DSN
+func clickHouseDsnAddress(dsn string) (string, int, error) {
+	dsnURL, err := url.Parse(dsn)
+	if err != nil {
+		return "", 0, fmt.Errorf("error parsing ClickHouse DSN: %v", err)
+	}
+
+	port := 8123
+	if dsnURL.Scheme == "https" {
+		port = 8443
+	}
+	if dsnURL.Port() != "" {
+		port, err = strconv.Atoi(dsnURL.Port())
+		if err != nil {
+			return "", 0, fmt.Errorf("malformed ClickHouse DSN port: %v", err)
+		}
+	}
+
+	return dsnURL.Hostname(), port, nil
+}
+
+// replaceDsnAddress returns DSN with host:port replaced with the address
+// https DSNs get TLS config with the original host as the server name, so the certificate is verified against it
+// and not against the local tunnel address. Root CAs are taken from the TLS config of the DSN (tls_config parameter)
+func replaceDsnAddress(dsn, address string, tlsConfigs map[string]*tls.Config) (string, error) {
+	dsnURL, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("error parsing ClickHouse DSN: %v", err)
+	}
+
+	if dsnURL.Scheme == "https" {
+		query := dsnURL.Query()
+		tlsName := query.Get("tls_config")
+		tunnelTLSConfig := &tls.Config{}
+		if config, ok := tlsConfigs[tlsName]; ok {
+			tunnelTLSConfig = config.Clone()
+		}
+		tunnelTLSConfig.ServerName = dsnURL.Hostname()
+
+		tunnelTLSName := fmt.Sprintf("ssh_tunnel_%s_%s", tlsName, dsnURL.Host)
+		if err := clickhouse.RegisterTLSConfig(tunnelTLSName, tunnelTLSConfig); err != nil {
+			return "", err
+		}
+		query.Set("tls_config", tunnelTLSName)
+		dsnURL.RawQuery = query.Encode()
+	}
+
+	dsnURL.Host = address
+	return dsnURL.String(), nil
+}
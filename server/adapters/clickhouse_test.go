@@ -171,6 +171,33 @@ func TestTableStatementFactory(t *testing.T) {
 	}
 }
 
+func TestClickHouseCreateTableStatementWithPrimaryKeys(t *testing.T) {
+	factory, err := NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1"})
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE \"db1\".\"test_table\"  (a String,b String) ENGINE = ReplacingMergeTree() PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (a,b)",
+		strings.TrimSpace(factory.CreateTableStatementWithPrimaryKeys("test_table", "a String,b String", []string{"a", "b"})))
+
+	//configured sorting key isn't overridden
+	factory, err = NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1", Engine: &EngineConfig{OrderFields: []FieldConfig{{Field: "b"}}}})
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE \"db1\".\"test_table\"  (a String,b String) ENGINE = ReplacingMergeTree() PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (b)",
+		strings.TrimSpace(factory.CreateTableStatementWithPrimaryKeys("test_table", "a String,b String", []string{"a"})))
+}
+
+func TestClickHouseReplacingEngine(t *testing.T) {
+	factory, err := NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1"})
+	require.NoError(t, err)
+	require.True(t, factory.IsReplacingEngine())
+
+	factory, err = NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1", Cluster: "cluster1"})
+	require.NoError(t, err)
+	require.True(t, factory.IsReplacingEngine())
+
+	factory, err = NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1", Engine: &EngineConfig{RawStatement: "ENGINE = MergeTree() ORDER BY (eventn_ctx_event_id)"}})
+	require.NoError(t, err)
+	require.False(t, factory.IsReplacingEngine(), "users recognition isn't supported for MergeTree tables")
+}
+
 func TestClickhouseTruncateExistingTable(t *testing.T) {
 	recordsCount := len(timestamps)
 	table := &Table{
@@ -219,7 +246,7 @@ func setupClickHouseDatabase(t *testing.T, table *Table) (*test.ClickHouseContai
 	if err != nil {
 		t.Fatalf("failed to initialize table statement factory: %v", err)
 	}
-	adapter, err := NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, tsf, map[string]bool{},
+	adapter, err := NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, nil, tsf, map[string]bool{},
 		&logging.QueryLogger{}, typing.SQLTypes{})
 	if err != nil {
 		t.Fatalf("Failed to create ClickHouse adapter: %v", err)
@@ -0,0 +1,77 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//jsonValue returns JSON string for objects and arrays (typing.JSON and typing.ARRAY values)
+//other values are returned as is. It is used by adapters which write such values as text (e.g. jsonb, JSON columns)
+func jsonValue(value interface{}) interface{} {
+	if !isComplexValue(value) {
+		return value
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		logging.SystemErrorf("Error marshalling value [%v] into JSON: %v", value, err)
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
+
+//stringsArray returns array elements as strings (objects are serialized as JSON)
+//returns false if value isn't an array
+func stringsArray(value interface{}) ([]string, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	result := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		switch element := rv.Index(i).Interface().(type) {
+		case string:
+			result = append(result, element)
+		case time.Time:
+			result = append(result, element.Format(timestamp.Layout))
+		case nil:
+			result = append(result, "")
+		default:
+			if isComplexValue(element) {
+				result = append(result, jsonValue(element).(string))
+			} else {
+				result = append(result, fmt.Sprint(element))
+			}
+		}
+	}
+
+	return result, true
+}
+
+//isComplexValue returns true if value is an object or an array (except []byte)
+func isComplexValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if _, ok := value.([]byte); ok {
+		return false
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,26 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONValue(t *testing.T) {
+	require.Equal(t, `{"plan":"pro"}`, jsonValue(map[string]interface{}{"plan": "pro"}))
+	require.Equal(t, `[1,"a"]`, jsonValue([]interface{}{1, "a"}))
+	require.Equal(t, "abc", jsonValue("abc"))
+	require.Equal(t, 10, jsonValue(10))
+	require.Nil(t, jsonValue(nil))
+	require.Equal(t, []byte("raw"), jsonValue([]byte("raw")))
+}
+
+func TestStringsArray(t *testing.T) {
+	arr, ok := stringsArray([]interface{}{"a", int64(1), 1.5, true, nil, map[string]interface{}{"id": 1}, time.Date(2021, 6, 16, 23, 0, 0, 0, time.UTC)})
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "1", "1.5", "true", "", `{"id":1}`, "2021-06-16T23:00:00.000000Z"}, arr)
+
+	_, ok = stringsArray("a")
+	require.False(t, ok)
+}
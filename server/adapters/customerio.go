@@ -0,0 +1,200 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+//customerIORegionHosts are Customer.io Track API hosts of data residency regions
+var customerIORegionHosts = map[string]string{
+	"us": "https://track.customer.io",
+	"eu": "https://track-eu.customer.io",
+}
+
+//CustomerIORequestFactory is a factory for building Customer.io Track API requests from input events
+//objects with 'name' are events of a person ('id') or anonymous events ('anonymous_id'), other objects are identify calls
+type CustomerIORequestFactory struct {
+	authorization string
+	host          string
+	uniqueIDField string
+}
+
+//newCustomerIORequestFactory returns configured HTTPRequestFactory instance for customer.io requests
+func newCustomerIORequestFactory(config *CustomerIOConfig, uniqueIDField string) (*CustomerIORequestFactory, error) {
+	return &CustomerIORequestFactory{
+		authorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(config.SiteID+":"+config.APIKey)),
+		host:          config.host(),
+		uniqueIDField: uniqueIDField,
+	}, nil
+}
+
+//Create returns created customer.io request depends on object type
+func (crf *CustomerIORequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	body := make(map[string]interface{}, len(object))
+	for name, value := range object {
+		if name != crf.uniqueIDField {
+			body[name] = value
+		}
+	}
+
+	personID := ""
+	if id, ok := body["id"]; ok && id != nil {
+		personID = fmt.Sprint(id)
+	}
+	if _, ok := body["name"]; !ok {
+		//identify: all other fields are person attributes
+		if personID == "" {
+			return nil, errors.New("customer.io identify object must contain 'id' field")
+		}
+		delete(body, "id")
+		return crf.request(http.MethodPut, "/api/v1/customers/"+url.PathEscape(personID), body)
+	}
+
+	if personID != "" {
+		delete(body, "id")
+		return crf.request(http.MethodPost, "/api/v1/customers/"+url.PathEscape(personID)+"/events", body)
+	}
+	if anonymousID, ok := body["anonymous_id"]; !ok || anonymousID == nil || fmt.Sprint(anonymousID) == "" {
+		return nil, errors.New("customer.io event must contain 'id' or 'anonymous_id' field")
+	}
+
+	return crf.request(http.MethodPost, "/api/v1/events", body)
+}
+
+func (crf *CustomerIORequestFactory) request(method, path string, body map[string]interface{}) (*Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling customer.io request [%v]: %v", body, err)
+	}
+
+	return &Request{
+		URL:     crf.host + path,
+		Method:  method,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json", "Authorization": crf.authorization, "user-agent": JitsuUserAgent},
+	}, nil
+}
+
+func (crf *CustomerIORequestFactory) Close() {
+}
+
+//CustomerIOMergeObject returns identify object with anonymous_id attribute which merges anonymous activity into the person
+//person id is taken from the object (transformed event of the identified user). Returns nil if the object doesn't have it
+func CustomerIOMergeObject(anonymousID string, object map[string]interface{}) map[string]interface{} {
+	if id, ok := object["id"]; ok && id != nil && fmt.Sprint(id) != "" {
+		return map[string]interface{}{"id": id, "anonymous_id": anonymousID}
+	}
+
+	return nil
+}
+
+//CustomerIOConfig is a dto for parsing Customer.io configuration
+type CustomerIOConfig struct {
+	SiteID string `mapstructure:"site_id" json:"site_id,omitempty" yaml:"site_id,omitempty"`
+	APIKey string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	//Region is a data residency region: us or eu. Default is us
+	Region string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a custom Track API host (e.g. proxy). Overrides region
+	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+//Validate returns err if invalid
+func (cc *CustomerIOConfig) Validate() error {
+	if cc == nil {
+		return errors.New("customer.io config is required")
+	}
+	if cc.SiteID == "" {
+		return errors.New("'site_id' is required parameter")
+	}
+	if cc.APIKey == "" {
+		return errors.New("'api_key' is required parameter")
+	}
+	if _, ok := customerIORegionHosts[strings.ToLower(cc.Region)]; cc.Region != "" && !ok {
+		return fmt.Errorf("unknown customer.io region [%s]. Supported: us, eu", cc.Region)
+	}
+
+	return nil
+}
+
+//host returns Track API host of the configured region or custom endpoint
+func (cc *CustomerIOConfig) host() string {
+	if cc.Endpoint != "" {
+		return strings.TrimSuffix(cc.Endpoint, "/")
+	}
+
+	return customerIORegionHosts[strings.ToLower(utils.NvlString(cc.Region, "us"))]
+}
+
+//CustomerIO is an adapter for sending HTTP requests to Customer.io
+type CustomerIO struct {
+	AbstractHTTP
+
+	config *CustomerIOConfig
+}
+
+//NewCustomerIO returns configured CustomerIO adapter instance
+//uniqueIDField is a flat name of the event ID field which is removed from objects
+func NewCustomerIO(config *CustomerIOConfig, uniqueIDField string, httpAdapterConfiguration *HTTPAdapterConfiguration) (*CustomerIO, error) {
+	httpReqFactory, err := newCustomerIORequestFactory(config, uniqueIDField)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CustomerIO{config: config}
+	c.httpAdapter = httpAdapter
+	return c, nil
+}
+
+//NewTestCustomerIO returns test instance of adapter
+func NewTestCustomerIO(config *CustomerIOConfig) *CustomerIO {
+	return &CustomerIO{config: config}
+}
+
+//TestAccess sends account region request to Customer.io and checks the credentials
+func (c *CustomerIO) TestAccess() error {
+	httpReqFactory, err := newCustomerIORequestFactory(c.config, "")
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodGet, httpReqFactory.host+"/api/v1/accounts/region", nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Add("Authorization", httpReqFactory.authorization)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		responseBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("Error reading customer.io response body: %v", err)
+		}
+		return fmt.Errorf("error connecting to customer.io [code=%d]: %s", resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (c *CustomerIO) Type() string {
+	return "CustomerIO"
+}
@@ -0,0 +1,101 @@
+package adapters
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerIOConfigValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        *CustomerIOConfig
+		expectedError string
+	}{
+		{"nil config", nil, "customer.io config is required"},
+		{"empty site id", &CustomerIOConfig{APIKey: "key"}, "'site_id' is required parameter"},
+		{"empty api key", &CustomerIOConfig{SiteID: "site"}, "'api_key' is required parameter"},
+		{"unknown region", &CustomerIOConfig{SiteID: "site", APIKey: "key", Region: "asia"}, "unknown customer.io region [asia]. Supported: us, eu"},
+		{"valid", &CustomerIOConfig{SiteID: "site", APIKey: "key", Region: "EU"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestCustomerIORequests(t *testing.T) {
+	factory, err := newCustomerIORequestFactory(&CustomerIOConfig{SiteID: "site", APIKey: "key", Region: "eu"}, "eventn_ctx_event_id")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		object         map[string]interface{}
+		expectedMethod string
+		expectedURL    string
+		expectedBody   string
+		expectedError  string
+	}{
+		{
+			"identify",
+			map[string]interface{}{"id": "u1", "email": "a@b.com", "anonymous_id": "a1", "eventn_ctx_event_id": "1"},
+			http.MethodPut,
+			"https://track-eu.customer.io/api/v1/customers/u1",
+			`{"email":"a@b.com","anonymous_id":"a1"}`,
+			"",
+		},
+		{
+			"identify without id",
+			map[string]interface{}{"email": "a@b.com"},
+			"", "", "",
+			"customer.io identify object must contain 'id' field",
+		},
+		{
+			"person event",
+			map[string]interface{}{"id": "u/1", "name": "purchase", "data": map[string]interface{}{"revenue": 10}},
+			http.MethodPost,
+			"https://track-eu.customer.io/api/v1/customers/u%2F1/events",
+			`{"name":"purchase","data":{"revenue":10}}`,
+			"",
+		},
+		{
+			"anonymous event",
+			map[string]interface{}{"anonymous_id": "a1", "name": "https://jitsu.com", "type": "page"},
+			http.MethodPost,
+			"https://track-eu.customer.io/api/v1/events",
+			`{"anonymous_id":"a1","name":"https://jitsu.com","type":"page"}`,
+			"",
+		},
+		{
+			"event without ids",
+			map[string]interface{}{"name": "click"},
+			"", "", "",
+			"customer.io event must contain 'id' or 'anonymous_id' field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := factory.Create(tt.object)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedMethod, req.Method)
+			require.Equal(t, tt.expectedURL, req.URL)
+			require.Equal(t, "Basic c2l0ZTprZXk=", req.Headers["Authorization"])
+			require.JSONEq(t, tt.expectedBody, string(req.Body))
+		})
+	}
+
+	require.Equal(t, map[string]interface{}{"id": "u1", "anonymous_id": "a1"}, CustomerIOMergeObject("a1", map[string]interface{}{"id": "u1", "name": "click"}))
+	require.Nil(t, CustomerIOMergeObject("a1", map[string]interface{}{"anonymous_id": "a1", "name": "click"}), "anonymous events can't be merged")
+}
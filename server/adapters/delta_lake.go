@@ -0,0 +1,447 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	deltaLogDir           = "_delta_log"
+	deltaMinReaderVersion = 1
+	deltaMinWriterVersion = 2
+	deltaCommitRetries    = 3
+)
+
+var (
+	errDeltaCommitConflict = errors.New("delta log version has been already committed")
+
+	//deltaTypes is a mapping between typing types and Delta Lake primitive types
+	//objects and arrays are written as JSON strings
+	deltaTypes = map[typing.DataType]string{
+		typing.STRING:    "string",
+		typing.INT64:     "long",
+		typing.FLOAT64:   "double",
+		typing.TIMESTAMP: "timestamp",
+		typing.BOOL:      "boolean",
+		typing.JSON:      "string",
+		typing.ARRAY:     "string",
+	}
+	deltaTypesReversed = map[string]typing.DataType{
+		"string":    typing.STRING,
+		"long":      typing.INT64,
+		"double":    typing.FLOAT64,
+		"timestamp": typing.TIMESTAMP,
+		"boolean":   typing.BOOL,
+	}
+)
+
+//DeltaLake writes batches into Delta Lake tables: parquet data files and JSON commits in the _delta_log directory
+//the table schema evolves with batches: new columns are added into table metadata
+//commits of the same table must be serialized by the caller (e.g. with a coordination lock)
+type DeltaLake struct {
+	storage     ObjectStorage
+	compression FileCompression
+
+	mutex  *sync.Mutex
+	tables map[string]*deltaTable
+}
+
+//DeltaCommit is a result of writing a batch
+type DeltaCommit struct {
+	Version       int64
+	DataFile      string
+	SchemaChanged bool
+}
+
+//deltaTable is a cached table state: last read log version and table metadata
+type deltaTable struct {
+	version  int64
+	metadata *deltaMetadata
+}
+
+type deltaAction struct {
+	CommitInfo *deltaCommitInfo `json:"commitInfo,omitempty"`
+	Protocol   *deltaProtocol   `json:"protocol,omitempty"`
+	MetaData   *deltaMetadata   `json:"metaData,omitempty"`
+	Add        *deltaAdd        `json:"add,omitempty"`
+}
+
+type deltaCommitInfo struct {
+	Timestamp           int64             `json:"timestamp"`
+	Operation           string            `json:"operation"`
+	OperationParameters map[string]string `json:"operationParameters"`
+}
+
+type deltaProtocol struct {
+	MinReaderVersion int `json:"minReaderVersion"`
+	MinWriterVersion int `json:"minWriterVersion"`
+}
+
+type deltaMetadata struct {
+	ID               string            `json:"id"`
+	Format           deltaFormat       `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	Configuration    map[string]string `json:"configuration"`
+	CreatedTime      int64             `json:"createdTime"`
+
+	fields []deltaField
+}
+
+type deltaFormat struct {
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
+}
+
+type deltaAdd struct {
+	Path             string            `json:"path"`
+	PartitionValues  map[string]string `json:"partitionValues"`
+	Size             int64             `json:"size"`
+	ModificationTime int64             `json:"modificationTime"`
+	DataChange       bool              `json:"dataChange"`
+	Stats            string            `json:"stats"`
+}
+
+type deltaSchema struct {
+	Type   string       `json:"type"`
+	Fields []deltaField `json:"fields"`
+}
+
+type deltaField struct {
+	Name     string                 `json:"name"`
+	Type     string                 `json:"type"`
+	Nullable bool                   `json:"nullable"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+//NewDeltaLake returns DeltaLake which writes tables into the storage
+//parquet data files are compressed with the compression codec (parquet default codec is used if compression is empty)
+func NewDeltaLake(storage ObjectStorage, compression FileCompression) *DeltaLake {
+	return &DeltaLake{
+		storage:     storage,
+		compression: compression,
+		mutex:       &sync.Mutex{},
+		tables:      map[string]*deltaTable{},
+	}
+}
+
+//Write writes objects as a parquet data file and commits it into the table log
+//new columns from batchHeader are added into the table schema. Values of existing columns are converted into the table column types
+func (dl *DeltaLake) Write(batchHeader *schema.BatchHeader, objects []map[string]interface{}) (*DeltaCommit, error) {
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	tableName := batchHeader.TableName
+	for i := 0; ; i++ {
+		commit, err := dl.write(batchHeader, objects)
+		if err == errDeltaCommitConflict && i < deltaCommitRetries {
+			//the table has been changed by another writer: reload the log and retry
+			delete(dl.tables, tableName)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing delta table %s: %v", tableName, err)
+		}
+
+		return commit, nil
+	}
+}
+
+func (dl *DeltaLake) write(batchHeader *schema.BatchHeader, objects []map[string]interface{}) (*DeltaCommit, error) {
+	tableName := batchHeader.TableName
+	table, err := dl.loadTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, dataHeader, schemaChanged, err := mergeDeltaSchema(table.metadata, batchHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := convertDeltaValues(batchHeader, dataHeader, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	marshaller, err := schema.NewParquetMarshallerWithCompression(string(dl.compression))
+	if err != nil {
+		return nil, err
+	}
+	payload, err := marshaller.Marshal(dataHeader, data)
+	if err != nil {
+		return nil, err
+	}
+
+	extension := "parquet"
+	switch dl.compression {
+	case FileCompressionGZIP:
+		extension = "gz.parquet"
+	case FileCompressionZSTD, FileCompressionSnappy:
+		extension = string(dl.compression) + ".parquet"
+	}
+	dataFile := fmt.Sprintf("part-00000-%s-c000.%s", uuid.New(), extension)
+	if err := dl.storage.PutObject(tableName+"/"+dataFile, payload); err != nil {
+		return nil, err
+	}
+
+	now := timestamp.Now().UnixNano() / 1e6
+	version := table.version + 1
+	var actions []*deltaAction
+	actions = append(actions, &deltaAction{CommitInfo: &deltaCommitInfo{
+		Timestamp:           now,
+		Operation:           "WRITE",
+		OperationParameters: map[string]string{"mode": "Append", "partitionBy": "[]"},
+	}})
+	if version == 0 {
+		actions = append(actions, &deltaAction{Protocol: &deltaProtocol{MinReaderVersion: deltaMinReaderVersion, MinWriterVersion: deltaMinWriterVersion}})
+	}
+	if schemaChanged {
+		actions = append(actions, &deltaAction{MetaData: metadata})
+	}
+	actions = append(actions, &deltaAction{Add: &deltaAdd{
+		Path:             dataFile,
+		PartitionValues:  map[string]string{},
+		Size:             int64(len(payload)),
+		ModificationTime: now,
+		DataChange:       true,
+		Stats:            fmt.Sprintf(`{"numRecords":%d}`, len(data)),
+	}})
+
+	if err := dl.commit(tableName, version, actions); err != nil {
+		return nil, err
+	}
+
+	dl.tables[tableName] = &deltaTable{version: version, metadata: metadata}
+	return &DeltaCommit{Version: version, DataFile: dataFile, SchemaChanged: schemaChanged}, nil
+}
+
+//commit writes actions into the next log version file
+//object storages don't have put-if-absent operation so the check only narrows conflicts window
+func (dl *DeltaLake) commit(tableName string, version int64, actions []*deltaAction) error {
+	key := deltaLogKey(tableName, version)
+	if _, err := dl.storage.GetObject(key); err != ErrObjectNotExist {
+		if err != nil {
+			return err
+		}
+		return errDeltaCommitConflict
+	}
+
+	buf := bytes.Buffer{}
+	for _, action := range actions {
+		b, err := json.Marshal(action)
+		if err != nil {
+			return fmt.Errorf("error marshalling delta log action: %v", err)
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	return dl.storage.PutObject(key, buf.Bytes())
+}
+
+//loadTable returns cached table state updated with log commits which have been written after the cached version
+//returns table with version -1 if the table doesn't exist
+func (dl *DeltaLake) loadTable(tableName string) (*deltaTable, error) {
+	table, ok := dl.tables[tableName]
+	if !ok {
+		table = &deltaTable{version: -1}
+	}
+
+	keys, err := dl.storage.ListObjects(tableName + "/" + deltaLogDir + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int64
+	for _, key := range keys {
+		version, ok := parseDeltaLogVersion(tableName, key)
+		if ok && version > table.version {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	for _, version := range versions {
+		payload, err := dl.storage.GetObject(deltaLogKey(tableName, version))
+		if err != nil {
+			return nil, err
+		}
+
+		metadata, err := parseDeltaLogMetadata(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing delta log version %d: %v", version, err)
+		}
+
+		if metadata != nil {
+			table.metadata = metadata
+		}
+		table.version = version
+	}
+
+	return table, nil
+}
+
+//mergeDeltaSchema returns table metadata with new columns from batchHeader and batch header with table column types
+func mergeDeltaSchema(current *deltaMetadata, batchHeader *schema.BatchHeader) (*deltaMetadata, *schema.BatchHeader, bool, error) {
+	metadata := &deltaMetadata{
+		ID:               uuid.New(),
+		Format:           deltaFormat{Provider: "parquet", Options: map[string]string{}},
+		PartitionColumns: []string{},
+		Configuration:    map[string]string{},
+		CreatedTime:      timestamp.Now().UnixNano() / 1e6,
+	}
+	tableTypes := map[string]string{}
+	if current != nil {
+		copied := *current
+		metadata = &copied
+		metadata.fields = append([]deltaField{}, current.fields...)
+		for _, field := range current.fields {
+			tableTypes[field.Name] = field.Type
+		}
+	}
+
+	var newFields []string
+	dataHeader := &schema.BatchHeader{TableName: batchHeader.TableName, Fields: schema.Fields{}, Partition: batchHeader.Partition}
+	for name, field := range batchHeader.Fields {
+		batchType := field.GetType()
+		deltaType, ok := deltaTypes[batchType]
+		if !ok {
+			return nil, nil, false, fmt.Errorf("field %s has unmappable data type %s", name, batchType.String())
+		}
+
+		tableType, exists := tableTypes[name]
+		switch {
+		case !exists:
+			newFields = append(newFields, name)
+			dataHeader.Fields[name] = field
+		case tableType == deltaType:
+			dataHeader.Fields[name] = field
+		default:
+			dataType, ok := deltaTypesReversed[tableType]
+			if !ok {
+				return nil, nil, false, fmt.Errorf("column %s has unsupported delta type %s", name, tableType)
+			}
+			dataHeader.Fields[name] = schema.NewField(dataType)
+		}
+	}
+
+	if current != nil && len(newFields) == 0 {
+		return current, dataHeader, false, nil
+	}
+
+	sort.Strings(newFields)
+	for _, name := range newFields {
+		metadata.fields = append(metadata.fields, deltaField{
+			Name:     name,
+			Type:     deltaTypes[batchHeader.Fields[name].GetType()],
+			Nullable: true,
+			Metadata: map[string]interface{}{},
+		})
+	}
+
+	schemaString, err := json.Marshal(deltaSchema{Type: "struct", Fields: metadata.fields})
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("error marshalling delta schema: %v", err)
+	}
+	metadata.SchemaString = string(schemaString)
+
+	return metadata, dataHeader, true, nil
+}
+
+//convertDeltaValues returns objects with values converted into table column types (if they differ from batch types)
+func convertDeltaValues(batchHeader, dataHeader *schema.BatchHeader, objects []map[string]interface{}) ([]map[string]interface{}, error) {
+	var convertFields []string
+	for name, field := range dataHeader.Fields {
+		if batchHeader.Fields[name].GetType() != field.GetType() {
+			convertFields = append(convertFields, name)
+		}
+	}
+	if len(convertFields) == 0 {
+		return objects, nil
+	}
+
+	converted := make([]map[string]interface{}, 0, len(objects))
+	for _, object := range objects {
+		convertedObject := make(map[string]interface{}, len(object))
+		for k, v := range object {
+			convertedObject[k] = v
+		}
+		for _, name := range convertFields {
+			value, ok := object[name]
+			if !ok || value == nil {
+				continue
+			}
+
+			convertedValue, err := typing.Convert(dataHeader.Fields[name].GetType(), value)
+			if err != nil {
+				return nil, fmt.Errorf("error converting column %s value [%v] into table type %s: %v", name, value, dataHeader.Fields[name].GetType().String(), err)
+			}
+			convertedObject[name] = convertedValue
+		}
+		converted = append(converted, convertedObject)
+	}
+
+	return converted, nil
+}
+
+//parseDeltaLogMetadata returns the last metaData action from the log file or nil if there is no metaData action
+func parseDeltaLogMetadata(payload []byte) (*deltaMetadata, error) {
+	var metadata *deltaMetadata
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		action := &deltaAction{}
+		if err := json.Unmarshal(line, action); err != nil {
+			return nil, err
+		}
+		if action.MetaData == nil {
+			continue
+		}
+
+		tableSchema := &deltaSchema{}
+		if err := json.Unmarshal([]byte(action.MetaData.SchemaString), tableSchema); err != nil {
+			return nil, fmt.Errorf("malformed schemaString: %v", err)
+		}
+		action.MetaData.fields = tableSchema.Fields
+		metadata = action.MetaData
+	}
+
+	return metadata, scanner.Err()
+}
+
+//parseDeltaLogVersion returns version from the log file key: <table>/_delta_log/00000000000000000001.json
+func parseDeltaLogVersion(tableName, key string) (int64, bool) {
+	name := strings.TrimPrefix(key, tableName+"/"+deltaLogDir+"/")
+	if name == key || !strings.HasSuffix(name, ".json") {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
+
+func deltaLogKey(tableName string, version int64) string {
+	return fmt.Sprintf("%s/%s/%020d.json", tableName, deltaLogDir, version)
+}
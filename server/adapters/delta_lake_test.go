@@ -0,0 +1,106 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeltaLakeWrite(t *testing.T) {
+	storage, err := NewLocalFileSystem(t.TempDir())
+	require.NoError(t, err)
+
+	deltaLake := NewDeltaLake(storage, "")
+	commit, err := deltaLake.Write(&schema.BatchHeader{TableName: "events", Fields: schema.Fields{
+		"id":         schema.NewField(typing.STRING),
+		"value":      schema.NewField(typing.INT64),
+		"_timestamp": schema.NewField(typing.TIMESTAMP),
+	}}, []map[string]interface{}{
+		{"id": "1", "value": int64(1), "_timestamp": time.Date(2022, 5, 18, 0, 0, 0, 0, time.UTC)},
+		{"id": "2", "value": int64(2), "_timestamp": time.Date(2022, 5, 18, 0, 0, 1, 0, time.UTC)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), commit.Version)
+	require.True(t, commit.SchemaChanged)
+
+	//the same schema: only add action
+	commit, err = deltaLake.Write(&schema.BatchHeader{TableName: "events", Fields: schema.Fields{
+		"id": schema.NewField(typing.STRING),
+	}}, []map[string]interface{}{{"id": "3"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), commit.Version)
+	require.False(t, commit.SchemaChanged)
+
+	//new writer reads the log: new column is added and value is converted into existing column type
+	deltaLake = NewDeltaLake(storage, FileCompressionGZIP)
+	commit, err = deltaLake.Write(&schema.BatchHeader{TableName: "events", Fields: schema.Fields{
+		"id":   schema.NewField(typing.INT64),
+		"name": schema.NewField(typing.STRING),
+	}}, []map[string]interface{}{{"id": int64(4), "name": "four"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), commit.Version)
+	require.True(t, commit.SchemaChanged)
+	require.True(t, strings.HasSuffix(commit.DataFile, ".gz.parquet"))
+
+	keys, err := storage.ListObjects("events/_delta_log/")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		"events/_delta_log/00000000000000000000.json",
+		"events/_delta_log/00000000000000000001.json",
+		"events/_delta_log/00000000000000000002.json",
+	}, keys)
+
+	log, err := storage.GetObject("events/_delta_log/00000000000000000000.json")
+	require.NoError(t, err)
+	firstMetadata, err := parseDeltaLogMetadata(log)
+	require.NoError(t, err)
+	require.Equal(t, []deltaField{
+		{Name: "_timestamp", Type: "timestamp", Nullable: true, Metadata: map[string]interface{}{}},
+		{Name: "id", Type: "string", Nullable: true, Metadata: map[string]interface{}{}},
+		{Name: "value", Type: "long", Nullable: true, Metadata: map[string]interface{}{}},
+	}, firstMetadata.fields)
+	require.Contains(t, string(log), `"protocol":{"minReaderVersion":1,"minWriterVersion":2}`)
+
+	log, err = storage.GetObject("events/_delta_log/00000000000000000001.json")
+	require.NoError(t, err)
+	metadata, err := parseDeltaLogMetadata(log)
+	require.NoError(t, err)
+	require.Nil(t, metadata)
+
+	log, err = storage.GetObject("events/_delta_log/00000000000000000002.json")
+	require.NoError(t, err)
+	metadata, err = parseDeltaLogMetadata(log)
+	require.NoError(t, err)
+	require.Equal(t, firstMetadata.ID, metadata.ID)
+	require.Equal(t, `{"type":"struct","fields":[{"name":"_timestamp","type":"timestamp","nullable":true,"metadata":{}},{"name":"id","type":"string","nullable":true,"metadata":{}},{"name":"value","type":"long","nullable":true,"metadata":{}},{"name":"name","type":"string","nullable":true,"metadata":{}}]}`,
+		metadata.SchemaString)
+
+	dataFiles, err := storage.ListObjects("events/part-")
+	require.NoError(t, err)
+	require.Len(t, dataFiles, 3)
+}
+
+func TestDeltaLakeConvertValues(t *testing.T) {
+	batchHeader := &schema.BatchHeader{TableName: "events", Fields: schema.Fields{"value": schema.NewField(typing.STRING)}}
+	dataHeader := &schema.BatchHeader{TableName: "events", Fields: schema.Fields{"value": schema.NewField(typing.INT64)}}
+
+	_, err := convertDeltaValues(batchHeader, dataHeader, []map[string]interface{}{{"value": "abc"}})
+	require.Error(t, err)
+
+	converted, err := convertDeltaValues(dataHeader, batchHeader, []map[string]interface{}{{"value": int64(1)}, {"value": nil}})
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{"value": "1"}, {"value": nil}}, converted)
+}
+
+func TestFileConfigValidateTableFormat(t *testing.T) {
+	fileConfig := &FileConfig{TableFormat: FileTableFormatDelta}
+	require.NoError(t, fileConfig.ValidateTableFormat())
+	require.Equal(t, FileFormatParquet, fileConfig.Format)
+
+	require.EqualError(t, (&FileConfig{TableFormat: FileTableFormatDelta, Format: FileFormatCSV}).ValidateTableFormat(), "table_format delta requires parquet format")
+	require.EqualError(t, (&FileConfig{TableFormat: "iceberg"}).ValidateTableFormat(), "unsupported table_format: iceberg. Supported values: delta")
+}
@@ -0,0 +1,681 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/errorj"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+	"go.uber.org/atomic"
+)
+
+const (
+	//elasticsearchBulkSize is a max documents count in one _bulk request
+	elasticsearchBulkSize = 1000
+	elasticsearchTimeout  = time.Minute
+
+	elasticsearchTextType   = "text"
+	elasticsearchObjectType = "object"
+)
+
+var (
+	//SchemaToElasticsearch is mapping between JSON types and Elasticsearch/OpenSearch field types
+	//strings are mapped into text fields with keyword subfield (the same as Elasticsearch dynamic mapping does)
+	SchemaToElasticsearch = map[typing.DataType]string{
+		typing.STRING:    elasticsearchTextType,
+		typing.INT64:     "long",
+		typing.FLOAT64:   "double",
+		typing.TIMESTAMP: "date",
+		typing.BOOL:      "boolean",
+		typing.JSON:      elasticsearchObjectType,
+		typing.ARRAY:     "keyword",
+		typing.UNKNOWN:   "keyword",
+	}
+)
+
+//ElasticsearchConfig is a dto for deserialized Elasticsearch/OpenSearch destination configuration
+type ElasticsearchConfig struct {
+	//Hosts are cluster nodes URLs (e.g. https://localhost:9200). Requests are sent to the next node if the node is unavailable
+	Hosts    []string `mapstructure:"hosts,omitempty" json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Username string   `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
+	Password string   `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	//APIKey is a base64 encoded Elasticsearch API key (id:api_key)
+	APIKey string `mapstructure:"api_key,omitempty" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	//Shards and Replicas are applied to created indices. Cluster defaults are used if they aren't set
+	Shards   int  `mapstructure:"number_of_shards,omitempty" json:"number_of_shards,omitempty" yaml:"number_of_shards,omitempty"`
+	Replicas *int `mapstructure:"number_of_replicas,omitempty" json:"number_of_replicas,omitempty" yaml:"number_of_replicas,omitempty"`
+	//Refresh is a _bulk refresh parameter: true, false or wait_for. Default is false
+	Refresh string `mapstructure:"refresh,omitempty" json:"refresh,omitempty" yaml:"refresh,omitempty"`
+}
+
+//Validate returns err if invalid
+func (ec *ElasticsearchConfig) Validate() error {
+	if ec == nil {
+		return errors.New("Elasticsearch config is required")
+	}
+	if len(ec.Hosts) == 0 {
+		return errors.New("Elasticsearch hosts is required parameter")
+	}
+	for _, host := range ec.Hosts {
+		if u, err := url.Parse(host); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("malformed Elasticsearch host [%s]: URL with scheme is expected (e.g. https://localhost:9200)", host)
+		}
+	}
+	if ec.APIKey != "" && ec.Username != "" {
+		return errors.New("Elasticsearch username and api_key can't be used together")
+	}
+	switch ec.Refresh {
+	case "", "true", "false", "wait_for":
+	default:
+		return fmt.Errorf("unsupported Elasticsearch refresh: %s. Supported values: true, false, wait_for", ec.Refresh)
+	}
+
+	return nil
+}
+
+//ElasticsearchItemError is an error of the document in _bulk response
+type ElasticsearchItemError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (eie *ElasticsearchItemError) Error() string {
+	return fmt.Sprintf("[%d %s] %s: %s", eie.Status, http.StatusText(eie.Status), eie.Type, eie.Reason)
+}
+
+//Retryable returns true if the document has been rejected because of cluster state (e.g. overloaded) and not because of the document itself
+func (eie *ElasticsearchItemError) Retryable() bool {
+	return eie.Status == http.StatusTooManyRequests || eie.Status >= http.StatusInternalServerError
+}
+
+//ElasticsearchBulkError is returned from batch Insert if some documents have been rejected
+//other documents have been written successfully
+type ElasticsearchBulkError struct {
+	Index string
+	Total int
+	//Items are errors of rejected documents: index of the object in the inserted batch -> error
+	Items map[int]*ElasticsearchItemError
+}
+
+func (ebe *ElasticsearchBulkError) Error() string {
+	indices := make([]int, 0, len(ebe.Items))
+	for i := range ebe.Items {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	return fmt.Sprintf("%d of %d documents have been rejected by index %s. First error: %v", len(ebe.Items), ebe.Total, ebe.Index, ebe.Items[indices[0]])
+}
+
+//Retryable returns true if at least one document can be written on retry
+func (ebe *ElasticsearchBulkError) Retryable() bool {
+	for _, itemErr := range ebe.Items {
+		if itemErr.Retryable() {
+			return true
+		}
+	}
+
+	return false
+}
+
+//elasticsearchBulkResponse is a dto for parsing _bulk response
+type elasticsearchBulkResponse struct {
+	Errors bool                                      `json:"errors"`
+	Items  []map[string]*elasticsearchBulkItemResult `json:"items"`
+}
+
+type elasticsearchBulkItemResult struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+//elasticsearchMappingProperty is a field mapping in index mappings
+type elasticsearchMappingProperty struct {
+	Type       string                                   `json:"type,omitempty"`
+	Properties map[string]*elasticsearchMappingProperty `json:"properties,omitempty"`
+}
+
+//Elasticsearch is an adapter for writing documents into Elasticsearch or OpenSearch indices via REST API
+//tables are indices, table columns are index mappings. Documents are written with _bulk API
+//with unique ID field as a document _id (retries don't produce duplicates)
+type Elasticsearch struct {
+	ctx           context.Context
+	config        *ElasticsearchConfig
+	client        *http.Client
+	uniqueIDField *identifiers.UniqueID
+	queryLogger   *logging.QueryLogger
+	sqlTypes      typing.SQLTypes
+
+	//nextHost is an index of the host for the next request
+	nextHost *atomic.Uint32
+}
+
+//NewElasticsearch returns configured Elasticsearch adapter instance and checks cluster availability
+func NewElasticsearch(ctx context.Context, config *ElasticsearchConfig, uniqueIDField *identifiers.UniqueID, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Elasticsearch, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Elasticsearch{
+		ctx:           ctx,
+		config:        config,
+		client:        &http.Client{Timeout: elasticsearchTimeout},
+		uniqueIDField: uniqueIDField,
+		queryLogger:   queryLogger,
+		sqlTypes:      reformatMappings(sqlTypes, SchemaToElasticsearch),
+		nextHost:      atomic.NewUint32(0),
+	}
+
+	if _, _, err := e.request(http.MethodGet, "/", nil, ""); err != nil {
+		e.client.CloseIdleConnections()
+		return nil, fmt.Errorf("error connecting to Elasticsearch: %v", err)
+	}
+
+	return e, nil
+}
+
+//Insert writes single document or batch of documents with _bulk API
+//returns *ElasticsearchBulkError if some documents have been rejected
+func (e *Elasticsearch) Insert(insertContext *InsertContext) error {
+	if insertContext.eventContext != nil {
+		return e.insertSingle(insertContext.eventContext)
+	}
+
+	return e.insertBatch(insertContext.table, insertContext.objects)
+}
+
+func (e *Elasticsearch) insertSingle(eventContext *EventContext) error {
+	if err := e.bulk(eventContext.Table.Name, []map[string]interface{}{eventContext.ProcessedEvent}); err != nil {
+		var bulkErr *ElasticsearchBulkError
+		if errors.As(err, &bulkErr) {
+			err = bulkErr.Items[0]
+		}
+		return errorj.ExecuteInsertError.Wrap(err, "failed to execute single insert").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:           eventContext.Table.Name,
+				ValuesMapString: fmt.Sprintf("%v", eventContext.ProcessedEvent),
+			})
+	}
+
+	return nil
+}
+
+//insertBatch writes objects with _bulk requests of elasticsearchBulkSize documents
+//all objects are written even if some of them are rejected
+func (e *Elasticsearch) insertBatch(table *Table, objects []map[string]interface{}) error {
+	bulkErr := &ElasticsearchBulkError{Index: e.index(table.Name), Total: len(objects), Items: map[int]*ElasticsearchItemError{}}
+	for offset := 0; offset < len(objects); offset += elasticsearchBulkSize {
+		end := offset + elasticsearchBulkSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+
+		err := e.bulk(table.Name, objects[offset:end])
+		if err == nil {
+			continue
+		}
+
+		var chunkErr *ElasticsearchBulkError
+		if !errors.As(err, &chunkErr) {
+			return errorj.ExecuteInsertInBatchError.Wrap(err, "failed to execute bulk insert").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Table:        table.Name,
+					TotalObjects: len(objects),
+				})
+		}
+		for i, itemErr := range chunkErr.Items {
+			bulkErr.Items[offset+i] = itemErr
+		}
+	}
+
+	if len(bulkErr.Items) > 0 {
+		return bulkErr
+	}
+
+	return nil
+}
+
+//bulk sends one _bulk request with index actions
+func (e *Elasticsearch) bulk(tableName string, objects []map[string]interface{}) error {
+	index := e.index(tableName)
+	payload := &bytes.Buffer{}
+	encoder := json.NewEncoder(payload)
+	for _, object := range objects {
+		action := map[string]interface{}{"_index": index}
+		if id := e.uniqueIDField.Extract(object); id != "" {
+			action["_id"] = id
+		}
+		if err := encoder.Encode(map[string]interface{}{"index": action}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(object); err != nil {
+			return fmt.Errorf("error serializing document: %v", err)
+		}
+	}
+
+	path := "/_bulk"
+	if e.config.Refresh != "" {
+		path += "?refresh=" + e.config.Refresh
+	}
+	e.queryLogger.LogQuery(fmt.Sprintf("Bulk indexing [%d] documents into index %s", len(objects), index))
+	_, body, err := e.request(http.MethodPost, path, payload.Bytes(), "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+
+	response := &elasticsearchBulkResponse{}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("error parsing _bulk response: %v", err)
+	}
+	if !response.Errors {
+		return nil
+	}
+
+	bulkErr := &ElasticsearchBulkError{Index: index, Total: len(objects), Items: map[int]*ElasticsearchItemError{}}
+	for i, item := range response.Items {
+		for _, result := range item {
+			if result.Error != nil {
+				bulkErr.Items[i] = &ElasticsearchItemError{Status: result.Status, Type: result.Error.Type, Reason: result.Error.Reason}
+			}
+		}
+	}
+	if len(bulkErr.Items) == 0 {
+		return nil
+	}
+
+	return bulkErr
+}
+
+//GetTableSchema returns index mappings as table columns. Returns table without columns if index doesn't exist
+func (e *Elasticsearch) GetTableSchema(tableName string) (*Table, error) {
+	table := &Table{Name: tableName, Columns: Columns{}}
+
+	status, body, err := e.request(http.MethodGet, "/"+url.PathEscape(e.index(tableName))+"/_mapping", nil, "")
+	if err != nil {
+		if status == http.StatusNotFound {
+			return table, nil
+		}
+
+		return nil, errorj.GetTableError.Wrap(err, "failed to get index mapping").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table: tableName,
+			})
+	}
+
+	//index name -> mappings. Alias can point to several indices
+	indices := map[string]struct {
+		Mappings struct {
+			Properties map[string]*elasticsearchMappingProperty `json:"properties"`
+		} `json:"mappings"`
+	}{}
+	if err := json.Unmarshal(body, &indices); err != nil {
+		return nil, errorj.GetTableError.Wrap(err, "failed to parse index mapping").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:     tableName,
+				Statement: string(body),
+			})
+	}
+
+	for _, index := range indices {
+		for name, property := range index.Mappings.Properties {
+			columnType := property.Type
+			if columnType == "" {
+				columnType = elasticsearchObjectType
+			}
+			table.Columns[name] = typing.SQLColumn{Type: columnType}
+		}
+	}
+
+	return table, nil
+}
+
+//CreateTable creates index with mappings and configured settings
+//patches mappings if index has been created concurrently
+func (e *Elasticsearch) CreateTable(table *Table) error {
+	settings := map[string]interface{}{}
+	if e.config.Shards > 0 {
+		settings["number_of_shards"] = e.config.Shards
+	}
+	if e.config.Replicas != nil {
+		settings["number_of_replicas"] = *e.config.Replicas
+	}
+	body := map[string]interface{}{"mappings": map[string]interface{}{"properties": e.mappingProperties(table)}}
+	if len(settings) > 0 {
+		body["settings"] = settings
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	e.queryLogger.LogDDL(fmt.Sprintf("PUT /%s %s", e.index(table.Name), string(payload)))
+	status, responseBody, err := e.request(http.MethodPut, "/"+url.PathEscape(e.index(table.Name)), payload, "application/json")
+	if err != nil {
+		if status == http.StatusBadRequest && strings.Contains(string(responseBody), "resource_already_exists_exception") {
+			return e.PatchTableSchema(table)
+		}
+
+		return errorj.CreateTableError.Wrap(err, "failed to create index").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:     table.Name,
+				Statement: string(payload),
+			})
+	}
+
+	return nil
+}
+
+//PatchTableSchema adds fields to index mappings
+func (e *Elasticsearch) PatchTableSchema(patchTable *Table) error {
+	payload, err := json.Marshal(map[string]interface{}{"properties": e.mappingProperties(patchTable)})
+	if err != nil {
+		return err
+	}
+
+	e.queryLogger.LogDDL(fmt.Sprintf("PUT /%s/_mapping %s", e.index(patchTable.Name), string(payload)))
+	if _, _, err := e.request(http.MethodPut, "/"+url.PathEscape(e.index(patchTable.Name))+"/_mapping", payload, "application/json"); err != nil {
+		return errorj.PatchTableError.Wrap(err, "failed to patch index mapping").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:     patchTable.Name,
+				Statement: string(payload),
+			})
+	}
+
+	return nil
+}
+
+//Truncate deletes all documents from the index
+func (e *Elasticsearch) Truncate(tableName string) error {
+	payload := []byte(`{"query":{"match_all":{}}}`)
+	e.queryLogger.LogQuery(fmt.Sprintf("POST /%s/_delete_by_query %s", e.index(tableName), string(payload)))
+	status, _, err := e.request(http.MethodPost, "/"+url.PathEscape(e.index(tableName))+"/_delete_by_query?refresh=true", payload, "application/json")
+	if err != nil {
+		extraText := ""
+		if status == http.StatusNotFound {
+			extraText = ": " + ErrTableNotExist.Error()
+		}
+		return errorj.TruncateError.Wrap(err, "failed to truncate index"+extraText).
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table: tableName,
+			})
+	}
+
+	return nil
+}
+
+//Update updates fields of the document with whereValue _id. whereKey must be the unique ID field
+func (e *Elasticsearch) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"doc": object})
+	if err != nil {
+		return err
+	}
+
+	id := fmt.Sprint(whereValue)
+	e.queryLogger.LogQueryWithValues(fmt.Sprintf("POST /%s/_update/%s", e.index(table.Name), id), []interface{}{string(payload)})
+	if _, _, err := e.request(http.MethodPost, "/"+url.PathEscape(e.index(table.Name))+"/_update/"+url.PathEscape(id), payload, "application/json"); err != nil {
+		return errorj.UpdateError.Wrap(err, "failed to update document").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:           table.Name,
+				PrimaryKeys:     []string{whereKey},
+				ValuesMapString: fmt.Sprintf("%v", object),
+			})
+	}
+
+	return nil
+}
+
+//DropTable deletes the index or all indices of the alias (see ReplaceTable)
+func (e *Elasticsearch) DropTable(table *Table) error {
+	indices, isAlias, err := e.aliasIndices(table.Name)
+	if err != nil {
+		return err
+	}
+	if !isAlias {
+		indices = []string{e.index(table.Name)}
+	}
+
+	for _, index := range indices {
+		if err := e.deleteIndex(index); err != nil {
+			return errorj.DropError.Wrap(err, "failed to delete index").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Table: table.Name,
+				})
+		}
+	}
+
+	return nil
+}
+
+//ReplaceTable copies replacement index documents with _reindex API into a new index with replacement index mappings
+//and atomically points original table alias to the new index. Previous original index is deleted with the same
+//_aliases request (or after it if the original table is already an alias), so original table isn't changed if reindex fails
+func (e *Elasticsearch) ReplaceTable(originalTable, replacementTable string, dropOldTable bool) error {
+	replacement, err := e.GetTableSchema(replacementTable)
+	if err != nil {
+		return err
+	}
+
+	originalIndex := e.index(originalTable)
+	newIndex := fmt.Sprintf("%s_%d", originalIndex, timestamp.Now().UnixNano())
+	if err := e.CreateTable(&Table{Name: newIndex, Columns: replacement.Columns}); err != nil {
+		return err
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"source": map[string]string{"index": e.index(replacementTable)},
+		"dest":   map[string]string{"index": newIndex},
+	})
+	e.queryLogger.LogQuery("POST /_reindex " + string(payload))
+	if _, _, err := e.request(http.MethodPost, "/_reindex?refresh=true&wait_for_completion=true", payload, "application/json"); err != nil {
+		e.cleanupIndex(newIndex)
+		return errorj.CopyError.Wrap(err, "failed to reindex documents").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:     originalTable,
+				Statement: string(payload),
+			})
+	}
+
+	oldIndices, isAlias, err := e.aliasIndices(originalTable)
+	if err != nil {
+		e.cleanupIndex(newIndex)
+		return err
+	}
+	var actions []map[string]interface{}
+	if isAlias {
+		for _, oldIndex := range oldIndices {
+			actions = append(actions, map[string]interface{}{"remove": map[string]string{"index": oldIndex, "alias": originalIndex}})
+		}
+	} else {
+		exists, err := e.indexExists(originalIndex)
+		if err != nil {
+			e.cleanupIndex(newIndex)
+			return err
+		}
+		if exists {
+			//index can't be replaced with alias of the same name: it is removed in the same atomic request
+			actions = append(actions, map[string]interface{}{"remove_index": map[string]string{"index": originalIndex}})
+		}
+	}
+	actions = append(actions, map[string]interface{}{"add": map[string]string{"index": newIndex, "alias": originalIndex}})
+
+	payload, _ = json.Marshal(map[string]interface{}{"actions": actions})
+	e.queryLogger.LogDDL("POST /_aliases " + string(payload))
+	if _, _, err := e.request(http.MethodPost, "/_aliases", payload, "application/json"); err != nil {
+		e.cleanupIndex(newIndex)
+		return errorj.CopyError.Wrap(err, "failed to swap index alias").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:     originalTable,
+				Statement: string(payload),
+			})
+	}
+
+	if isAlias {
+		for _, oldIndex := range oldIndices {
+			e.cleanupIndex(oldIndex)
+		}
+	}
+
+	if dropOldTable {
+		return e.DropTable(&Table{Name: replacementTable})
+	}
+
+	return nil
+}
+
+//aliasIndices returns indices of the alias and true if table is an alias
+func (e *Elasticsearch) aliasIndices(tableName string) ([]string, bool, error) {
+	status, body, err := e.request(http.MethodGet, "/_alias/"+url.PathEscape(e.index(tableName)), nil, "")
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, false, nil
+		}
+
+		return nil, false, errorj.GetTableError.Wrap(err, "failed to get index alias").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table: tableName,
+			})
+	}
+
+	//index name -> aliases
+	aliases := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &aliases); err != nil {
+		return nil, false, errorj.GetTableError.Wrap(err, "failed to parse index alias").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table:     tableName,
+				Statement: string(body),
+			})
+	}
+	indices := make([]string, 0, len(aliases))
+	for index := range aliases {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	return indices, len(indices) > 0, nil
+}
+
+//indexExists returns true if index (not alias) exists
+func (e *Elasticsearch) indexExists(index string) (bool, error) {
+	status, _, err := e.request(http.MethodHead, "/"+url.PathEscape(index), nil, "")
+	if err != nil {
+		if status == http.StatusNotFound {
+			return false, nil
+		}
+
+		return false, errorj.GetTableError.Wrap(err, "failed to check index existence").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Table: index,
+			})
+	}
+
+	return true, nil
+}
+
+func (e *Elasticsearch) deleteIndex(index string) error {
+	e.queryLogger.LogDDL("DELETE /" + index)
+	_, _, err := e.request(http.MethodDelete, "/"+url.PathEscape(index), nil, "")
+	return err
+}
+
+//cleanupIndex deletes index and logs error if any
+func (e *Elasticsearch) cleanupIndex(index string) {
+	if err := e.deleteIndex(index); err != nil {
+		logging.Warnf("Failed to delete Elasticsearch index %s: %v", index, err)
+	}
+}
+
+//Close closes idle connections
+func (e *Elasticsearch) Close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+//mappingProperties returns index mapping properties of table columns
+func (e *Elasticsearch) mappingProperties(table *Table) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for name, column := range table.Columns {
+		fieldType := column.DDLType()
+		if sqlType, ok := e.sqlTypes[name]; ok {
+			fieldType = sqlType.DDLType()
+		}
+
+		property := map[string]interface{}{"type": fieldType}
+		if fieldType == elasticsearchTextType {
+			property["fields"] = map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256}}
+		}
+		properties[name] = property
+	}
+
+	return properties
+}
+
+//index returns index name of the table. Elasticsearch index names must be lowercase
+func (e *Elasticsearch) index(tableName string) string {
+	return strings.ToLower(tableName)
+}
+
+//request sends request to the next cluster node (or to other nodes if the node is unavailable)
+//returns response status, body and err if request has failed or response status isn't 2xx
+func (e *Elasticsearch) request(method, path string, payload []byte, contentType string) (int, []byte, error) {
+	var lastErr error
+	start := e.nextHost.Inc()
+	for i := 0; i < len(e.config.Hosts); i++ {
+		host := e.config.Hosts[(int(start)+i)%len(e.config.Hosts)]
+		status, body, err := e.requestHost(host, method, path, payload, contentType)
+		if status > 0 {
+			return status, body, err
+		}
+		lastErr = err
+	}
+
+	return 0, nil, lastErr
+}
+
+func (e *Elasticsearch) requestHost(host, method, path string, payload []byte, contentType string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(e.ctx, method, strings.TrimSuffix(host, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.config.APIKey)
+	} else if e.config.Username != "" {
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, body, &HTTPStatusError{StatusCode: resp.StatusCode,
+			Message: fmt.Sprintf("%s %s: [%d %s] %s", method, path, resp.StatusCode, http.StatusText(resp.StatusCode), string(body))}
+	}
+
+	return resp.StatusCode, body, nil
+}
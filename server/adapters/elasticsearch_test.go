@@ -0,0 +1,329 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElasticsearchConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *ElasticsearchConfig
+		errMsg string
+	}{
+		{"valid", &ElasticsearchConfig{Hosts: []string{"https://localhost:9200"}, Refresh: "wait_for"}, ""},
+		{"without hosts", &ElasticsearchConfig{}, "Elasticsearch hosts is required parameter"},
+		{"host without scheme", &ElasticsearchConfig{Hosts: []string{"localhost:9200"}},
+			"malformed Elasticsearch host [localhost:9200]: URL with scheme is expected (e.g. https://localhost:9200)"},
+		{"basic auth and api key", &ElasticsearchConfig{Hosts: []string{"https://localhost:9200"}, Username: "elastic", APIKey: "key"},
+			"Elasticsearch username and api_key can't be used together"},
+		{"unknown refresh", &ElasticsearchConfig{Hosts: []string{"https://localhost:9200"}, Refresh: "always"},
+			"unsupported Elasticsearch refresh: always. Supported values: true, false, wait_for"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+//testElasticsearchServer is a stub of Elasticsearch REST API which keeps mappings and documents in memory
+//documents with "invalid" field are rejected with mapping error, documents with "overloaded" field are rejected with 429
+//aliases can point only to one index
+type testElasticsearchServer struct {
+	mutex       sync.Mutex
+	mappings    map[string]map[string]interface{}
+	documents   map[string]map[string]json.RawMessage
+	aliases     map[string]string
+	auth        string
+	failReindex bool
+}
+
+func newTestElasticsearchServer(t *testing.T) (*testElasticsearchServer, string) {
+	es := &testElasticsearchServer{mappings: map[string]map[string]interface{}{}, documents: map[string]map[string]json.RawMessage{}, aliases: map[string]string{}}
+	server := httptest.NewServer(es)
+	t.Cleanup(server.Close)
+	return es, server.URL
+}
+
+func (es *testElasticsearchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	es.auth = r.Header.Get("Authorization")
+	body, _ := ioutil.ReadAll(r.Body)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if index, ok := es.aliases[parts[0]]; ok && r.Method != http.MethodDelete && r.Method != http.MethodHead {
+		parts[0] = index
+	}
+	switch {
+	case r.URL.Path == "/":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"version": map[string]string{"number": "7.17.0"}})
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		es.bulk(w, body)
+	case r.Method == http.MethodPost && r.URL.Path == "/_reindex":
+		es.reindex(w, body)
+	case r.Method == http.MethodPost && r.URL.Path == "/_aliases":
+		es.updateAliases(w, body)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "_alias":
+		index, ok := es.aliases[parts[1]]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "alias [" + parts[1] + "] missing", "status": http.StatusNotFound})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{index: map[string]interface{}{"aliases": map[string]interface{}{parts[1]: map[string]interface{}{}}}})
+	case r.Method == http.MethodHead && len(parts) == 1:
+		if _, ok := es.mappings[parts[0]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[1] == "_mapping":
+		properties, ok := es.mappings[parts[0]]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": map[string]string{"type": "index_not_found_exception"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{parts[0]: map[string]interface{}{"mappings": map[string]interface{}{"properties": properties}}})
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[1] == "_mapping":
+		request := map[string]map[string]interface{}{}
+		_ = json.Unmarshal(body, &request)
+		for name, property := range request["properties"] {
+			es.mappings[parts[0]][name] = property
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+	case r.Method == http.MethodPut && len(parts) == 1:
+		if _, ok := es.mappings[parts[0]]; ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": map[string]string{"type": "resource_already_exists_exception"}})
+			return
+		}
+		request := map[string]map[string]map[string]interface{}{}
+		_ = json.Unmarshal(body, &request)
+		es.mappings[parts[0]] = request["mappings"]["properties"]
+		es.documents[parts[0]] = map[string]json.RawMessage{}
+		writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		if _, ok := es.aliases[parts[0]]; ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": map[string]string{"type": "illegal_argument_exception"}})
+			return
+		}
+		for alias, index := range es.aliases {
+			if index == parts[0] {
+				delete(es.aliases, alias)
+			}
+		}
+		delete(es.mappings, parts[0])
+		delete(es.documents, parts[0])
+		writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (es *testElasticsearchServer) bulk(w http.ResponseWriter, body []byte) {
+	var items []map[string]interface{}
+	hasErrors := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		action := map[string]map[string]string{}
+		_ = json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		document := append([]byte{}, scanner.Bytes()...)
+
+		index, id := action["index"]["_index"], action["index"]["_id"]
+		result := map[string]interface{}{"_index": index, "_id": id, "status": http.StatusCreated}
+		switch {
+		case bytes.Contains(document, []byte(`"invalid"`)):
+			hasErrors = true
+			result["status"] = http.StatusBadRequest
+			result["error"] = map[string]string{"type": "mapper_parsing_exception", "reason": "failed to parse field [invalid]"}
+		case bytes.Contains(document, []byte(`"overloaded"`)):
+			hasErrors = true
+			result["status"] = http.StatusTooManyRequests
+			result["error"] = map[string]string{"type": "es_rejected_execution_exception", "reason": "rejected execution"}
+		default:
+			if es.documents[index] == nil {
+				es.documents[index] = map[string]json.RawMessage{}
+			}
+			es.documents[index][id] = document
+		}
+		items = append(items, map[string]interface{}{"index": result})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errors": hasErrors, "items": items})
+}
+
+func (es *testElasticsearchServer) reindex(w http.ResponseWriter, body []byte) {
+	request := map[string]map[string]string{}
+	_ = json.Unmarshal(body, &request)
+	source, dest := request["source"]["index"], request["dest"]["index"]
+	if es.failReindex {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": map[string]string{"type": "reindex_failure"}})
+		return
+	}
+	if es.documents[dest] == nil {
+		es.documents[dest] = map[string]json.RawMessage{}
+	}
+	for id, document := range es.documents[source] {
+		es.documents[dest][id] = document
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"total": len(es.documents[source])})
+}
+
+func (es *testElasticsearchServer) updateAliases(w http.ResponseWriter, body []byte) {
+	request := struct {
+		Actions []map[string]map[string]string `json:"actions"`
+	}{}
+	_ = json.Unmarshal(body, &request)
+	for _, action := range request.Actions {
+		if remove, ok := action["remove"]; ok {
+			delete(es.aliases, remove["alias"])
+		}
+		if removeIndex, ok := action["remove_index"]; ok {
+			delete(es.mappings, removeIndex["index"])
+			delete(es.documents, removeIndex["index"])
+		}
+		if add, ok := action["add"]; ok {
+			es.aliases[add["alias"]] = add["index"]
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"acknowledged": true})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestElasticsearch(t *testing.T) {
+	server, url := newTestElasticsearchServer(t)
+
+	_, err := NewElasticsearch(context.Background(), &ElasticsearchConfig{Hosts: []string{"http://127.0.0.1:1"}}, identifiers.NewUniqueID("/eventn_ctx/event_id"), &logging.QueryLogger{}, typing.SQLTypes{})
+	require.Error(t, err, "unavailable cluster must fail")
+
+	replicas := 0
+	config := &ElasticsearchConfig{Hosts: []string{"http://127.0.0.1:1", url}, Username: "elastic", Password: "secret", Replicas: &replicas}
+	es, err := NewElasticsearch(context.Background(), config, identifiers.NewUniqueID("/eventn_ctx/event_id"), &logging.QueryLogger{}, typing.SQLTypes{})
+	require.NoError(t, err, "unavailable hosts must be skipped")
+	defer es.Close()
+	require.True(t, strings.HasPrefix(server.auth, "Basic "))
+
+	table, err := es.GetTableSchema("Events_2022_05_18")
+	require.NoError(t, err)
+	require.False(t, table.Exists())
+
+	require.NoError(t, es.CreateTable(&Table{Name: "Events_2022_05_18", Columns: Columns{
+		"eventn_ctx_event_id": typing.SQLColumn{Type: "keyword"},
+		"user_email":          typing.SQLColumn{Type: "text"},
+	}}))
+	require.NoError(t, es.CreateTable(&Table{Name: "Events_2022_05_18", Columns: Columns{"revenue": typing.SQLColumn{Type: "double"}}}),
+		"mappings of existing index must be patched")
+
+	table, err = es.GetTableSchema("Events_2022_05_18")
+	require.NoError(t, err)
+	require.Equal(t, Columns{
+		"eventn_ctx_event_id": typing.SQLColumn{Type: "keyword"},
+		"user_email":          typing.SQLColumn{Type: "text"},
+		"revenue":             typing.SQLColumn{Type: "double"},
+	}, table.Columns)
+	require.Equal(t, map[string]interface{}{"type": "text", "fields": map[string]interface{}{"keyword": map[string]interface{}{"type": "keyword", "ignore_above": float64(256)}}},
+		server.mappings["events_2022_05_18"]["user_email"], "strings must have keyword subfield")
+
+	//batch: rejected documents are returned with indices in the batch
+	objects := []map[string]interface{}{
+		{"eventn_ctx_event_id": "1", "user_email": "a@jitsu.com"},
+		{"eventn_ctx_event_id": "2", "invalid": true},
+		{"eventn_ctx_event_id": "3", "user_email": "b@jitsu.com"},
+	}
+	err = es.Insert(NewBatchInsertContext(table, objects, true, nil))
+	var bulkErr *ElasticsearchBulkError
+	require.True(t, errors.As(err, &bulkErr))
+	require.False(t, bulkErr.Retryable())
+	require.Len(t, bulkErr.Items, 1)
+	require.Equal(t, "mapper_parsing_exception", bulkErr.Items[1].Type)
+	require.EqualError(t, bulkErr, "1 of 3 documents have been rejected by index events_2022_05_18. First error: [400 Bad Request] mapper_parsing_exception: failed to parse field [invalid]")
+	require.Len(t, server.documents["events_2022_05_18"], 2)
+	require.JSONEq(t, `{"eventn_ctx_event_id":"3","user_email":"b@jitsu.com"}`, string(server.documents["events_2022_05_18"]["3"]), "unique ID must be used as a document _id")
+
+	//stream: document errors are returned as insert errors
+	err = es.Insert(NewSingleInsertContext(&EventContext{Table: table, ProcessedEvent: map[string]interface{}{"eventn_ctx_event_id": "4", "overloaded": true}}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "[429 Too Many Requests] es_rejected_execution_exception: rejected execution")
+
+	require.NoError(t, es.Insert(NewSingleInsertContext(&EventContext{Table: table, ProcessedEvent: map[string]interface{}{"eventn_ctx_event_id": "1", "user_email": "c@jitsu.com"}})))
+	require.Len(t, server.documents["events_2022_05_18"], 2, "document with the same unique ID must be overwritten")
+
+	require.NoError(t, es.DropTable(&Table{Name: "Events_2022_05_18"}))
+	table, err = es.GetTableSchema("Events_2022_05_18")
+	require.NoError(t, err)
+	require.False(t, table.Exists())
+}
+
+func TestElasticsearchReplaceTable(t *testing.T) {
+	server, url := newTestElasticsearchServer(t)
+	es, err := NewElasticsearch(context.Background(), &ElasticsearchConfig{Hosts: []string{url}}, identifiers.NewUniqueID("/eventn_ctx/event_id"), &logging.QueryLogger{}, typing.SQLTypes{})
+	require.NoError(t, err)
+	defer es.Close()
+
+	createIndex := func(name string, ids ...string) {
+		table := &Table{Name: name, Columns: Columns{"eventn_ctx_event_id": typing.SQLColumn{Type: "keyword"}}}
+		require.NoError(t, es.CreateTable(table))
+		var objects []map[string]interface{}
+		for _, id := range ids {
+			objects = append(objects, map[string]interface{}{"eventn_ctx_event_id": id})
+		}
+		require.NoError(t, es.Insert(NewBatchInsertContext(table, objects, true, nil)))
+	}
+
+	//index is replaced with alias
+	createIndex("users", "1")
+	createIndex("users_tmp", "2", "3")
+	require.NoError(t, es.ReplaceTable("users", "users_tmp", true))
+	firstIndex := server.aliases["users"]
+	require.True(t, strings.HasPrefix(firstIndex, "users_"), "original table must be an alias")
+	require.NotContains(t, server.mappings, "users_tmp", "replacement index must be dropped")
+	require.Len(t, server.documents[firstIndex], 2)
+	table, err := es.GetTableSchema("users")
+	require.NoError(t, err)
+	require.True(t, table.Exists())
+
+	//failed reindex doesn't change original table
+	createIndex("users_tmp", "4")
+	server.failReindex = true
+	require.Error(t, es.ReplaceTable("users", "users_tmp", true))
+	require.Equal(t, firstIndex, server.aliases["users"])
+	require.Len(t, server.documents[firstIndex], 2)
+	require.Len(t, server.mappings, 2, "only alias index and replacement index must exist")
+
+	//alias is swapped and the previous index is deleted
+	server.failReindex = false
+	require.NoError(t, es.ReplaceTable("users", "users_tmp", true))
+	secondIndex := server.aliases["users"]
+	require.NotEqual(t, firstIndex, secondIndex)
+	require.NotContains(t, server.mappings, firstIndex, "previous index must be deleted after alias swap")
+	require.Len(t, server.documents[secondIndex], 1)
+
+	require.NoError(t, es.DropTable(&Table{Name: "users"}))
+	require.Empty(t, server.mappings)
+	require.Empty(t, server.aliases)
+}
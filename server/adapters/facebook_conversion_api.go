@@ -18,6 +18,8 @@ import (
 
 const (
 	eventsURLTemplate = "https://graph.facebook.com/v13.0/%s/events?access_token=%s&locale=en_EN"
+	//facebookMaxBatchSize is a max events count in one Conversion API request
+	facebookMaxBatchSize = 1000
 )
 
 var (
@@ -35,8 +37,9 @@ var (
 
 //FacebookConversionAPIConfig dto for deserialized datasource config (e.g. in Facebook destination)
 type FacebookConversionAPIConfig struct {
-	PixelID     string `mapstructure:"pixel_id,omitempty" json:"pixel_id,omitempty" yaml:"pixel_id,omitempty"`
-	AccessToken string `mapstructure:"access_token,omitempty" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	PixelID     string           `mapstructure:"pixel_id,omitempty" json:"pixel_id,omitempty" yaml:"pixel_id,omitempty"`
+	AccessToken string           `mapstructure:"access_token,omitempty" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	Batch       *HTTPBatchConfig `mapstructure:"batch,omitempty" json:"batch,omitempty" yaml:"batch,omitempty"`
 }
 
 //Validate required fields in FacebookConversionAPIConfig
@@ -52,7 +55,7 @@ func (fmc *FacebookConversionAPIConfig) Validate() error {
 		return errors.New("access_token is required parameter")
 	}
 
-	return nil
+	return fmc.Batch.Validate()
 }
 
 //FacebookResponse is a dto for parsing Facebook response
@@ -89,6 +92,7 @@ func NewTestFacebookConversion(config *FacebookConversionAPIConfig) *FacebookCon
 //NewFacebookConversion returns new instance of adapter
 func NewFacebookConversion(config *FacebookConversionAPIConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*FacebookConversionAPI, error) {
 	httpAdapterConfiguration.HTTPReqFactory = &FacebookRequestFactory{config: config}
+	httpAdapterConfiguration.Batch = config.Batch
 
 	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
 	if err != nil {
@@ -262,5 +266,49 @@ func (frf *FacebookRequestFactory) hashFields(object map[string]interface{}) {
 	}
 }
 
+//BatchKey returns key of requests with the same URL and test_event_code
+func (frf *FacebookRequestFactory) BatchKey(req *Request) string {
+	eventsReq := &FacebookConversionEventsReq{}
+	if err := json.Unmarshal(req.Body, eventsReq); err != nil {
+		return ""
+	}
+
+	return requestBatchKey(req) + "\n" + eventsReq.TestEventCode
+}
+
+//MaxBatchSize returns Conversion API limit of events in one request
+func (frf *FacebookRequestFactory) MaxBatchSize() int {
+	return facebookMaxBatchSize
+}
+
+//CreateBatch returns request with data[] of all requests
+func (frf *FacebookRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	batch := &FacebookConversionEventsReq{Data: make([]map[string]interface{}, 0, len(requests))}
+	for _, req := range requests {
+		eventsReq := &FacebookConversionEventsReq{}
+		if err := json.Unmarshal(req.Body, eventsReq); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling facebook request: %v", err)
+		}
+		batch.Data = append(batch.Data, eventsReq.Data...)
+		batch.TestEventCode = eventsReq.TestEventCode
+	}
+
+	bodyPayload, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling facebook batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    bodyPayload,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns nil: Conversion API accepts or rejects requests as a whole
+func (frf *FacebookRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	return nil
+}
+
 func (frf *FacebookRequestFactory) Close() {
 }
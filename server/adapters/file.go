@@ -3,8 +3,12 @@ package adapters
 import (
 	"bytes"
 	"compress/gzip"
+	"io"
 
+	"github.com/golang/snappy"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 	"github.com/pkg/errors"
 )
 
@@ -14,17 +18,107 @@ type (
 )
 
 const (
-	FileFormatFlatJSON  FileEncodingFormat = "flat_json" //flattened json objects with \n delimiter
-	FileFormatJSON      FileEncodingFormat = "json"      //file with json objects with \n delimiter (not flattened)
-	FileFormatCSV       FileEncodingFormat = "csv"       //flattened csv objects with \n delimiter
-	FileFormatParquet   FileEncodingFormat = "parquet"   //flattened objects which are marshalled in apache parquet file
-	FileCompressionGZIP FileCompression    = "gzip"      //gzip compression
+	FileFormatFlatJSON    FileEncodingFormat = "flat_json" //flattened json objects with \n delimiter
+	FileFormatJSON        FileEncodingFormat = "json"      //file with json objects with \n delimiter (not flattened)
+	FileFormatCSV         FileEncodingFormat = "csv"       //flattened csv objects with \n delimiter
+	FileFormatParquet     FileEncodingFormat = "parquet"   //flattened objects which are marshalled in apache parquet file
+	FileFormatAvro        FileEncodingFormat = "avro"      //flattened objects which are marshalled in apache avro container file with embedded schema
+	FileFormatORC         FileEncodingFormat = "orc"       //flattened objects which are marshalled in apache orc file
+	FileCompressionGZIP   FileCompression    = "gzip"      //gzip compression
+	FileCompressionZSTD   FileCompression    = "zstd"      //zstandard compression
+	FileCompressionSnappy FileCompression    = "snappy"    //snappy compression
+	FileCompressionLZ4    FileCompression    = "lz4"       //lz4 compression
+
+	FileTableFormatDelta = "delta" //parquet data files with Delta Lake transaction log
 )
 
+//ErrObjectNotExist is returned by ObjectStorage if requested object doesn't exist
+var ErrObjectNotExist = errors.New("object doesn't exist")
+
+//ObjectStorage is a raw objects storage which is used by table formats (e.g. Delta Lake)
+//keys are relative to the configured folder and objects are written without compression
+type ObjectStorage interface {
+	PutObject(key string, data []byte) error
+	//GetObject returns ErrObjectNotExist if object doesn't exist
+	GetObject(key string) ([]byte, error)
+	//ListObjects returns keys of objects with the prefix
+	ListObjects(prefix string) ([]string, error)
+}
+
 type FileConfig struct {
 	Folder      string             `mapstructure:"folder,omitempty" json:"folder,omitempty" yaml:"folder,omitempty"`
 	Format      FileEncodingFormat `mapstructure:"format,omitempty" json:"format,omitempty" yaml:"format,omitempty"`
 	Compression FileCompression    `mapstructure:"compression,omitempty" json:"compression,omitempty" yaml:"compression,omitempty"`
+	TableFormat string             `mapstructure:"table_format,omitempty" json:"table_format,omitempty" yaml:"table_format,omitempty"`
+}
+
+//ValidateTableFormat returns err if table format isn't supported or can't be used with configured format
+//table formats are written as parquet files
+func (c *FileConfig) ValidateTableFormat() error {
+	if c.TableFormat == "" {
+		return nil
+	}
+
+	if c.TableFormat != FileTableFormatDelta {
+		return errors.Errorf("unsupported table_format: %s. Supported values: %s", c.TableFormat, FileTableFormatDelta)
+	}
+
+	if c.Format != "" && c.Format != FileFormatParquet {
+		return errors.Errorf("table_format %s requires %s format", c.TableFormat, FileFormatParquet)
+	}
+	c.Format = FileFormatParquet
+
+	return nil
+}
+
+//ValidateCompression returns err if compression isn't supported or can't be used with configured format
+func (c *FileConfig) ValidateCompression() error {
+	switch c.Compression {
+	case "", FileCompressionGZIP, FileCompressionZSTD, FileCompressionSnappy:
+	case FileCompressionLZ4:
+		//parquet-go writes LZ4 pages as lz4 frames which other parquet readers can't decode
+		if c.Format == FileFormatAvro || c.Format == FileFormatParquet {
+			return errors.Errorf("compression %s isn't supported by %s format", c.Compression, c.Format)
+		}
+	default:
+		return errors.Errorf("unsupported compression: %s. Supported values: %s, %s, %s, %s", c.Compression, FileCompressionGZIP, FileCompressionZSTD, FileCompressionSnappy, FileCompressionLZ4)
+	}
+
+	return nil
+}
+
+//CompressesFile returns true if the whole file is compressed (and has compression extension)
+//parquet, avro and orc files are compressed by blocks inside the file instead.
+//gzip parquet files are additionally compressed as a whole for backward compatibility
+func (c FileConfig) CompressesFile() bool {
+	switch c.Format {
+	case FileFormatAvro, FileFormatORC:
+		return false
+	case FileFormatParquet:
+		return c.Compression == FileCompressionGZIP
+	default:
+		return c.Compression != ""
+	}
+}
+
+//ContentType returns file MIME type if the whole file is compressed or empty string
+func (c FileConfig) ContentType() string {
+	if !c.CompressesFile() {
+		return ""
+	}
+
+	switch c.Compression {
+	case FileCompressionGZIP:
+		return "application/gzip"
+	case FileCompressionZSTD:
+		return "application/zstd"
+	case FileCompressionSnappy:
+		return "application/x-snappy-framed"
+	case FileCompressionLZ4:
+		return "application/x-lz4"
+	default:
+		return ""
+	}
 }
 
 func (c FileConfig) PrepareFile(fileName *string, fileBytes *[]byte) error {
@@ -32,11 +126,10 @@ func (c FileConfig) PrepareFile(fileName *string, fileBytes *[]byte) error {
 		*fileName = c.Folder + "/" + *fileName
 	}
 
-	if c.Compression == FileCompressionGZIP {
-		*fileName = fileNameGZIP(*fileName)
+	if c.CompressesFile() {
+		*fileName = fileNameWithCompression(*fileName, c.Compression)
 		if fileBytes != nil {
-			var err error
-			buf, err := compressGZIP(*fileBytes)
+			buf, err := compressFile(*fileBytes, c.Compression)
 			if err != nil {
 				return errors.Errorf("Error compressing file %v", err)
 			}
@@ -48,20 +141,77 @@ func (c FileConfig) PrepareFile(fileName *string, fileBytes *[]byte) error {
 	return nil
 }
 
+//ObjectsPrefix returns objects key prefix with configured folder
+func (c FileConfig) ObjectsPrefix(prefix string) string {
+	if c.Folder != "" {
+		return c.Folder + "/" + prefix
+	}
+
+	return prefix
+}
+
 func (c *FileConfig) RequireDefaultStage(storageType string) {
 	if c.Folder != "" {
 		logging.Warnf("customizing folder [%s] is not supported for [%s] stage, using root directory", c.Folder, storageType)
 		c.Folder = ""
 	}
 
+	if c.TableFormat != "" {
+		logging.Warnf("customizing table_format [%s] is not supported for [%s] stage, using plain files", c.TableFormat, storageType)
+		c.TableFormat = ""
+	}
+
 	if c.Compression != "" {
 		logging.Warnf("customizing compression [%s] is not supported for [%s] stage, using no compression", c.Compression, storageType)
 		c.Compression = ""
 	}
 }
 
-func fileNameGZIP(fileName string) string {
-	return fileName + ".gz"
+func fileNameWithCompression(fileName string, compression FileCompression) string {
+	switch compression {
+	case FileCompressionGZIP:
+		return fileName + ".gz"
+	case FileCompressionZSTD:
+		return fileName + ".zst"
+	case FileCompressionSnappy:
+		return fileName + ".sz"
+	case FileCompressionLZ4:
+		return fileName + ".lz4"
+	default:
+		return fileName
+	}
+}
+
+//compressFile returns file bytes compressed as a stream: gzip, zstd frame, snappy framing format or lz4 frame
+func compressFile(b []byte, compression FileCompression) (*bytes.Buffer, error) {
+	if compression == FileCompressionGZIP {
+		return compressGZIP(b)
+	}
+
+	buf := new(bytes.Buffer)
+	var w io.WriteCloser
+	switch compression {
+	case FileCompressionZSTD:
+		encoder, err := zstd.NewWriter(buf)
+		if err != nil {
+			return nil, err
+		}
+		w = encoder
+	case FileCompressionSnappy:
+		w = snappy.NewBufferedWriter(buf)
+	case FileCompressionLZ4:
+		w = lz4.NewWriter(buf)
+	default:
+		return nil, errors.Errorf("unsupported compression: %s", compression)
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
 }
 
 func compressGZIP(b []byte) (*bytes.Buffer, error) {
@@ -0,0 +1,270 @@
+package adapters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+const (
+	FileRotationHourly = "hourly" //files are grouped into <yyyy-mm-dd>/<hh> directories
+	FileRotationDaily  = "daily"  //files are grouped into <yyyy-mm-dd> directories
+
+	fileManifestSuffix = ".manifest.json"
+)
+
+//FileDropConfig is a base configuration of file drop destinations (local directory, SFTP server)
+type FileDropConfig struct {
+	//Directory is a root directory of written files
+	Directory string `mapstructure:"directory,omitempty" json:"directory,omitempty" yaml:"directory,omitempty"`
+	//Rotation is a files grouping period: hourly, daily or empty (all files in one directory)
+	Rotation   string `mapstructure:"rotation,omitempty" json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	FileConfig `mapstructure:",squash" yaml:"-,inline"`
+}
+
+//Validate returns err if rotation, format or compression aren't supported
+func (fdc *FileDropConfig) Validate() error {
+	switch fdc.Rotation {
+	case "", FileRotationHourly, FileRotationDaily:
+	default:
+		return fmt.Errorf("unsupported rotation: %s. Supported values: %s, %s", fdc.Rotation, FileRotationHourly, FileRotationDaily)
+	}
+
+	if fdc.TableFormat != "" {
+		return fmt.Errorf("table_format isn't supported by file drop destinations")
+	}
+
+	if fdc.Format == "" {
+		fdc.Format = FileFormatFlatJSON
+	}
+
+	return fdc.ValidateCompression()
+}
+
+//rotationDirectory returns directory of the period which the time belongs to or empty string if rotation isn't configured
+func (fdc *FileDropConfig) rotationDirectory(t time.Time) string {
+	switch fdc.Rotation {
+	case FileRotationHourly:
+		return t.UTC().Format("2006-01-02/15")
+	case FileRotationDaily:
+		return t.UTC().Format("2006-01-02")
+	default:
+		return ""
+	}
+}
+
+//FileBatch is a written batch description
+type FileBatch struct {
+	Table string
+	Rows  int
+}
+
+//FileManifest is written next to every data file after the data file has been written completely
+//consumers should pick up only data files with manifests
+type FileManifest struct {
+	File        string    `json:"file"`
+	Table       string    `json:"table,omitempty"`
+	Rows        int       `json:"rows,omitempty"`
+	Size        int       `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Format      string    `json:"format"`
+	Compression string    `json:"compression,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+//fileDropStorage is a storage of file drop destinations
+type fileDropStorage interface {
+	ObjectStorage
+	DeleteObject(key string) error
+}
+
+//FileDrop writes batch files into ObjectStorage (local directory or SFTP server):
+//every file is written atomically (temporary file and rename) and is followed by the manifest file
+type FileDrop struct {
+	config  *FileDropConfig
+	storage fileDropStorage
+	closer  io.Closer
+}
+
+//NewLocalFileDrop returns FileDrop which writes files into the local directory inside baseDir
+//creates the directory if it doesn't exist
+func NewLocalFileDrop(config *FileDropConfig, baseDir string) (*FileDrop, error) {
+	dir, err := ResolveLocalDirectory(config, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	localFileSystem, err := NewLocalFileSystem(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileDrop{config: config, storage: localFileSystem}, nil
+}
+
+//ResolveLocalDirectory returns absolute path of the configured directory. Relative directory is resolved against baseDir
+//returns err if the directory or the folder inside it (symlinks are resolved) is outside of baseDir
+func ResolveLocalDirectory(config *FileDropConfig, baseDir string) (string, error) {
+	if baseDir == "" {
+		return "", errors.New("file destinations base directory isn't configured (server.file_destinations.base_dir)")
+	}
+	if config.Directory == "" {
+		return "", errors.New("directory is required parameter")
+	}
+
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("error resolving file destinations base directory %s: %v", baseDir, err)
+	}
+	base = resolveSymlinks(base)
+
+	dir := config.Directory
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	dir = resolveSymlinks(filepath.Clean(dir))
+	if !isSubPath(base, dir) || !isSubPath(base, resolveSymlinks(filepath.Join(dir, filepath.FromSlash(config.Folder)))) {
+		return "", fmt.Errorf("directory %s (folder: %s) is outside of the file destinations base directory %s", config.Directory, config.Folder, base)
+	}
+
+	return dir, nil
+}
+
+//resolveSymlinks returns path with resolved symlinks of the longest existing part of the path
+func resolveSymlinks(path string) string {
+	existing, rest := path, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+//ValidateLocalDirectory checks that the configured directory inside baseDir exists and is writable
+//doesn't create any directories: a temporary file is created in the directory and removed
+func ValidateLocalDirectory(config *FileDropConfig, baseDir string) error {
+	dir, err := ResolveLocalDirectory(config, baseDir)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("directory %s doesn't exist", dir)
+		}
+		return fmt.Errorf("error checking directory %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s isn't a directory", dir)
+	}
+
+	testFile, err := ioutil.TempFile(dir, ".jitsu_test_*"+localTmpFileSuffix)
+	if err != nil {
+		return fmt.Errorf("directory %s isn't writable: %v", dir, err)
+	}
+	testFile.Close()
+	if err := os.Remove(testFile.Name()); err != nil {
+		logging.Warnf("Cannot remove test file %q: %v", testFile.Name(), err)
+	}
+
+	return nil
+}
+
+//NewSFTPFileDrop returns FileDrop which writes files into the directory on the SFTP server
+func NewSFTPFileDrop(config *SFTPConfig) (*FileDrop, error) {
+	sftp, err := NewSFTP(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileDrop{config: &config.FileDropConfig, storage: sftp, closer: sftp}, nil
+}
+
+func (fd *FileDrop) Format() FileEncodingFormat {
+	return fd.config.Format
+}
+
+func (fd *FileDrop) Compression() FileCompression {
+	return fd.config.Compression
+}
+
+//UploadBytes writes the file with the manifest
+func (fd *FileDrop) UploadBytes(fileName string, fileBytes []byte) error {
+	return fd.UploadBatch(fileName, fileBytes, nil)
+}
+
+//UploadBatch writes the file into the current rotation directory and then writes the manifest with batch description
+func (fd *FileDrop) UploadBatch(fileName string, fileBytes []byte, batch *FileBatch) error {
+	now := timestamp.Now().UTC()
+	fileName = path.Join(fd.config.rotationDirectory(now), fileName)
+	if err := fd.config.PrepareFile(&fileName, &fileBytes); err != nil {
+		return err
+	}
+
+	if err := fd.storage.PutObject(fileName, fileBytes); err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(fileBytes)
+	manifest := &FileManifest{
+		File:        path.Base(fileName),
+		Size:        len(fileBytes),
+		SHA256:      hex.EncodeToString(checksum[:]),
+		Format:      string(fd.config.Format),
+		Compression: string(fd.config.Compression),
+		CreatedAt:   now,
+	}
+	if batch != nil {
+		manifest.Table = batch.Table
+		manifest.Rows = batch.Rows
+	}
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializing manifest of %s: %v", fileName, err)
+	}
+
+	return fd.storage.PutObject(fileName+fileManifestSuffix, manifestBytes)
+}
+
+//ValidateWritePermission tries to create temporary file and remove it.
+//returns nil if file creation was successful.
+func (fd *FileDrop) ValidateWritePermission() error {
+	key := fd.config.ObjectsPrefix(fmt.Sprintf("test_%v", timestamp.NowUTC()))
+	if err := fd.storage.PutObject(key, []byte{}); err != nil {
+		return err
+	}
+
+	if err := fd.storage.DeleteObject(key); err != nil {
+		logging.Warnf("Cannot remove test file %q: %v", key, err)
+	}
+
+	return nil
+}
+
+//Close closes underlying connection if any
+func (fd *FileDrop) Close() error {
+	if fd.closer != nil {
+		return fd.closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package adapters
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFileDropConfigValidate(t *testing.T) {
+	config := &FileDropConfig{Directory: "/tmp", Rotation: FileRotationHourly}
+	require.NoError(t, config.Validate())
+	require.Equal(t, FileFormatFlatJSON, config.Format, "default format must be set")
+
+	require.EqualError(t, (&FileDropConfig{Rotation: "weekly"}).Validate(), "unsupported rotation: weekly. Supported values: hourly, daily")
+	require.EqualError(t, (&FileDropConfig{FileConfig: FileConfig{TableFormat: FileTableFormatDelta}}).Validate(), "table_format isn't supported by file drop destinations")
+	require.EqualError(t, (&FileDropConfig{FileConfig: FileConfig{Compression: "brotli"}}).Validate(), "unsupported compression: brotli. Supported values: gzip, zstd, snappy, lz4")
+
+	require.EqualError(t, (&SFTPConfig{Host: "localhost"}).Validate(), "SFTP user is required parameter")
+	require.EqualError(t, (&SFTPConfig{Host: "localhost", User: "jitsu"}).Validate(), "SFTP password or private_key is required parameter")
+	require.EqualError(t, (&SFTPConfig{Host: "localhost", User: "jitsu", Password: "secret"}).Validate(), "SFTP known_host_key is required parameter: SFTP server public key (e.g. output of 'ssh-keyscan -t ed25519 host')")
+}
+
+func TestLocalFileDrop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_drop")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := &FileDropConfig{Directory: "exports", Rotation: FileRotationHourly, FileConfig: FileConfig{Folder: "events", Format: FileFormatJSON, Compression: FileCompressionGZIP}}
+	require.NoError(t, config.Validate())
+	fileDrop, err := NewLocalFileDrop(config, dir)
+	require.NoError(t, err)
+	defer fileDrop.Close()
+
+	testFileDrop(t, fileDrop, func(key string) string {
+		return filepath.Join(dir, "exports", filepath.FromSlash(key))
+	})
+
+	localFileSystem, err := NewLocalFileSystem(filepath.Join(dir, "exports"))
+	require.NoError(t, err)
+	require.EqualError(t, localFileSystem.PutObject("../outside.log", []byte("{}")),
+		fmt.Sprintf("object key ../outside.log points outside of the directory %s", filepath.Join(dir, "exports")))
+}
+
+func TestResolveLocalDirectory(t *testing.T) {
+	baseDir := resolveSymlinks(t.TempDir())
+	outsideDir := t.TempDir()
+	require.NoError(t, os.Symlink(outsideDir, filepath.Join(baseDir, "link")))
+
+	tests := []struct {
+		name        string
+		config      *FileDropConfig
+		baseDir     string
+		expectedDir string
+		expectedErr string
+	}{
+		{"relative directory", &FileDropConfig{Directory: "exports"}, baseDir, filepath.Join(baseDir, "exports"), ""},
+		{"absolute directory", &FileDropConfig{Directory: filepath.Join(baseDir, "a", "b")}, baseDir, filepath.Join(baseDir, "a", "b"), ""},
+		{"base directory isn't configured", &FileDropConfig{Directory: "exports"}, "", "", "file destinations base directory isn't configured (server.file_destinations.base_dir)"},
+		{"without directory", &FileDropConfig{}, baseDir, "", "directory is required parameter"},
+		{"absolute directory outside", &FileDropConfig{Directory: "/etc"}, baseDir, "", fmt.Sprintf("directory /etc (folder: ) is outside of the file destinations base directory %s", baseDir)},
+		{"relative directory outside", &FileDropConfig{Directory: "../etc"}, baseDir, "", fmt.Sprintf("directory ../etc (folder: ) is outside of the file destinations base directory %s", baseDir)},
+		{"folder outside", &FileDropConfig{Directory: "exports", FileConfig: FileConfig{Folder: "../../etc"}}, baseDir, "", fmt.Sprintf("directory exports (folder: ../../etc) is outside of the file destinations base directory %s", baseDir)},
+		{"symlink outside", &FileDropConfig{Directory: "link/exports"}, baseDir, "", fmt.Sprintf("directory link/exports (folder: ) is outside of the file destinations base directory %s", baseDir)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ResolveLocalDirectory(tt.config, tt.baseDir)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedDir, dir)
+			}
+		})
+	}
+}
+
+func TestValidateLocalDirectory(t *testing.T) {
+	baseDir := resolveSymlinks(t.TempDir())
+
+	require.EqualError(t, ValidateLocalDirectory(&FileDropConfig{Directory: "exports"}, baseDir), fmt.Sprintf("directory %s doesn't exist", filepath.Join(baseDir, "exports")))
+	_, err := os.Stat(filepath.Join(baseDir, "exports"))
+	require.True(t, os.IsNotExist(err), "directory mustn't be created by validation")
+
+	require.NoError(t, os.Mkdir(filepath.Join(baseDir, "exports"), 0755))
+	require.NoError(t, ValidateLocalDirectory(&FileDropConfig{Directory: "exports"}, baseDir))
+	files, err := ioutil.ReadDir(filepath.Join(baseDir, "exports"))
+	require.NoError(t, err)
+	require.Empty(t, files, "test file must be removed")
+}
+
+func TestSFTPFileDrop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sftp_file_drop")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	addr, hostKey := startTestSFTPServer(t, "jitsu", "secret")
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	_, err = NewSFTPFileDrop(&SFTPConfig{Host: host, Port: portNumber, User: "jitsu", Password: "wrong", KnownHostKey: hostKey, FileDropConfig: FileDropConfig{Directory: dir}})
+	require.Error(t, err, "wrong password must fail")
+
+	_, otherHostKey := startTestSFTPServer(t, "jitsu", "secret")
+	_, err = NewSFTPFileDrop(&SFTPConfig{Host: host, Port: portNumber, User: "jitsu", Password: "secret", KnownHostKey: otherHostKey, FileDropConfig: FileDropConfig{Directory: dir}})
+	require.Error(t, err, "unknown host key must fail")
+
+	config := &SFTPConfig{Host: host, Port: portNumber, User: "jitsu", Password: "secret", KnownHostKey: hostKey,
+		FileDropConfig: FileDropConfig{Directory: dir, Rotation: FileRotationHourly, FileConfig: FileConfig{Folder: "events", Format: FileFormatJSON, Compression: FileCompressionGZIP}}}
+	fileDrop, err := NewSFTPFileDrop(config)
+	require.NoError(t, err)
+	defer fileDrop.Close()
+
+	testFileDrop(t, fileDrop, func(key string) string {
+		return filepath.Join(dir, filepath.FromSlash(key))
+	})
+
+	require.NoError(t, fileDrop.ValidateWritePermission())
+
+	require.NoError(t, fileDrop.Close())
+	require.EqualError(t, fileDrop.UploadBytes("file.log", []byte("{}")), "attempt to use closed SFTP instance")
+}
+
+func testFileDrop(t *testing.T, fileDrop *FileDrop, localPath func(key string) string) {
+	timestamp.FreezeTime()
+	timestamp.SetFreezeTime(time.Date(2022, 5, 18, 13, 45, 1, 0, time.UTC))
+	defer timestamp.UnfreezeTime()
+
+	payload := []byte(`{"id":1}` + "\n" + `{"id":2}` + "\n")
+	require.NoError(t, fileDrop.UploadBatch("events-2022-05-18T13:44:00-13:45:00-server.log", payload, &FileBatch{Table: "events", Rows: 2}))
+
+	dataFile := "events/2022-05-18/13/events-2022-05-18T13:44:00-13:45:00-server.log.gz"
+	data, err := ioutil.ReadFile(localPath(dataFile))
+	require.NoError(t, err)
+
+	manifestBytes, err := ioutil.ReadFile(localPath(dataFile + fileManifestSuffix))
+	require.NoError(t, err)
+	manifest := &FileManifest{}
+	require.NoError(t, json.Unmarshal(manifestBytes, manifest))
+	require.Equal(t, "events-2022-05-18T13:44:00-13:45:00-server.log.gz", manifest.File)
+	require.Equal(t, "events", manifest.Table)
+	require.Equal(t, 2, manifest.Rows)
+	require.Equal(t, len(data), manifest.Size)
+	require.Len(t, manifest.SHA256, 64)
+	require.Equal(t, "json", manifest.Format)
+	require.Equal(t, "gzip", manifest.Compression)
+
+	//next hour is written into the next rotation directory
+	timestamp.SetFreezeTime(time.Date(2022, 5, 18, 14, 0, 0, 0, time.UTC))
+	require.NoError(t, fileDrop.UploadBytes("events-2022-05-18T13:59:00-14:00:00-server.log", payload))
+
+	keys, err := fileDrop.storage.ListObjects("events/")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	require.Equal(t, []string{
+		dataFile,
+		dataFile + fileManifestSuffix,
+		"events/2022-05-18/14/events-2022-05-18T13:59:00-14:00:00-server.log.gz",
+		"events/2022-05-18/14/events-2022-05-18T13:59:00-14:00:00-server.log.gz" + fileManifestSuffix,
+	}, keys, "temporary files must be renamed")
+}
+
+//startTestSFTPServer starts SSH server with SFTP subsystem on the random local port and returns its address and host key
+func startTestSFTPServer(t *testing.T, user, password string) (string, string) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if conn.User() == user && string(pass) == password {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSFTPConnection(conn, serverConfig)
+		}
+	}()
+
+	return listener.Addr().String(), string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+}
+
+func serveTestSFTPConnection(conn net.Conn, serverConfig *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			for request := range channelRequests {
+				isSFTP := request.Type == "subsystem" && string(request.Payload[4:]) == "sftp"
+				_ = request.Reply(isSFTP, nil)
+				if isSFTP {
+					server, err := sftp.NewServer(channel)
+					if err != nil {
+						return
+					}
+					_ = server.Serve()
+					_ = channel.Close()
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileConfigValidateCompression(t *testing.T) {
+	for _, compression := range []FileCompression{"", FileCompressionGZIP, FileCompressionZSTD, FileCompressionSnappy, FileCompressionLZ4} {
+		require.NoError(t, (&FileConfig{Format: FileFormatORC, Compression: compression}).ValidateCompression())
+	}
+
+	require.EqualError(t, (&FileConfig{Format: FileFormatAvro, Compression: FileCompressionLZ4}).ValidateCompression(), "compression lz4 isn't supported by avro format")
+	require.EqualError(t, (&FileConfig{Format: FileFormatParquet, Compression: FileCompressionLZ4}).ValidateCompression(), "compression lz4 isn't supported by parquet format")
+	require.EqualError(t, (&FileConfig{Compression: "brotli"}).ValidateCompression(), "unsupported compression: brotli. Supported values: gzip, zstd, snappy, lz4")
+}
+
+func TestFileConfigPrepareFile(t *testing.T) {
+	payload := []byte(`{"id":1}` + "\n" + `{"id":2}` + "\n")
+	tests := []struct {
+		name             string
+		config           FileConfig
+		expectedFileName string
+		decompress       func([]byte) ([]byte, error)
+	}{
+		{
+			name:             "no compression",
+			config:           FileConfig{Folder: "events", Format: FileFormatJSON},
+			expectedFileName: "events/file.log",
+		},
+		{
+			name:             "gzip json",
+			config:           FileConfig{Format: FileFormatJSON, Compression: FileCompressionGZIP},
+			expectedFileName: "file.log.gz",
+			decompress: func(b []byte) ([]byte, error) {
+				r, err := gzip.NewReader(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				return ioutil.ReadAll(r)
+			},
+		},
+		{
+			name:             "zstd csv",
+			config:           FileConfig{Format: FileFormatCSV, Compression: FileCompressionZSTD},
+			expectedFileName: "file.log.zst",
+			decompress: func(b []byte) ([]byte, error) {
+				r, err := zstd.NewReader(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				defer r.Close()
+				return ioutil.ReadAll(r)
+			},
+		},
+		{
+			name:             "snappy flat json",
+			config:           FileConfig{Format: FileFormatFlatJSON, Compression: FileCompressionSnappy},
+			expectedFileName: "file.log.sz",
+			decompress: func(b []byte) ([]byte, error) {
+				return ioutil.ReadAll(snappy.NewReader(bytes.NewReader(b)))
+			},
+		},
+		{
+			name:             "lz4 json",
+			config:           FileConfig{Format: FileFormatJSON, Compression: FileCompressionLZ4},
+			expectedFileName: "file.log.lz4",
+			decompress: func(b []byte) ([]byte, error) {
+				return ioutil.ReadAll(lz4.NewReader(bytes.NewReader(b)))
+			},
+		},
+		{
+			name:             "zstd avro is compressed inside the file",
+			config:           FileConfig{Format: FileFormatAvro, Compression: FileCompressionZSTD},
+			expectedFileName: "file.log",
+		},
+		{
+			name:             "snappy orc is compressed inside the file",
+			config:           FileConfig{Format: FileFormatORC, Compression: FileCompressionSnappy},
+			expectedFileName: "file.log",
+		},
+		{
+			name:             "zstd parquet is compressed inside the file",
+			config:           FileConfig{Format: FileFormatParquet, Compression: FileCompressionZSTD},
+			expectedFileName: "file.log",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := "file.log"
+			fileBytes := payload
+			require.NoError(t, tt.config.PrepareFile(&fileName, &fileBytes))
+			require.Equal(t, tt.expectedFileName, fileName)
+
+			if tt.decompress == nil {
+				require.Equal(t, payload, fileBytes)
+				return
+			}
+
+			require.NotEqual(t, payload, fileBytes)
+			decompressed, err := tt.decompress(fileBytes)
+			require.NoError(t, err)
+			require.Equal(t, payload, decompressed)
+		})
+	}
+}
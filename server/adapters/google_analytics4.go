@@ -0,0 +1,306 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+const (
+	//ga4MaxBatchSize is a max events count in one Measurement Protocol request
+	ga4MaxBatchSize = 25
+)
+
+//ga4RegionHosts are Measurement Protocol hosts of data collection regions
+var ga4RegionHosts = map[string]string{
+	"us": "https://www.google-analytics.com",
+	"eu": "https://region1.google-analytics.com",
+}
+
+//GA4Request is a dto for sending Measurement Protocol requests to Google Analytics 4
+type GA4Request struct {
+	ClientID           string                   `json:"client_id"`
+	UserID             string                   `json:"user_id,omitempty"`
+	TimestampMicros    int64                    `json:"timestamp_micros,omitempty"`
+	UserProperties     map[string]interface{}   `json:"user_properties,omitempty"`
+	NonPersonalizedAds bool                     `json:"non_personalized_ads,omitempty"`
+	Events             []map[string]interface{} `json:"events,omitempty"`
+}
+
+//GA4ValidationResponse is a dto for receiving Measurement Protocol validation server response
+type GA4ValidationResponse struct {
+	ValidationMessages []struct {
+		FieldPath      string `json:"fieldPath"`
+		Description    string `json:"description"`
+		ValidationCode string `json:"validationCode"`
+	} `json:"validationMessages"`
+}
+
+//GA4RequestFactory is a factory for building Google Analytics 4 Measurement Protocol requests from input events
+//objects are Measurement Protocol payloads: client_id, user_id, timestamp_micros, user_properties and events
+type GA4RequestFactory struct {
+	host  string
+	query string
+}
+
+//newGA4RequestFactory returns configured HTTPRequestFactory instance for ga4 requests
+func newGA4RequestFactory(config *GA4Config) (*GA4RequestFactory, error) {
+	query := url.Values{}
+	query.Set("measurement_id", config.MeasurementID)
+	query.Set("api_secret", config.APISecret)
+	return &GA4RequestFactory{host: config.host(), query: query.Encode()}, nil
+}
+
+//Create returns created Measurement Protocol request. Other fields of the object aren't sent
+func (grf *GA4RequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling ga4 object [%v]: %v", object, err)
+	}
+	payload := &GA4Request{}
+	if err := json.Unmarshal(b, payload); err != nil {
+		return nil, fmt.Errorf("Error parsing ga4 object [%s]: %v", string(b), err)
+	}
+
+	if payload.ClientID == "" {
+		return nil, errors.New("ga4 object must contain 'client_id' field")
+	}
+	if len(payload.Events) == 0 {
+		return nil, errors.New("ga4 object must contain 'events' field")
+	}
+	if len(payload.Events) > ga4MaxBatchSize {
+		return nil, fmt.Errorf("ga4 object must contain at most %d events. Got: %d", ga4MaxBatchSize, len(payload.Events))
+	}
+	for _, event := range payload.Events {
+		if name, _ := event["name"].(string); name == "" {
+			return nil, errors.New("ga4 event must contain 'name' field")
+		}
+	}
+
+	return grf.request("/mp/collect", payload)
+}
+
+func (grf *GA4RequestFactory) request(path string, payload *GA4Request) (*Request, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling ga4 request [%v]: %v", payload, err)
+	}
+
+	return &Request{
+		URL:     grf.host + path + "?" + grf.query,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json", "user-agent": JitsuUserAgent},
+	}, nil
+}
+
+//BatchKey returns key of requests with the same user: client_id, user_id and user properties are shared by all events of the request
+//requests with several events aren't batched so the batch doesn't exceed the events limit
+func (grf *GA4RequestFactory) BatchKey(req *Request) string {
+	payload := &GA4Request{}
+	if err := json.Unmarshal(req.Body, payload); err != nil || len(payload.Events) != 1 {
+		return ""
+	}
+
+	payload.Events = nil
+	payload.TimestampMicros = 0
+	user, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	return requestBatchKey(req) + "\n" + string(user)
+}
+
+//MaxBatchSize returns Measurement Protocol limit of events in one request
+func (grf *GA4RequestFactory) MaxBatchSize() int {
+	return ga4MaxBatchSize
+}
+
+//CreateBatch returns Measurement Protocol request with events of all requests
+//timestamp of every request is moved into its events (event-level timestamp_micros) so events keep their own time
+//and the batch doesn't have request-level timestamp
+func (grf *GA4RequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	var batch *GA4Request
+	for _, req := range requests {
+		payload := &GA4Request{}
+		if err := json.Unmarshal(req.Body, payload); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling ga4 request: %v", err)
+		}
+		if payload.TimestampMicros != 0 {
+			for _, event := range payload.Events {
+				if _, ok := event["timestamp_micros"]; !ok {
+					event["timestamp_micros"] = payload.TimestampMicros
+				}
+			}
+		}
+		if batch == nil {
+			batch = payload
+			continue
+		}
+		batch.Events = append(batch.Events, payload.Events...)
+	}
+	//events without their own timestamps mustn't get the time of another request
+	batch.TimestampMicros = 0
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling ga4 batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns nil: Measurement Protocol doesn't return validation errors
+func (grf *GA4RequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	return nil
+}
+
+func (grf *GA4RequestFactory) Close() {
+}
+
+//Validate sends the payload to Measurement Protocol validation server and returns validation messages as an error
+func (grf *GA4RequestFactory) Validate(payload *GA4Request) error {
+	req, err := grf.request("/debug/mp/collect", payload)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return err
+	}
+	for name, value := range req.Headers {
+		httpReq.Header.Add(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading ga4 response body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error connecting to ga4 [code=%d]: %s", resp.StatusCode, string(responseBody))
+	}
+
+	response := &GA4ValidationResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return fmt.Errorf("Error unmarshalling ga4 validation response [%s]: %v", string(responseBody), err)
+	}
+	if len(response.ValidationMessages) > 0 {
+		messages := make([]string, 0, len(response.ValidationMessages))
+		for _, message := range response.ValidationMessages {
+			messages = append(messages, fmt.Sprintf("%s [%s]: %s", message.ValidationCode, message.FieldPath, message.Description))
+		}
+		return fmt.Errorf("ga4 validation error: %s", strings.Join(messages, "; "))
+	}
+
+	return nil
+}
+
+//GA4Config is a dto for parsing Google Analytics 4 configuration
+type GA4Config struct {
+	//MeasurementID is an ID of web data stream (G-XXXXXXXXXX)
+	MeasurementID string `mapstructure:"measurement_id" json:"measurement_id,omitempty" yaml:"measurement_id,omitempty"`
+	//APISecret is a Measurement Protocol API secret of the data stream
+	APISecret string `mapstructure:"api_secret" json:"api_secret,omitempty" yaml:"api_secret,omitempty"`
+	//Region is a data collection region: us (global) or eu. Default is us
+	Region string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a custom collection host (e.g. server-side tagging). Overrides region
+	Endpoint string           `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Batch    *HTTPBatchConfig `mapstructure:"batch" json:"batch,omitempty" yaml:"batch,omitempty"`
+}
+
+//Validate returns err if invalid
+func (gc *GA4Config) Validate() error {
+	if gc == nil {
+		return errors.New("ga4 config is required")
+	}
+	if gc.MeasurementID == "" {
+		return errors.New("'measurement_id' is required parameter")
+	}
+	if gc.APISecret == "" {
+		return errors.New("'api_secret' is required parameter")
+	}
+	if _, ok := ga4RegionHosts[strings.ToLower(gc.Region)]; gc.Region != "" && !ok {
+		return fmt.Errorf("unknown ga4 region [%s]. Supported: us, eu", gc.Region)
+	}
+
+	return gc.Batch.Validate()
+}
+
+//host returns Measurement Protocol host of the configured region or custom endpoint
+func (gc *GA4Config) host() string {
+	if gc.Endpoint != "" {
+		return strings.TrimSuffix(gc.Endpoint, "/")
+	}
+
+	return ga4RegionHosts[strings.ToLower(utils.NvlString(gc.Region, "us"))]
+}
+
+//GA4 is an adapter for sending events into Google Analytics 4 with Measurement Protocol
+type GA4 struct {
+	AbstractHTTP
+
+	config *GA4Config
+}
+
+//NewGA4 returns configured GA4 adapter instance
+func NewGA4(config *GA4Config, httpAdapterConfiguration *HTTPAdapterConfiguration) (*GA4, error) {
+	httpReqFactory, err := newGA4RequestFactory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GA4{config: config}
+	g.httpAdapter = httpAdapter
+	return g, nil
+}
+
+//NewTestGA4 returns test instance of adapter
+func NewTestGA4(config *GA4Config) *GA4 {
+	return &GA4{config: config}
+}
+
+//TestAccess sends test event to Measurement Protocol validation server (debug endpoint)
+//the validation server checks the payload and measurement_id format, api_secret isn't checked
+func (g *GA4) TestAccess() error {
+	httpReqFactory, err := newGA4RequestFactory(g.config)
+	if err != nil {
+		return err
+	}
+
+	return httpReqFactory.Validate(&GA4Request{
+		ClientID: "jitsu_test_connection",
+		Events: []map[string]interface{}{{
+			"name":   "page_view",
+			"params": map[string]interface{}{"page_location": "https://jitsu.com", "engagement_time_msec": 1},
+		}},
+	})
+}
+
+//Type returns adapter type
+func (g *GA4) Type() string {
+	return "GA4"
+}
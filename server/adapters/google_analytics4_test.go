@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGA4Batch(t *testing.T) {
+	config := &GA4Config{MeasurementID: "G-ABC123", APISecret: "secret", Region: "eu"}
+	require.NoError(t, config.Validate())
+	require.EqualError(t, (&GA4Config{APISecret: "secret"}).Validate(), "'measurement_id' is required parameter")
+	require.EqualError(t, (&GA4Config{MeasurementID: "G-ABC123", APISecret: "secret", Region: "asia"}).Validate(), "unknown ga4 region [asia]. Supported: us, eu")
+	factory, err := newGA4RequestFactory(config)
+	require.NoError(t, err)
+
+	first, err := factory.Create(map[string]interface{}{"client_id": "a1", "timestamp_micros": float64(1627812000000000), "eventn_ctx_event_id": "1",
+		"events": []interface{}{map[string]interface{}{"name": "page_view", "params": map[string]interface{}{"page_location": "https://jitsu.com"}}}})
+	require.NoError(t, err)
+	require.Equal(t, "https://region1.google-analytics.com/mp/collect?api_secret=secret&measurement_id=G-ABC123", first.URL)
+	require.JSONEq(t, `{"client_id":"a1","timestamp_micros":1627812000000000,"events":[{"name":"page_view","params":{"page_location":"https://jitsu.com"}}]}`, string(first.Body), "only payload fields must be sent")
+	second, err := factory.Create(map[string]interface{}{"client_id": "a1", "timestamp_micros": float64(1627812001000000), "events": []interface{}{map[string]interface{}{"name": "sign_up"}}})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second), "events of the same user are batched")
+
+	identified, err := factory.Create(map[string]interface{}{"client_id": "a1", "user_id": "u1", "user_properties": map[string]interface{}{"plan": map[string]interface{}{"value": "pro"}},
+		"events": []interface{}{map[string]interface{}{"name": "login"}}})
+	require.NoError(t, err)
+	require.NotEqual(t, factory.BatchKey(first), factory.BatchKey(identified))
+	several, err := factory.Create(map[string]interface{}{"client_id": "a1", "events": []interface{}{map[string]interface{}{"name": "login"}, map[string]interface{}{"name": "sign_up"}}})
+	require.NoError(t, err)
+	require.Empty(t, factory.BatchKey(several), "requests with several events aren't batched")
+
+	_, err = factory.Create(map[string]interface{}{"events": []interface{}{map[string]interface{}{"name": "login"}}})
+	require.EqualError(t, err, "ga4 object must contain 'client_id' field")
+	_, err = factory.Create(map[string]interface{}{"client_id": "a1"})
+	require.EqualError(t, err, "ga4 object must contain 'events' field")
+	_, err = factory.Create(map[string]interface{}{"client_id": "a1", "events": []interface{}{map[string]interface{}{"params": map[string]interface{}{}}}})
+	require.EqualError(t, err, "ga4 event must contain 'name' field")
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, first.URL, batch.URL)
+	require.JSONEq(t, `{"client_id":"a1","events":[
+		{"name":"page_view","params":{"page_location":"https://jitsu.com"},"timestamp_micros":1627812000000000},
+		{"name":"sign_up","timestamp_micros":1627812001000000}
+	]}`, string(batch.Body), "every event keeps timestamp of its request")
+
+	withoutTimestamp, err := factory.Create(map[string]interface{}{"client_id": "a1", "events": []interface{}{map[string]interface{}{"name": "search"}}})
+	require.NoError(t, err)
+	withEventTimestamp, err := factory.Create(map[string]interface{}{"client_id": "a1", "timestamp_micros": float64(1627812003000000),
+		"events": []interface{}{map[string]interface{}{"name": "login", "timestamp_micros": float64(1627812002000000)}}})
+	require.NoError(t, err)
+	batch, err = factory.CreateBatch([]*Request{second, withoutTimestamp, withEventTimestamp})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"client_id":"a1","events":[
+		{"name":"sign_up","timestamp_micros":1627812001000000},
+		{"name":"search"},
+		{"name":"login","timestamp_micros":1627812002000000}
+	]}`, string(batch.Body), "events without timestamps don't get time of other requests, event-level timestamps aren't overridden")
+}
+
+func TestGA4Validate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/debug/mp/collect", r.URL.Path)
+		require.Equal(t, "G-ABC123", r.URL.Query().Get("measurement_id"))
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "page_view") {
+			w.Write([]byte(`{"validationMessages":[]}`))
+			return
+		}
+		w.Write([]byte(`{"validationMessages":[{"fieldPath":"events","description":"Event at index: [0] has invalid name [2fa].","validationCode":"NAME_INVALID"}]}`))
+	}))
+	defer server.Close()
+
+	ga4 := NewTestGA4(&GA4Config{MeasurementID: "G-ABC123", APISecret: "secret", Endpoint: server.URL})
+	require.NoError(t, ga4.TestAccess())
+
+	factory, err := newGA4RequestFactory(ga4.config)
+	require.NoError(t, err)
+	err = factory.Validate(&GA4Request{ClientID: "a1", Events: []map[string]interface{}{{"name": "2fa"}}})
+	require.EqualError(t, err, "ga4 validation error: NAME_INVALID [events]: Event at index: [0] has invalid name [2fa].")
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/schema"
@@ -14,6 +16,7 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -95,6 +98,13 @@ type GoogleCloudStorage struct {
 }
 
 func NewGoogleCloudStorage(ctx context.Context, config *GoogleConfig) (*GoogleCloudStorage, error) {
+	if err := config.ValidateTableFormat(); err != nil {
+		return nil, err
+	}
+	if err := config.ValidateCompression(); err != nil {
+		return nil, err
+	}
+
 	var client *storage.Client
 	var err error
 	if config.credentials == nil {
@@ -160,6 +170,77 @@ func (gcs *GoogleCloudStorage) UploadBytes(fileName string, fileBytes []byte) (e
 	return nil
 }
 
+//PutObject writes object as is (without compression) with the key relative to the configured folder
+func (gcs *GoogleCloudStorage) PutObject(key string, data []byte) error {
+	if gcs.closed.Load() {
+		return fmt.Errorf("attempt to use closed GoogleCloudStorage instance")
+	}
+
+	objectKey := gcs.config.ObjectsPrefix(key)
+	w := gcs.client.Bucket(gcs.config.Bucket).Object(objectKey).NewWriter(gcs.ctx)
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return errorj.SaveOnStageError.Wrap(err, "failed to write file to google cloud storage").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    gcs.config.Bucket,
+				Statement: fmt.Sprintf("file: %s", objectKey),
+			})
+	}
+
+	if err := w.Close(); err != nil {
+		return errorj.SaveOnStageError.Wrap(err, "failed to close google cloud writer").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    gcs.config.Bucket,
+				Statement: fmt.Sprintf("file: %s", objectKey),
+			})
+	}
+
+	return nil
+}
+
+//GetObject returns object content by the key relative to the configured folder
+func (gcs *GoogleCloudStorage) GetObject(key string) ([]byte, error) {
+	if gcs.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed GoogleCloudStorage instance")
+	}
+
+	objectKey := gcs.config.ObjectsPrefix(key)
+	r, err := gcs.client.Bucket(gcs.config.Bucket).Object(objectKey).NewReader(gcs.ctx)
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return nil, ErrObjectNotExist
+		}
+		return nil, fmt.Errorf("failed to read file %s from google cloud storage: %v", objectKey, err)
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+//ListObjects returns keys (relative to the configured folder) of objects with the prefix
+func (gcs *GoogleCloudStorage) ListObjects(prefix string) ([]string, error) {
+	if gcs.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed GoogleCloudStorage instance")
+	}
+
+	folderPrefix := gcs.config.ObjectsPrefix("")
+	var keys []string
+	objects := gcs.client.Bucket(gcs.config.Bucket).Objects(gcs.ctx, &storage.Query{Prefix: gcs.config.ObjectsPrefix(prefix)})
+	for {
+		attrs, err := objects.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files with prefix %s in google cloud storage: %v", prefix, err)
+		}
+
+		keys = append(keys, strings.TrimPrefix(attrs.Name, folderPrefix))
+	}
+
+	return keys, nil
+}
+
 //DeleteObject deletes object from google cloud storage bucket
 func (gcs *GoogleCloudStorage) DeleteObject(key string) (err error) {
 	//panic handler
@@ -187,6 +268,51 @@ func (gcs *GoogleCloudStorage) DeleteObject(key string) (err error) {
 	return nil
 }
 
+//DeleteExpired deletes objects with rule prefix which were updated before expiredBefore
+func (gcs *GoogleCloudStorage) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if gcs.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed GoogleCloudStorage instance")
+	}
+
+	prefix := gcs.config.ObjectsPrefix(rule.Prefix)
+	bucket := gcs.client.Bucket(gcs.config.Bucket)
+	report := &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore}
+	objects := bucket.Objects(gcs.ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := objects.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errorj.DeleteFromTableError.Wrap(err, "failed to list objects in google cloud storage").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Bucket:    gcs.config.Bucket,
+					Statement: fmt.Sprintf("prefix: %s", prefix),
+				})
+		}
+
+		if attrs.Updated.Before(expiredBefore) {
+			report.Objects = append(report.Objects, attrs.Name)
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for _, name := range report.Objects {
+		if err := bucket.Object(name).Delete(gcs.ctx); err != nil && err != storage.ErrObjectNotExist {
+			return nil, errorj.DeleteFromTableError.Wrap(err, "failed to delete expired object from google cloud storage").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Bucket:    gcs.config.Bucket,
+					Statement: fmt.Sprintf("file: %s", name),
+				})
+		}
+	}
+
+	return report, nil
+}
+
 //ValidateWritePermission tries to create temporary file and remove it.
 //returns nil if file creation was successful.
 func (gcs *GoogleCloudStorage) ValidateWritePermission() error {
@@ -4,15 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
-	"github.com/jitsucom/jitsu/server/queue"
-	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/timestamp"
-	"github.com/panjf2000/ants/v2"
-	"go.uber.org/atomic"
 	"io/ioutil"
-	"math"
 	"net/http"
 	"time"
 )
@@ -28,6 +24,10 @@ type HTTPAdapterConfiguration struct {
 	DebugLogger    *logging.QueryLogger
 	ErrorHandler   func(fallback bool, eventContext *EventContext, err error)
 	SuccessHandler func(eventContext *EventContext)
+	//Batch is applied only if HTTPReqFactory implements HTTPBatchRequestFactory
+	Batch *HTTPBatchConfig
+	//CircuitBreaker pauses requests sending while it is open (optional)
+	CircuitBreaker *circuitbreaker.CircuitBreaker
 }
 
 //HTTPConfiguration is a dto for HTTP adapter (client) configuration
@@ -44,10 +44,11 @@ type HTTPConfiguration struct {
 
 //HTTPAdapter is an adapter for sending HTTP requests with retries
 //has persistent request queue and workers pool under the hood
+//if batching is configured, queued requests are aggregated into batch requests
 type HTTPAdapter struct {
 	client         *http.Client
-	workersPool    *ants.PoolWithFunc
 	queue          *HTTPRequestQueue
+	sender         *queuedSender
 	debugLogger    *logging.QueryLogger
 	httpReqFactory HTTPRequestFactory
 
@@ -55,12 +56,6 @@ type HTTPAdapter struct {
 	successHandler func(eventContext *EventContext)
 
 	destinationID string
-	retryCount    int
-	retryDelay    time.Duration
-	//when reached - requests can't be retried => fallback
-	queueFullnessThreshold uint64
-
-	closed *atomic.Bool
 }
 
 //NewHTTPAdapter returns configured HTTPAdapter and starts queue observing goroutine
@@ -73,75 +68,39 @@ func NewHTTPAdapter(config *HTTPAdapterConfiguration) (*HTTPAdapter, error) {
 				MaxIdleConnsPerHost: config.HTTPConfig.ClientMaxIdleConnsPerHost,
 			},
 		},
+		queue:          NewHTTPRequestQueue(config.DestinationID, config.QueueFactory),
 		debugLogger:    config.DebugLogger,
 		httpReqFactory: config.HTTPReqFactory,
 
 		errorHandler:   config.ErrorHandler,
 		successHandler: config.SuccessHandler,
 
-		destinationID:          config.DestinationID,
-		retryCount:             config.HTTPConfig.RetryCount,
-		retryDelay:             config.HTTPConfig.RetryDelay,
-		queueFullnessThreshold: config.HTTPConfig.QueueFullnessThreshold,
-		closed:                 atomic.NewBool(false),
+		destinationID: config.DestinationID,
 	}
 
-	reqQueue := NewHTTPRequestQueue(config.DestinationID, config.QueueFactory)
-
-	pool, err := ants.NewPoolWithFunc(config.PoolWorkers, httpAdapter.sendRequestWithRetry)
+	sender, err := newQueuedSender(&queuedSenderConfiguration{
+		destinationID:  config.DestinationID,
+		itemName:       "HTTP request",
+		queue:          httpAdapter.queue,
+		poolWorkers:    config.PoolWorkers,
+		send:           httpAdapter.send,
+		batcher:        newHTTPBatcher(config.Batch, config.HTTPReqFactory),
+		batchKey:       httpAdapter.batchKey,
+		circuitBreaker: config.CircuitBreaker,
+		errorHandler:   config.ErrorHandler,
+		httpConfig:     config.HTTPConfig,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("Error creating HTTP adapter workers pool: %v", err)
 	}
-
-	httpAdapter.workersPool = pool
-	httpAdapter.queue = reqQueue
-	httpAdapter.startObserver()
+	httpAdapter.sender = sender
 
 	return httpAdapter, nil
 }
 
-//startObserver run goroutine for polling from the queue and executes Request
-func (h *HTTPAdapter) startObserver() {
-	safego.RunWithRestart(func() {
-		for {
-			if h.closed.Load() {
-				break
-			}
-
-			if h.workersPool.Free() > 0 {
-				retryableRequest, err := h.queue.DequeueBlock()
-				if err != nil {
-					if err == queue.ErrQueueClosed && h.closed.Load() {
-						continue
-					}
-					logging.SystemErrorf("[%s] Error reading HTTP request from the queue: %v", h.destinationID, err)
-					time.Sleep(time.Second)
-					continue
-				}
-				//dequeued request was from retry call and retry timeout hasn't come
-				if timestamp.Now().UTC().Before(retryableRequest.DequeuedTime) {
-					if err := h.queue.AddRequest(retryableRequest); err != nil {
-						logging.SystemErrorf("[%s] Error enqueueing HTTP request after dequeuing: %v", h.destinationID, err)
-						h.errorHandler(true, retryableRequest.EventContext, err)
-					}
-
-					continue
-				}
-				if err := h.workersPool.Invoke(retryableRequest); err != nil {
-					if err != ants.ErrPoolClosed {
-						logging.SystemErrorf("[%s] Error invoking HTTP request task: %v", h.destinationID, err)
-					}
-
-					if err := h.queue.AddRequest(retryableRequest); err != nil {
-						logging.SystemErrorf("[%s] Error enqueueing HTTP request after invoking: %v", h.destinationID, err)
-						h.errorHandler(true, retryableRequest.EventContext, err)
-					}
-				}
-			} else {
-				time.Sleep(time.Millisecond * 50)
-			}
-		}
-	})
+//batchKey returns key of requests which can be merged into one batch request
+func (h *HTTPAdapter) batchKey(item retryableItem) string {
+	return h.httpReqFactory.(HTTPBatchRequestFactory).BatchKey(item.(*RetryableRequest).Request)
 }
 
 //SendAsync puts request to the queue
@@ -155,13 +114,19 @@ func (h *HTTPAdapter) SendAsync(eventContext *EventContext) error {
 	return h.queue.Add(req, eventContext)
 }
 
-func (h *HTTPAdapter) sendRequestWithRetry(i interface{}) {
-	retryableRequest, ok := i.(*RetryableRequest)
-	if !ok {
+//send is a workers pool function: sends single request or batch
+func (h *HTTPAdapter) send(i interface{}) {
+	switch task := i.(type) {
+	case *RetryableRequest:
+		h.sendRequestWithRetry(task)
+	case *itemsBatch:
+		h.sendBatchWithRetry(task)
+	default:
 		logging.SystemErrorf("HTTP webhook request has unknown type: %T", i)
-		return
 	}
+}
 
+func (h *HTTPAdapter) sendRequestWithRetry(retryableRequest *RetryableRequest) {
 	retries := ""
 	if retryableRequest.Retry > 0 {
 		retries = fmt.Sprintf(" with %d retry", retryableRequest.Retry)
@@ -171,7 +136,7 @@ func (h *HTTPAdapter) sendRequestWithRetry(i interface{}) {
 
 	h.debugLogger.LogQueryWithValues(debugQuery, []interface{}{string(retryableRequest.Request.Body)})
 
-	err := h.doRequest(retryableRequest.Request)
+	_, err := h.doRequest(retryableRequest.Request)
 	if err != nil {
 		logging.Errorf("[%s] HTTP request URL: [%s] Method: [%s] Body: [%s] Headers: [%s] will be retried [retry count=%d] after err: %v", h.destinationID, retryableRequest.Request.URL, retryableRequest.Request.Method, string(retryableRequest.Request.Body), retryableRequest.Request.Headers, retryableRequest.Retry, err)
 		h.doRetry(retryableRequest, err)
@@ -181,36 +146,91 @@ func (h *HTTPAdapter) sendRequestWithRetry(i interface{}) {
 	}
 }
 
-//doRetry retries request.
-//without retry if:
-// - queue size is greater than threshold
-// - retry limit is reached
-func (h *HTTPAdapter) doRetry(retryableRequest *RetryableRequest, sendErr error) {
-	//if queue fullness threshold configured check if queue size not exceed
-	if h.queueFullnessThreshold == 0 || h.queueFullnessThreshold > h.queue.Size() {
-
-		//check retry count and increment exponential 2^X
-		if retryableRequest.Retry < h.retryCount {
-			delay := time.Duration(math.Pow(2, float64(retryableRequest.Retry))) * h.retryDelay
-			retryableRequest.Retry += 1
-			retryableRequest.DequeuedTime = timestamp.Now().UTC().Add(delay)
-			if err := h.queue.AddRequest(retryableRequest); err != nil {
-				logging.SystemErrorf("[%s] Error enqueueing HTTP request after sending: %v", h.destinationID, err)
-				h.errorHandler(true, retryableRequest.EventContext, sendErr)
-			} else {
-				h.errorHandler(false, retryableRequest.EventContext, sendErr)
-			}
+//sendBatchWithRetry merges requests into one batch request and sends it
+//per-item errors from the response are passed to errorHandler of the item events (without retries),
+//if the whole request has failed every item is retried separately
+func (h *HTTPAdapter) sendBatchWithRetry(batch *itemsBatch) {
+	batchFactory := h.httpReqFactory.(HTTPBatchRequestFactory)
+	retryableRequests := make([]*RetryableRequest, 0, len(batch.items))
+	requests := make([]*Request, 0, len(batch.items))
+	for _, item := range batch.items {
+		retryableRequest := item.(*RetryableRequest)
+		retryableRequests = append(retryableRequests, retryableRequest)
+		requests = append(requests, retryableRequest.Request)
+	}
+
+	batchRequest, err := batchFactory.CreateBatch(requests)
+	if err != nil {
+		logging.Errorf("[%s] Error creating HTTP batch request from %d requests: %v. Requests will be sent one by one", h.destinationID, len(requests), err)
+		for _, retryableRequest := range retryableRequests {
+			h.sendRequestWithRetry(retryableRequest)
+		}
+		return
+	}
+
+	debugQuery := fmt.Sprintf("%s %s. Headers: %v. Batch of %d requests", batchRequest.Method, batchRequest.URL, batchRequest.Headers, len(requests))
+	h.debugLogger.LogQueryWithValues(debugQuery, []interface{}{string(batchRequest.Body)})
+
+	response, err := h.doRequest(batchRequest)
+	if response != nil {
+		if result := batchFactory.ParseBatchResponse(len(requests), response); result != nil && len(result.ItemErrors) > 0 {
+			h.handleBatchResult(retryableRequests, result)
 			return
 		}
 	}
 
+	if err != nil {
+		logging.Errorf("[%s] HTTP batch request URL: [%s] Method: [%s] with %d requests will be retried after err: %v", h.destinationID, batchRequest.URL, batchRequest.Method, len(requests), err)
+		for _, retryableRequest := range retryableRequests {
+			h.doRetry(retryableRequest, err)
+		}
+		return
+	}
+
+	for _, retryableRequest := range retryableRequests {
+		retryableRequest.EventContext.HTTPRequest = retryableRequest.Request
+		h.successHandler(retryableRequest.EventContext)
+	}
+}
+
+//handleBatchResult passes per-item errors to errorHandler (fallback).
+//Other items are put back to the queue if the whole batch has been rejected or are marked as succeeded
+func (h *HTTPAdapter) handleBatchResult(retryableRequests []*RetryableRequest, result *BatchResult) {
+	var retry []retryableItem
+	for i, retryableRequest := range retryableRequests {
+		if itemErr, ok := result.ItemErrors[i]; ok {
+			logging.Errorf("[%s] HTTP request URL: [%s] Body: [%s] has been rejected in the batch: %v", h.destinationID, retryableRequest.Request.URL, string(retryableRequest.Request.Body), itemErr)
+			h.errorHandler(true, retryableRequest.EventContext, itemErr)
+			continue
+		}
+
+		if result.RetryOthers {
+			retryableRequest.DequeuedTime = timestamp.Now().UTC()
+			retry = append(retry, retryableRequest)
+		} else {
+			retryableRequest.EventContext.HTTPRequest = retryableRequest.Request
+			h.successHandler(retryableRequest.EventContext)
+		}
+	}
+
+	h.sender.requeue(retry)
+}
+
+//doRetry retries request or passes sendErr to errorHandler (fallback) if it can't be retried
+func (h *HTTPAdapter) doRetry(retryableRequest *RetryableRequest, sendErr error) {
+	if h.sender.retry(retryableRequest, sendErr) {
+		return
+	}
+
 	headersJSON, _ := json.Marshal(retryableRequest.Request.Headers)
 	logging.Errorf("[%s] Error sending HTTP request URL: [%s] Method: [%s] Body: [%s] Headers: [%s]: %v", h.destinationID, retryableRequest.Request.URL, retryableRequest.Request.Method, string(retryableRequest.Request.Body), headersJSON, sendErr)
 
 	h.errorHandler(true, retryableRequest.EventContext, sendErr)
 }
 
-func (h *HTTPAdapter) doRequest(req *Request) error {
+//doRequest sends request and returns response if it has been received
+//returns err if request has failed or response status code isn't 2xx
+func (h *HTTPAdapter) doRequest(req *Request) (*HTTPResponse, error) {
 	var httpReq *http.Request
 	var err error
 	if req.Body != nil && len(req.Body) > 0 {
@@ -220,7 +240,7 @@ func (h *HTTPAdapter) doRequest(req *Request) error {
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for header, value := range req.Headers {
@@ -229,42 +249,48 @@ func (h *HTTPAdapter) doRequest(req *Request) error {
 
 	resp, err := h.client.Do(httpReq)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if resp != nil && resp.Body != nil {
 		defer resp.Body.Close()
 	}
 
-	//check HTTP response code
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		//read response body
-		responsePayload := "no HTTP response body"
-		if resp != nil && resp.Body != nil {
-			responseBody, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				responsePayload = fmt.Sprintf("[%s] Error reading HTTP response body: %v", h.destinationID, err)
-			} else {
-				responsePayload = string(responseBody)
-			}
+	//read response body
+	response := &HTTPResponse{StatusCode: resp.StatusCode}
+	responsePayload := "no HTTP response body"
+	if resp.Body != nil {
+		responseBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			responsePayload = fmt.Sprintf("[%s] Error reading HTTP response body: %v", h.destinationID, err)
+		} else {
+			response.Body = responseBody
+			responsePayload = string(responseBody)
 		}
+	}
 
+	//check HTTP response code
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		headers, _ := json.MarshalIndent(resp.Header, " ", " ")
 
-		return fmt.Errorf("HTTP Response status code: [%d],\n\tResponse body: [%s],\n\tResponse headers: [%s]", resp.StatusCode, responsePayload, string(headers))
+		return response, &HTTPStatusError{StatusCode: resp.StatusCode,
+			Message: fmt.Sprintf("HTTP Response status code: [%d],\n\tResponse body: [%s],\n\tResponse headers: [%s]", resp.StatusCode, responsePayload, string(headers))}
+	}
+
+	if validator, ok := h.httpReqFactory.(HTTPResponseValidator); ok {
+		if err := validator.ValidateResponse(req, response); err != nil {
+			return response, err
+		}
 	}
 
-	return nil
+	return response, nil
 }
 
 //Close closes underlying queue, workers pool and HTTP client
 //returns err if occurred
 func (h *HTTPAdapter) Close() (err error) {
-	h.closed.Store(true)
 	h.httpReqFactory.Close()
-	err = h.queue.Close()
-
-	h.workersPool.Release()
+	err = h.sender.close()
 	h.client.CloseIdleConnections()
 
 	return err
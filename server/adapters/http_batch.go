@@ -0,0 +1,122 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPBatchWindow = time.Second
+	//minHTTPBatchWindow is a min aggregation window. Batches are checked every window/2
+	minHTTPBatchWindow = 100 * time.Millisecond
+)
+
+//HTTPBatchConfig is a dto for parsing batch delivery configuration of HTTP destinations
+//if it is configured, queued events are aggregated into one request (if destination API supports batches)
+type HTTPBatchConfig struct {
+	//MaxSize is a max events count in one request
+	MaxSize int `mapstructure:"max_size,omitempty" json:"max_size,omitempty" yaml:"max_size,omitempty"`
+	//MaxBytes is a max summary size of events payloads in one request. Unlimited if 0
+	MaxBytes int `mapstructure:"max_bytes,omitempty" json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
+	//Window is a max time of events aggregation (e.g. 500ms, 5s). Default is 1s
+	Window string `mapstructure:"window,omitempty" json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+//Validate returns err if invalid
+func (hbc *HTTPBatchConfig) Validate() error {
+	if hbc == nil {
+		return nil
+	}
+	if hbc.MaxSize < 0 || hbc.MaxBytes < 0 {
+		return errors.New("batch.max_size and batch.max_bytes must be positive")
+	}
+	if hbc.Window != "" {
+		window, err := time.ParseDuration(hbc.Window)
+		if err != nil {
+			return fmt.Errorf("malformed batch.window [%s]: %v", hbc.Window, err)
+		}
+		if window < minHTTPBatchWindow {
+			return fmt.Errorf("batch.window [%s] must be at least %s", hbc.Window, minHTTPBatchWindow)
+		}
+	}
+
+	return nil
+}
+
+//window returns parsed aggregation window or default value
+//the window is not less than minHTTPBatchWindow
+func (hbc *HTTPBatchConfig) window() time.Duration {
+	if hbc.Window == "" {
+		return defaultHTTPBatchWindow
+	}
+	window, err := time.ParseDuration(hbc.Window)
+	if err != nil || window <= 0 {
+		return defaultHTTPBatchWindow
+	}
+	if window < minHTTPBatchWindow {
+		return minHTTPBatchWindow
+	}
+
+	return window
+}
+
+//HTTPResponse is a dto for passing HTTP response into HTTPBatchRequestFactory
+type HTTPResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+//HTTPStatusError is returned if HTTP response status code isn't 2xx
+type HTTPStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (hse *HTTPStatusError) Error() string {
+	return hse.Message
+}
+
+//BatchResult is a result of batch request with per-item errors
+type BatchResult struct {
+	//ItemErrors are errors of rejected items: index in the batch -> error. Rejected items aren't retried
+	ItemErrors map[int]error
+	//RetryOthers is true if items without errors haven't been accepted either (the whole batch has been rejected)
+	RetryOthers bool
+}
+
+//HTTPBatchRequestFactory is implemented by HTTPRequestFactory which can merge several requests into one vendor batch API request
+type HTTPBatchRequestFactory interface {
+	HTTPRequestFactory
+	//BatchKey returns key of requests which can be merged into one batch or empty string if request can't be batched
+	BatchKey(req *Request) string
+	//MaxBatchSize returns vendor API limit of items in one batch request (0 - unlimited)
+	MaxBatchSize() int
+	//CreateBatch merges requests (created with Create and with the same BatchKey) into one request
+	CreateBatch(requests []*Request) (*Request, error)
+	//ParseBatchResponse returns per-item errors from the batch response or nil if the response doesn't contain them
+	ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult
+}
+
+//newHTTPBatcher returns itemsBatcher or nil if batching isn't configured or isn't supported by the factory
+func newHTTPBatcher(config *HTTPBatchConfig, factory HTTPRequestFactory) *itemsBatcher {
+	batchFactory, ok := factory.(HTTPBatchRequestFactory)
+	if !ok {
+		return nil
+	}
+
+	return newItemsBatcher(config, batchFactory.MaxBatchSize())
+}
+
+//requestBatchKey returns key of requests with the same method, URL and headers
+func requestBatchKey(req *Request) string {
+	headers := make([]string, 0, len(req.Headers))
+	for name, value := range req.Headers {
+		headers = append(headers, name+":"+value)
+	}
+	sort.Strings(headers)
+
+	return req.Method + " " + req.URL + "\n" + strings.Join(headers, "\n")
+}
@@ -0,0 +1,273 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPBatchConfigValidate(t *testing.T) {
+	var config *HTTPBatchConfig
+	require.NoError(t, config.Validate(), "batch section is optional")
+	require.NoError(t, (&HTTPBatchConfig{MaxSize: 100, Window: "500ms"}).Validate())
+	require.EqualError(t, (&HTTPBatchConfig{MaxSize: -1}).Validate(), "batch.max_size and batch.max_bytes must be positive")
+	require.Error(t, (&HTTPBatchConfig{Window: "1 minute"}).Validate())
+	require.EqualError(t, (&HTTPBatchConfig{Window: "1ns"}).Validate(), "batch.window [1ns] must be at least 100ms")
+
+	require.Equal(t, defaultHTTPBatchWindow, (&HTTPBatchConfig{}).window())
+	require.Equal(t, 5*time.Second, (&HTTPBatchConfig{Window: "5s"}).window())
+	require.Equal(t, minHTTPBatchWindow, (&HTTPBatchConfig{Window: "1ns"}).window())
+
+	require.Equal(t, 500*time.Millisecond, newItemsBatcher(&HTTPBatchConfig{}, 0).flushPeriod())
+	require.Equal(t, minHTTPBatchWindow/2, (&itemsBatcher{window: time.Nanosecond}).flushPeriod(), "ticker period must be positive")
+}
+
+func TestHTTPBatcher(t *testing.T) {
+	require.Nil(t, newHTTPBatcher(nil, &AmplitudeRequestFactory{}), "batching isn't configured")
+	require.Nil(t, newHTTPBatcher(&HTTPBatchConfig{}, &GoogleAnalyticsRequestFactory{}), "factory doesn't support batches")
+	require.Nil(t, newHTTPBatcher(&HTTPBatchConfig{MaxSize: 1}, &AmplitudeRequestFactory{}), "batch of one request is a single request")
+	require.Equal(t, amplitudeMaxBatchSize, newHTTPBatcher(&HTTPBatchConfig{MaxSize: 5000}, &AmplitudeRequestFactory{}).maxSize, "max size must be limited by vendor API")
+
+	timestamp.FreezeTime()
+	timestamp.SetFreezeTime(time.Date(2022, 5, 18, 13, 45, 0, 0, time.UTC))
+	defer timestamp.UnfreezeTime()
+
+	request := func(body string) *RetryableRequest {
+		return &RetryableRequest{Request: &Request{Body: []byte(body)}}
+	}
+
+	//by size
+	batcher := newHTTPBatcher(&HTTPBatchConfig{MaxSize: 2}, &WebhookRequestFactory{})
+	require.Empty(t, batcher.add("a", request(`{"id":1}`)))
+	require.Empty(t, batcher.add("b", request(`{"id":2}`)))
+	ready := batcher.add("a", request(`{"id":3}`))
+	require.Len(t, ready, 1)
+	require.Equal(t, "a", ready[0].key)
+	require.Len(t, ready[0].items, 2)
+
+	//by bytes: the batch is sent before it exceeds the limit
+	batcher = newHTTPBatcher(&HTTPBatchConfig{MaxBytes: 20}, &WebhookRequestFactory{})
+	require.Empty(t, batcher.add("a", request(`{"id":1}`)))
+	require.Empty(t, batcher.add("a", request(`{"id":2}`)))
+	ready = batcher.add("a", request(`{"id":3}`))
+	require.Len(t, ready, 1)
+	require.Len(t, ready[0].items, 2)
+	require.Equal(t, 16, ready[0].bytes)
+
+	//by time window
+	batcher = newHTTPBatcher(&HTTPBatchConfig{MaxSize: 10, Window: "10s"}, &WebhookRequestFactory{})
+	require.Empty(t, batcher.add("a", request(`{"id":1}`)))
+	timestamp.SetFreezeTime(time.Date(2022, 5, 18, 13, 45, 5, 0, time.UTC))
+	require.Empty(t, batcher.add("b", request(`{"id":2}`)))
+	require.Empty(t, batcher.expired())
+
+	timestamp.SetFreezeTime(time.Date(2022, 5, 18, 13, 45, 10, 0, time.UTC))
+	ready = batcher.expired()
+	require.Len(t, ready, 1)
+	require.Equal(t, "a", ready[0].key)
+
+	pending := batcher.drain()
+	require.Len(t, pending, 1)
+	require.Equal(t, "b", pending[0].key)
+	require.Empty(t, batcher.drain())
+}
+
+func TestAmplitudeBatch(t *testing.T) {
+	factory := &AmplitudeRequestFactory{apiKey: "key", endpoint: amplitudeDefaultAPIURL}
+	first, err := factory.Create(map[string]interface{}{"event_type": "pageview"})
+	require.NoError(t, err)
+	second, err := factory.Create(map[string]interface{}{"event_type": "click"})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, amplitudeDefaultAPIURL, batch.URL)
+	require.JSONEq(t, `{"api_key":"key","events":[{"event_type":"pageview"},{"event_type":"click"}]}`, string(batch.Body))
+
+	empty, err := factory.Create(nil)
+	require.NoError(t, err)
+	_, err = factory.CreateBatch([]*Request{first, empty})
+	require.EqualError(t, err, "amplitude request must contain one event: 0")
+
+	require.Nil(t, factory.ParseBatchResponse(2, &HTTPResponse{StatusCode: http.StatusTooManyRequests, Body: []byte(`{"code":429}`)}))
+
+	result := factory.ParseBatchResponse(2, &HTTPResponse{StatusCode: http.StatusBadRequest,
+		Body: []byte(`{"code":400,"error":"Request missing required field","events_with_missing_fields":{"event_type":[1]},"events_with_invalid_fields":{"time":[5]}}`)})
+	require.NotNil(t, result)
+	require.True(t, result.RetryOthers)
+	require.Len(t, result.ItemErrors, 1, "out of range indices must be skipped")
+	require.EqualError(t, result.ItemErrors[1], "amplitude event has missing field [event_type]: Request missing required field")
+}
+
+func TestWebhookBatch(t *testing.T) {
+	factory := &WebhookRequestFactory{}
+	headers := map[string]string{"Content-Type": "application/json"}
+	require.Empty(t, factory.BatchKey(&Request{URL: "https://example.com", Method: http.MethodPost, Body: []byte("id=1")}), "non JSON bodies can't be batched")
+
+	first := &Request{URL: "https://example.com", Method: http.MethodPost, Body: []byte(`{"id":1}`), Headers: headers}
+	second := &Request{URL: "https://example.com", Method: http.MethodPost, Body: []byte(`{"id":2}`), Headers: headers}
+	other := &Request{URL: "https://example.com/other", Method: http.MethodPost, Body: []byte(`{"id":3}`), Headers: headers}
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+	require.NotEqual(t, factory.BatchKey(first), factory.BatchKey(other))
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, `[{"id":1},{"id":2}]`, string(batch.Body))
+	require.Equal(t, headers, batch.Headers)
+}
+
+func TestHubSpotBatch(t *testing.T) {
+	factory := &HubSpotRequestFactory{apiKey: "key"}
+	contact := func(email, name string) *Request {
+		return &Request{
+			URL:    fmt.Sprintf(hubSpotContactWithEmailAPIURLTemplate, email) + "?hapikey=key",
+			Method: http.MethodPost,
+			Body:   []byte(`{"properties":[{"property":"firstname","value":"` + name + `"}]}`),
+		}
+	}
+
+	require.Empty(t, factory.BatchKey(&Request{URL: hubSpotEventURL, Method: http.MethodGet}), "events can't be batched")
+	require.Empty(t, factory.BatchKey(&Request{URL: hubSpotContactWithoutEmailAPIURLTemplate + "?hapikey=key", Method: http.MethodPost}), "contacts without email can't be batched")
+	require.NotEmpty(t, factory.BatchKey(contact("a@jitsu.com", "A")))
+
+	batch, err := factory.CreateBatch([]*Request{contact("a@jitsu.com", "A"), contact("b@jitsu.com", "B")})
+	require.NoError(t, err)
+	require.Equal(t, hubSpotContactsBatchAPIURL+"?hapikey=key", batch.URL)
+	require.JSONEq(t, `[{"email":"a@jitsu.com","properties":[{"property":"firstname","value":"A"}]},{"email":"b@jitsu.com","properties":[{"property":"firstname","value":"B"}]}]`, string(batch.Body))
+
+	result := factory.ParseBatchResponse(2, &HTTPResponse{StatusCode: http.StatusBadRequest,
+		Body: []byte(`{"status":"error","message":"Errors found processing batch update","failureMessages":[{"index":0,"error":{"status":"error","message":"Email address is invalid","category":"VALIDATION_ERROR"}}]}`)})
+	require.NotNil(t, result)
+	require.True(t, result.RetryOthers)
+	require.Len(t, result.ItemErrors, 1)
+	require.Contains(t, result.ItemErrors[0].Error(), "Email address is invalid")
+}
+
+//testBatchRequestFactory sends objects as JSON and batches as JSON arrays
+//the server responds with indices of rejected items: {"rejected":[1]}
+type testBatchRequestFactory struct {
+	url string
+}
+
+func (f *testBatchRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{URL: f.url, Method: http.MethodPost, Body: b}, nil
+}
+
+func (f *testBatchRequestFactory) BatchKey(req *Request) string {
+	return requestBatchKey(req)
+}
+
+func (f *testBatchRequestFactory) MaxBatchSize() int {
+	return 0
+}
+
+func (f *testBatchRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	return (&WebhookRequestFactory{}).CreateBatch(requests)
+}
+
+func (f *testBatchRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	body := struct {
+		Rejected []int `json:"rejected"`
+	}{}
+	if err := json.Unmarshal(response.Body, &body); err != nil || len(body.Rejected) == 0 {
+		return nil
+	}
+
+	itemErrors := map[int]error{}
+	for _, index := range body.Rejected {
+		itemErrors[index] = fmt.Errorf("item %d has been rejected", index)
+	}
+	return &BatchResult{ItemErrors: itemErrors, RetryOthers: true}
+}
+
+func (f *testBatchRequestFactory) Close() {
+}
+
+func TestHTTPAdapterBatch(t *testing.T) {
+	mutex := &sync.Mutex{}
+	var received [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var batch []map[string]interface{}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mutex.Lock()
+		received = append(received, batch)
+		mutex.Unlock()
+
+		//the whole batch is rejected if it contains invalid items
+		var rejected []int
+		for i, item := range batch {
+			if item["invalid"] == true {
+				rejected = append(rejected, i)
+			}
+		}
+		if len(rejected) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"rejected": rejected})
+		}
+	}))
+	defer server.Close()
+
+	var succeeded, failed []string
+	adapter, err := NewHTTPAdapter(&HTTPAdapterConfiguration{
+		DestinationID:  "test_batch",
+		HTTPConfig:     &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second, RetryCount: 0},
+		HTTPReqFactory: &testBatchRequestFactory{url: server.URL},
+		QueueFactory:   events.NewQueueFactory(nil, 0),
+		PoolWorkers:    2,
+		DebugLogger:    logging.NewQueryLogger("test_batch", nil, nil),
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			require.True(t, fallback, "rejected items mustn't be retried")
+			failed = append(failed, eventContext.EventID)
+		},
+		SuccessHandler: func(eventContext *EventContext) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			succeeded = append(succeeded, eventContext.EventID)
+		},
+		Batch: &HTTPBatchConfig{MaxSize: 3, Window: "100ms"},
+	})
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	for i, event := range []events.Event{{"id": 1}, {"id": 2, "invalid": true}, {"id": 3}} {
+		require.NoError(t, adapter.SendAsync(&EventContext{EventID: fmt.Sprint(i + 1), ProcessedEvent: event}))
+	}
+
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(succeeded)+len(failed) == 3
+	}, 5*time.Second, 50*time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	sort.Strings(succeeded)
+	require.Equal(t, []string{"1", "3"}, succeeded)
+	require.Equal(t, []string{"2"}, failed)
+	require.Len(t, received, 2, "rejected batch must be resent without the invalid item")
+	require.Len(t, received[0], 3)
+	require.Len(t, received[1], 2)
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/queue"
 	"github.com/jitsucom/jitsu/server/timestamp"
-	"time"
 )
 
 //QueuedRequest is a dto for serialization in persistent queue
@@ -16,10 +15,12 @@ type QueuedRequest struct {
 
 //RetryableRequest is an HTTP request with retry count
 type RetryableRequest struct {
-	Request      *Request
-	Retry        int
-	DequeuedTime time.Time
-	EventContext *EventContext
+	RetryState
+	Request *Request
+}
+
+func (rr *RetryableRequest) payloadSize() int {
+	return len(rr.Request.Body)
 }
 
 //Request is a dto for serialization custom http.Request
@@ -48,7 +49,7 @@ func NewHTTPRequestQueue(identifier string, queueFactory *events.QueueFactory) *
 
 //Add puts HTTP request and error callback to the queue
 func (pq *HTTPRequestQueue) Add(req *Request, eventContext *EventContext) error {
-	return pq.AddRequest(&RetryableRequest{Request: req, DequeuedTime: timestamp.Now().UTC(), Retry: 0, EventContext: eventContext})
+	return pq.AddRequest(&RetryableRequest{Request: req, RetryState: RetryState{DequeuedTime: timestamp.Now().UTC(), EventContext: eventContext}})
 }
 
 //AddRequest puts request to the queue with retryCount
@@ -78,6 +79,19 @@ func (pq *HTTPRequestQueue) DequeueBlock() (*RetryableRequest, error) {
 	return retryableRequest, nil
 }
 
+func (pq *HTTPRequestQueue) addItem(item retryableItem) error {
+	return pq.AddRequest(item.(*RetryableRequest))
+}
+
+func (pq *HTTPRequestQueue) dequeueItem() (retryableItem, error) {
+	item, err := pq.DequeueBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
 //Size returns queue size
 func (pq *HTTPRequestQueue) Size() uint64 {
 	return uint64(pq.queue.Size())
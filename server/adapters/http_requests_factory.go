@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
@@ -23,6 +24,12 @@ type HTTPRequestFactory interface {
 	Close()
 }
 
+//HTTPResponseValidator is implemented by factories of APIs which report errors in the body of 2xx responses
+type HTTPResponseValidator interface {
+	//ValidateResponse returns err if the request has failed
+	ValidateResponse(req *Request, response *HTTPResponse) error
+}
+
 //WebhookRequestFactory is a factory for building webhook (templating) HTTP requests from input events
 type WebhookRequestFactory struct {
 	httpMethod string
@@ -115,6 +122,42 @@ func (wrf *WebhookRequestFactory) Create(object map[string]interface{}) (req *Re
 	}, nil
 }
 
+//BatchKey returns key of requests with the same method, URL and headers. Only requests with JSON body can be batched
+func (wrf *WebhookRequestFactory) BatchKey(req *Request) string {
+	if len(req.Body) == 0 || !json.Valid(req.Body) {
+		return ""
+	}
+
+	return requestBatchKey(req)
+}
+
+//MaxBatchSize returns 0: webhook batches are limited only by configuration
+func (wrf *WebhookRequestFactory) MaxBatchSize() int {
+	return 0
+}
+
+//CreateBatch returns request with JSON array of requests bodies
+func (wrf *WebhookRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	bodies := make([][]byte, 0, len(requests))
+	for _, req := range requests {
+		bodies = append(bodies, req.Body)
+	}
+
+	body := append([]byte{'['}, bytes.Join(bodies, []byte{','})...)
+	body = append(body, ']')
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    body,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns nil: webhook batches are accepted or rejected as a whole
+func (wrf *WebhookRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	return nil
+}
+
 func (wrf *WebhookRequestFactory) Close() {
 	wrf.urlTmpl.Close()
 	wrf.bodyTmpl.Close()
@@ -24,7 +24,10 @@ const (
 	hubSpotContactWithEmailAPIURLTemplate    = "https://api.hubapi.com/contacts/v1/contact/createOrUpdate/email/%v"
 	hubSpotContactWithoutEmailAPIURLTemplate = "https://api.hubapi.com/contacts/v1/contact"
 	hubSpotEventURL                          = "https://track.hubspot.com/v1/event"
+	hubSpotContactsBatchAPIURL               = "https://api.hubapi.com/contacts/v1/contact/batch/"
 	JitsuUserAgent                           = "Jitsu.com/1.0"
+	//hubSpotMaxBatchSize is a max contacts count in one batch request
+	hubSpotMaxBatchSize = 1000
 )
 
 var (
@@ -54,9 +57,19 @@ type HubSpotResponse struct {
 
 // HubSpotContactRequest is a dto for sending contact requests to HubSpot
 type HubSpotContactRequest struct {
+	Email      string                             `json:"email,omitempty"`
 	Properties []HubSpotContactPropertyWithValues `json:"properties"`
 }
 
+// HubSpotBatchResponse is a dto for receiving contacts batch response errors from HubSpot
+type HubSpotBatchResponse struct {
+	HubSpotResponse
+	FailureMessages []struct {
+		Index int             `json:"index"`
+		Error HubSpotResponse `json:"error"`
+	} `json:"failureMessages"`
+}
+
 // HubSpotRequestFactory is a factory for building HubSpot HTTP requests from input events
 // reloads properties configuration every minutes in background goroutine
 type HubSpotRequestFactory struct {
@@ -172,6 +185,85 @@ func (hf *HubSpotRequestFactory) Create(object map[string]interface{}) (*Request
 	}
 }
 
+// BatchKey returns key of contact requests with email. Events and contacts without email can't be batched
+func (hf *HubSpotRequestFactory) BatchKey(req *Request) string {
+	if hf.contactEmail(req) == "" {
+		return ""
+	}
+
+	return "contacts\n" + req.Headers["Authorization"]
+}
+
+// MaxBatchSize returns HubSpot limit of contacts in one batch request
+func (hf *HubSpotRequestFactory) MaxBatchSize() int {
+	return hubSpotMaxBatchSize
+}
+
+// CreateBatch returns contacts batch createOrUpdate request
+func (hf *HubSpotRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	contacts := make([]HubSpotContactRequest, 0, len(requests))
+	for _, req := range requests {
+		contact := HubSpotContactRequest{}
+		if err := json.Unmarshal(req.Body, &contact); err != nil {
+			return nil, fmt.Errorf("error unmarshalling hubspot contact request: %v", err)
+		}
+		contact.Email = hf.contactEmail(req)
+		contacts = append(contacts, contact)
+	}
+
+	b, err := json.Marshal(contacts)
+	if err != nil {
+		return nil, err
+	}
+
+	reqURL := hubSpotContactsBatchAPIURL
+	if hf.accessToken == "" {
+		reqURL += "?hapikey=" + hf.apiKey
+	}
+	return &Request{
+		URL:     reqURL,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+// ParseBatchResponse returns errors of invalid contacts. HubSpot rejects the whole batch if it contains invalid contacts
+func (hf *HubSpotRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	if response.StatusCode != http.StatusBadRequest {
+		return nil
+	}
+
+	batchResponse := &HubSpotBatchResponse{}
+	if err := json.Unmarshal(response.Body, batchResponse); err != nil {
+		return nil
+	}
+
+	itemErrors := map[int]error{}
+	for _, failure := range batchResponse.FailureMessages {
+		if failure.Index >= 0 && failure.Index < batchSize {
+			itemErrors[failure.Index] = fmt.Errorf("hubspot contact has been rejected: %s [%s]: %s", failure.Error.Status, failure.Error.Category, failure.Error.Message)
+		}
+	}
+
+	return &BatchResult{ItemErrors: itemErrors, RetryOthers: true}
+}
+
+// contactEmail returns email from contact createOrUpdate request URL or empty string
+func (hf *HubSpotRequestFactory) contactEmail(req *Request) string {
+	emailURLPrefix := fmt.Sprintf(hubSpotContactWithEmailAPIURLTemplate, "")
+	if !strings.HasPrefix(req.URL, emailURLPrefix) {
+		return ""
+	}
+
+	email := strings.TrimPrefix(req.URL, emailURLPrefix)
+	if hf.accessToken == "" {
+		email = strings.TrimSuffix(email, "?hapikey="+hf.apiKey)
+	}
+
+	return email
+}
+
 // Close closes underlying goroutine
 func (hf *HubSpotRequestFactory) Close() {
 	hf.closed.Store(true)
@@ -205,9 +297,10 @@ func (hf *HubSpotRequestFactory) extractUserProperties(object map[string]interfa
 
 // HubSpotConfig is a dto for parsing HubSpot configuration
 type HubSpotConfig struct {
-	APIKey      string `mapstructure:"api_key,omitempty" json:"api_key,omitempty" yaml:"api_key,omitempty"`
-	AccessToken string `mapstructure:"access_token,omitempty" json:"access_token,omitempty" yaml:"access_token,omitempty"`
-	HubID       string `mapstructure:"hub_id,omitempty" json:"hub_id,omitempty" yaml:"hub_id,omitempty"`
+	APIKey      string           `mapstructure:"api_key,omitempty" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	AccessToken string           `mapstructure:"access_token,omitempty" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	HubID       string           `mapstructure:"hub_id,omitempty" json:"hub_id,omitempty" yaml:"hub_id,omitempty"`
+	Batch       *HTTPBatchConfig `mapstructure:"batch,omitempty" json:"batch,omitempty" yaml:"batch,omitempty"`
 }
 
 // Validate returns err if invalid
@@ -222,7 +315,7 @@ func (hc *HubSpotConfig) Validate() error {
 		return errors.New("'hub_id' is required parameter")
 	}
 
-	return nil
+	return hc.Batch.Validate()
 }
 
 // HubSpot is an adapter for sending HTTP requests to HubSpot
@@ -240,6 +333,7 @@ func NewHubSpot(config *HubSpotConfig, httpAdapterConfiguration *HTTPAdapterConf
 	}
 
 	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
 
 	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
 	if err != nil {
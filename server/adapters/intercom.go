@@ -0,0 +1,299 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+const (
+	intercomAPIVersion = "2.10"
+	//intercomMaxCachedContacts is a max size of contacts IDs cache. The cache is reset when it is reached
+	intercomMaxCachedContacts = 10_000
+)
+
+//intercomRegionHosts are Intercom API hosts of data residency regions
+var intercomRegionHosts = map[string]string{
+	"us": "https://api.intercom.io",
+	"eu": "https://api.eu.intercom.io",
+	"au": "https://api.au.intercom.io",
+}
+
+var (
+	//intercomContactFields are fields of Intercom contact. Other fields of the object aren't sent
+	intercomContactFields = []string{"role", "external_id", "email", "phone", "name", "avatar", "signed_up_at", "last_seen_at", "owner_id", "unsubscribed_from_emails", "custom_attributes"}
+	//intercomEventFields are fields of Intercom data event. Other fields of the object aren't sent
+	intercomEventFields = []string{"event_name", "created_at", "user_id", "id", "email", "metadata"}
+)
+
+//IntercomSearchResponse is a dto for receiving contacts search response from Intercom
+type IntercomSearchResponse struct {
+	TotalCount int `json:"total_count"`
+	Data       []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+//IntercomErrorResponse is a dto for receiving error response from Intercom
+type IntercomErrorResponse struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+//IntercomRequestFactory is a factory for building Intercom HTTP requests from input events
+//objects with 'event_name' are sent with /events API, objects with 'role' are contacts which are created or updated with /contacts API
+type IntercomRequestFactory struct {
+	accessToken string
+	host        string
+	client      *http.Client
+
+	mutex sync.Mutex
+	//contactIDs are Intercom contact IDs by external_id or email
+	contactIDs map[string]string
+}
+
+//newIntercomRequestFactory returns configured HTTPRequestFactory instance for intercom requests
+func newIntercomRequestFactory(config *IntercomConfig) (*IntercomRequestFactory, error) {
+	return &IntercomRequestFactory{
+		accessToken: config.AccessToken,
+		host:        config.host(),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		contactIDs:  map[string]string{},
+	}, nil
+}
+
+//Create returns created intercom request depends on object type
+//Intercom doesn't support contacts upsert so existing contacts are searched by external_id or email
+func (irf *IntercomRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	if eventName, ok := object["event_name"]; ok && eventName != nil {
+		return irf.request(http.MethodPost, "/events", intercomFields(object, intercomEventFields))
+	}
+
+	if _, ok := object["role"]; !ok {
+		return nil, errors.New("intercom object must contain 'event_name' (event) or 'role' (contact) field")
+	}
+	contactID, err := irf.findContact(object)
+	if err != nil {
+		return nil, err
+	}
+	contact := intercomFields(object, intercomContactFields)
+	if contactID == "" {
+		return irf.request(http.MethodPost, "/contacts", contact)
+	}
+
+	return irf.request(http.MethodPut, "/contacts/"+contactID, contact)
+}
+
+//findContact returns Intercom ID of the contact with the same external_id (or email) or empty string if it doesn't exist
+func (irf *IntercomRequestFactory) findContact(object map[string]interface{}) (string, error) {
+	field, value := "external_id", fmt.Sprint(utils.Nvl(object["external_id"], ""))
+	if value == "" {
+		field, value = "email", fmt.Sprint(utils.Nvl(object["email"], ""))
+	}
+	if value == "" {
+		return "", errors.New("intercom contact must contain 'external_id' or 'email' field")
+	}
+
+	cacheKey := field + ":" + value
+	irf.mutex.Lock()
+	contactID, ok := irf.contactIDs[cacheKey]
+	irf.mutex.Unlock()
+	if ok {
+		return contactID, nil
+	}
+
+	query := map[string]interface{}{"query": map[string]interface{}{"field": field, "operator": "=", "value": value}}
+	responseBody, err := irf.do(http.MethodPost, "/contacts/search", query)
+	if err != nil {
+		return "", fmt.Errorf("Error searching intercom contact by %s: %v", field, err)
+	}
+	response := &IntercomSearchResponse{}
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return "", fmt.Errorf("Error unmarshalling intercom contacts search response [%s]: %v", string(responseBody), err)
+	}
+	if len(response.Data) == 0 {
+		return "", nil
+	}
+
+	contactID = response.Data[0].ID
+	irf.mutex.Lock()
+	if len(irf.contactIDs) >= intercomMaxCachedContacts {
+		irf.contactIDs = map[string]string{}
+	}
+	irf.contactIDs[cacheKey] = contactID
+	irf.mutex.Unlock()
+
+	return contactID, nil
+}
+
+func (irf *IntercomRequestFactory) request(method, path string, body map[string]interface{}) (*Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling intercom request [%v]: %v", body, err)
+	}
+
+	return &Request{
+		URL:     irf.host + path,
+		Method:  method,
+		Body:    b,
+		Headers: irf.headers(),
+	}, nil
+}
+
+//do sends request to Intercom API and returns response body
+func (irf *IntercomRequestFactory) do(method, path string, body interface{}) ([]byte, error) {
+	var reqBody []byte
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+	}
+	httpReq, err := http.NewRequest(method, irf.host+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range irf.headers() {
+		httpReq.Header.Add(name, value)
+	}
+
+	resp, err := irf.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading intercom response body: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		response := &IntercomErrorResponse{}
+		if err := json.Unmarshal(responseBody, response); err != nil || len(response.Errors) == 0 {
+			return nil, fmt.Errorf("intercom error [code=%d]: %s", resp.StatusCode, string(responseBody))
+		}
+		return nil, fmt.Errorf("intercom error [code=%d]: %s", resp.StatusCode, response.Errors[0].Message)
+	}
+
+	return responseBody, nil
+}
+
+func (irf *IntercomRequestFactory) headers() map[string]string {
+	return map[string]string{
+		"Content-Type":     "application/json",
+		"Accept":           "application/json",
+		"Authorization":    "Bearer " + irf.accessToken,
+		"Intercom-Version": intercomAPIVersion,
+		"user-agent":       JitsuUserAgent,
+	}
+}
+
+func (irf *IntercomRequestFactory) Close() {
+	irf.client.CloseIdleConnections()
+}
+
+//intercomFields returns object with only fields from the list
+func intercomFields(object map[string]interface{}, fields []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if value, ok := object[name]; ok && value != nil {
+			result[name] = value
+		}
+	}
+
+	return result
+}
+
+//IntercomConfig is a dto for parsing Intercom configuration
+type IntercomConfig struct {
+	AccessToken string `mapstructure:"access_token" json:"access_token,omitempty" yaml:"access_token,omitempty"`
+	//Region is a data residency region: us, eu or au. Default is us
+	Region string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a custom API host (e.g. proxy). Overrides region
+	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+}
+
+//Validate returns err if invalid
+func (ic *IntercomConfig) Validate() error {
+	if ic == nil {
+		return errors.New("intercom config is required")
+	}
+	if ic.AccessToken == "" {
+		return errors.New("'access_token' is required parameter")
+	}
+	if _, ok := intercomRegionHosts[strings.ToLower(ic.Region)]; ic.Region != "" && !ok {
+		return fmt.Errorf("unknown intercom region [%s]. Supported: us, eu, au", ic.Region)
+	}
+
+	return nil
+}
+
+//host returns API host of the configured region or custom endpoint
+func (ic *IntercomConfig) host() string {
+	if ic.Endpoint != "" {
+		return strings.TrimSuffix(ic.Endpoint, "/")
+	}
+
+	return intercomRegionHosts[strings.ToLower(utils.NvlString(ic.Region, "us"))]
+}
+
+//Intercom is an adapter for sending HTTP requests to Intercom
+type Intercom struct {
+	AbstractHTTP
+
+	config *IntercomConfig
+}
+
+//NewIntercom returns configured Intercom adapter instance
+func NewIntercom(config *IntercomConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*Intercom, error) {
+	httpReqFactory, err := newIntercomRequestFactory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Intercom{config: config}
+	i.httpAdapter = httpAdapter
+	return i, nil
+}
+
+//NewTestIntercom returns test instance of adapter
+func NewTestIntercom(config *IntercomConfig) *Intercom {
+	return &Intercom{config: config}
+}
+
+//TestAccess requests the workspace of the access token from Intercom
+func (i *Intercom) TestAccess() error {
+	httpReqFactory, err := newIntercomRequestFactory(i.config)
+	if err != nil {
+		return err
+	}
+	defer httpReqFactory.Close()
+
+	if _, err := httpReqFactory.do(http.MethodGet, "/me", nil); err != nil {
+		return fmt.Errorf("error connecting to intercom: %v", err)
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (i *Intercom) Type() string {
+	return "Intercom"
+}
@@ -0,0 +1,74 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntercomRequests(t *testing.T) {
+	mutex := &sync.Mutex{}
+	var searches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "/contacts/search", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		searches = append(searches, string(body))
+		mutex.Unlock()
+
+		query := struct {
+			Query struct {
+				Value string `json:"value"`
+			} `json:"query"`
+		}{}
+		require.NoError(t, json.Unmarshal(body, &query))
+		if query.Query.Value == "u1" {
+			w.Write([]byte(`{"type":"list","total_count":1,"data":[{"type":"contact","id":"c1"}]}`))
+			return
+		}
+		w.Write([]byte(`{"type":"list","total_count":0,"data":[]}`))
+	}))
+	defer server.Close()
+
+	config := &IntercomConfig{AccessToken: "token", Endpoint: server.URL + "/"}
+	require.NoError(t, config.Validate())
+	require.EqualError(t, (&IntercomConfig{AccessToken: "token", Region: "asia"}).Validate(), "unknown intercom region [asia]. Supported: us, eu, au")
+	factory, err := newIntercomRequestFactory(config)
+	require.NoError(t, err)
+	defer factory.Close()
+
+	update, err := factory.Create(map[string]interface{}{"role": "user", "external_id": "u1", "name": "John", "eventn_ctx_event_id": "1"})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPut, update.Method)
+	require.Equal(t, server.URL+"/contacts/c1", update.URL)
+	require.JSONEq(t, `{"role":"user","external_id":"u1","name":"John"}`, string(update.Body), "only contact fields must be sent")
+
+	_, err = factory.Create(map[string]interface{}{"role": "user", "external_id": "u1", "name": "John Doe"})
+	require.NoError(t, err)
+	require.Len(t, searches, 1, "contact ID must be cached")
+
+	create, err := factory.Create(map[string]interface{}{"role": "user", "email": "new@b.com"})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, create.Method)
+	require.Equal(t, server.URL+"/contacts", create.URL)
+	require.JSONEq(t, `{"query":{"field":"email","operator":"=","value":"new@b.com"}}`, searches[1])
+
+	event, err := factory.Create(map[string]interface{}{"event_name": "pageview", "user_id": "u1", "created_at": 1630000000, "metadata": map[string]interface{}{"url": "https://jitsu.com"}, "eventn_ctx_event_id": "2"})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, event.Method)
+	require.Equal(t, server.URL+"/events", event.URL)
+	require.Equal(t, intercomAPIVersion, event.Headers["Intercom-Version"])
+	require.JSONEq(t, `{"event_name":"pageview","user_id":"u1","created_at":1630000000,"metadata":{"url":"https://jitsu.com"}}`, string(event.Body))
+	require.Len(t, searches, 2, "events don't require contacts search")
+
+	_, err = factory.Create(map[string]interface{}{"role": "user"})
+	require.EqualError(t, err, "intercom contact must contain 'external_id' or 'email' field")
+	_, err = factory.Create(map[string]interface{}{"name": "John"})
+	require.EqualError(t, err, "intercom object must contain 'event_name' (event) or 'role' (contact) field")
+}
@@ -0,0 +1,144 @@
+package adapters
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const localTmpFileSuffix = ".tmp"
+
+//LocalFileSystem is an ObjectStorage which stores objects as files in a local directory
+//objects are written into temporary files and then renamed so readers never see partially written objects
+type LocalFileSystem struct {
+	dir string
+}
+
+//NewLocalFileSystem returns LocalFileSystem and creates the directory if it doesn't exist
+func NewLocalFileSystem(dir string) (*LocalFileSystem, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("local file system directory is required")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory %s: %v", dir, err)
+	}
+
+	return &LocalFileSystem{dir: dir}, nil
+}
+
+//PutObject atomically writes object into the file
+func (lfs *LocalFileSystem) PutObject(key string, data []byte) error {
+	path, err := lfs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %v", key, err)
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*"+localTmpFileSuffix)
+	if err != nil {
+		return fmt.Errorf("error creating temporary file for %s: %v", key, err)
+	}
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("error writing file %s: %v", key, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("error closing file %s: %v", key, err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("error renaming file %s: %v", key, err)
+	}
+
+	return nil
+}
+
+//GetObject returns file content or ErrObjectNotExist
+func (lfs *LocalFileSystem) GetObject(key string) ([]byte, error) {
+	path, err := lfs.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrObjectNotExist
+		}
+		return nil, fmt.Errorf("error reading file %s: %v", key, err)
+	}
+
+	return data, nil
+}
+
+//DeleteObject removes the file. Returns nil if file doesn't exist
+func (lfs *LocalFileSystem) DeleteObject(key string) error {
+	path, err := lfs.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing file %s: %v", key, err)
+	}
+
+	return nil
+}
+
+//ListObjects returns keys of files with the prefix. Temporary files are skipped
+func (lfs *LocalFileSystem) ListObjects(prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(lfs.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(info.Name(), localTmpFileSuffix) {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(lfs.dir, path)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relativePath)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing files with prefix %s: %v", prefix, err)
+	}
+
+	return keys, nil
+}
+
+//path returns file path of the object. Returns err if the key points outside of the directory (e.g. contains ..)
+func (lfs *LocalFileSystem) path(key string) (string, error) {
+	path := filepath.Join(lfs.dir, filepath.FromSlash(key))
+	if !isSubPath(lfs.dir, path) {
+		return "", fmt.Errorf("object key %s points outside of the directory %s", key, lfs.dir)
+	}
+
+	return path, nil
+}
+
+//isSubPath returns true if path is dir or is inside dir. Both paths must be cleaned
+func isSubPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
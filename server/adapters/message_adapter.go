@@ -0,0 +1,167 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+//MessageAdapterConfiguration is a dto for creating MessageAdapter
+type MessageAdapterConfiguration struct {
+	DestinationID string
+	//HTTPConfig is used only for retries configuration (retry count, delay and queue fullness threshold)
+	HTTPConfig     *HTTPConfiguration
+	Publisher      MessagePublisher
+	MessageFactory *MessageFactory
+	QueueFactory   *events.QueueFactory
+	PoolWorkers    int
+	DebugLogger    *logging.QueryLogger
+	ErrorHandler   func(fallback bool, eventContext *EventContext, err error)
+	SuccessHandler func(eventContext *EventContext)
+	Batch          *HTTPBatchConfig
+	//CircuitBreaker pauses messages publishing while it is open (optional)
+	CircuitBreaker *circuitbreaker.CircuitBreaker
+}
+
+//MessageAdapter is an adapter for publishing messages into message brokers with retries
+//has persistent message queue and workers pool under the hood (the same as HTTPAdapter)
+//if batching is configured, queued messages with the same topic are published in one call
+type MessageAdapter struct {
+	publisher      MessagePublisher
+	messageFactory *MessageFactory
+	queue          *MessageQueue
+	sender         *queuedSender
+	debugLogger    *logging.QueryLogger
+
+	errorHandler   func(fallback bool, eventContext *EventContext, err error)
+	successHandler func(eventContext *EventContext)
+
+	destinationID string
+}
+
+//NewMessageAdapter returns configured MessageAdapter and starts queue observing goroutine
+func NewMessageAdapter(config *MessageAdapterConfiguration) (*MessageAdapter, error) {
+	ma := &MessageAdapter{
+		publisher:      config.Publisher,
+		messageFactory: config.MessageFactory,
+		queue:          NewMessageQueue(config.DestinationID, config.QueueFactory),
+		debugLogger:    config.DebugLogger,
+
+		errorHandler:   config.ErrorHandler,
+		successHandler: config.SuccessHandler,
+
+		destinationID: config.DestinationID,
+	}
+
+	sender, err := newQueuedSender(&queuedSenderConfiguration{
+		destinationID:  config.DestinationID,
+		itemName:       "message",
+		queue:          ma.queue,
+		poolWorkers:    config.PoolWorkers,
+		send:           ma.publish,
+		batcher:        newMessageBatcher(config.Batch, config.Publisher),
+		batchKey:       messageTopic,
+		circuitBreaker: config.CircuitBreaker,
+		errorHandler:   config.ErrorHandler,
+		httpConfig:     config.HTTPConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating message adapter workers pool: %v", err)
+	}
+	ma.sender = sender
+
+	return ma, nil
+}
+
+//messageTopic is a batch key of messages: messages with the same topic are published in one call
+func messageTopic(item retryableItem) string {
+	return item.(*RetryableMessage).Message.Topic
+}
+
+//Insert creates message from the event and puts it to the queue
+//returns err if the message can't be created or put to the queue
+func (ma *MessageAdapter) Insert(insertContext *InsertContext) error {
+	message, err := ma.messageFactory.Create(insertContext.eventContext)
+	if err != nil {
+		return err
+	}
+
+	return ma.queue.Add(message, insertContext.eventContext)
+}
+
+//publish is a workers pool function: publishes a message or batch of messages with the same topic
+//rejected messages are passed to errorHandler (fallback) without retries, other failed messages are retried
+func (ma *MessageAdapter) publish(i interface{}) {
+	var retryableMessages []*RetryableMessage
+	switch task := i.(type) {
+	case *RetryableMessage:
+		retryableMessages = []*RetryableMessage{task}
+	case *itemsBatch:
+		for _, item := range task.items {
+			retryableMessages = append(retryableMessages, item.(*RetryableMessage))
+		}
+	default:
+		logging.SystemErrorf("[%s] Message publishing task has unknown type: %T", ma.destinationID, i)
+		return
+	}
+
+	topic := retryableMessages[0].Message.Topic
+	messages := make([]*Message, 0, len(retryableMessages))
+	for _, message := range retryableMessages {
+		messages = append(messages, message.Message)
+	}
+	ma.debugLogger.LogQuery(fmt.Sprintf("Publishing %d messages to %s", len(messages), topic))
+
+	itemErrors, err := ma.publisher.Publish(messages)
+	if err != nil {
+		logging.Errorf("[%s] Publishing %d messages to %s will be retried after err: %v", ma.destinationID, len(messages), topic, err)
+		for _, message := range retryableMessages {
+			ma.doRetry(message, err)
+		}
+		return
+	}
+
+	for i, message := range retryableMessages {
+		var itemErr error
+		if i < len(itemErrors) {
+			itemErr = itemErrors[i]
+		}
+
+		var rejectedErr *MessageRejectedError
+		switch {
+		case itemErr == nil:
+			ma.successHandler(message.EventContext)
+		case errors.As(itemErr, &rejectedErr):
+			logging.Errorf("[%s] Message to %s with body [%s] has been rejected: %v", ma.destinationID, topic, string(message.Message.Body), itemErr)
+			ma.errorHandler(true, message.EventContext, itemErr)
+		default:
+			ma.doRetry(message, itemErr)
+		}
+	}
+}
+
+//doRetry retries message publishing or passes publishErr to errorHandler (fallback) if it can't be retried
+func (ma *MessageAdapter) doRetry(message *RetryableMessage, publishErr error) {
+	if ma.sender.retry(message, publishErr) {
+		return
+	}
+
+	logging.Errorf("[%s] Error publishing message to %s with body [%s]: %v", ma.destinationID, message.Message.Topic, string(message.Message.Body), publishErr)
+
+	ma.errorHandler(true, message.EventContext, publishErr)
+}
+
+//Close closes underlying queue, workers pool, publisher and templates
+//returns err if occurred
+func (ma *MessageAdapter) Close() (err error) {
+	ma.messageFactory.Close()
+	err = ma.sender.close()
+	if publisherErr := ma.publisher.Close(); publisherErr != nil && err == nil {
+		err = publisherErr
+	}
+
+	return err
+}
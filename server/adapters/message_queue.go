@@ -0,0 +1,270 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/jitsucom/jitsu/server/templates"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//MessageConfig is a dto for parsing message templates and batching configuration of message queue destinations
+type MessageConfig struct {
+	//Body is a message body template. Default is JSON of the event
+	Body string `mapstructure:"body,omitempty" json:"body,omitempty" yaml:"body,omitempty"`
+	//Key is an ordering key template (Pub/Sub ordering key, SQS FIFO message group ID)
+	Key string `mapstructure:"key,omitempty" json:"key,omitempty" yaml:"key,omitempty"`
+	//Attributes are message attributes templates (Pub/Sub attributes, SQS message attributes, NATS headers)
+	Attributes map[string]string `mapstructure:"attributes,omitempty" json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	//Batch enables aggregating messages into vendor API batch requests
+	Batch *HTTPBatchConfig `mapstructure:"batch,omitempty" json:"batch,omitempty" yaml:"batch,omitempty"`
+}
+
+//Message is a dto for serialization of the message in persistent queue
+type Message struct {
+	//Topic is a SQS queue URL, Pub/Sub topic or NATS subject
+	Topic      string
+	Key        string
+	ID         string
+	Body       []byte
+	Attributes map[string]string
+}
+
+//RetryableMessage is a message with retry count
+type RetryableMessage struct {
+	RetryState
+	Message *Message
+}
+
+func (rm *RetryableMessage) payloadSize() int {
+	return len(rm.Message.Body)
+}
+
+//QueuedMessage is a dto for serialization in persistent queue
+type QueuedMessage struct {
+	SerializedRetryableMessage []byte
+}
+
+//QueuedMessageBuilder creates and returns a new *adapters.QueuedMessage (must be pointer).
+func QueuedMessageBuilder() interface{} {
+	return &QueuedMessage{}
+}
+
+//MessageRejectedError is returned from MessagePublisher for messages which have been rejected by the broker
+//because of the message itself (e.g. too large message or malformed attributes). Such messages aren't retried
+type MessageRejectedError struct {
+	Err error
+}
+
+func (mre *MessageRejectedError) Error() string {
+	return mre.Err.Error()
+}
+
+func (mre *MessageRejectedError) Unwrap() error {
+	return mre.Err
+}
+
+//MessagePublisher is implemented by message brokers clients
+type MessagePublisher interface {
+	//Publish sends messages with the same topic and returns errors of every message (nil if the message has been published)
+	//returns err if all messages have been failed
+	Publish(messages []*Message) ([]error, error)
+	//MaxBatchSize returns broker API limit of messages in one batch request (0 - unlimited)
+	MaxBatchSize() int
+	Close() error
+}
+
+//MessageQueue is a queue (persisted on file system or in Redis) with messages
+type MessageQueue struct {
+	queue queue.Queue
+}
+
+//NewMessageQueue returns configured MessageQueue instance
+func NewMessageQueue(identifier string, queueFactory *events.QueueFactory) *MessageQueue {
+	underlyingQueue := queueFactory.CreateHTTPQueue(identifier, QueuedMessageBuilder)
+	return &MessageQueue{queue: underlyingQueue}
+}
+
+//Add puts message and event context to the queue
+func (mq *MessageQueue) Add(message *Message, eventContext *EventContext) error {
+	return mq.AddMessage(&RetryableMessage{Message: message, RetryState: RetryState{DequeuedTime: timestamp.Now().UTC(), EventContext: eventContext}})
+}
+
+//AddMessage puts message with retry count to the queue
+func (mq *MessageQueue) AddMessage(message *RetryableMessage) error {
+	serialized, _ := json.Marshal(message)
+	return mq.queue.Push(&QueuedMessage{SerializedRetryableMessage: serialized})
+}
+
+//DequeueBlock waits when enqueued message is ready and return it
+func (mq *MessageQueue) DequeueBlock() (*RetryableMessage, error) {
+	iface, err := mq.queue.Pop()
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedMessage, ok := iface.(*QueuedMessage)
+	if !ok {
+		return nil, fmt.Errorf("Dequeued object is not a QueuedMessage instance. Type is: %T", iface)
+	}
+
+	message := &RetryableMessage{}
+	if err := json.Unmarshal(wrappedMessage.SerializedRetryableMessage, message); err != nil {
+		return nil, fmt.Errorf("Error deserializing RetryableMessage from the queue: %v", err)
+	}
+
+	return message, nil
+}
+
+func (mq *MessageQueue) addItem(item retryableItem) error {
+	return mq.AddMessage(item.(*RetryableMessage))
+}
+
+func (mq *MessageQueue) dequeueItem() (retryableItem, error) {
+	item, err := mq.DequeueBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+//Size returns queue size
+func (mq *MessageQueue) Size() uint64 {
+	return uint64(mq.queue.Size())
+}
+
+//Close closes underlying persistent queue
+func (mq *MessageQueue) Close() error {
+	return mq.queue.Close()
+}
+
+//MessageFactory creates messages from events with templates
+type MessageFactory struct {
+	topicTmpl      templates.TemplateExecutor
+	keyTmpl        templates.TemplateExecutor
+	bodyTmpl       templates.TemplateExecutor
+	attributeTmpls map[string]templates.TemplateExecutor
+}
+
+//NewMessageFactory returns configured MessageFactory
+//topic, key and attributes are Go templates (plain text is used as is), body is a Go or JavaScript template (the same as WebHook body)
+func NewMessageFactory(destinationID, destinationType, topic string, config *MessageConfig) (*MessageFactory, error) {
+	templateFunctions := templates.EnrichedFuncMap(map[string]interface{}{"destinationId": destinationID, "destinationType": destinationType})
+	mf := &MessageFactory{attributeTmpls: map[string]templates.TemplateExecutor{}}
+
+	topicTmpl, err := templates.NewGoTemplateExecutor("topic", topic, templateFunctions)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing topic template [%s]: %v", topic, err)
+	}
+	mf.topicTmpl = topicTmpl
+	if config.Key != "" {
+		keyTmpl, err := templates.NewGoTemplateExecutor("key", config.Key, templateFunctions)
+		if err != nil {
+			mf.Close()
+			return nil, fmt.Errorf("Error parsing key template [%s]: %v", config.Key, err)
+		}
+		mf.keyTmpl = keyTmpl
+	}
+	if config.Body != "" {
+		bodyTmpl, err := templates.SmartParse("body", config.Body, templateFunctions)
+		if err != nil {
+			mf.Close()
+			return nil, fmt.Errorf("Error parsing body template [%s]: %v", config.Body, err)
+		}
+		mf.bodyTmpl = bodyTmpl
+	}
+	for name, attribute := range config.Attributes {
+		attributeTmpl, err := templates.NewGoTemplateExecutor("attribute_"+name, attribute, templateFunctions)
+		if err != nil {
+			mf.Close()
+			return nil, fmt.Errorf("Error parsing attribute [%s] template [%s]: %v", name, attribute, err)
+		}
+		mf.attributeTmpls[name] = attributeTmpl
+	}
+
+	return mf, nil
+}
+
+//Create returns message built from the event with templates
+//attributes with empty values are skipped
+func (mf *MessageFactory) Create(eventContext *EventContext) (message *Message, err error) {
+	//panic handler
+	defer func() {
+		if r := recover(); r != nil {
+			message = nil
+			err = fmt.Errorf("Error constructing message: %v", r)
+		}
+	}()
+
+	object := eventContext.ProcessedEvent
+	message = &Message{ID: eventContext.EventID, Attributes: map[string]string{}}
+	if message.Topic, err = executeTemplate(mf.topicTmpl, object); err != nil {
+		return nil, fmt.Errorf("Error executing topic template: %v", err)
+	}
+	if message.Topic == "" {
+		return nil, errors.New("topic template returned empty value")
+	}
+	if message.Key, err = executeTemplate(mf.keyTmpl, object); err != nil {
+		return nil, fmt.Errorf("Error executing key template: %v", err)
+	}
+	for name, attributeTmpl := range mf.attributeTmpls {
+		value, err := executeTemplate(attributeTmpl, object)
+		if err != nil {
+			return nil, fmt.Errorf("Error executing attribute [%s] template: %v", name, err)
+		}
+		if value != "" {
+			message.Attributes[name] = value
+		}
+	}
+
+	if mf.bodyTmpl == nil {
+		message.Body, err = json.Marshal(object)
+		return message, err
+	}
+
+	rawBody, err := mf.bodyTmpl.ProcessEvent(object, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error executing body template: %v", err)
+	}
+	if message.Body, err = templates.ToJSONorStringBytes(rawBody); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+//executeTemplate returns Go template result as a string or empty string if the template isn't configured
+func executeTemplate(tmpl templates.TemplateExecutor, object map[string]interface{}) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	raw, err := tmpl.ProcessEvent(object, nil)
+	if err != nil {
+		return "", err
+	}
+
+	//missing fields are rendered as "<no value>" by Go templates
+	return strings.ReplaceAll(templates.ToString(raw, false, false, false), "<no value>", ""), nil
+}
+
+//Close closes underlying templates
+func (mf *MessageFactory) Close() {
+	for _, tmpl := range []templates.TemplateExecutor{mf.topicTmpl, mf.keyTmpl, mf.bodyTmpl} {
+		if tmpl != nil {
+			tmpl.Close()
+		}
+	}
+	for _, tmpl := range mf.attributeTmpls {
+		tmpl.Close()
+	}
+}
+
+//newMessageBatcher returns itemsBatcher or nil if batching isn't configured or isn't supported by the publisher
+func newMessageBatcher(config *HTTPBatchConfig, publisher MessagePublisher) *itemsBatcher {
+	return newItemsBatcher(config, publisher.MaxBatchSize())
+}
@@ -0,0 +1,264 @@
+package adapters
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageFactory(t *testing.T) {
+	factory, err := NewMessageFactory("test", "pubsub", "events_{{.event_type}}", &MessageConfig{
+		Key:        "{{.user.id}}",
+		Attributes: map[string]string{"event_type": "{{.event_type}}", "source": "{{.src}}"},
+	})
+	require.NoError(t, err)
+	defer factory.Close()
+
+	event := events.Event{"event_type": "pageview", "user": map[string]interface{}{"id": "u1"}}
+	message, err := factory.Create(&EventContext{EventID: "1", ProcessedEvent: event})
+	require.NoError(t, err)
+	require.Equal(t, "events_pageview", message.Topic)
+	require.Equal(t, "u1", message.Key)
+	require.Equal(t, "1", message.ID)
+	require.Equal(t, map[string]string{"event_type": "pageview"}, message.Attributes, "empty attributes must be skipped")
+	require.JSONEq(t, `{"event_type":"pageview","user":{"id":"u1"}}`, string(message.Body), "event JSON is a default body")
+
+	factory, err = NewMessageFactory("test", "sqs", "events", &MessageConfig{Body: `{{.event_type}}`})
+	require.NoError(t, err)
+	defer factory.Close()
+
+	message, err = factory.Create(&EventContext{EventID: "1", ProcessedEvent: event})
+	require.NoError(t, err)
+	require.Equal(t, "pageview", string(message.Body))
+}
+
+//testPublisher rejects messages with "too_large" body and fails "flaky" messages on the first attempt
+type testPublisher struct {
+	mutex   sync.Mutex
+	batches [][]string
+	flaky   map[string]bool
+}
+
+func (tp *testPublisher) Publish(messages []*Message) ([]error, error) {
+	tp.mutex.Lock()
+	defer tp.mutex.Unlock()
+
+	var batch []string
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		body := string(message.Body)
+		batch = append(batch, message.Topic+":"+body)
+		switch {
+		case strings.Contains(body, "too_large"):
+			errs[i] = &MessageRejectedError{Err: errors.New("message is too large")}
+		case strings.Contains(body, "flaky") && !tp.flaky[message.ID]:
+			tp.flaky[message.ID] = true
+			errs[i] = errors.New("broker is unavailable")
+		}
+	}
+	tp.batches = append(tp.batches, batch)
+
+	return errs, nil
+}
+
+func (tp *testPublisher) MaxBatchSize() int {
+	return 2
+}
+
+func (tp *testPublisher) Close() error {
+	return nil
+}
+
+func TestMessageAdapter(t *testing.T) {
+	publisher := &testPublisher{flaky: map[string]bool{}}
+	factory, err := NewMessageFactory("test_mq", "test", "{{.topic}}", &MessageConfig{Body: "{{.body}}"})
+	require.NoError(t, err)
+
+	mutex := &sync.Mutex{}
+	var succeeded, failed, retried []string
+	adapter, err := NewMessageAdapter(&MessageAdapterConfiguration{
+		DestinationID:  "test_mq",
+		HTTPConfig:     &HTTPConfiguration{RetryDelay: 10 * time.Millisecond, RetryCount: 1},
+		Publisher:      publisher,
+		MessageFactory: factory,
+		QueueFactory:   events.NewQueueFactory(nil, 0),
+		PoolWorkers:    2,
+		DebugLogger:    logging.NewQueryLogger("test_mq", nil, nil),
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if fallback {
+				failed = append(failed, eventContext.EventID)
+			} else {
+				retried = append(retried, eventContext.EventID)
+			}
+		},
+		SuccessHandler: func(eventContext *EventContext) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			succeeded = append(succeeded, eventContext.EventID)
+		},
+		Batch: &HTTPBatchConfig{MaxSize: 10, Window: "100ms"},
+	})
+	require.NoError(t, err)
+	defer adapter.Close()
+
+	for i, event := range []events.Event{
+		{"topic": "a", "body": "1"},
+		{"topic": "a", "body": "too_large"},
+		{"topic": "b", "body": "3"},
+		{"topic": "a", "body": "flaky"},
+	} {
+		require.NoError(t, adapter.Insert(NewSingleInsertContext(&EventContext{EventID: fmt.Sprint(i + 1), ProcessedEvent: event})))
+	}
+
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(succeeded)+len(failed) == 4
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	sort.Strings(succeeded)
+	require.Equal(t, []string{"1", "3", "4"}, succeeded)
+	require.Equal(t, []string{"2"}, failed, "rejected messages mustn't be retried")
+	require.Equal(t, []string{"4"}, retried)
+
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+	for _, batch := range publisher.batches {
+		require.LessOrEqual(t, len(batch), 2, "publisher batch size limit must be applied")
+		for _, message := range batch {
+			require.Equal(t, batch[0][:1], message[:1], "batch must contain messages of one topic")
+		}
+	}
+}
+
+func TestSQSPublish(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.PostForm
+		body := r.PostForm.Get("SendMessageBatchRequestEntry.1.MessageBody")
+		md5sum := md5.Sum([]byte(body))
+		fmt.Fprintf(w, `<SendMessageBatchResponse><SendMessageBatchResult>
+<SendMessageBatchResultEntry><Id>0</Id><MessageId>m1</MessageId><MD5OfMessageBody>%s</MD5OfMessageBody></SendMessageBatchResultEntry>
+<BatchResultErrorEntry><Id>1</Id><Code>InvalidParameterValue</Code><Message>message is too long</Message><SenderFault>true</SenderFault></BatchResultErrorEntry>
+<BatchResultErrorEntry><Id>2</Id><Code>InternalError</Code><Message>internal error</Message><SenderFault>false</SenderFault></BatchResultErrorEntry>
+</SendMessageBatchResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></SendMessageBatchResponse>`, hex.EncodeToString(md5sum[:]))
+	}))
+	defer server.Close()
+
+	config := &SQSConfig{Region: "us-east-1", AccessKeyID: "key", SecretKey: "secret", Endpoint: server.URL, QueueURL: server.URL + "/queue/events.fifo"}
+	require.NoError(t, config.Validate())
+	sqs, err := NewSQS(config)
+	require.NoError(t, err)
+
+	errs, err := sqs.Publish([]*Message{
+		{Topic: config.QueueURL, ID: "e1", Key: "u1", Body: []byte(`{"id":1}`), Attributes: map[string]string{"event_type": "pageview"}},
+		{Topic: config.QueueURL, ID: "e2", Body: []byte(`{"id":2}`)},
+		{Topic: config.QueueURL, ID: "e3", Body: []byte(`{"id":3}`)},
+	})
+	require.NoError(t, err)
+	require.Len(t, errs, 3)
+	require.NoError(t, errs[0])
+	var rejectedErr *MessageRejectedError
+	require.True(t, errors.As(errs[1], &rejectedErr), "sender fault errors must be rejected")
+	require.Error(t, errs[2])
+	require.False(t, errors.As(errs[2], &rejectedErr), "server errors must be retried")
+
+	require.Equal(t, "u1", form["SendMessageBatchRequestEntry.1.MessageGroupId"][0])
+	require.Equal(t, "e1", form["SendMessageBatchRequestEntry.1.MessageDeduplicationId"][0])
+	require.Equal(t, sqsDefaultMessageGroupID, form["SendMessageBatchRequestEntry.2.MessageGroupId"][0], "FIFO queue messages must have a group ID")
+	require.Equal(t, "event_type", form["SendMessageBatchRequestEntry.1.MessageAttribute.1.Name"][0])
+	require.Equal(t, "pageview", form["SendMessageBatchRequestEntry.1.MessageAttribute.1.Value.StringValue"][0])
+}
+
+func TestSQSPublishSplitsBySize(t *testing.T) {
+	var requestSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		response := "<SendMessageBatchResponse><SendMessageBatchResult>"
+		entries := 0
+		for i := 1; ; i++ {
+			id := r.PostForm.Get(fmt.Sprintf("SendMessageBatchRequestEntry.%d.Id", i))
+			if id == "" {
+				break
+			}
+			entries++
+			md5sum := md5.Sum([]byte(r.PostForm.Get(fmt.Sprintf("SendMessageBatchRequestEntry.%d.MessageBody", i))))
+			response += fmt.Sprintf("<SendMessageBatchResultEntry><Id>%s</Id><MessageId>m%s</MessageId><MD5OfMessageBody>%s</MD5OfMessageBody></SendMessageBatchResultEntry>", id, id, hex.EncodeToString(md5sum[:]))
+		}
+		requestSizes = append(requestSizes, entries)
+		fmt.Fprint(w, response+"</SendMessageBatchResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></SendMessageBatchResponse>")
+	}))
+	defer server.Close()
+
+	config := &SQSConfig{Region: "us-east-1", AccessKeyID: "key", SecretKey: "secret", Endpoint: server.URL, QueueURL: server.URL + "/queue/events"}
+	sqs, err := NewSQS(config)
+	require.NoError(t, err)
+
+	body := func(size int) []byte {
+		return []byte(strings.Repeat("a", size))
+	}
+	errs, err := sqs.Publish([]*Message{
+		{Topic: config.QueueURL, Body: body(100 * 1024)},
+		{Topic: config.QueueURL, Body: body(100 * 1024)},
+		{Topic: config.QueueURL, Body: body(100 * 1024)},
+		{Topic: config.QueueURL, Body: body(300 * 1024)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 1}, requestSizes, "messages must be split into requests up to 256KB")
+	require.Len(t, errs, 4)
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	require.NoError(t, errs[2])
+	var rejectedErr *MessageRejectedError
+	require.True(t, errors.As(errs[3], &rejectedErr), "messages larger than 256KB must be rejected")
+	require.EqualError(t, errs[3], "SQS message size 307200 bytes exceeds limit 262144 bytes")
+}
+
+func TestPubSubPublish(t *testing.T) {
+	server := pstest.NewServer()
+	defer server.Close()
+
+	ctx := context.Background()
+	config := &PubSubConfig{Project: "test", Endpoint: server.Addr, Topic: "events", MessageConfig: MessageConfig{Key: "{{.user_id}}"}}
+	require.NoError(t, config.Validate())
+	pubSub, err := NewPubSub(ctx, config)
+	require.NoError(t, err)
+	defer pubSub.Close()
+
+	require.EqualError(t, pubSub.TestAccess("events"), "Pub/Sub topic events doesn't exist")
+	_, err = pubSub.client.CreateTopic(ctx, "events")
+	require.NoError(t, err)
+	require.NoError(t, pubSub.TestAccess("events"))
+
+	errs, err := pubSub.Publish([]*Message{
+		{Topic: "events", Key: "u1", Body: []byte(`{"id":1}`), Attributes: map[string]string{"event_type": "pageview"}},
+		{Topic: "events", Key: "u1", Body: []byte(`{"id":2}`)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []error{nil, nil}, errs)
+
+	messages := server.Messages()
+	require.Len(t, messages, 2)
+	require.Equal(t, `{"id":1}`, string(messages[0].Data))
+	require.Equal(t, map[string]string{"event_type": "pageview"}, messages[0].Attributes)
+	require.Equal(t, "u1", messages[0].OrderingKey)
+}
@@ -0,0 +1,338 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+const (
+	//mixpanelMaxBatchSize is a max events (profile updates) count in one /import (/engage) request
+	mixpanelMaxBatchSize = 2000
+	//mixpanelCreateAliasEvent is sent with /track API. /import API doesn't support it
+	mixpanelCreateAliasEvent = "$create_alias"
+	mixpanelDistinctID       = "$distinct_id"
+)
+
+//mixpanelRegionHosts are Mixpanel ingestion API hosts of data residency regions
+var mixpanelRegionHosts = map[string]string{
+	"us": "https://api.mixpanel.com",
+	"eu": "https://api-eu.mixpanel.com",
+	"in": "https://api-in.mixpanel.com",
+}
+
+//MixpanelEvent is a dto for sending events to Mixpanel
+type MixpanelEvent struct {
+	Event      string                 `json:"event"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+//MixpanelResponse is a dto for receiving /import API response from Mixpanel
+type MixpanelResponse struct {
+	Code   int    `json:"code"`
+	Error  string `json:"error"`
+	Status string `json:"status"`
+
+	//records which haven't passed validation. Other records of the request are imported
+	FailedRecords []struct {
+		Index   int    `json:"index"`
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"failed_records,omitempty"`
+}
+
+//MixpanelVerboseResponse is a dto for receiving /engage and /track APIs response with verbose=1 parameter
+//without it these APIs respond 200 with 0 body on failure
+type MixpanelVerboseResponse struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+//MixpanelRequestFactory is a factory for building Mixpanel HTTP requests from input events
+//events are sent with /import API, profile updates (objects with $distinct_id) with /engage API and aliases with /track API
+//failures of /engage and /track requests are reported in the response body (see ValidateResponse)
+type MixpanelRequestFactory struct {
+	token     string
+	apiSecret string
+	projectID string
+	host      string
+}
+
+//newMixpanelRequestFactory returns configured HTTPRequestFactory instance for mixpanel requests
+func newMixpanelRequestFactory(config *MixpanelConfig) (*MixpanelRequestFactory, error) {
+	return &MixpanelRequestFactory{token: config.Token, apiSecret: config.APISecret, projectID: config.ProjectID, host: config.host()}, nil
+}
+
+//Create returns created mixpanel request depends on object type
+func (mrf *MixpanelRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	if _, ok := object[mixpanelDistinctID]; ok {
+		//only $ prefixed keys are profile update operations and parameters
+		profileUpdate := make(map[string]interface{}, len(object)+1)
+		for name, value := range object {
+			if strings.HasPrefix(name, "$") {
+				profileUpdate[name] = value
+			}
+		}
+		profileUpdate["$token"] = mrf.token
+
+		return mrf.request(mrf.host+"/engage?verbose=1", profileUpdate, nil)
+	}
+
+	event := &MixpanelEvent{}
+	if name, ok := object["event"]; ok {
+		event.Event = fmt.Sprint(name)
+	}
+	if event.Event == "" {
+		return nil, errors.New("mixpanel event must contain 'event' field")
+	}
+	properties, _ := object["properties"].(map[string]interface{})
+	event.Properties = make(map[string]interface{}, len(properties)+1)
+	for name, value := range properties {
+		event.Properties[name] = value
+	}
+	event.Properties["token"] = mrf.token
+
+	if event.Event == mixpanelCreateAliasEvent {
+		return mrf.request(mrf.host+"/track?verbose=1", event, nil)
+	}
+
+	return mrf.request(mrf.importURL(), event, map[string]string{"Authorization": mrf.authorization()})
+}
+
+//request returns request with JSON array body of one item
+func (mrf *MixpanelRequestFactory) request(reqURL string, item interface{}, headers map[string]string) (*Request, error) {
+	b, err := json.Marshal([]interface{}{item})
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling mixpanel request [%v]: %v", item, err)
+	}
+
+	requestHeaders := map[string]string{"Content-Type": "application/json", "user-agent": JitsuUserAgent}
+	for name, value := range headers {
+		requestHeaders[name] = value
+	}
+	return &Request{
+		URL:     reqURL,
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: requestHeaders,
+	}, nil
+}
+
+//importURL returns /import API URL with strict validation (invalid records are reported in the response)
+func (mrf *MixpanelRequestFactory) importURL() string {
+	query := url.Values{}
+	query.Add("strict", "1")
+	if mrf.projectID != "" {
+		query.Add("project_id", mrf.projectID)
+	}
+
+	return mrf.host + "/import?" + query.Encode()
+}
+
+//authorization returns basic auth header value with project API secret
+func (mrf *MixpanelRequestFactory) authorization() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(mrf.apiSecret+":"))
+}
+
+//BatchKey returns key of requests with the same URL and headers. Aliases aren't batched
+func (mrf *MixpanelRequestFactory) BatchKey(req *Request) string {
+	if strings.HasPrefix(req.URL, mrf.host+"/track") {
+		return ""
+	}
+
+	return requestBatchKey(req)
+}
+
+//MaxBatchSize returns Mixpanel /import and /engage APIs limit of items in one request
+func (mrf *MixpanelRequestFactory) MaxBatchSize() int {
+	return mixpanelMaxBatchSize
+}
+
+//CreateBatch returns request with items of all requests. Every request must contain exactly one item
+func (mrf *MixpanelRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	items := make([]json.RawMessage, 0, len(requests))
+	for _, req := range requests {
+		var requestItems []json.RawMessage
+		if err := json.Unmarshal(req.Body, &requestItems); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling mixpanel request: %v", err)
+		}
+		if len(requestItems) != 1 {
+			return nil, fmt.Errorf("mixpanel request must contain one item: %d", len(requestItems))
+		}
+		items = append(items, requestItems[0])
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling mixpanel batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns errors of records which haven't passed /import validation. Other records are imported
+func (mrf *MixpanelRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	if response.StatusCode != http.StatusBadRequest {
+		return nil
+	}
+
+	mixpanelResponse := &MixpanelResponse{}
+	if err := json.Unmarshal(response.Body, mixpanelResponse); err != nil || len(mixpanelResponse.FailedRecords) == 0 {
+		return nil
+	}
+
+	itemErrors := map[int]error{}
+	for _, record := range mixpanelResponse.FailedRecords {
+		if record.Index >= 0 && record.Index < batchSize {
+			itemErrors[record.Index] = fmt.Errorf("mixpanel event has invalid field [%s]: %s", record.Field, record.Message)
+		}
+	}
+
+	return &BatchResult{ItemErrors: itemErrors}
+}
+
+//ValidateResponse returns err if /engage or /track request has failed: these APIs respond 200 with status 0 on failure
+//(/import API responds with error status codes)
+func (mrf *MixpanelRequestFactory) ValidateResponse(req *Request, response *HTTPResponse) error {
+	if strings.HasPrefix(req.URL, mrf.host+"/import") {
+		return nil
+	}
+
+	verboseResponse := &MixpanelVerboseResponse{}
+	if err := json.Unmarshal(response.Body, verboseResponse); err != nil {
+		return fmt.Errorf("Error parsing mixpanel response [%s]: %v", string(response.Body), err)
+	}
+	if verboseResponse.Status != 1 {
+		return fmt.Errorf("mixpanel request has failed: %s", utils.NvlString(verboseResponse.Error, string(response.Body)))
+	}
+
+	return nil
+}
+
+func (mrf *MixpanelRequestFactory) Close() {
+}
+
+//MixpanelConfig is a dto for parsing Mixpanel configuration
+type MixpanelConfig struct {
+	Token     string `mapstructure:"token" json:"token,omitempty" yaml:"token,omitempty"`
+	APISecret string `mapstructure:"api_secret" json:"api_secret,omitempty" yaml:"api_secret,omitempty"`
+	ProjectID string `mapstructure:"project_id" json:"project_id,omitempty" yaml:"project_id,omitempty"`
+	//Region is a data residency region: us, eu or in. Default is us
+	Region string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a custom API host (e.g. proxy). Overrides region
+	Endpoint string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	//UsersEnabled enables User Profiles updates on user_identify events
+	UsersEnabled bool             `mapstructure:"users_enabled" json:"users_enabled,omitempty" yaml:"users_enabled,omitempty"`
+	Batch        *HTTPBatchConfig `mapstructure:"batch" json:"batch,omitempty" yaml:"batch,omitempty"`
+}
+
+//Validate returns err if invalid
+func (mc *MixpanelConfig) Validate() error {
+	if mc == nil {
+		return errors.New("mixpanel config is required")
+	}
+	if mc.Token == "" {
+		return errors.New("'token' is required parameter")
+	}
+	if mc.APISecret == "" {
+		return errors.New("'api_secret' is required parameter")
+	}
+	if _, ok := mixpanelRegionHosts[strings.ToLower(mc.Region)]; mc.Region != "" && !ok {
+		return fmt.Errorf("unknown mixpanel region [%s]. Supported: us, eu, in", mc.Region)
+	}
+
+	return mc.Batch.Validate()
+}
+
+//host returns API host of the configured region or custom endpoint
+func (mc *MixpanelConfig) host() string {
+	if mc.Endpoint != "" {
+		return strings.TrimSuffix(mc.Endpoint, "/")
+	}
+
+	return mixpanelRegionHosts[strings.ToLower(utils.NvlString(mc.Region, "us"))]
+}
+
+//Mixpanel is an adapter for sending HTTP requests to Mixpanel
+type Mixpanel struct {
+	AbstractHTTP
+
+	config *MixpanelConfig
+}
+
+//NewMixpanel returns configured Mixpanel adapter instance
+func NewMixpanel(config *MixpanelConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*Mixpanel, error) {
+	httpReqFactory, err := newMixpanelRequestFactory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mixpanel{config: config}
+	m.httpAdapter = httpAdapter
+	return m, nil
+}
+
+//NewTestMixpanel returns test instance of adapter
+func NewTestMixpanel(config *MixpanelConfig) *Mixpanel {
+	return &Mixpanel{config: config}
+}
+
+//TestAccess sends empty /import request to Mixpanel and checks the project secret
+func (m *Mixpanel) TestAccess() error {
+	httpReqFactory, err := newMixpanelRequestFactory(m.config)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, httpReqFactory.importURL(), bytes.NewBufferString("[]"))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Add("Content-Type", "application/json")
+	httpReq.Header.Add("Authorization", httpReqFactory.authorization())
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		responseBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("Error reading mixpanel response body: %v", err)
+		}
+
+		response := &MixpanelResponse{}
+		if err := json.Unmarshal(responseBody, response); err != nil || response.Error == "" {
+			return fmt.Errorf("error connecting to mixpanel [code=%d]: %s", resp.StatusCode, string(responseBody))
+		}
+		return fmt.Errorf("error connecting to mixpanel [code=%d]: %s", resp.StatusCode, response.Error)
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (m *Mixpanel) Type() string {
+	return "Mixpanel"
+}
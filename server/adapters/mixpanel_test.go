@@ -0,0 +1,184 @@
+package adapters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixpanelRequests(t *testing.T) {
+	factory, err := newMixpanelRequestFactory(&MixpanelConfig{Token: "token", APISecret: "secret", Region: "eu"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		object        map[string]interface{}
+		expectedURL   string
+		expectedBody  string
+		expectedError string
+	}{
+		{
+			"event",
+			map[string]interface{}{"event": "pageview", "properties": map[string]interface{}{"distinct_id": "u1"}},
+			"https://api-eu.mixpanel.com/import?strict=1",
+			`[{"event":"pageview","properties":{"distinct_id":"u1","token":"token"}}]`,
+			"",
+		},
+		{
+			"profile update",
+			map[string]interface{}{"$distinct_id": "u1", "$set": map[string]interface{}{"plan": "pro"}, "eventn_ctx_event_id": "1"},
+			"https://api-eu.mixpanel.com/engage?verbose=1",
+			`[{"$distinct_id":"u1","$set":{"plan":"pro"},"$token":"token"}]`,
+			"",
+		},
+		{
+			"alias",
+			map[string]interface{}{"event": "$create_alias", "properties": map[string]interface{}{"distinct_id": "a1", "alias": "u1"}},
+			"https://api-eu.mixpanel.com/track?verbose=1",
+			`[{"event":"$create_alias","properties":{"distinct_id":"a1","alias":"u1","token":"token"}}]`,
+			"",
+		},
+		{
+			"without event",
+			map[string]interface{}{"properties": map[string]interface{}{"distinct_id": "u1"}},
+			"",
+			"",
+			"mixpanel event must contain 'event' field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := factory.Create(tt.object)
+			if tt.expectedError != "" {
+				require.EqualError(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, http.MethodPost, req.Method)
+			require.Equal(t, tt.expectedURL, req.URL)
+			require.JSONEq(t, tt.expectedBody, string(req.Body))
+		})
+	}
+}
+
+func TestMixpanelValidateResponse(t *testing.T) {
+	factory, err := newMixpanelRequestFactory(&MixpanelConfig{Token: "token", APISecret: "secret"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		url           string
+		body          string
+		expectedError string
+	}{
+		{"import", "https://api.mixpanel.com/import?strict=1", `{"code":200,"num_records_imported":1,"status":"OK"}`, ""},
+		{"profile update", "https://api.mixpanel.com/engage?verbose=1", `{"status":1,"error":null}`, ""},
+		{"failed profile update", "https://api.mixpanel.com/engage?verbose=1", `{"status":0,"error":"$distinct_id is required"}`, "mixpanel request has failed: $distinct_id is required"},
+		{"failed alias", "https://api.mixpanel.com/track?verbose=1", `{"status":0,"error":"token, missing or empty"}`, "mixpanel request has failed: token, missing or empty"},
+		{"malformed", "https://api.mixpanel.com/track?verbose=1", `not json`, "Error parsing mixpanel response [not json]: invalid character 'o' in literal null (expecting 'u')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := factory.ValidateResponse(&Request{URL: tt.url}, &HTTPResponse{StatusCode: http.StatusOK, Body: []byte(tt.body)})
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestMixpanelFailedProfileUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/engage", r.URL.Path)
+		require.Equal(t, "1", r.URL.Query().Get("verbose"))
+		w.Write([]byte(`{"status":0,"error":"$set must be an object"}`))
+	}))
+	defer server.Close()
+
+	mutex := &sync.Mutex{}
+	var failures []error
+	succeeded := 0
+	mixpanel, err := NewMixpanel(&MixpanelConfig{Token: "token", APISecret: "secret", Endpoint: server.URL}, &HTTPAdapterConfiguration{
+		DestinationID: "test_mixpanel",
+		HTTPConfig:    &HTTPConfiguration{GlobalClientTimeout: time.Second, RetryDelay: time.Second, RetryCount: 0},
+		QueueFactory:  events.NewQueueFactory(nil, 0),
+		PoolWorkers:   1,
+		DebugLogger:   logging.NewQueryLogger("test_mixpanel", nil, nil),
+		ErrorHandler: func(fallback bool, eventContext *EventContext, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			failures = append(failures, err)
+		},
+		SuccessHandler: func(eventContext *EventContext) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			succeeded++
+		},
+	})
+	require.NoError(t, err)
+	defer mixpanel.Close()
+
+	require.NoError(t, mixpanel.httpAdapter.SendAsync(&EventContext{EventID: "1", ProcessedEvent: events.Event{"$distinct_id": "u1", "$set": "pro"}}))
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(failures) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Zero(t, succeeded, "200 response with status 0 is a failure")
+	require.EqualError(t, failures[0], "mixpanel request has failed: $set must be an object")
+}
+
+func TestMixpanelBatch(t *testing.T) {
+	config := &MixpanelConfig{Token: "token", APISecret: "secret", ProjectID: "1", Region: "eu"}
+	require.NoError(t, config.Validate())
+	require.EqualError(t, (&MixpanelConfig{Token: "token", APISecret: "secret", Region: "asia"}).Validate(), "unknown mixpanel region [asia]. Supported: us, eu, in")
+	factory, err := newMixpanelRequestFactory(config)
+	require.NoError(t, err)
+
+	first, err := factory.Create(map[string]interface{}{"event": "pageview", "properties": map[string]interface{}{"distinct_id": "u1"}})
+	require.NoError(t, err)
+	require.Equal(t, "https://api-eu.mixpanel.com/import?project_id=1&strict=1", first.URL)
+	require.Equal(t, "Basic c2VjcmV0Og==", first.Headers["Authorization"])
+	second, err := factory.Create(map[string]interface{}{"event": "click", "properties": map[string]interface{}{"distinct_id": "u2"}})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+
+	profile, err := factory.Create(map[string]interface{}{"$distinct_id": "u1", "$set": map[string]interface{}{"plan": "pro"}, "eventn_ctx_event_id": "1_1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://api-eu.mixpanel.com/engage?verbose=1", profile.URL)
+	require.JSONEq(t, `[{"$distinct_id":"u1","$set":{"plan":"pro"},"$token":"token"}]`, string(profile.Body), "only profile operations must be sent")
+	require.NotEqual(t, factory.BatchKey(first), factory.BatchKey(profile))
+
+	alias, err := factory.Create(map[string]interface{}{"event": mixpanelCreateAliasEvent, "properties": map[string]interface{}{"distinct_id": "a1", "alias": "u1"}})
+	require.NoError(t, err)
+	require.Equal(t, "https://api-eu.mixpanel.com/track?verbose=1", alias.URL)
+	require.Empty(t, factory.BatchKey(alias), "aliases can't be batched")
+
+	_, err = factory.Create(map[string]interface{}{"properties": map[string]interface{}{}})
+	require.EqualError(t, err, "mixpanel event must contain 'event' field")
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.Equal(t, first.URL, batch.URL)
+	require.JSONEq(t, `[{"event":"pageview","properties":{"distinct_id":"u1","token":"token"}},{"event":"click","properties":{"distinct_id":"u2","token":"token"}}]`, string(batch.Body))
+
+	require.Nil(t, factory.ParseBatchResponse(2, &HTTPResponse{StatusCode: http.StatusUnauthorized, Body: []byte(`{"code":401,"error":"Invalid credentials"}`)}))
+	result := factory.ParseBatchResponse(2, &HTTPResponse{StatusCode: http.StatusBadRequest,
+		Body: []byte(`{"code":400,"error":"some data points in the request failed validation","failed_records":[{"index":1,"field":"properties.time","message":"'properties.time' is invalid"}],"num_records_imported":1,"status":"Bad Request"}`)})
+	require.NotNil(t, result)
+	require.False(t, result.RetryOthers, "valid records are imported")
+	require.Len(t, result.ItemErrors, 1)
+	require.EqualError(t, result.ItemErrors[1], "mixpanel event has invalid field [properties.time]: 'properties.time' is invalid")
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoDBConfigValidate(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           *MongoDBConfig
+		expectedDatabase string
+		errMsg           string
+	}{
+		{"database from uri", &MongoDBConfig{URI: "mongodb://localhost:27017/events"}, "events", ""},
+		{"database field", &MongoDBConfig{URI: "mongodb://localhost:27017/events", Database: "analytics"}, "analytics", ""},
+		{"without uri", &MongoDBConfig{}, "", "MongoDB uri is required parameter"},
+		{"without database", &MongoDBConfig{URI: "mongodb://localhost:27017"}, "", "MongoDB database is required parameter (in database field or in uri path)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errMsg != "" {
+				require.EqualError(t, err, tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedDatabase, tt.config.Database)
+		})
+	}
+}
+
+func TestMongoDBWriteModels(t *testing.T) {
+	m := &MongoDB{pkFields: mongoDBPaths(map[string]bool{"/eventn_ctx/event_id": true, "user.id": true})}
+	require.Equal(t, []string{"eventn_ctx.event_id", "user.id"}, m.pkFields)
+
+	models := m.writeModels([]map[string]interface{}{
+		{
+			"eventn_ctx": map[string]interface{}{"event_id": "1"},
+			"user":       map[string]interface{}{"id": json.Number("15")},
+			"items":      []interface{}{map[string]interface{}{"price": json.Number("9.99")}},
+			"utc_time":   "2022-05-18T13:44:00.000Z",
+		},
+		{
+			"eventn_ctx": map[string]interface{}{"event_id": "2"},
+		},
+	})
+	require.Len(t, models, 2)
+
+	upsert, ok := models[0].(*mongo.ReplaceOneModel)
+	require.True(t, ok, "document with primary key values must be upserted")
+	require.True(t, *upsert.Upsert)
+	require.Equal(t, bson.D{{Key: "eventn_ctx.event_id", Value: "1"}, {Key: "user.id", Value: int64(15)}}, upsert.Filter)
+	require.Equal(t, map[string]interface{}{
+		"eventn_ctx": map[string]interface{}{"event_id": "1"},
+		"user":       map[string]interface{}{"id": int64(15)},
+		"items":      []interface{}{map[string]interface{}{"price": 9.99}},
+		"utc_time":   time.Date(2022, 5, 18, 13, 44, 0, 0, time.UTC),
+	}, upsert.Replacement, "nested structure must be kept with BSON friendly values")
+
+	_, ok = models[1].(*mongo.InsertOneModel)
+	require.True(t, ok, "document without primary key values and unique ID field must be inserted")
+
+	m = &MongoDB{pkFields: mongoDBPaths(nil)}
+	_, ok = m.writeModels([]map[string]interface{}{{"eventn_ctx": map[string]interface{}{"event_id": "1"}}})[0].(*mongo.InsertOneModel)
+	require.True(t, ok, "documents must be inserted without primary key fields and unique ID field")
+
+	m = &MongoDB{pkFields: mongoDBPaths(map[string]bool{"user.id": true}), uniqueIDField: identifiers.NewUniqueID("/eventn_ctx/event_id")}
+	models = m.writeModels([]map[string]interface{}{
+		{"eventn_ctx": map[string]interface{}{"event_id": "1"}, "user": map[string]interface{}{"id": "u1"}},
+		{"eventn_ctx": map[string]interface{}{"event_id": "2"}},
+		{"eventn_ctx_event_id": "3"},
+		{"event_type": "no id"},
+	})
+	require.Len(t, models, 4)
+
+	upsert = models[0].(*mongo.ReplaceOneModel)
+	require.Equal(t, bson.D{{Key: "user.id", Value: "u1"}}, upsert.Filter, "primary key fields have priority over unique ID")
+
+	upsert, ok = models[1].(*mongo.ReplaceOneModel)
+	require.True(t, ok, "document without primary key values must be upserted by unique ID so retries don't create duplicates")
+	require.True(t, *upsert.Upsert)
+	require.Equal(t, bson.D{{Key: "_id", Value: "2"}}, upsert.Filter)
+	require.Equal(t, map[string]interface{}{"_id": "2", "eventn_ctx": map[string]interface{}{"event_id": "2"}}, upsert.Replacement)
+
+	upsert = models[2].(*mongo.ReplaceOneModel)
+	require.Equal(t, bson.D{{Key: "_id", Value: "3"}}, upsert.Filter, "flat unique ID field must be used too")
+
+	_, ok = models[3].(*mongo.InsertOneModel)
+	require.True(t, ok, "document without unique ID must be inserted")
+}
@@ -36,6 +36,8 @@ const (
 	mySQLMergeTemplate               = "INSERT INTO `%s`.`%s` (%s) VALUES %s ON DUPLICATE KEY UPDATE %s"
 	mySQLBulkMergeTemplate           = "INSERT INTO `%s`.`%s` (%s) SELECT * FROM (SELECT %s FROM `%s`.`%s`) AS tmp ON DUPLICATE KEY UPDATE %s"
 	mySQLDeleteQueryTemplate         = "DELETE FROM `%s`.`%s` WHERE %s"
+	mySQLCountExpiredTemplate        = "SELECT COUNT(*) FROM `%s`.`%s` WHERE `%s` < ?"
+	mySQLDeleteExpiredTemplate       = "DELETE FROM `%s`.`%s` WHERE `%s` < ?"
 	mySQLAddColumnTemplate           = "ALTER TABLE `%s`.`%s` ADD COLUMN %s"
 	mySQLRenameTableTemplate         = "RENAME TABLE `%s`.`%s` TO `%s`.`%s`"
 
@@ -53,6 +55,8 @@ var (
 		typing.FLOAT64:   "DOUBLE",
 		typing.TIMESTAMP: "DATETIME", // TIMESTAMP type only supports values from 1970 to 2038, DATETIME doesn't have such constrains
 		typing.BOOL:      "BOOLEAN",
+		typing.JSON:      "JSON",
+		typing.ARRAY:     "JSON",
 		typing.UNKNOWN:   "TEXT",
 	}
 
@@ -67,6 +71,7 @@ type MySQL struct {
 	ctx         context.Context
 	config      *DataSourceConfig
 	dataSource  *sql.DB
+	sshTunnel   *SSHTunnel
 	queryLogger *logging.QueryLogger
 
 	sqlTypes typing.SQLTypes
@@ -78,14 +83,21 @@ func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *loggin
 		// similar to postgres default value of sslmode option
 		config.Parameters["tls"] = "preferred"
 	}
-	connectionString := mySQLDriverConnectionString(config)
+	host, port, sshTunnel, err := config.address()
+	if err != nil {
+		return nil, err
+	}
+
+	connectionString := mySQLDriverConnectionString(config, host, port)
 	dataSource, err := sql.Open("mysql", connectionString)
 	if err != nil {
+		sshTunnel.Close()
 		return nil, err
 	}
 
 	if err := dataSource.Ping(); err != nil {
 		dataSource.Close()
+		sshTunnel.Close()
 		return nil, err
 	}
 
@@ -93,7 +105,7 @@ func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *loggin
 	dataSource.SetConnMaxLifetime(3 * time.Minute)
 	dataSource.SetMaxIdleConns(10)
 
-	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToMySQL)}, nil
+	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, sshTunnel: sshTunnel, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToMySQL)}, nil
 }
 
 //Type returns MySQL type
@@ -303,7 +315,7 @@ func (m *MySQL) Update(table *Table, object map[string]interface{}, whereKey str
 	i := 0
 	for name, value := range object {
 		columns[i] = m.quote(name) + "= ?"
-		values[i] = value
+		values[i] = m.mapColumnValue(value)
 		i++
 	}
 	values[i] = whereValue
@@ -420,9 +432,51 @@ func (m *MySQL) Truncate(tableName string) error {
 	return nil
 }
 
+//Query executes read query and returns result rows
+func (m *MySQL) Query(query string) ([]map[string]interface{}, error) {
+	sqlParams := SqlParams{
+		dataSource:  m.dataSource,
+		queryLogger: m.queryLogger,
+		ctx:         m.ctx,
+	}
+	rows, err := sqlParams.commonQuery(query)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(err, "failed to execute query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Database:  m.config.Db,
+				Statement: query,
+			})
+	}
+
+	return rows, nil
+}
+
+//DeleteExpired deletes rows which are older than expiredBefore
+func (m *MySQL) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if err := requireRetentionTable(rule); err != nil {
+		return nil, err
+	}
+
+	sqlParams := &SqlParams{ctx: m.ctx, dataSource: m.dataSource, queryLogger: m.queryLogger}
+	rows, err := deleteExpiredRows(sqlParams,
+		fmt.Sprintf(mySQLCountExpiredTemplate, m.config.Db, rule.Table, rule.Field),
+		fmt.Sprintf(mySQLDeleteExpiredTemplate, m.config.Db, rule.Table, rule.Field),
+		expiredBefore.UTC(), dryRun, &ErrorPayload{Database: m.config.Db, Table: rule.Table})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore, Rows: rows}, nil
+}
+
 //Close underlying sql.DB
 func (m *MySQL) Close() error {
-	return m.dataSource.Close()
+	err := m.dataSource.Close()
+	if tunnelErr := m.sshTunnel.Close(); tunnelErr != nil && err == nil {
+		err = tunnelErr
+	}
+
+	return err
 }
 
 func (m *MySQL) getTable(tableName string) (*Table, error) {
@@ -520,10 +574,10 @@ func (m *MySQL) getPrimaryKeys(tableName string) (map[string]bool, error) {
 	return primaryKeys, nil
 }
 
-func mySQLDriverConnectionString(config *DataSourceConfig) string {
+func mySQLDriverConnectionString(config *DataSourceConfig, host string, port int) string {
 	// [user[:password]@][net[(addr)]]/dbname[?param1=value1&paramN=valueN]
 	connectionString := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		config.Username, config.Password, config.Host, config.Port, config.Db)
+		config.Username, config.Password, host, port, config.Db)
 	if len(config.Parameters) > 0 {
 		connectionString += "?"
 		paramList := make([]string, 10)
@@ -844,7 +898,7 @@ func (m *MySQL) buildInsertPayload(valuesMap map[string]interface{}) ([]string,
 		header[i] = name
 
 		placeholders[i] = "?"
-		values[i] = value
+		values[i] = m.mapColumnValue(value)
 		i++
 	}
 
@@ -858,7 +912,7 @@ func (m *MySQL) mapColumnValue(columnVal interface{}) interface{} {
 			return time.Date(1, 1, 1, 0, 0, 0, 1, time.UTC)
 		}
 	}
-	return columnVal
+	return jsonValue(columnVal)
 }
 
 func (m *MySQL) destinationId() interface{} {
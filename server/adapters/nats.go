@@ -0,0 +1,174 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const natsTimeout = 10 * time.Second
+
+//NATSConfig is a dto for parsing NATS destination configuration
+type NATSConfig struct {
+	//Servers are NATS servers URLs (e.g. nats://localhost:4222)
+	Servers  []string `mapstructure:"servers,omitempty" json:"servers,omitempty" yaml:"servers,omitempty"`
+	Username string   `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
+	Password string   `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string   `mapstructure:"token,omitempty" json:"token,omitempty" yaml:"token,omitempty"`
+	//CredentialsFile is a path to NATS user credentials (.creds) file
+	CredentialsFile string `mapstructure:"credentials_file,omitempty" json:"credentials_file,omitempty" yaml:"credentials_file,omitempty"`
+	//JetStream enables publishing with JetStream acknowledgements and deduplication by event ID
+	JetStream bool `mapstructure:"jetstream,omitempty" json:"jetstream,omitempty" yaml:"jetstream,omitempty"`
+	//Subject is a subject template
+	Subject       string `mapstructure:"subject,omitempty" json:"subject,omitempty" yaml:"subject,omitempty"`
+	MessageConfig `mapstructure:",squash" yaml:"-,inline"`
+}
+
+//Validate returns err if invalid
+func (nc *NATSConfig) Validate() error {
+	if nc == nil {
+		return errors.New("NATS config is required")
+	}
+	if len(nc.Servers) == 0 {
+		return errors.New("NATS servers is required parameter")
+	}
+	if nc.Subject == "" {
+		return errors.New("NATS subject is required parameter")
+	}
+	if nc.Key != "" {
+		return errors.New("NATS doesn't support ordering keys: messages of one subject are ordered")
+	}
+
+	return nc.Batch.Validate()
+}
+
+//NATS is a MessagePublisher for NATS core and JetStream
+//attributes are sent as message headers
+type NATS struct {
+	conn      *nats.Conn
+	jetStream nats.JetStreamContext
+}
+
+//NewNATS returns configured NATS publisher
+func NewNATS(destinationID string, config *NATSConfig) (*NATS, error) {
+	options := []nats.Option{nats.Name("jitsu-" + destinationID), nats.Timeout(natsTimeout), nats.MaxReconnects(-1)}
+	if config.Username != "" {
+		options = append(options, nats.UserInfo(config.Username, config.Password))
+	}
+	if config.Token != "" {
+		options = append(options, nats.Token(config.Token))
+	}
+	if config.CredentialsFile != "" {
+		options = append(options, nats.UserCredentials(config.CredentialsFile))
+	}
+
+	conn, err := nats.Connect(strings.Join(config.Servers, ","), options...)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to NATS: %v", err)
+	}
+
+	n := &NATS{conn: conn}
+	if config.JetStream {
+		if n.jetStream, err = conn.JetStream(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error creating JetStream context: %v", err)
+		}
+	}
+
+	return n, nil
+}
+
+//Publish publishes messages. In JetStream mode waits for acknowledgements of all messages
+//messages exceeding the server max payload are returned as MessageRejectedError
+func (n *NATS) Publish(messages []*Message) ([]error, error) {
+	if n.jetStream != nil {
+		return n.publishJetStream(messages)
+	}
+
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		if err := n.conn.PublishMsg(n.natsMessage(message)); err != nil {
+			errs[i] = natsError(err)
+		}
+	}
+	if err := n.conn.FlushTimeout(natsTimeout); err != nil {
+		return nil, err
+	}
+
+	return errs, nil
+}
+
+func (n *NATS) publishJetStream(messages []*Message) ([]error, error) {
+	errs := make([]error, len(messages))
+	futures := make([]nats.PubAckFuture, len(messages))
+	for i, message := range messages {
+		var options []nats.PubOpt
+		if message.ID != "" {
+			options = append(options, nats.MsgId(message.ID))
+		}
+		future, err := n.jetStream.PublishMsgAsync(n.natsMessage(message), options...)
+		if err != nil {
+			errs[i] = natsError(err)
+			continue
+		}
+		futures[i] = future
+	}
+
+	timeout := time.After(natsTimeout)
+	for i, future := range futures {
+		if future == nil {
+			continue
+		}
+		select {
+		case <-future.Ok():
+		case err := <-future.Err():
+			errs[i] = natsError(err)
+		case <-timeout:
+			errs[i] = errors.New("JetStream acknowledgement timeout")
+		}
+	}
+
+	return errs, nil
+}
+
+//MaxBatchSize returns 0: NATS messages are published one by one in one connection
+func (n *NATS) MaxBatchSize() int {
+	return 0
+}
+
+//TestAccess returns err if JetStream mode is enabled but JetStream isn't available for the account
+func (n *NATS) TestAccess() error {
+	if n.jetStream == nil {
+		return nil
+	}
+
+	_, err := n.jetStream.AccountInfo()
+	return err
+}
+
+//Close drains and closes the connection
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
+
+func (n *NATS) natsMessage(message *Message) *nats.Msg {
+	natsMessage := nats.NewMsg(message.Topic)
+	natsMessage.Data = message.Body
+	for name, value := range message.Attributes {
+		natsMessage.Header.Set(name, value)
+	}
+
+	return natsMessage
+}
+
+//natsError returns MessageRejectedError if the message can't be published because of the message itself
+func natsError(err error) error {
+	if errors.Is(err, nats.ErrMaxPayload) || errors.Is(err, nats.ErrBadSubject) {
+		return &MessageRejectedError{Err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,123 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//partitionsLookahead is a time range of future partitions which are created in advance
+const partitionsLookahead = 48 * time.Hour
+
+//Partitioning is a declarative time-based tables partitioning configuration
+type Partitioning struct {
+	Field            string
+	Granularity      schema.Granularity
+	ClusteringFields []string
+	//Retention is a max age of partitions. Older partitions are dropped. 0 means that partitions aren't dropped
+	Retention time.Duration
+}
+
+//NewPartitioning returns validated Partitioning with default values
+func NewPartitioning(field, granularity string, clusteringFields []string, retentionDays int) (*Partitioning, error) {
+	if field == "" {
+		field = "_timestamp"
+	}
+
+	g := schema.DAY
+	if granularity != "" {
+		g = schema.Granularity(strings.ToUpper(granularity))
+		if partitionSuffixLayout(g) == "" {
+			return nil, fmt.Errorf("unsupported partitioning granularity: %s. Supported values: HOUR, DAY, WEEK, MONTH, QUARTER, YEAR", granularity)
+		}
+	}
+
+	if retentionDays < 0 {
+		return nil, fmt.Errorf("partitioning retention_days must be positive: %d", retentionDays)
+	}
+
+	return &Partitioning{
+		Field:            field,
+		Granularity:      g,
+		ClusteringFields: clusteringFields,
+		Retention:        time.Duration(retentionDays) * 24 * time.Hour,
+	}, nil
+}
+
+//DatePartition returns table partition
+func (p *Partitioning) DatePartition() schema.DatePartition {
+	if p == nil {
+		return schema.DatePartition{}
+	}
+
+	return schema.DatePartition{Field: p.Field, Granularity: p.Granularity}
+}
+
+//isTimePartitioned returns true if table partition is configured and partition column exists
+func isTimePartitioned(table *Table) bool {
+	if table.Partition.Field == "" || partitionSuffixLayout(table.Partition.Granularity) == "" {
+		return false
+	}
+
+	_, ok := table.Columns[table.Partition.Field]
+	return ok
+}
+
+//partitionSuffixLayout returns time layout of partition name suffix (the same as BigQuery partition ids)
+//or empty string if granularity isn't supported
+func partitionSuffixLayout(g schema.Granularity) string {
+	switch g {
+	case schema.HOUR:
+		return "2006010215"
+	case schema.DAY, schema.WEEK:
+		return "20060102"
+	case schema.MONTH, schema.QUARTER:
+		return "200601"
+	case schema.YEAR:
+		return "2006"
+	default:
+		return ""
+	}
+}
+
+//partitionName returns name of the table partition which contains t
+func partitionName(tableName string, g schema.Granularity, t time.Time) string {
+	return tableName + "_p" + g.Lower(t.UTC()).Format(partitionSuffixLayout(g))
+}
+
+//partitionLowerBound parses partition name and returns lower bound of the partition
+//returns false if partition name wasn't created by partitionName
+func partitionLowerBound(tableName, partition string, g schema.Granularity) (time.Time, bool) {
+	prefix := tableName + "_p"
+	if !strings.HasPrefix(partition, prefix) {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(partitionSuffixLayout(g), strings.TrimPrefix(partition, prefix))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
+
+//partitionUpperBound returns exclusive upper bound of the partition (lower bound of the next one)
+func partitionUpperBound(g schema.Granularity, lowerBound time.Time) time.Time {
+	return g.Upper(lowerBound).Add(time.Nanosecond)
+}
+
+//partitionsLowerBounds returns lower bounds of the current partition and future partitions within partitionsLookahead
+//at least 3 partitions are returned
+func partitionsLowerBounds(g schema.Granularity, now time.Time) []time.Time {
+	now = now.UTC()
+	end := now.Add(partitionsLookahead)
+
+	var bounds []time.Time
+	for t := g.Lower(now); t.Before(end) || len(bounds) < 3; t = partitionUpperBound(g, t) {
+		bounds = append(bounds, t)
+	}
+
+	return bounds
+}
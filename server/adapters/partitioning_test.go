@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPartitioning(t *testing.T) {
+	partitioning, err := NewPartitioning("", "", nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, &Partitioning{Field: "_timestamp", Granularity: schema.DAY}, partitioning)
+
+	partitioning, err = NewPartitioning("created_at", "month", []string{"event_type"}, 400)
+	require.NoError(t, err)
+	require.Equal(t, &Partitioning{Field: "created_at", Granularity: schema.MONTH, ClusteringFields: []string{"event_type"}, Retention: 400 * 24 * time.Hour}, partitioning)
+
+	_, err = NewPartitioning("", "ALL", nil, 0)
+	require.EqualError(t, err, "unsupported partitioning granularity: ALL. Supported values: HOUR, DAY, WEEK, MONTH, QUARTER, YEAR")
+
+	_, err = NewPartitioning("", "DAY", nil, -1)
+	require.EqualError(t, err, "partitioning retention_days must be positive: -1")
+}
+
+func TestPartitionNames(t *testing.T) {
+	now := time.Date(2022, 5, 18, 13, 45, 0, 0, time.UTC)
+	tests := []struct {
+		granularity   schema.Granularity
+		expectedName  string
+		expectedLower time.Time
+		expectedUpper time.Time
+	}{
+		{schema.HOUR, "events_p2022051813", time.Date(2022, 5, 18, 13, 0, 0, 0, time.UTC), time.Date(2022, 5, 18, 14, 0, 0, 0, time.UTC)},
+		{schema.DAY, "events_p20220518", time.Date(2022, 5, 18, 0, 0, 0, 0, time.UTC), time.Date(2022, 5, 19, 0, 0, 0, 0, time.UTC)},
+		{schema.WEEK, "events_p20220516", time.Date(2022, 5, 16, 0, 0, 0, 0, time.UTC), time.Date(2022, 5, 23, 0, 0, 0, 0, time.UTC)},
+		{schema.MONTH, "events_p202205", time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{schema.QUARTER, "events_p202204", time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{schema.YEAR, "events_p2022", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.granularity.String(), func(t *testing.T) {
+			name := partitionName("events", tt.granularity, now)
+			require.Equal(t, tt.expectedName, name)
+
+			lowerBound, ok := partitionLowerBound("events", name, tt.granularity)
+			require.True(t, ok)
+			require.Equal(t, tt.expectedLower, lowerBound)
+			require.Equal(t, tt.expectedUpper, partitionUpperBound(tt.granularity, lowerBound))
+		})
+	}
+
+	_, ok := partitionLowerBound("events", "events_default", schema.DAY)
+	require.False(t, ok)
+	_, ok = partitionLowerBound("events", "pageviews_p20220518", schema.DAY)
+	require.False(t, ok)
+}
+
+func TestPostgresCreatePartitionsStatements(t *testing.T) {
+	p := &Postgres{config: &DataSourceConfig{Schema: "public"}}
+	now := time.Date(2022, 5, 18, 13, 45, 0, 0, time.UTC)
+
+	require.Equal(t, []string{
+		`CREATE TABLE IF NOT EXISTS "public"."events_p202205" PARTITION OF "public"."events" FOR VALUES FROM ('2022-05-01 00:00:00') TO ('2022-06-01 00:00:00')`,
+		`CREATE TABLE IF NOT EXISTS "public"."events_p202206" PARTITION OF "public"."events" FOR VALUES FROM ('2022-06-01 00:00:00') TO ('2022-07-01 00:00:00')`,
+		`CREATE TABLE IF NOT EXISTS "public"."events_p202207" PARTITION OF "public"."events" FOR VALUES FROM ('2022-07-01 00:00:00') TO ('2022-08-01 00:00:00')`,
+		`CREATE TABLE IF NOT EXISTS "public"."events_default" PARTITION OF "public"."events" DEFAULT`,
+	}, p.createPartitionsStatements("events", schema.MONTH, now))
+
+	//partitions for the next 48 hours
+	require.Len(t, p.createPartitionsStatements("events", schema.HOUR, now), 50)
+	require.Len(t, p.createPartitionsStatements("events", schema.DAY, now), 4)
+}
+
+func TestIsRangePartitionKey(t *testing.T) {
+	require.True(t, isRangePartitionKey("RANGE (_timestamp)", "_timestamp"))
+	require.True(t, isRangePartitionKey(`RANGE ("eventTime")`, "eventTime"))
+	require.False(t, isRangePartitionKey("RANGE (created_at)", "_timestamp"))
+	require.False(t, isRangePartitionKey("LIST (_timestamp)", "_timestamp"))
+	require.False(t, isRangePartitionKey("RANGE (_timestamp, id)", "_timestamp"))
+}
@@ -0,0 +1,131 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/templates"
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+const (
+	//PersonalizationResultType is a type of synchronous results which are put into the response personalization object
+	PersonalizationResultType = "personalization"
+
+	defaultPersonalizationTimeout        = 100 * time.Millisecond
+	defaultPersonalizationMaxConcurrency = 1000
+)
+
+//PersonalizationConfig is a dto for parsing Personalization destination configuration
+type PersonalizationConfig struct {
+	//Key is a key of the result in the response personalization object. Default is destination ID
+	Key string `mapstructure:"key,omitempty" json:"key,omitempty" yaml:"key,omitempty"`
+	//Filter is a JavaScript expression for filtering events. Default is all events
+	Filter string `mapstructure:"filter,omitempty" json:"filter,omitempty" yaml:"filter,omitempty"`
+	//Template is a Go template or JavaScript expression of the result. Default is the transformed event
+	Template string `mapstructure:"template,omitempty" json:"template,omitempty" yaml:"template,omitempty"`
+	//Timeout is a max time the response waits for the result (e.g. 50ms). Default is 100ms
+	Timeout string `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	//MaxConcurrency is a max count of events which are processed at the same time. Default is 1000
+	MaxConcurrency int `mapstructure:"max_concurrency,omitempty" json:"max_concurrency,omitempty" yaml:"max_concurrency,omitempty"`
+}
+
+//Validate returns err if invalid
+func (pc *PersonalizationConfig) Validate() error {
+	if pc == nil {
+		return errors.New("personalization config is required")
+	}
+	if pc.Timeout != "" {
+		if timeout, err := time.ParseDuration(pc.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("malformed timeout [%s]: positive duration is expected (e.g. 50ms)", pc.Timeout)
+		}
+	}
+	if pc.MaxConcurrency < 0 {
+		return errors.New("max_concurrency must be positive")
+	}
+
+	return nil
+}
+
+//GetTimeout returns parsed timeout or default one
+func (pc *PersonalizationConfig) GetTimeout() time.Duration {
+	if pc.Timeout == "" {
+		return defaultPersonalizationTimeout
+	}
+
+	timeout, _ := time.ParseDuration(pc.Timeout)
+	return timeout
+}
+
+//GetMaxConcurrency returns configured max concurrency or default one
+func (pc *PersonalizationConfig) GetMaxConcurrency() int {
+	if pc.MaxConcurrency == 0 {
+		return defaultPersonalizationMaxConcurrency
+	}
+
+	return pc.MaxConcurrency
+}
+
+//Personalization returns JSON results based on incoming events. Results are put into /api/v1/event response
+//under personalization.<key> and can be used by the page (feature flags, segments, recommendations)
+type Personalization struct {
+	key      string
+	template templates.TemplateExecutor
+}
+
+//NewPersonalization returns configured Personalization adapter
+func NewPersonalization(config *PersonalizationConfig, destinationID string) (*Personalization, error) {
+	p := &Personalization{key: utils.NvlString(config.Key, destinationID)}
+	if config.Template != "" {
+		template, err := templates.SmartParse(p.key, config.Template, templates.JSONSerializeFuncs)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing personalization template [%s]: %v", config.Template, err)
+		}
+		p.template = template
+	}
+
+	return p, nil
+}
+
+//ProcessEvent returns personalization result of the event. Template string results are parsed as JSON if possible
+func (p *Personalization) ProcessEvent(event map[string]interface{}) (map[string]interface{}, error) {
+	var value interface{} = event
+	if p.template != nil {
+		result, err := p.template.ProcessEvent(event, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error in processing personalization template: %v", err)
+		}
+
+		value = result
+		if str, ok := result.(string); ok {
+			str = strings.TrimSpace(strings.ReplaceAll(str, "<no value>", ""))
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(str), &parsed); err == nil {
+				value = parsed
+			} else {
+				value = str
+			}
+		}
+	}
+
+	return map[string]interface{}{"type": PersonalizationResultType, "id": p.key, "value": value}, nil
+}
+
+func (p *Personalization) Insert(insertContext *InsertContext) error {
+	return fmt.Errorf("Insert not supported for personalization destination")
+}
+
+func (p *Personalization) Close() error {
+	if p.template != nil {
+		p.template.Close()
+	}
+	return nil
+}
+
+//Type returns adapter type
+func (p *Personalization) Type() string {
+	return "personalization"
+}
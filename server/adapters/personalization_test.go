@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonalizationConfig(t *testing.T) {
+	require.NoError(t, (&PersonalizationConfig{}).Validate())
+	require.Equal(t, defaultPersonalizationTimeout, (&PersonalizationConfig{}).GetTimeout())
+	require.Equal(t, 30*time.Millisecond, (&PersonalizationConfig{Timeout: "30ms"}).GetTimeout())
+	require.EqualError(t, (&PersonalizationConfig{Timeout: "-1s"}).Validate(), "malformed timeout [-1s]: positive duration is expected (e.g. 50ms)")
+	require.Equal(t, defaultPersonalizationMaxConcurrency, (&PersonalizationConfig{}).GetMaxConcurrency())
+	require.Equal(t, 10, (&PersonalizationConfig{MaxConcurrency: 10}).GetMaxConcurrency())
+	require.EqualError(t, (&PersonalizationConfig{MaxConcurrency: -1}).Validate(), "max_concurrency must be positive")
+}
+
+func TestPersonalizationProcessEvent(t *testing.T) {
+	event := map[string]interface{}{"user": map[string]interface{}{"id": "u1", "plan": "pro"}}
+	tests := []struct {
+		name     string
+		config   *PersonalizationConfig
+		expected map[string]interface{}
+	}{
+		{
+			"transformed event is a default result",
+			&PersonalizationConfig{},
+			map[string]interface{}{"type": "personalization", "id": "dest1", "value": event},
+		},
+		{
+			"go template with JSON",
+			&PersonalizationConfig{Key: "flags", Template: `{"new_pricing": {{if eq .user.plan "pro"}}true{{else}}false{{end}}}`},
+			map[string]interface{}{"type": "personalization", "id": "flags", "value": map[string]interface{}{"new_pricing": true}},
+		},
+		{
+			"go template with plain string",
+			&PersonalizationConfig{Key: "segment", Template: `{{.user.plan}}_users{{.missing}}`},
+			map[string]interface{}{"type": "personalization", "id": "segment", "value": "pro_users"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.config.Validate())
+			personalization, err := NewPersonalization(tt.config, "dest1")
+			require.NoError(t, err)
+			defer personalization.Close()
+
+			result, err := personalization.ProcessEvent(event)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/errorj"
+	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/utils"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"github.com/lib/pq"
 	"math"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -31,7 +34,7 @@ const (
          					LEFT JOIN pg_attrdef pg_attrdef ON pg_attrdef.adrelid = pg_class.oid AND pg_attrdef.adnum = pg_attribute.attnum
          					LEFT JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
          					LEFT JOIN pg_constraint ON pg_constraint.conrelid = pg_class.oid AND pg_attribute.attnum = ANY (pg_constraint.conkey)
-						WHERE pg_class.relkind = 'r'::char
+						WHERE pg_class.relkind IN ('r', 'p')
   							AND  pg_namespace.nspname = $1
   							AND pg_class.relname = $2
   							AND pg_attribute.attnum > 0`
@@ -64,16 +67,55 @@ WHERE tco.constraint_type = 'PRIMARY KEY' AND
 	renameColumnTemplate          = `ALTER TABLE "%s"."%s" RENAME COLUMN %s TO %s`
 	renameTableTemplate           = `ALTER TABLE "%s"."%s" RENAME TO "%s"`
 	postgresTruncateTableTemplate = `TRUNCATE "%s"."%s"`
+	copySavepointTemplate         = `SAVEPOINT %s`
+	rollbackToSavepointTemplate   = `ROLLBACK TO SAVEPOINT %s`
+	releaseSavepointTemplate      = `RELEASE SAVEPOINT %s`
+	copySavepoint                 = `jitsu_copy`
 	PostgresValuesLimit           = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
+
+	partitionByRangeTemplate       = ` PARTITION BY RANGE ("%s")`
+	createPartitionTemplate        = `CREATE TABLE IF NOT EXISTS "%s"."%s" PARTITION OF "%s"."%s" FOR VALUES FROM ('%s') TO ('%s')`
+	createDefaultPartitionTemplate = `CREATE TABLE IF NOT EXISTS "%s"."%s_default" PARTITION OF "%s"."%s" DEFAULT`
+	createIndexTemplate            = `CREATE INDEX IF NOT EXISTS "%s" ON "%s"."%s" (%s)`
+
+	partitionedTableCommentTemplate = `COMMENT ON TABLE "%s"."%s" IS '%s'`
+	//partitionedTablesQuery selects partitioned tables with the comment which is set on creation by the destination
+	partitionedTablesQuery = `SELECT c.relname, pg_get_partkeydef(c.oid) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1 AND c.relkind = 'p' AND obj_description(c.oid, 'pg_class') = $2`
+	//moveDefaultPartitionRowsTemplate moves rows of the new partition range out of the default partition
+	//Postgres doesn't allow creating a partition if the default partition contains rows of its range
+	moveDefaultPartitionRowsTemplate = `CREATE TEMPORARY TABLE "%s" ON COMMIT DROP AS
+WITH moved AS (DELETE FROM "%s"."%s_default" WHERE "%s" >= '%s' AND "%s" < '%s' RETURNING *) SELECT * FROM moved`
+	insertMovedRowsTemplate = `INSERT INTO "%s"."%s" SELECT * FROM "%s"`
+	movedRowsTable          = "jitsu_moved_rows"
+
+	tablePartitionsQuery = `SELECT c.relname FROM pg_inherits i
+         JOIN pg_class c ON c.oid = i.inhrelid
+         JOIN pg_class p ON p.oid = i.inhparent
+         JOIN pg_namespace n ON n.oid = p.relnamespace
+WHERE n.nspname = $1 AND p.relname = $2`
+	partitionBoundLayout      = "2006-01-02 15:04:05"
+	tablePartitionBoundsQuery = `SELECT c.relname, pg_get_expr(c.relpartbound, c.oid) FROM pg_inherits i
+         JOIN pg_class c ON c.oid = i.inhrelid
+         JOIN pg_class p ON p.oid = i.inhparent
+         JOIN pg_namespace n ON n.oid = p.relnamespace
+WHERE n.nspname = $1 AND p.relname = $2`
+	partitionKeyQuery     = `SELECT pg_get_partkeydef(c.oid) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind = 'p'`
+	countExpiredTemplate  = `SELECT COUNT(*) FROM "%s"."%s" WHERE "%s" < $1`
+	deleteExpiredTemplate = `DELETE FROM "%s"."%s" WHERE "%s" < $1`
 )
 
 var (
+	partitionUpperBoundRegex = regexp.MustCompile(`TO \('([^']+)'\)`)
+
 	SchemaToPostgres = map[typing.DataType]string{
 		typing.STRING:    "text",
 		typing.INT64:     "bigint",
 		typing.FLOAT64:   "double precision",
 		typing.TIMESTAMP: "timestamp",
 		typing.BOOL:      "boolean",
+		typing.JSON:      "jsonb",
+		typing.ARRAY:     "jsonb",
 		typing.UNKNOWN:   "text",
 	}
 )
@@ -166,6 +208,7 @@ type DataSourceConfig struct {
 	Parameters       map[string]string `mapstructure:"parameters,omitempty" json:"parameters,omitempty" yaml:"parameters,omitempty"`
 	SSLConfiguration *SSLConfig        `mapstructure:"ssl,omitempty" json:"ssl,omitempty" yaml:"ssl,omitempty"`
 	S3               *S3Config         `mapstructure:"s3,omitempty" json:"s3,omitempty" yaml:"s3,omitempty"`
+	SSHTunnel        *SSHTunnelConfig  `mapstructure:"ssh_tunnel,omitempty" json:"ssh_tunnel,omitempty" yaml:"ssh_tunnel,omitempty"`
 }
 
 //Validate required fields in DataSourceConfig
@@ -192,14 +235,50 @@ func (dsc *DataSourceConfig) Validate() error {
 			return err
 		}
 	}
+	if err := dsc.SSHTunnel.Validate(); err != nil {
+		return err
+	}
+	if err := dsc.SSHTunnel.ValidateTLS(dsc.hostnameVerification()); err != nil {
+		return err
+	}
 	return nil
 }
 
+//hostnameVerification returns TLS mode which verifies the server certificate hostname (Postgres sslmode=verify-full
+//or MySQL tls=true) or empty string
+func (dsc *DataSourceConfig) hostnameVerification() string {
+	if dsc.Parameters["sslmode"] == SSLModeVerifyFull.String() ||
+		(dsc.SSLConfiguration != nil && dsc.SSLConfiguration.Mode == SSLModeVerifyFull) {
+		return "sslmode=verify-full"
+	}
+	if dsc.Parameters["tls"] == "true" {
+		return "tls=true"
+	}
+
+	return ""
+}
+
+//address returns host and port for connecting to the database. If SSH tunnel is configured it is opened and
+//local tunnel address is returned. Caller is responsible for closing the tunnel
+func (dsc *DataSourceConfig) address() (string, int, *SSHTunnel, error) {
+	if dsc.SSHTunnel == nil {
+		return dsc.Host, dsc.Port, nil, nil
+	}
+
+	tunnel, err := NewSSHTunnel(dsc.SSHTunnel, dsc.Host, dsc.Port)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	return tunnel.LocalHost(), tunnel.LocalPort(), tunnel, nil
+}
+
 //Postgres is adapter for creating,patching (schema or table), inserting data to postgres
 type Postgres struct {
 	ctx         context.Context
 	config      *DataSourceConfig
 	dataSource  *sql.DB
+	sshTunnel   *SSHTunnel
 	queryLogger *logging.QueryLogger
 
 	sqlTypes typing.SQLTypes
@@ -207,50 +286,53 @@ type Postgres struct {
 
 //NewPostgresUnderRedshift returns configured Postgres adapter instance without mapping old types
 func NewPostgresUnderRedshift(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
-	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s ",
-		config.Host, config.Port, config.Db, config.Username, config.Password)
-	//concat provided connection parameters
-	for k, v := range config.Parameters {
-		connectionString += k + "=" + v + " "
-	}
-	dataSource, err := sql.Open("postgres", connectionString)
+	dataSource, sshTunnel, err := openPostgres(config)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := dataSource.Ping(); err != nil {
-		dataSource.Close()
+	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, sshTunnel: sshTunnel, queryLogger: queryLogger, sqlTypes: sqlTypes}, nil
+}
+
+//NewPostgres return configured Postgres adapter instance
+func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
+	dataSource, sshTunnel, err := openPostgres(config)
+	if err != nil {
 		return nil, err
 	}
 
-	//set default value
-	dataSource.SetConnMaxLifetime(10 * time.Minute)
-
-	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: sqlTypes}, nil
+	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, sshTunnel: sshTunnel, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToPostgres)}, nil
 }
 
-//NewPostgres return configured Postgres adapter instance
-func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, sqlTypes typing.SQLTypes) (*Postgres, error) {
+//openPostgres opens SSH tunnel (if configured) and connection pool with the provided config
+func openPostgres(config *DataSourceConfig) (*sql.DB, *SSHTunnel, error) {
+	host, port, sshTunnel, err := config.address()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s ",
-		config.Host, config.Port, config.Db, config.Username, config.Password)
+		host, port, config.Db, config.Username, config.Password)
 	//concat provided connection parameters
 	for k, v := range config.Parameters {
 		connectionString += k + "=" + v + " "
 	}
 	dataSource, err := sql.Open("postgres", connectionString)
 	if err != nil {
-		return nil, err
+		sshTunnel.Close()
+		return nil, nil, err
 	}
 
 	if err := dataSource.Ping(); err != nil {
 		dataSource.Close()
-		return nil, err
+		sshTunnel.Close()
+		return nil, nil, err
 	}
 
 	//set default value
 	dataSource.SetConnMaxLifetime(10 * time.Minute)
 
-	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, sqlTypes: reformatMappings(sqlTypes, SchemaToPostgres)}, nil
+	return dataSource, sshTunnel, nil
 }
 
 //Type returns Postgres type
@@ -392,7 +474,7 @@ func (p *Postgres) insertBatch(table *Table, objects []map[string]interface{}, d
 	}
 
 	if len(table.PKFields) == 0 {
-		return p.bulkInsertInTransaction(wrappedTx, table, objects, PostgresValuesLimit)
+		return p.bulkLoadInTransaction(wrappedTx, table, objects)
 	}
 
 	//deduplication for bulkMerge success (it fails if there is any duplicate)
@@ -457,6 +539,25 @@ func (p *Postgres) Truncate(tableName string) error {
 	return nil
 }
 
+//Query executes read query and returns result rows
+func (p *Postgres) Query(query string) ([]map[string]interface{}, error) {
+	sqlParams := SqlParams{
+		dataSource:  p.dataSource,
+		queryLogger: p.queryLogger,
+		ctx:         p.ctx,
+	}
+	rows, err := sqlParams.commonQuery(query)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(err, "failed to execute query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Statement: query,
+			})
+	}
+
+	return rows, nil
+}
+
 func (p *Postgres) getTable(tableName string) (*Table, error) {
 	table := &Table{Schema: p.config.Schema, Name: tableName, Columns: map[string]typing.SQLColumn{}, PKFields: map[string]bool{}}
 	rows, err := p.dataSource.QueryContext(p.ctx, tableSchemaQuery, p.config.Schema, tableName)
@@ -521,6 +622,10 @@ func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table
 	//sorting columns asc
 	sort.Strings(columnsDDL)
 	query := fmt.Sprintf(createTableTemplate, p.config.Schema, table.Name, strings.Join(columnsDDL, ", "))
+	partitioned := isTimePartitioned(table)
+	if partitioned {
+		query += fmt.Sprintf(partitionByRangeTemplate, table.Partition.Field)
+	}
 	p.queryLogger.LogDDL(query)
 
 	if _, err := wrappedTx.tx.ExecContext(p.ctx, query); err != nil {
@@ -539,9 +644,374 @@ func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table
 		return err
 	}
 
+	if partitioned {
+		statements := append(p.createPartitionsStatements(table.Name, table.Partition.Granularity, timestamp.Now()),
+			fmt.Sprintf(partitionedTableCommentTemplate, p.config.Schema, table.Name, p.partitionedTableComment()))
+		for _, statement := range statements {
+			p.queryLogger.LogDDL(statement)
+			if _, err := wrappedTx.tx.ExecContext(p.ctx, statement); err != nil {
+				return errorj.CreatePartitionError.Wrap(checkErr(err), "failed to create partition").
+					WithProperty(errorj.DBInfo, &ErrorPayload{
+						Schema:    p.config.Schema,
+						Table:     table.Name,
+						Statement: statement,
+					})
+			}
+		}
+	}
+
+	return p.createClusteringIndexInTransaction(wrappedTx, table)
+}
+
+//createClusteringIndexInTransaction creates index on clustering fields (if all of them exist in the table)
+func (p *Postgres) createClusteringIndexInTransaction(wrappedTx *Transaction, table *Table) error {
+	if len(table.ClusteringFields) == 0 {
+		return nil
+	}
+
+	var quotedColumnNames []string
+	for _, field := range table.ClusteringFields {
+		if _, ok := table.Columns[field]; !ok {
+			logging.Warnf("[%s] Clustering index isn't created in table %s: column %s doesn't exist", p.destinationId(), table.Name, field)
+			return nil
+		}
+		quotedColumnNames = append(quotedColumnNames, fmt.Sprintf(`"%s"`, field))
+	}
+
+	statement := fmt.Sprintf(createIndexTemplate, table.Name+"_clustering_idx", p.config.Schema, table.Name, strings.Join(quotedColumnNames, ","))
+	p.queryLogger.LogDDL(statement)
+
+	if _, err := wrappedTx.tx.ExecContext(p.ctx, statement); err != nil {
+		return errorj.CreateIndexError.Wrap(checkErr(err), "failed to create clustering index").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Table:     table.Name,
+				Statement: statement,
+			})
+	}
+
+	return nil
+}
+
+//MaintainPartitions creates future partitions and drops partitions older than partitioning.Retention
+//in partitioned tables which have been created by the destination and are partitioned by partitioning.Field
+//Rows which don't fit any partition are written into the default one
+func (p *Postgres) MaintainPartitions(partitioning *Partitioning) error {
+	tableNames, err := p.maintainedPartitionedTables(partitioning.Field)
+	if err != nil {
+		return err
+	}
+
+	now := timestamp.Now()
+	var multiErr error
+	for _, tableName := range tableNames {
+		partitions, err := p.queryNames(tablePartitionsQuery, p.config.Schema, tableName)
+		if err != nil {
+			multiErr = multierror.Append(multiErr, err)
+			continue
+		}
+
+		if err := p.createMissingPartitions(tableName, partitioning, partitions, now); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+			continue
+		}
+
+		if partitioning.Retention == 0 {
+			continue
+		}
+
+		expiredBefore := now.Add(-partitioning.Retention)
+		for _, partition := range partitions {
+			lowerBound, ok := partitionLowerBound(tableName, partition, partitioning.Granularity)
+			if !ok || partitionUpperBound(partitioning.Granularity, lowerBound).After(expiredBefore) {
+				continue
+			}
+
+			logging.Infof("[%s] Dropping expired partition %s of table %s", p.destinationId(), partition, tableName)
+			statement := fmt.Sprintf(dropTableTemplate, "IF EXISTS ", p.config.Schema, partition)
+			p.queryLogger.LogDDL(statement)
+			if _, err := p.dataSource.ExecContext(p.ctx, statement); err != nil {
+				multiErr = multierror.Append(multiErr, errorj.DropError.Wrap(checkErr(err), "failed to drop partition").
+					WithProperty(errorj.DBInfo, &ErrorPayload{
+						Schema:    p.config.Schema,
+						Table:     tableName,
+						Partition: partition,
+						Statement: statement,
+					}))
+			}
+		}
+
+		//expired rows of the default partition
+		statement := fmt.Sprintf(deleteExpiredTemplate, p.config.Schema, tableName+"_default", partitioning.Field)
+		p.queryLogger.LogQueryWithValues(statement, []interface{}{expiredBefore.UTC()})
+		if _, err := p.dataSource.ExecContext(p.ctx, statement, expiredBefore.UTC()); err != nil {
+			multiErr = multierror.Append(multiErr, errorj.DeleteFromTableError.Wrap(checkErr(err), "failed to delete expired rows").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    p.config.Schema,
+					Table:     tableName,
+					Partition: tableName + "_default",
+					Statement: statement,
+					Values:    []interface{}{expiredBefore.UTC()},
+				}))
+		}
+	}
+
+	return multiErr
+}
+
+//maintainedPartitionedTables returns names of tables which have been created by the destination (have the comment)
+//and are partitioned by RANGE (field). Other partitioned tables of the schema aren't changed
+func (p *Postgres) maintainedPartitionedTables(field string) ([]string, error) {
+	rows, err := p.dataSource.QueryContext(p.ctx, partitionedTablesQuery, p.config.Schema, p.partitionedTableComment())
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(checkErr(err), "failed to get partitioned tables").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Statement: partitionedTablesQuery,
+			})
+	}
+	defer rows.Close()
+
+	var tableNames []string
+	for rows.Next() {
+		var tableName, partitionKey string
+		if err := rows.Scan(&tableName, &partitionKey); err != nil {
+			return nil, errorj.QueryError.Wrap(err, "failed to scan result").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    p.config.Schema,
+					Statement: partitionedTablesQuery,
+				})
+		}
+		if !isRangePartitionKey(partitionKey, field) {
+			logging.Warnf("[%s] Partitions of table %s aren't maintained: partition key [%s] doesn't match RANGE (%s)", p.destinationId(), tableName, partitionKey, field)
+			continue
+		}
+		tableNames = append(tableNames, tableName)
+	}
+
+	return tableNames, rows.Err()
+}
+
+//createMissingPartitions creates the current and future partitions which don't exist yet and then the default partition
+//rows of the new partition range are moved out of the default partition in the same transaction
+func (p *Postgres) createMissingPartitions(tableName string, partitioning *Partitioning, partitions []string, now time.Time) error {
+	existing := make(map[string]bool, len(partitions))
+	for _, partition := range partitions {
+		existing[partition] = true
+	}
+	hasDefault := existing[tableName+"_default"]
+
+	for _, lowerBound := range partitionsLowerBounds(partitioning.Granularity, now) {
+		partition := partitionName(tableName, partitioning.Granularity, lowerBound)
+		if existing[partition] {
+			continue
+		}
+
+		statements := []string{p.createPartitionStatement(tableName, partitioning.Granularity, lowerBound)}
+		if hasDefault {
+			upperBound := partitionUpperBound(partitioning.Granularity, lowerBound)
+			statements = []string{
+				fmt.Sprintf(moveDefaultPartitionRowsTemplate, movedRowsTable, p.config.Schema, tableName,
+					partitioning.Field, lowerBound.Format(partitionBoundLayout), partitioning.Field, upperBound.Format(partitionBoundLayout)),
+				statements[0],
+				fmt.Sprintf(insertMovedRowsTemplate, p.config.Schema, tableName, movedRowsTable),
+			}
+		}
+		if err := p.execPartitionStatementsInTransaction(tableName, statements); err != nil {
+			return err
+		}
+	}
+
+	if !hasDefault {
+		return p.execPartitionStatementsInTransaction(tableName, []string{fmt.Sprintf(createDefaultPartitionTemplate, p.config.Schema, tableName, p.config.Schema, tableName)})
+	}
+
 	return nil
 }
 
+//execPartitionStatementsInTransaction executes statements in one transaction
+func (p *Postgres) execPartitionStatementsInTransaction(tableName string, statements []string) error {
+	wrappedTx, err := p.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		p.queryLogger.LogDDL(statement)
+		if _, err := wrappedTx.tx.ExecContext(p.ctx, statement); err != nil {
+			wrappedTx.Rollback()
+			return errorj.CreatePartitionError.Wrap(checkErr(err), "failed to create partition").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    p.config.Schema,
+					Table:     tableName,
+					Statement: statement,
+				})
+		}
+	}
+
+	return wrappedTx.Commit()
+}
+
+//partitionedTableComment returns the comment of partitioned tables which are created by the destination
+func (p *Postgres) partitionedTableComment() string {
+	return strings.ReplaceAll(fmt.Sprintf("jitsu:%v", p.destinationId()), "'", "''")
+}
+
+//isRangePartitionKey returns true if pg_get_partkeydef result is RANGE by the field
+func isRangePartitionKey(partitionKey, field string) bool {
+	return strings.ReplaceAll(partitionKey, `"`, "") == fmt.Sprintf("RANGE (%s)", field)
+}
+
+//DeleteExpired drops partitions which end before expiredBefore (if the table is partitioned by rule.Field)
+//and deletes the rest of expired rows
+func (p *Postgres) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if err := requireRetentionTable(rule); err != nil {
+		return nil, err
+	}
+
+	partitions, err := p.expiredPartitions(rule.Table, rule.Field, expiredBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore, Partitions: partitions}
+	if !dryRun {
+		for _, partition := range partitions {
+			statement := fmt.Sprintf(dropTableTemplate, "IF EXISTS ", p.config.Schema, partition)
+			p.queryLogger.LogDDL(statement)
+			if _, err := p.dataSource.ExecContext(p.ctx, statement); err != nil {
+				return nil, errorj.DropError.Wrap(checkErr(err), "failed to drop partition").
+					WithProperty(errorj.DBInfo, &ErrorPayload{
+						Schema:    p.config.Schema,
+						Table:     rule.Table,
+						Partition: partition,
+						Statement: statement,
+					})
+			}
+		}
+	}
+
+	sqlParams := &SqlParams{ctx: p.ctx, dataSource: p.dataSource, queryLogger: p.queryLogger}
+	report.Rows, err = deleteExpiredRows(sqlParams,
+		fmt.Sprintf(countExpiredTemplate, p.config.Schema, rule.Table, rule.Field),
+		fmt.Sprintf(deleteExpiredTemplate, p.config.Schema, rule.Table, rule.Field),
+		expiredBefore.UTC(), dryRun, &ErrorPayload{Schema: p.config.Schema, Table: rule.Table})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+//expiredPartitions returns names of partitions which end before expiredBefore
+//returns empty list if the table isn't partitioned by the field
+func (p *Postgres) expiredPartitions(tableName, field string, expiredBefore time.Time) ([]string, error) {
+	partitionKeys, err := p.queryNames(partitionKeyQuery, p.config.Schema, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(partitionKeys) == 0 || !isRangePartitionKey(partitionKeys[0], field) {
+		return nil, nil
+	}
+
+	rows, err := p.dataSource.QueryContext(p.ctx, tablePartitionBoundsQuery, p.config.Schema, tableName)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(checkErr(err), "failed to get table partitions").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Table:     tableName,
+				Statement: tablePartitionBoundsQuery,
+				Values:    []interface{}{p.config.Schema, tableName},
+			})
+	}
+	defer rows.Close()
+
+	var partitions []string
+	for rows.Next() {
+		var partition, bound string
+		if err := rows.Scan(&partition, &bound); err != nil {
+			return nil, errorj.QueryError.Wrap(err, "failed to scan result").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    p.config.Schema,
+					Table:     tableName,
+					Statement: tablePartitionBoundsQuery,
+					Values:    []interface{}{p.config.Schema, tableName},
+				})
+		}
+
+		upperBound, ok := parsePartitionUpperBound(bound)
+		if ok && !upperBound.After(expiredBefore.UTC()) {
+			partitions = append(partitions, partition)
+		}
+	}
+
+	return partitions, rows.Err()
+}
+
+//parsePartitionUpperBound parses upper bound of the range partition bound expression:
+//FOR VALUES FROM ('2022-05-18 00:00:00') TO ('2022-05-19 00:00:00')
+//returns false for the default partition and partitions which aren't bounded by timestamps
+func parsePartitionUpperBound(bound string) (time.Time, bool) {
+	match := partitionUpperBoundRegex.FindStringSubmatch(bound)
+	if len(match) != 2 {
+		return time.Time{}, false
+	}
+
+	upperBound, err := time.Parse(partitionBoundLayout, match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return upperBound, true
+}
+
+//createPartitionsStatements returns statements for creating the current partition, future partitions and the default partition
+//the default partition is created last: a range partition can't be created if the default one contains rows of its range
+func (p *Postgres) createPartitionsStatements(tableName string, granularity schema.Granularity, now time.Time) []string {
+	var statements []string
+	for _, lowerBound := range partitionsLowerBounds(granularity, now) {
+		statements = append(statements, p.createPartitionStatement(tableName, granularity, lowerBound))
+	}
+
+	return append(statements, fmt.Sprintf(createDefaultPartitionTemplate, p.config.Schema, tableName, p.config.Schema, tableName))
+}
+
+//createPartitionStatement returns statement for creating the partition which starts at lowerBound
+func (p *Postgres) createPartitionStatement(tableName string, granularity schema.Granularity, lowerBound time.Time) string {
+	return fmt.Sprintf(createPartitionTemplate, p.config.Schema, partitionName(tableName, granularity, lowerBound),
+		p.config.Schema, tableName, lowerBound.Format(partitionBoundLayout), partitionUpperBound(granularity, lowerBound).Format(partitionBoundLayout))
+}
+
+//queryNames returns values of the first column of the query result
+func (p *Postgres) queryNames(query string, values ...interface{}) ([]string, error) {
+	rows, err := p.dataSource.QueryContext(p.ctx, query, values...)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(checkErr(err), "failed to execute query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Statement: query,
+				Values:    values,
+			})
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errorj.QueryError.Wrap(err, "failed to scan result").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Schema:    p.config.Schema,
+					Statement: query,
+					Values:    values,
+				})
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
 //alter table with columns (if not empty)
 //recreate primary key (if not empty) or delete primary key if Table.DeletePkFields is true
 func (p *Postgres) patchTableSchemaInTransaction(wrappedTx *Transaction, patchTable *Table) error {
@@ -685,7 +1155,7 @@ func (p *Postgres) Update(table *Table, object map[string]interface{}, whereKey
 	i := 0
 	for name, value := range object {
 		columns[i] = name + "= $" + strconv.Itoa(i+1) //$0 - wrong
-		values[i] = value
+		values[i] = jsonValue(value)
 		i++
 	}
 	values[i] = whereValue
@@ -709,6 +1179,97 @@ func (p *Postgres) Update(table *Table, object map[string]interface{}, whereKey
 	return nil
 }
 
+//bulkLoadInTransaction loads objects with COPY FROM STDIN. If COPY fails because of invalid data (e.g. a value
+//can't be parsed as a column type) the COPY is rolled back to the savepoint and objects are inserted with
+//bulkInsertInTransaction so the error contains failed values and the batch can be sent to fallback
+func (p *Postgres) bulkLoadInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	if err := p.execInTransaction(wrappedTx, fmt.Sprintf(copySavepointTemplate, copySavepoint)); err != nil {
+		return err
+	}
+
+	copyErr := p.copyInTransaction(wrappedTx, table, objects)
+	if copyErr == nil {
+		return p.execInTransaction(wrappedTx, fmt.Sprintf(releaseSavepointTemplate, copySavepoint))
+	}
+
+	if !isDataException(copyErr) {
+		return copyErr
+	}
+
+	logging.Warnf("[%s] COPY into table %s failed: %v. Objects will be inserted with INSERT statements", p.destinationId(), table.Name, checkErr(copyErr))
+	if err := p.execInTransaction(wrappedTx, fmt.Sprintf(rollbackToSavepointTemplate, copySavepoint)); err != nil {
+		return err
+	}
+
+	return p.bulkInsertInTransaction(wrappedTx, table, objects, PostgresValuesLimit)
+}
+
+//copyInTransaction writes all objects into the table with a single COPY FROM STDIN statement
+//returns not wrapped pq error if COPY has been failed on the server side
+func (p *Postgres) copyInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	header := table.SortedColumnNames()
+	statement := pq.CopyInSchema(p.config.Schema, table.Name, header...)
+	p.queryLogger.LogQuery(fmt.Sprintf("%s [%d rows]", statement, len(objects)))
+
+	stmt, err := wrappedTx.tx.PrepareContext(p.ctx, statement)
+	if err != nil {
+		return p.copyError(err, table, statement)
+	}
+
+	for _, row := range objects {
+		values := make([]interface{}, len(header))
+		for i, column := range header {
+			values[i] = jsonValue(removeZeroBytes(table.Columns[column], row[column]))
+		}
+
+		if _, err := stmt.ExecContext(p.ctx, values...); err != nil {
+			stmt.Close()
+			return p.copyError(err, table, statement)
+		}
+	}
+
+	//flush buffered rows
+	if _, err := stmt.ExecContext(p.ctx); err != nil {
+		stmt.Close()
+		return p.copyError(err, table, statement)
+	}
+
+	if err := stmt.Close(); err != nil {
+		return p.copyError(err, table, statement)
+	}
+
+	return nil
+}
+
+//copyError returns pq data exceptions as is (they are handled in bulkLoadInTransaction) and wrapped errorj.CopyError otherwise
+func (p *Postgres) copyError(err error, table *Table, statement string) error {
+	if isDataException(err) {
+		return err
+	}
+
+	return errorj.CopyError.Wrap(checkErr(err), "failed to copy data").
+		WithProperty(errorj.DBInfo, &ErrorPayload{
+			Schema:      p.config.Schema,
+			Table:       table.Name,
+			PrimaryKeys: table.GetPKFields(),
+			Statement:   statement,
+		})
+}
+
+//execInTransaction executes statement without values (e.g. savepoints) in transaction
+func (p *Postgres) execInTransaction(wrappedTx *Transaction, statement string) error {
+	p.queryLogger.LogQuery(statement)
+	if _, err := wrappedTx.tx.ExecContext(p.ctx, statement); err != nil {
+		return errorj.ExecuteInsertInBatchError.Wrap(checkErr(err), "failed to execute statement").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    p.config.Schema,
+				Statement: statement,
+			})
+	}
+
+	return nil
+}
+
 //bulkInsertInTransaction should be used when table has no primary keys. Inserts data in batches to improve performance.
 func (p *Postgres) bulkInsertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}, valuesLimit int) error {
 	var placeholdersBuilder strings.Builder
@@ -741,16 +1302,7 @@ func (p *Postgres) bulkInsertInTransaction(wrappedTx *Transaction, table *Table,
 		_, _ = placeholdersBuilder.WriteString("(")
 
 		for i, column := range headerWithoutQuotes {
-			value, _ := row[column]
-			//replace zero byte character for text fields
-			if table.Columns[column].Type == "text" {
-				if v, ok := value.(string); ok {
-					if strings.ContainsRune(v, '\u0000') {
-						value = strings.ReplaceAll(v, "\u0000", "")
-					}
-				}
-			}
-			valueArgs = append(valueArgs, value)
+			valueArgs = append(valueArgs, jsonValue(removeZeroBytes(table.Columns[column], row[column])))
 			castClause := p.getCastClause(column, table.Columns[column])
 
 			_, _ = placeholdersBuilder.WriteString("$" + strconv.Itoa(placeholdersCounter) + castClause)
@@ -787,7 +1339,7 @@ func (p *Postgres) bulkMergeInTransaction(wrappedTx *Transaction, table *Table,
 		return errorj.Decorate(err, "failed to create temporary table")
 	}
 
-	if err := p.bulkInsertInTransaction(wrappedTx, tmpTable, objects, PostgresValuesLimit); err != nil {
+	if err := p.bulkLoadInTransaction(wrappedTx, tmpTable, objects); err != nil {
 		return errorj.Decorate(err, "failed to insert into temporary table")
 	}
 
@@ -977,7 +1529,12 @@ func (p *Postgres) getDefaultValueStatement(sqlType string) string {
 
 //Close underlying sql.DB
 func (p *Postgres) Close() error {
-	return p.dataSource.Close()
+	err := p.dataSource.Close()
+	if tunnelErr := p.sshTunnel.Close(); tunnelErr != nil && err == nil {
+		err = tunnelErr
+	}
+
+	return err
 }
 
 //getPrimaryKey returns primary key name and fields
@@ -1056,7 +1613,7 @@ func (p *Postgres) buildInsertPayload(table *Table, valuesMap map[string]interfa
 
 		//$1::type, $2::type, $3, etc ($0 - wrong)
 		placeholders[i] = fmt.Sprintf("$%d%s", i+1, p.getCastClause(name, table.Columns[name]))
-		values[i] = value
+		values[i] = jsonValue(value)
 	}
 
 	return header, quotedHeader, placeholders, values
@@ -1163,6 +1720,25 @@ func getDeduplicatedAndOthers(pkFields []string, objects []map[string]interface{
 	return deduplicatedObjects, duplicatedObjects
 }
 
+//removeZeroBytes replaces zero byte character for text fields (Postgres doesn't accept it in text values)
+func removeZeroBytes(column typing.SQLColumn, value interface{}) interface{} {
+	if column.Type != "text" {
+		return value
+	}
+
+	if v, ok := value.(string); ok && strings.ContainsRune(v, '\u0000') {
+		return strings.ReplaceAll(v, "\u0000", "")
+	}
+
+	return value
+}
+
+//isDataException returns true if err is pq data exception (SQLSTATE class 22) e.g. invalid input syntax for type
+func isDataException(err error) bool {
+	pgErr, ok := err.(*pq.Error)
+	return ok && pgErr.Code.Class() == "22"
+}
+
 //checkErr checks and extracts parsed pg.Error and extract code,message,details
 func checkErr(err error) error {
 	if err == nil {
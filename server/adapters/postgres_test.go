@@ -2,10 +2,12 @@ package adapters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/test"
 	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/lib/pq"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
@@ -73,6 +75,44 @@ func TestPostgresTruncateNonexistentTable(t *testing.T) {
 	require.Contains(t, err.Error(), "table doesn't exist")
 }
 
+func TestPostgresCopyWithInsertFallback(t *testing.T) {
+	table := &Table{
+		Name:    "test_copy_fallback",
+		Columns: Columns{"field1": typing.SQLColumn{Type: "text"}, "field2": typing.SQLColumn{Type: "text"}, "field3": typing.SQLColumn{Type: "bigint"}, "user": typing.SQLColumn{Type: "text"}},
+	}
+	container, pg := setupDatabase(t, table)
+	defer container.Close()
+
+	err := pg.insertBatch(table, createObjects(1000), nil)
+	require.NoError(t, err, "Failed to copy 1000 objects")
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err, "Failed to count objects at "+table.Name)
+	assert.Equal(t, rows, 1000)
+
+	//COPY fails on invalid bigint value and INSERT fallback returns error with values
+	invalidObjects := createObjects(5)
+	invalidObjects[3]["field3"] = "not a number"
+	err = pg.insertBatch(table, invalidObjects, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to execute insert")
+	rows, err = container.CountRows(table.Name)
+	require.NoError(t, err, "Failed to count objects at "+table.Name)
+	assert.Equal(t, rows, 1000)
+}
+
+func TestIsDataException(t *testing.T) {
+	require.True(t, isDataException(&pq.Error{Code: "22P02"}))
+	require.True(t, isDataException(&pq.Error{Code: "22003"}))
+	require.False(t, isDataException(&pq.Error{Code: "23505"}))
+	require.False(t, isDataException(errors.New("22P02")))
+}
+
+func TestRemoveZeroBytes(t *testing.T) {
+	require.Equal(t, "abc", removeZeroBytes(typing.SQLColumn{Type: "text"}, "a\u0000bc"))
+	require.Equal(t, "a\u0000bc", removeZeroBytes(typing.SQLColumn{Type: "jsonb"}, "a\u0000bc"))
+	require.Equal(t, 1, removeZeroBytes(typing.SQLColumn{Type: "text"}, 1))
+}
+
 func setupDatabase(t *testing.T, table *Table) (*test.PostgresContainer, *Postgres) {
 	ctx := context.Background()
 	container, err := test.NewPostgresContainer(ctx)
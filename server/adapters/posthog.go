@@ -0,0 +1,231 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+const (
+	//postHogMaxBatchSize is a max events count in one /batch request
+	postHogMaxBatchSize = 1000
+)
+
+//postHogEventFields are fields of PostHog event. Other fields of the object aren't sent
+var postHogEventFields = []string{"event", "distinct_id", "timestamp", "uuid", "properties"}
+
+//postHogRegionHosts are PostHog Cloud hosts of data residency regions
+var postHogRegionHosts = map[string]string{
+	"us": "https://app.posthog.com",
+	"eu": "https://eu.posthog.com",
+}
+
+//PostHogRequest is a dto for sending /batch requests to PostHog
+type PostHogRequest struct {
+	APIKey string                   `json:"api_key"`
+	Batch  []map[string]interface{} `json:"batch"`
+}
+
+//PostHogResponse is a dto for receiving error response from PostHog
+type PostHogResponse struct {
+	Type   string `json:"type"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+//PostHogRequestFactory is a factory for building PostHog HTTP requests from input events
+//events, identify ($identify) and alias ($create_alias) calls are sent with /batch API
+type PostHogRequestFactory struct {
+	apiKey string
+	host   string
+}
+
+//newPostHogRequestFactory returns configured HTTPRequestFactory instance for posthog requests
+func newPostHogRequestFactory(config *PostHogConfig) (*PostHogRequestFactory, error) {
+	return &PostHogRequestFactory{apiKey: config.APIKey, host: config.host()}, nil
+}
+
+//Create returns created posthog /batch request with one event
+func (prf *PostHogRequestFactory) Create(object map[string]interface{}) (*Request, error) {
+	if event, _ := object["event"].(string); event == "" {
+		return nil, errors.New("posthog event must contain 'event' field")
+	}
+	if distinctID, ok := object["distinct_id"]; !ok || distinctID == nil || fmt.Sprint(distinctID) == "" {
+		return nil, errors.New("posthog event must contain 'distinct_id' field")
+	}
+
+	event := make(map[string]interface{}, len(postHogEventFields))
+	for _, name := range postHogEventFields {
+		if value, ok := object[name]; ok {
+			event[name] = value
+		}
+	}
+
+	req := PostHogRequest{APIKey: prf.apiKey, Batch: []map[string]interface{}{event}}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling posthog request [%v]: %v", req, err)
+	}
+	return &Request{
+		URL:     prf.host + "/batch/",
+		Method:  http.MethodPost,
+		Body:    b,
+		Headers: map[string]string{"Content-Type": "application/json", "user-agent": JitsuUserAgent},
+	}, nil
+}
+
+//BatchKey returns key of requests with the same URL and headers
+func (prf *PostHogRequestFactory) BatchKey(req *Request) string {
+	return requestBatchKey(req)
+}
+
+//MaxBatchSize returns max events count in one /batch request
+func (prf *PostHogRequestFactory) MaxBatchSize() int {
+	return postHogMaxBatchSize
+}
+
+//CreateBatch returns request with events of all requests. Every request must contain exactly one event
+func (prf *PostHogRequestFactory) CreateBatch(requests []*Request) (*Request, error) {
+	batch := PostHogRequest{APIKey: prf.apiKey, Batch: make([]map[string]interface{}, 0, len(requests))}
+	for _, req := range requests {
+		postHogRequest := &PostHogRequest{}
+		if err := json.Unmarshal(req.Body, postHogRequest); err != nil {
+			return nil, fmt.Errorf("Error unmarshalling posthog request: %v", err)
+		}
+		if len(postHogRequest.Batch) != 1 {
+			return nil, fmt.Errorf("posthog request must contain one event: %d", len(postHogRequest.Batch))
+		}
+		batch.Batch = append(batch.Batch, postHogRequest.Batch[0])
+	}
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling posthog batch request: %v", err)
+	}
+	return &Request{
+		URL:     requests[0].URL,
+		Method:  requests[0].Method,
+		Body:    b,
+		Headers: requests[0].Headers,
+	}, nil
+}
+
+//ParseBatchResponse returns nil: PostHog accepts or rejects the whole request
+func (prf *PostHogRequestFactory) ParseBatchResponse(batchSize int, response *HTTPResponse) *BatchResult {
+	return nil
+}
+
+func (prf *PostHogRequestFactory) Close() {
+}
+
+//PostHogConfig is a dto for parsing PostHog configuration
+type PostHogConfig struct {
+	//APIKey is a project API key
+	APIKey string `mapstructure:"api_key" json:"api_key,omitempty" yaml:"api_key,omitempty"`
+	//Region is PostHog Cloud region: us or eu. Default is us
+	Region string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a self-hosted PostHog URL. Overrides region
+	Endpoint string           `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Batch    *HTTPBatchConfig `mapstructure:"batch" json:"batch,omitempty" yaml:"batch,omitempty"`
+}
+
+//Validate returns err if invalid
+func (pc *PostHogConfig) Validate() error {
+	if pc == nil {
+		return errors.New("posthog config is required")
+	}
+	if pc.APIKey == "" {
+		return errors.New("'api_key' is required parameter")
+	}
+	if _, ok := postHogRegionHosts[strings.ToLower(pc.Region)]; pc.Region != "" && !ok {
+		return fmt.Errorf("unknown posthog region [%s]. Supported: us, eu", pc.Region)
+	}
+
+	return pc.Batch.Validate()
+}
+
+//host returns PostHog Cloud host of the configured region or self-hosted endpoint
+func (pc *PostHogConfig) host() string {
+	if pc.Endpoint != "" {
+		return strings.TrimSuffix(pc.Endpoint, "/")
+	}
+
+	return postHogRegionHosts[strings.ToLower(utils.NvlString(pc.Region, "us"))]
+}
+
+//PostHog is an adapter for sending HTTP requests to PostHog
+type PostHog struct {
+	AbstractHTTP
+
+	config *PostHogConfig
+}
+
+//NewPostHog returns configured PostHog adapter instance
+func NewPostHog(config *PostHogConfig, httpAdapterConfiguration *HTTPAdapterConfiguration) (*PostHog, error) {
+	httpReqFactory, err := newPostHogRequestFactory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
+	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PostHog{config: config}
+	p.httpAdapter = httpAdapter
+	return p, nil
+}
+
+//NewTestPostHog returns test instance of adapter
+func NewTestPostHog(config *PostHogConfig) *PostHog {
+	return &PostHog{config: config}
+}
+
+//TestAccess sends /decide request to PostHog and checks the project API key
+func (p *PostHog) TestAccess() error {
+	body, err := json.Marshal(map[string]interface{}{"api_key": p.config.APIKey, "distinct_id": "connection_test"})
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, p.config.host()+"/decide/?v=2", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Add("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		responseBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("Error reading posthog response body: %v", err)
+		}
+
+		response := &PostHogResponse{}
+		if err := json.Unmarshal(responseBody, response); err != nil || response.Detail == "" {
+			return fmt.Errorf("error connecting to posthog [code=%d]: %s", resp.StatusCode, string(responseBody))
+		}
+		return fmt.Errorf("error connecting to posthog [code=%d]: %s", resp.StatusCode, response.Detail)
+	}
+
+	return nil
+}
+
+//Type returns adapter type
+func (p *PostHog) Type() string {
+	return "PostHog"
+}
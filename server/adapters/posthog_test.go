@@ -0,0 +1,28 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostHogBatch(t *testing.T) {
+	config := &PostHogConfig{APIKey: "key", Endpoint: "https://posthog.example.com/"}
+	require.NoError(t, config.Validate())
+	factory, err := newPostHogRequestFactory(config)
+	require.NoError(t, err)
+
+	first, err := factory.Create(map[string]interface{}{"event": "$pageview", "distinct_id": "u1", "eventn_ctx_event_id": "1"})
+	require.NoError(t, err)
+	require.Equal(t, "https://posthog.example.com/batch/", first.URL)
+	second, err := factory.Create(map[string]interface{}{"event": "$identify", "distinct_id": "u1", "properties": map[string]interface{}{"$set": map[string]interface{}{"plan": "pro"}}})
+	require.NoError(t, err)
+	require.Equal(t, factory.BatchKey(first), factory.BatchKey(second))
+
+	_, err = factory.Create(map[string]interface{}{"event": "$pageview"})
+	require.EqualError(t, err, "posthog event must contain 'distinct_id' field")
+
+	batch, err := factory.CreateBatch([]*Request{first, second})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"api_key":"key","batch":[{"event":"$pageview","distinct_id":"u1"},{"event":"$identify","distinct_id":"u1","properties":{"$set":{"plan":"pro"}}}]}`, string(batch.Body))
+}
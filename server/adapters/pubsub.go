@@ -0,0 +1,187 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	//pubSubMaxBatchSize is a max messages count in one Pub/Sub publish request
+	pubSubMaxBatchSize = 1000
+	pubSubTimeout      = time.Minute
+)
+
+//PubSubConfig is a dto for parsing Google Pub/Sub destination configuration
+type PubSubConfig struct {
+	Project string      `mapstructure:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+	KeyFile interface{} `mapstructure:"key_file,omitempty" json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	//Endpoint is a Pub/Sub emulator host:port. Requests are sent without authentication
+	Endpoint string `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	//Topic is a topic ID template
+	Topic         string `mapstructure:"topic,omitempty" json:"topic,omitempty" yaml:"topic,omitempty"`
+	MessageConfig `mapstructure:",squash" yaml:"-,inline"`
+
+	//will be set on validation
+	credentials option.ClientOption
+}
+
+//Validate returns err if invalid
+func (psc *PubSubConfig) Validate() error {
+	if psc == nil {
+		return errors.New("Pub/Sub config is required")
+	}
+	if psc.Project == "" {
+		return errors.New("Pub/Sub project is required parameter")
+	}
+	if psc.Topic == "" {
+		return errors.New("Pub/Sub topic is required parameter")
+	}
+	if psc.Endpoint == "" {
+		googleConfig := &GoogleConfig{KeyFile: psc.KeyFile}
+		if err := googleConfig.Validate(); err != nil {
+			return err
+		}
+		psc.credentials = googleConfig.credentials
+	}
+
+	return psc.Batch.Validate()
+}
+
+//PubSub is a MessagePublisher for Google Pub/Sub
+//the client aggregates messages into publish requests itself. Key is used as an ordering key
+type PubSub struct {
+	ctx      context.Context
+	client   *pubsub.Client
+	ordering bool
+
+	mutex  *sync.RWMutex
+	topics map[string]*pubsub.Topic
+}
+
+//NewPubSub returns configured PubSub publisher
+func NewPubSub(ctx context.Context, config *PubSubConfig) (*PubSub, error) {
+	var options []option.ClientOption
+	if config.Endpoint != "" {
+		options = append(options, option.WithEndpoint(config.Endpoint), option.WithoutAuthentication(), option.WithGRPCDialOption(grpc.WithInsecure()))
+	} else if config.credentials != nil {
+		options = append(options, config.credentials)
+	}
+
+	client, err := pubsub.NewClient(ctx, config.Project, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Pub/Sub client: %v", err)
+	}
+
+	return &PubSub{
+		ctx:      ctx,
+		client:   client,
+		ordering: config.Key != "",
+		mutex:    &sync.RWMutex{},
+		topics:   map[string]*pubsub.Topic{},
+	}, nil
+}
+
+//Publish publishes messages and waits for results of all of them
+//messages with invalid arguments (e.g. too large) are returned as MessageRejectedError
+func (ps *PubSub) Publish(messages []*Message) ([]error, error) {
+	topic := ps.topic(messages[0].Topic)
+	ctx, cancel := context.WithTimeout(ps.ctx, pubSubTimeout)
+	defer cancel()
+
+	results := make([]*pubsub.PublishResult, 0, len(messages))
+	for _, message := range messages {
+		results = append(results, topic.Publish(ctx, &pubsub.Message{
+			Data:        message.Body,
+			Attributes:  message.Attributes,
+			OrderingKey: message.Key,
+		}))
+	}
+
+	errs := make([]error, len(messages))
+	for i, result := range results {
+		_, err := result.Get(ctx)
+		if err == nil {
+			continue
+		}
+
+		//publishing with the ordering key is paused after an error
+		if key := messages[i].Key; key != "" {
+			topic.ResumePublish(key)
+		}
+		if status.Code(err) == codes.InvalidArgument {
+			err = &MessageRejectedError{Err: err}
+		}
+		errs[i] = err
+	}
+
+	return errs, nil
+}
+
+//MaxBatchSize returns Pub/Sub publish request limit
+func (ps *PubSub) MaxBatchSize() int {
+	return pubSubMaxBatchSize
+}
+
+//TestAccess returns err if the topic doesn't exist or access is denied
+//templated topics aren't checked
+func (ps *PubSub) TestAccess(topicID string) error {
+	if strings.Contains(topicID, "{{") {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ps.ctx, pubSubTimeout)
+	defer cancel()
+
+	exists, err := ps.client.Topic(topicID).Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("Pub/Sub topic %s doesn't exist", topicID)
+	}
+
+	return nil
+}
+
+//Close sends pending messages of all topics and closes the client
+func (ps *PubSub) Close() error {
+	ps.mutex.Lock()
+	for _, topic := range ps.topics {
+		topic.Stop()
+	}
+	ps.topics = map[string]*pubsub.Topic{}
+	ps.mutex.Unlock()
+
+	return ps.client.Close()
+}
+
+//topic returns cached topic publisher
+func (ps *PubSub) topic(topicID string) *pubsub.Topic {
+	ps.mutex.RLock()
+	topic, ok := ps.topics[topicID]
+	ps.mutex.RUnlock()
+	if ok {
+		return topic
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if topic, ok := ps.topics[topicID]; ok {
+		return topic
+	}
+
+	topic = ps.client.Topic(topicID)
+	topic.EnableMessageOrdering = ps.ordering
+	ps.topics[topicID] = topic
+	return topic
+}
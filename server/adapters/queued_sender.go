@@ -0,0 +1,350 @@
+package adapters
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/queue"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/panjf2000/ants/v2"
+	"go.uber.org/atomic"
+)
+
+//circuitBreakerPauseInterval is a delay between circuit breaker checks while it is open
+const circuitBreakerPauseInterval = time.Second
+
+//RetryState is a retry count and time after which the queued item can be sent
+//it is embedded into RetryableRequest and RetryableMessage and serialized with them in persistent queue
+type RetryState struct {
+	Retry        int
+	DequeuedTime time.Time
+	EventContext *EventContext
+}
+
+func (rs *RetryState) retryState() *RetryState {
+	return rs
+}
+
+//retryableItem is a queued HTTP request or message which is sent with retries
+type retryableItem interface {
+	retryState() *RetryState
+	//payloadSize returns size of the item payload for batch max_bytes limit
+	payloadSize() int
+}
+
+//retryableQueue is a persistent queue of retryable items
+type retryableQueue interface {
+	addItem(item retryableItem) error
+	dequeueItem() (retryableItem, error)
+	Size() uint64
+	Close() error
+}
+
+//queuedSenderConfiguration is a dto for creating queuedSender
+type queuedSenderConfiguration struct {
+	destinationID string
+	//itemName is used in logs (e.g. HTTP request, message)
+	itemName    string
+	queue       retryableQueue
+	poolWorkers int
+	//send is a workers pool function. It gets a retryableItem or *itemsBatch
+	send func(task interface{})
+	//batcher is optional. Items with empty batchKey are sent one by one
+	batcher        *itemsBatcher
+	batchKey       func(item retryableItem) string
+	circuitBreaker *circuitbreaker.CircuitBreaker
+	errorHandler   func(fallback bool, eventContext *EventContext, err error)
+	httpConfig     *HTTPConfiguration
+}
+
+//queuedSender polls items from the persistent queue, aggregates them into batches (if configured)
+//and sends them in the workers pool. Failed items are put back to the queue with exponential delay.
+//It is used by HTTPAdapter and MessageAdapter
+type queuedSender struct {
+	destinationID  string
+	itemName       string
+	queue          retryableQueue
+	workersPool    *ants.PoolWithFunc
+	batcher        *itemsBatcher
+	batchKey       func(item retryableItem) string
+	circuitBreaker *circuitbreaker.CircuitBreaker
+	errorHandler   func(fallback bool, eventContext *EventContext, err error)
+
+	retryCount int
+	retryDelay time.Duration
+	//when reached - items can't be retried => fallback
+	queueFullnessThreshold uint64
+
+	closed *atomic.Bool
+}
+
+//newQueuedSender returns queuedSender and starts queue observing (and batch flushing) goroutines
+func newQueuedSender(config *queuedSenderConfiguration) (*queuedSender, error) {
+	pool, err := ants.NewPoolWithFunc(config.poolWorkers, config.send)
+	if err != nil {
+		return nil, err
+	}
+
+	qs := &queuedSender{
+		destinationID:          config.destinationID,
+		itemName:               config.itemName,
+		queue:                  config.queue,
+		workersPool:            pool,
+		batcher:                config.batcher,
+		batchKey:               config.batchKey,
+		circuitBreaker:         config.circuitBreaker,
+		errorHandler:           config.errorHandler,
+		retryCount:             config.httpConfig.RetryCount,
+		retryDelay:             config.httpConfig.RetryDelay,
+		queueFullnessThreshold: config.httpConfig.QueueFullnessThreshold,
+		closed:                 atomic.NewBool(false),
+	}
+	qs.startObserver()
+	if qs.batcher != nil {
+		qs.startBatchFlusher()
+	}
+
+	return qs, nil
+}
+
+//startObserver runs goroutine for polling from the queue and sending items
+func (qs *queuedSender) startObserver() {
+	safego.RunWithRestart(func() {
+		for {
+			if qs.closed.Load() {
+				break
+			}
+
+			//destination is unavailable: items are kept in the queue
+			if qs.circuitBreaker.IsOpen() {
+				time.Sleep(circuitBreakerPauseInterval)
+				continue
+			}
+
+			if qs.workersPool.Free() > 0 {
+				item, err := qs.queue.dequeueItem()
+				if err != nil {
+					if err == queue.ErrQueueClosed && qs.closed.Load() {
+						continue
+					}
+					logging.SystemErrorf("[%s] Error reading %s from the queue: %v", qs.destinationID, qs.itemName, err)
+					time.Sleep(time.Second)
+					continue
+				}
+				//dequeued item was from retry call and retry timeout hasn't come
+				if timestamp.Now().UTC().Before(item.retryState().DequeuedTime) {
+					if err := qs.queue.addItem(item); err != nil {
+						logging.SystemErrorf("[%s] Error enqueueing %s after dequeuing: %v", qs.destinationID, qs.itemName, err)
+						qs.errorHandler(true, item.retryState().EventContext, err)
+					}
+
+					continue
+				}
+				if qs.batcher != nil {
+					if key := qs.batchKey(item); key != "" {
+						for _, batch := range qs.batcher.add(key, item) {
+							qs.invoke(batch, batch.items)
+						}
+						continue
+					}
+				}
+				qs.invoke(item, []retryableItem{item})
+			} else {
+				time.Sleep(time.Millisecond * 50)
+			}
+		}
+	})
+}
+
+//startBatchFlusher runs goroutine for sending batches which have been aggregated longer than the window
+func (qs *queuedSender) startBatchFlusher() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(qs.batcher.flushPeriod())
+		defer ticker.Stop()
+		for range ticker.C {
+			if qs.closed.Load() {
+				break
+			}
+
+			for _, batch := range qs.batcher.expired() {
+				qs.invoke(batch, batch.items)
+			}
+		}
+	})
+}
+
+//invoke runs sending of the task (item or batch) in the workers pool. Puts items back to the queue if pool is closed
+func (qs *queuedSender) invoke(task interface{}, items []retryableItem) {
+	if err := qs.workersPool.Invoke(task); err != nil {
+		if err != ants.ErrPoolClosed {
+			logging.SystemErrorf("[%s] Error invoking %s task: %v", qs.destinationID, qs.itemName, err)
+		}
+		qs.requeue(items)
+	}
+}
+
+//requeue puts items back to the queue without retry count increment
+func (qs *queuedSender) requeue(items []retryableItem) {
+	for _, item := range items {
+		if err := qs.queue.addItem(item); err != nil {
+			logging.SystemErrorf("[%s] Error enqueueing %s: %v", qs.destinationID, qs.itemName, err)
+			qs.errorHandler(true, item.retryState().EventContext, err)
+		}
+	}
+}
+
+//retry puts item back to the queue with exponential delay 2^X and passes sendErr to errorHandler (without fallback)
+//returns false without retry if:
+// - queue size is greater than threshold
+// - retry limit is reached
+func (qs *queuedSender) retry(item retryableItem, sendErr error) bool {
+	//if queue fullness threshold configured check if queue size not exceed
+	if qs.queueFullnessThreshold != 0 && qs.queueFullnessThreshold <= qs.queue.Size() {
+		return false
+	}
+
+	state := item.retryState()
+	if state.Retry >= qs.retryCount {
+		return false
+	}
+
+	delay := time.Duration(math.Pow(2, float64(state.Retry))) * qs.retryDelay
+	state.Retry += 1
+	state.DequeuedTime = timestamp.Now().UTC().Add(delay)
+	if err := qs.queue.addItem(item); err != nil {
+		logging.SystemErrorf("[%s] Error enqueueing %s after sending: %v", qs.destinationID, qs.itemName, err)
+		qs.errorHandler(true, state.EventContext, sendErr)
+	} else {
+		qs.errorHandler(false, state.EventContext, sendErr)
+	}
+
+	return true
+}
+
+//close stops goroutines, puts pending batches back to the persistent queue, closes the queue and releases workers pool
+func (qs *queuedSender) close() error {
+	qs.closed.Store(true)
+	if qs.batcher != nil {
+		for _, batch := range qs.batcher.drain() {
+			qs.requeue(batch.items)
+		}
+	}
+	err := qs.queue.Close()
+	qs.workersPool.Release()
+
+	return err
+}
+
+//itemsBatch is a group of queued items with the same batch key which are sent in one request
+type itemsBatch struct {
+	key     string
+	items   []retryableItem
+	bytes   int
+	created time.Time
+}
+
+//itemsBatcher aggregates items by batch key until size or time window limits are reached
+type itemsBatcher struct {
+	maxSize  int
+	maxBytes int
+	window   time.Duration
+
+	mutex   *sync.Mutex
+	batches map[string]*itemsBatch
+}
+
+//newItemsBatcher returns itemsBatcher or nil if batching isn't configured
+//limit is a vendor API limit of items in one batch request (0 - unlimited)
+func newItemsBatcher(config *HTTPBatchConfig, limit int) *itemsBatcher {
+	if config == nil {
+		return nil
+	}
+
+	maxSize := config.MaxSize
+	if limit > 0 && (maxSize == 0 || maxSize > limit) {
+		maxSize = limit
+	}
+	if maxSize == 1 {
+		return nil
+	}
+
+	return &itemsBatcher{
+		maxSize:  maxSize,
+		maxBytes: config.MaxBytes,
+		window:   config.window(),
+		mutex:    &sync.Mutex{},
+		batches:  map[string]*itemsBatch{},
+	}
+}
+
+//add puts item into the batch with the key and returns batches which are ready to be sent
+func (ib *itemsBatcher) add(key string, item retryableItem) []*itemsBatch {
+	ib.mutex.Lock()
+	defer ib.mutex.Unlock()
+
+	var ready []*itemsBatch
+	size := item.payloadSize()
+	batch, ok := ib.batches[key]
+	if ok && ib.maxBytes > 0 && batch.bytes+size > ib.maxBytes {
+		ready = append(ready, batch)
+		ok = false
+	}
+	if !ok {
+		batch = &itemsBatch{key: key, created: timestamp.Now()}
+		ib.batches[key] = batch
+	}
+
+	batch.items = append(batch.items, item)
+	batch.bytes += size
+	if (ib.maxSize > 0 && len(batch.items) >= ib.maxSize) || (ib.maxBytes > 0 && batch.bytes >= ib.maxBytes) {
+		ready = append(ready, batch)
+		delete(ib.batches, key)
+	}
+
+	return ready
+}
+
+//flushPeriod returns period of expired batches checks: a half of the window but not less than minHTTPBatchWindow/2
+func (ib *itemsBatcher) flushPeriod() time.Duration {
+	period := ib.window / 2
+	if period < minHTTPBatchWindow/2 {
+		return minHTTPBatchWindow / 2
+	}
+
+	return period
+}
+
+//expired returns batches which have been aggregated longer than the window
+func (ib *itemsBatcher) expired() []*itemsBatch {
+	ib.mutex.Lock()
+	defer ib.mutex.Unlock()
+
+	now := timestamp.Now()
+	var ready []*itemsBatch
+	for key, batch := range ib.batches {
+		if now.Sub(batch.created) >= ib.window {
+			ready = append(ready, batch)
+			delete(ib.batches, key)
+		}
+	}
+
+	return ready
+}
+
+//drain returns all pending batches
+func (ib *itemsBatcher) drain() []*itemsBatch {
+	ib.mutex.Lock()
+	defer ib.mutex.Unlock()
+
+	var pending []*itemsBatch
+	for _, batch := range ib.batches {
+		pending = append(pending, batch)
+	}
+	ib.batches = map[string]*itemsBatch{}
+
+	return pending
+}
@@ -0,0 +1,495 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/errorj"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/templates"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//Redis structure types
+const (
+	RedisProfile     = "profile"
+	RedisSortedSet   = "sorted_set"
+	RedisCounter     = "counter"
+	RedisHyperLogLog = "hyperloglog"
+
+	//redisLastSeenField is a profile hash field with the last event timestamp
+	redisLastSeenField = "last_seen"
+
+	//redisWatchRetries is a max count of writes which are aborted because of concurrent profile changes
+	redisWatchRetries = 10
+)
+
+//RedisConfig is a dto for parsing Redis destination configuration
+type RedisConfig struct {
+	//Host is a plain host or redis://, rediss://, sentinel:// URL
+	Host               string                  `mapstructure:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
+	Port               int                     `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
+	Password           string                  `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	Database           int                     `mapstructure:"database,omitempty" json:"database,omitempty" yaml:"database,omitempty"`
+	SentinelMasterName string                  `mapstructure:"sentinel_master_name,omitempty" json:"sentinel_master_name,omitempty" yaml:"sentinel_master_name,omitempty"`
+	TLSSkipVerify      bool                    `mapstructure:"tls_skip_verify,omitempty" json:"tls_skip_verify,omitempty" yaml:"tls_skip_verify,omitempty"`
+	Structures         []*RedisStructureConfig `mapstructure:"structures,omitempty" json:"structures,omitempty" yaml:"structures,omitempty"`
+}
+
+//RedisStructureConfig is a dto for parsing configuration of Redis structure which is written on every event
+type RedisStructureConfig struct {
+	//Type is one of: profile, sorted_set, counter, hyperloglog
+	Type string `mapstructure:"type,omitempty" json:"type,omitempty" yaml:"type,omitempty"`
+	//Key is a Go template. Structure isn't written if the key is empty
+	Key string `mapstructure:"key,omitempty" json:"key,omitempty" yaml:"key,omitempty"`
+	//Fields are JSON paths of event fields which are merged into the profile hash
+	Fields []string `mapstructure:"fields,omitempty" json:"fields,omitempty" yaml:"fields,omitempty"`
+	//Member is a Go template of sorted set or HyperLogLog member. Default is event ID
+	Member string `mapstructure:"member,omitempty" json:"member,omitempty" yaml:"member,omitempty"`
+	//MaxSize is a max sorted set size. The oldest members are removed
+	MaxSize int `mapstructure:"max_size,omitempty" json:"max_size,omitempty" yaml:"max_size,omitempty"`
+	//Increment is a Go template of counter increment. Default is 1
+	Increment string `mapstructure:"increment,omitempty" json:"increment,omitempty" yaml:"increment,omitempty"`
+	//TTL is a key expiration (e.g. 24h). The key doesn't expire if it isn't set
+	TTL string `mapstructure:"ttl,omitempty" json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+//Validate returns err if invalid
+func (rc *RedisConfig) Validate() error {
+	if rc == nil {
+		return errors.New("Redis config is required")
+	}
+	if rc.Host == "" {
+		return errors.New("Redis host is required parameter")
+	}
+	if len(rc.Structures) == 0 {
+		return errors.New("Redis structures are required")
+	}
+	for i, structure := range rc.Structures {
+		if err := structure.Validate(); err != nil {
+			return fmt.Errorf("structures[%d]: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+//Validate returns err if invalid
+func (rsc *RedisStructureConfig) Validate() error {
+	if rsc == nil {
+		return errors.New("structure config is required")
+	}
+	switch rsc.Type {
+	case RedisProfile:
+		if len(rsc.Fields) == 0 {
+			return errors.New("fields are required for profile")
+		}
+	case RedisSortedSet, RedisCounter, RedisHyperLogLog:
+	default:
+		return fmt.Errorf("unknown type [%s]. Supported: %s, %s, %s, %s", rsc.Type, RedisProfile, RedisSortedSet, RedisCounter, RedisHyperLogLog)
+	}
+	if rsc.Key == "" {
+		return errors.New("key is required parameter")
+	}
+	if rsc.MaxSize < 0 {
+		return errors.New("max_size must be positive")
+	}
+	if rsc.TTL != "" {
+		if ttl, err := time.ParseDuration(rsc.TTL); err != nil || ttl < time.Second {
+			return fmt.Errorf("malformed ttl [%s]: duration must be at least 1s", rsc.TTL)
+		}
+	}
+
+	return nil
+}
+
+//redisCommand is a Redis command with arguments
+type redisCommand struct {
+	name string
+	args []interface{}
+}
+
+func (rc *redisCommand) String() string {
+	return strings.TrimSuffix(fmt.Sprintln(append([]interface{}{rc.name}, rc.args...)...), "\n")
+}
+
+//redisStructure is a parsed RedisStructureConfig with templates
+type redisStructure struct {
+	structureType string
+	keyTmpl       templates.TemplateExecutor
+	memberTmpl    templates.TemplateExecutor
+	incrementTmpl templates.TemplateExecutor
+	fields        []jsonutils.JSONPath
+	maxSize       int
+	ttl           time.Duration
+}
+
+//newRedisStructure returns redisStructure with parsed templates
+func newRedisStructure(destinationID string, config *RedisStructureConfig) (*redisStructure, error) {
+	templateFunctions := templates.EnrichedFuncMap(map[string]interface{}{"destinationId": destinationID, "destinationType": "redis"})
+	rs := &redisStructure{structureType: config.Type, maxSize: config.MaxSize}
+	if config.TTL != "" {
+		rs.ttl, _ = time.ParseDuration(config.TTL)
+	}
+	for _, field := range config.Fields {
+		rs.fields = append(rs.fields, jsonutils.NewJSONPath(field))
+	}
+
+	keyTmpl, err := templates.NewGoTemplateExecutor("key", config.Key, templateFunctions)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing key template [%s]: %v", config.Key, err)
+	}
+	rs.keyTmpl = keyTmpl
+	if config.Member != "" {
+		memberTmpl, err := templates.NewGoTemplateExecutor("member", config.Member, templateFunctions)
+		if err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("Error parsing member template [%s]: %v", config.Member, err)
+		}
+		rs.memberTmpl = memberTmpl
+	}
+	if config.Increment != "" {
+		incrementTmpl, err := templates.NewGoTemplateExecutor("increment", config.Increment, templateFunctions)
+		if err != nil {
+			rs.Close()
+			return nil, fmt.Errorf("Error parsing increment template [%s]: %v", config.Increment, err)
+		}
+		rs.incrementTmpl = incrementTmpl
+	}
+
+	return rs, nil
+}
+
+//commands returns commands for writing sorted set, counter or HyperLogLog
+func (rs *redisStructure) commands(key, eventID string, object map[string]interface{}) ([]*redisCommand, error) {
+	var commands []*redisCommand
+	switch rs.structureType {
+	case RedisSortedSet, RedisHyperLogLog:
+		member, err := executeTemplate(rs.memberTmpl, object)
+		if err != nil {
+			return nil, fmt.Errorf("Error executing member template: %v", err)
+		}
+		member = firstNotEmpty(member, eventID)
+		if member == "" {
+			return nil, nil
+		}
+
+		if rs.structureType == RedisHyperLogLog {
+			commands = append(commands, &redisCommand{name: "PFADD", args: []interface{}{key, member}})
+			break
+		}
+		score := eventTimestamp(object).UnixNano() / int64(time.Millisecond)
+		commands = append(commands, &redisCommand{name: "ZADD", args: []interface{}{key, score, member}})
+		if rs.maxSize > 0 {
+			//sorted by timestamp => the oldest members have the lowest ranks
+			commands = append(commands, &redisCommand{name: "ZREMRANGEBYRANK", args: []interface{}{key, 0, -rs.maxSize - 1}})
+		}
+	case RedisCounter:
+		increment, err := executeTemplate(rs.incrementTmpl, object)
+		if err != nil {
+			return nil, fmt.Errorf("Error executing increment template: %v", err)
+		}
+		if increment == "" {
+			increment = "1"
+		}
+		value, err := strconv.ParseFloat(increment, 64)
+		if err != nil {
+			return nil, fmt.Errorf("counter increment must be a number: %s", increment)
+		}
+
+		if value == math.Trunc(value) {
+			commands = append(commands, &redisCommand{name: "INCRBY", args: []interface{}{key, int64(value)}})
+		} else {
+			commands = append(commands, &redisCommand{name: "INCRBYFLOAT", args: []interface{}{key, value}})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported structure type: %s", rs.structureType)
+	}
+
+	return append(commands, rs.expire(key)...), nil
+}
+
+//profile returns profile fields of the object and last seen timestamp
+func (rs *redisStructure) profile(object map[string]interface{}) map[string]interface{} {
+	profile := map[string]interface{}{}
+	for _, field := range rs.fields {
+		if value, ok := field.Get(object); ok && value != nil {
+			_ = field.Set(profile, value)
+		}
+	}
+	profile[redisLastSeenField] = eventTimestamp(object)
+
+	return profile
+}
+
+//profileCommands returns HSET of the profile fields. current are JSON values of the profile fields which are objects
+//they are merged with new values (jsonutils.Merge)
+func (rs *redisStructure) profileCommands(key string, profile map[string]interface{}, current map[string]string) ([]*redisCommand, error) {
+	args := []interface{}{key}
+	for _, name := range sortedKeys(profile) {
+		value := profile[name]
+		if object, ok := value.(map[string]interface{}); ok && current[name] != "" {
+			currentObject := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(current[name]), &currentObject); err == nil {
+				value = jsonutils.Merge(currentObject, object)
+			}
+		}
+
+		hashValue, err := redisValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("Error serializing profile field [%s]: %v", name, err)
+		}
+		args = append(args, name, hashValue)
+	}
+
+	return append([]*redisCommand{{name: "HSET", args: args}}, rs.expire(key)...), nil
+}
+
+//expire returns EXPIRE command if TTL is configured
+func (rs *redisStructure) expire(key string) []*redisCommand {
+	if rs.ttl == 0 {
+		return nil
+	}
+
+	return []*redisCommand{{name: "EXPIRE", args: []interface{}{key, int64(rs.ttl.Seconds())}}}
+}
+
+//Close closes underlying templates
+func (rs *redisStructure) Close() {
+	for _, tmpl := range []templates.TemplateExecutor{rs.keyTmpl, rs.memberTmpl, rs.incrementTmpl} {
+		if tmpl != nil {
+			tmpl.Close()
+		}
+	}
+}
+
+//Redis is an adapter for writing events into Redis structures
+//all commands of one event are executed in one transaction (MULTI/EXEC)
+type Redis struct {
+	pool        *meta.RedisPool
+	structures  []*redisStructure
+	queryLogger *logging.QueryLogger
+}
+
+//NewRedis returns configured Redis adapter or err if Redis isn't available
+func NewRedis(destinationID string, config *RedisConfig, queryLogger *logging.QueryLogger) (*Redis, error) {
+	r := &Redis{queryLogger: queryLogger}
+	for _, structureConfig := range config.Structures {
+		structure, err := newRedisStructure(destinationID, structureConfig)
+		if err != nil {
+			r.closeStructures()
+			return nil, err
+		}
+		r.structures = append(r.structures, structure)
+	}
+
+	factory := meta.NewRedisPoolFactory(config.Host, config.Port, config.Password, config.Database, config.TLSSkipVerify, config.SentinelMasterName)
+	if defaultPort, ok := factory.CheckAndSetDefaultPort(); ok {
+		logging.Infof("[%s] Redis port wasn't provided. Will be used default one: %d", destinationID, defaultPort)
+	}
+	pool, err := factory.Create()
+	if err != nil {
+		r.closeStructures()
+		return nil, err
+	}
+	r.pool = pool
+
+	return r, nil
+}
+
+//Insert writes event into configured structures. Redis destination works only in stream mode
+func (r *Redis) Insert(insertContext *InsertContext) error {
+	if err := r.write(insertContext.eventContext.EventID, insertContext.eventContext.ProcessedEvent); err != nil {
+		return errorj.ExecuteInsertError.Wrap(err, "failed to execute Redis commands")
+	}
+
+	return nil
+}
+
+//write builds commands of all structures and executes them in one transaction
+//profile keys with object fields are watched (WATCH) while current values are merged with new ones:
+//the transaction is aborted if a profile has been changed concurrently and the write is retried
+func (r *Redis) write(eventID string, object map[string]interface{}) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	for attempt := 0; attempt < redisWatchRetries; attempt++ {
+		commands, err := r.commands(conn, eventID, object)
+		if err != nil {
+			return err
+		}
+
+		executed, err := r.execute(conn, commands)
+		if err != nil || executed {
+			return err
+		}
+	}
+
+	return fmt.Errorf("profile has been changed concurrently %d times in a row", redisWatchRetries)
+}
+
+//commands returns commands of all structures. Profile keys with object fields are watched before reading current values
+func (r *Redis) commands(conn redis.Conn, eventID string, object map[string]interface{}) ([]*redisCommand, error) {
+	var commands []*redisCommand
+	for _, structure := range r.structures {
+		key, err := executeTemplate(structure.keyTmpl, object)
+		if err != nil {
+			return nil, fmt.Errorf("Error executing key template: %v", err)
+		}
+		if key == "" {
+			continue
+		}
+
+		var structureCommands []*redisCommand
+		if structure.structureType == RedisProfile {
+			profile := structure.profile(object)
+			current, err := r.currentProfileObjects(conn, key, profile)
+			if err != nil {
+				return nil, err
+			}
+			structureCommands, err = structure.profileCommands(key, profile, current)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			structureCommands, err = structure.commands(key, eventID, object)
+			if err != nil {
+				return nil, err
+			}
+		}
+		commands = append(commands, structureCommands...)
+	}
+
+	return commands, nil
+}
+
+//currentProfileObjects watches the profile key and returns current values of the profile fields which are objects
+func (r *Redis) currentProfileObjects(conn redis.Conn, key string, profile map[string]interface{}) (map[string]string, error) {
+	var fields []string
+	for _, name := range sortedKeys(profile) {
+		if _, ok := profile[name].(map[string]interface{}); ok {
+			fields = append(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	if _, err := conn.Do("WATCH", key); err != nil {
+		return nil, fmt.Errorf("Error watching profile [%s]: %v", key, err)
+	}
+
+	args := []interface{}{key}
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	values, err := redis.Strings(conn.Do("HMGET", args...))
+	if err != nil {
+		return nil, fmt.Errorf("Error getting profile [%s] fields: %v", key, err)
+	}
+
+	current := make(map[string]string, len(fields))
+	for i, value := range values {
+		current[fields[i]] = value
+	}
+	return current, nil
+}
+
+//execute runs commands in MULTI/EXEC transaction and returns the first error
+//returns false if the transaction has been aborted because of a changed watched key
+func (r *Redis) execute(conn redis.Conn, commands []*redisCommand) (bool, error) {
+	if len(commands) == 0 {
+		return true, nil
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return false, err
+	}
+	for _, command := range commands {
+		r.queryLogger.LogQuery(command.String())
+		if err := conn.Send(command.name, command.args...); err != nil {
+			return false, err
+		}
+	}
+
+	results, err := redis.Values(conn.Do("EXEC"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for i, result := range results {
+		if redisErr, ok := result.(redis.Error); ok && i < len(commands) {
+			return false, fmt.Errorf("%s: %v", commands[i].name, redisErr)
+		}
+	}
+
+	return true, nil
+}
+
+//Close closes connection pool and templates
+func (r *Redis) Close() error {
+	r.closeStructures()
+	return r.pool.Close()
+}
+
+func (r *Redis) closeStructures() {
+	for _, structure := range r.structures {
+		structure.Close()
+	}
+}
+
+//eventTimestamp returns event timestamp or current time if it isn't set
+func eventTimestamp(object map[string]interface{}) time.Time {
+	if t, err := typing.ParseTimestamp(object[timestamp.Key]); err == nil {
+		return t
+	}
+
+	return timestamp.Now()
+}
+
+//redisValue returns string value for hash field: strings as is, timestamps in ISO format and other values as JSON
+func redisValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return timestamp.ToISOFormat(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,202 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *RedisConfig
+		expectedErr string
+	}{
+		{
+			"nil config",
+			nil,
+			"Redis config is required",
+		},
+		{
+			"empty host",
+			&RedisConfig{Structures: []*RedisStructureConfig{{Type: RedisCounter, Key: "events"}}},
+			"Redis host is required parameter",
+		},
+		{
+			"empty structures",
+			&RedisConfig{Host: "localhost"},
+			"Redis structures are required",
+		},
+		{
+			"unknown type",
+			&RedisConfig{Host: "localhost", Structures: []*RedisStructureConfig{{Type: "list", Key: "events"}}},
+			"structures[0]: unknown type [list]. Supported: profile, sorted_set, counter, hyperloglog",
+		},
+		{
+			"profile without fields",
+			&RedisConfig{Host: "localhost", Structures: []*RedisStructureConfig{{Type: RedisProfile, Key: "user:{{.user.id}}"}}},
+			"structures[0]: fields are required for profile",
+		},
+		{
+			"malformed ttl",
+			&RedisConfig{Host: "localhost", Structures: []*RedisStructureConfig{{Type: RedisCounter, Key: "events", TTL: "10ms"}}},
+			"structures[0]: malformed ttl [10ms]: duration must be at least 1s",
+		},
+		{
+			"valid",
+			&RedisConfig{Host: "localhost", Structures: []*RedisStructureConfig{
+				{Type: RedisProfile, Key: "user:{{.user.id}}", Fields: []string{"/user/email"}},
+				{Type: RedisSortedSet, Key: "activity:{{.user.id}}", MaxSize: 100, TTL: "24h"},
+			}},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRedisStructureCommands(t *testing.T) {
+	eventTime := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	object := map[string]interface{}{"_timestamp": eventTime, "event_type": "pageview", "user": map[string]interface{}{"id": "u1"}, "revenue": 9.5}
+
+	tests := []struct {
+		name     string
+		config   *RedisStructureConfig
+		expected []string
+	}{
+		{
+			"sorted set with max size and ttl",
+			&RedisStructureConfig{Type: RedisSortedSet, Key: "activity", Member: "{{.event_type}}", MaxSize: 10, TTL: "1h"},
+			[]string{"ZADD activity 1614592800000 pageview", "ZREMRANGEBYRANK activity 0 -11", "EXPIRE activity 3600"},
+		},
+		{
+			"sorted set with default member",
+			&RedisStructureConfig{Type: RedisSortedSet, Key: "activity"},
+			[]string{"ZADD activity 1614592800000 e1"},
+		},
+		{
+			"default counter increment",
+			&RedisStructureConfig{Type: RedisCounter, Key: "events"},
+			[]string{"INCRBY events 1"},
+		},
+		{
+			"float counter increment",
+			&RedisStructureConfig{Type: RedisCounter, Key: "revenue", Increment: "{{.revenue}}"},
+			[]string{"INCRBYFLOAT revenue 9.5"},
+		},
+		{
+			"hyperloglog",
+			&RedisStructureConfig{Type: RedisHyperLogLog, Key: "uniques", Member: "{{.user.id}}"},
+			[]string{"PFADD uniques u1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.config.Validate())
+			structure, err := newRedisStructure("test", tt.config)
+			require.NoError(t, err)
+			defer structure.Close()
+
+			commands, err := structure.commands(tt.config.Key, "e1", object)
+			require.NoError(t, err)
+			var actual []string
+			for _, command := range commands {
+				actual = append(actual, command.String())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+
+	structure, err := newRedisStructure("test", &RedisStructureConfig{Type: RedisCounter, Key: "events", Increment: "{{.event_type}}"})
+	require.NoError(t, err)
+	defer structure.Close()
+	_, err = structure.commands("events", "e1", object)
+	require.EqualError(t, err, "counter increment must be a number: pageview")
+}
+
+func TestRedisProfileCommands(t *testing.T) {
+	eventTime := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	structure, err := newRedisStructure("test", &RedisStructureConfig{Type: RedisProfile, Key: "user:{{.user.id}}", Fields: []string{"/user/email", "/traits", "/missing"}})
+	require.NoError(t, err)
+	defer structure.Close()
+
+	profile := structure.profile(map[string]interface{}{
+		"_timestamp": eventTime,
+		"user":       map[string]interface{}{"id": "u1", "email": "a@b.com"},
+		"traits":     map[string]interface{}{"plan": "pro"},
+	})
+	require.Equal(t, map[string]interface{}{
+		"user":             map[string]interface{}{"email": "a@b.com"},
+		"traits":           map[string]interface{}{"plan": "pro"},
+		redisLastSeenField: eventTime,
+	}, profile)
+
+	commands, err := structure.profileCommands("user:u1", profile, map[string]string{"traits": `{"plan":"free","company":"acme"}`})
+	require.NoError(t, err)
+	require.Len(t, commands, 1)
+	require.Equal(t, "HSET", commands[0].name)
+	require.Equal(t, []interface{}{"user:u1",
+		redisLastSeenField, "2021-03-01T10:00:00.000000Z",
+		"traits", `{"company":"acme","plan":"pro"}`,
+		"user", `{"email":"a@b.com"}`,
+	}, commands[0].args)
+}
+
+func TestRedisConcurrentProfileWrites(t *testing.T) {
+	ctx := context.Background()
+	container, err := test.NewRedisContainer(ctx)
+	if err != nil {
+		t.Fatalf("failed to initialize container: %v", err)
+	}
+	defer container.Close()
+
+	r, err := NewRedis("test", &RedisConfig{Host: container.Host, Port: container.Port, Structures: []*RedisStructureConfig{
+		{Type: RedisProfile, Key: "user:{{.user.id}}", Fields: []string{"/traits"}},
+	}}, &logging.QueryLogger{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	const writers = 20
+	errs := make(chan error, writers)
+	wg := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			eventContext := &EventContext{EventID: fmt.Sprint(i), ProcessedEvent: map[string]interface{}{
+				"user":   map[string]interface{}{"id": "u1"},
+				"traits": map[string]interface{}{fmt.Sprintf("trait_%d", i): i},
+			}}
+			errs <- r.Insert(NewSingleInsertContext(eventContext))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+	traitsJSON, err := redis.String(conn.Do("HGET", "user:u1", "traits"))
+	require.NoError(t, err)
+	traits := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(traitsJSON), &traits))
+	require.Len(t, traits, writers, "all concurrently written traits must be merged")
+}
@@ -0,0 +1,123 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/errorj"
+)
+
+//RetentionRule is a rule of expired data deletion
+//Table is used by SQL adapters and Prefix is used by file adapters (S3, GCS)
+type RetentionRule struct {
+	Table  string
+	Prefix string
+	Field  string
+	TTL    time.Duration
+}
+
+//String returns human readable rule representation
+func (rr *RetentionRule) String() string {
+	if rr.Table != "" {
+		return fmt.Sprintf("table %s (%s older than %d days)", rr.Table, rr.Field, rr.Days())
+	}
+
+	return fmt.Sprintf("prefix '%s' (objects older than %d days)", rr.Prefix, rr.Days())
+}
+
+//Days returns TTL in days
+func (rr *RetentionRule) Days() int {
+	return int(rr.TTL / (24 * time.Hour))
+}
+
+//RetentionReport is a result of applying RetentionRule.
+//In dry run mode it contains data which would be deleted
+type RetentionReport struct {
+	Rule          *RetentionRule
+	DryRun        bool
+	ExpiredBefore time.Time
+	//Rows is an amount of deleted rows (SQL adapters). -1 if the driver doesn't return the amount
+	Rows       int64
+	Partitions []string
+	Objects    []string
+}
+
+//String returns human readable report representation
+func (rr *RetentionReport) String() string {
+	action := "deleted"
+	if rr.DryRun {
+		action = "would be deleted"
+	}
+
+	var parts []string
+	if rr.Rule.Table != "" {
+		parts = append(parts, fmt.Sprintf("rows: %d", rr.Rows))
+		if len(rr.Partitions) > 0 {
+			parts = append(parts, fmt.Sprintf("partitions: [%s]", strings.Join(rr.Partitions, ", ")))
+		}
+	} else {
+		parts = append(parts, fmt.Sprintf("objects: %d", len(rr.Objects)))
+	}
+
+	return fmt.Sprintf("%s: data before %s %s: %s", rr.Rule.String(), rr.ExpiredBefore.Format(time.RFC3339), action, strings.Join(parts, ", "))
+}
+
+//Retainer is implemented by adapters which are able to delete expired data:
+//drop partitions where available and DELETE rows otherwise (SQL adapters) or delete objects (file adapters)
+type Retainer interface {
+	DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error)
+}
+
+//s3DeleteObjectsBatchSize is a max amount of keys in one S3 DeleteObjects request
+const s3DeleteObjectsBatchSize = 1000
+
+//requireRetentionTable returns err if SQL retention rule doesn't have a table
+func requireRetentionTable(rule *RetentionRule) error {
+	if rule.Table == "" {
+		return errors.New("retention rule table is required for SQL destinations")
+	}
+
+	return nil
+}
+
+//deleteExpiredRows counts (in dry run mode) or deletes rows which are older than expiredBefore
+func deleteExpiredRows(sqlParams *SqlParams, countQuery, deleteQuery string, expiredBefore interface{}, dryRun bool, payload *ErrorPayload) (int64, error) {
+	values := []interface{}{expiredBefore}
+	payload.Values = values
+	if dryRun {
+		return countExpiredRows(sqlParams, countQuery, expiredBefore, payload)
+	}
+
+	sqlParams.queryLogger.LogQueryWithValues(deleteQuery, values)
+	result, err := sqlParams.dataSource.ExecContext(sqlParams.ctx, deleteQuery, values...)
+	if err != nil {
+		payload.Statement = deleteQuery
+		return 0, errorj.DeleteFromTableError.Wrap(checkErr(err), "failed to delete expired rows").
+			WithProperty(errorj.DBInfo, payload)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return -1, nil
+	}
+
+	return rows, nil
+}
+
+//countExpiredRows returns amount of rows which are older than expiredBefore
+func countExpiredRows(sqlParams *SqlParams, countQuery string, expiredBefore interface{}, payload *ErrorPayload) (int64, error) {
+	values := []interface{}{expiredBefore}
+	sqlParams.queryLogger.LogQueryWithValues(countQuery, values)
+
+	var rows int64
+	if err := sqlParams.dataSource.QueryRowContext(sqlParams.ctx, countQuery, values...).Scan(&rows); err != nil {
+		payload.Statement = countQuery
+		payload.Values = values
+		return 0, errorj.QueryError.Wrap(checkErr(err), "failed to count expired rows").
+			WithProperty(errorj.DBInfo, payload)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionReportString(t *testing.T) {
+	expiredBefore := time.Date(2021, 4, 13, 0, 0, 0, 0, time.UTC)
+	tableRule := &RetentionRule{Table: "events", Field: "_timestamp", TTL: 400 * 24 * time.Hour}
+	prefixRule := &RetentionRule{Prefix: "events/", TTL: 30 * 24 * time.Hour}
+
+	require.Equal(t, "table events (_timestamp older than 400 days): data before 2021-04-13T00:00:00Z deleted: rows: 10, partitions: [events_p20210411, events_p20210412]",
+		(&RetentionReport{Rule: tableRule, ExpiredBefore: expiredBefore, Rows: 10, Partitions: []string{"events_p20210411", "events_p20210412"}}).String())
+	require.Equal(t, "prefix 'events/' (objects older than 30 days): data before 2021-04-13T00:00:00Z would be deleted: objects: 2",
+		(&RetentionReport{Rule: prefixRule, DryRun: true, ExpiredBefore: expiredBefore, Objects: []string{"events/a.log", "events/b.log"}}).String())
+}
+
+func TestPostgresParsePartitionUpperBound(t *testing.T) {
+	upperBound, ok := parsePartitionUpperBound("FOR VALUES FROM ('2022-05-18 00:00:00') TO ('2022-05-19 00:00:00')")
+	require.True(t, ok)
+	require.Equal(t, time.Date(2022, 5, 19, 0, 0, 0, 0, time.UTC), upperBound)
+
+	_, ok = parsePartitionUpperBound("DEFAULT")
+	require.False(t, ok)
+}
+
+func TestClickHouseCreateTableStatementWithTTL(t *testing.T) {
+	rules := []*RetentionRule{{Table: "events", Field: "_timestamp", TTL: 400 * 24 * time.Hour}, {Prefix: "events/", TTL: time.Hour}}
+
+	factory, err := NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1"})
+	require.NoError(t, err)
+	factory.SetRetentionRules(rules)
+	require.Equal(t, "CREATE TABLE \"db1\".\"events\"  (a String) ENGINE = ReplacingMergeTree() PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (eventn_ctx_event_id)  TTL toDateTime(\"_timestamp\") + INTERVAL 400 DAY",
+		strings.TrimSpace(factory.CreateTableStatement("events", "a String")))
+	require.Equal(t, "CREATE TABLE \"db1\".\"pageviews\"  (a String) ENGINE = ReplacingMergeTree() PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (eventn_ctx_event_id)",
+		strings.TrimSpace(factory.CreateTableStatement("pageviews", "a String")))
+
+	//raw statement isn't modified
+	factory, err = NewTableStatementFactory(&ClickHouseConfig{Dsns: []string{}, Database: "db1", Engine: &EngineConfig{RawStatement: "ENGINE = MergeTree() ORDER BY (a)"}})
+	require.NoError(t, err)
+	factory.SetRetentionRules(rules)
+	require.Equal(t, "CREATE TABLE \"db1\".\"events\"  (a String) ENGINE = MergeTree() ORDER BY (a)",
+		strings.TrimSpace(factory.CreateTableStatement("events", "a String")))
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -43,7 +47,10 @@ func (s3c *S3Config) Validate() error {
 	if s3c.Region == "" {
 		return errors.New("S3 region is required parameter")
 	}
-	return nil
+	if err := s3c.ValidateTableFormat(); err != nil {
+		return err
+	}
+	return s3c.ValidateCompression()
 }
 
 //S3 is a S3 adapter for uploading/deleting files
@@ -96,10 +103,8 @@ func (a *S3) UploadBytes(fileName string, fileBytes []byte) error {
 		return err
 	}
 
-	var fileType string
-	if a.config.Compression == FileCompressionGZIP {
-		fileType = "application/gzip"
-	} else {
+	fileType := a.config.ContentType()
+	if fileType == "" {
 		fileType = http.DetectContentType(fileBytes)
 	}
 
@@ -116,6 +121,68 @@ func (a *S3) UploadBytes(fileName string, fileBytes []byte) error {
 	return nil
 }
 
+//PutObject writes object as is (without compression) with the key relative to the configured folder
+func (a *S3) PutObject(key string, data []byte) error {
+	if a.closed.Load() {
+		return fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	objectKey := a.config.ObjectsPrefix(key)
+	if _, err := a.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(a.config.Bucket),
+		Key:    aws.String(objectKey),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return errorj.SaveOnStageError.Wrap(err, "failed to write file to s3").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    a.config.Bucket,
+				Statement: fmt.Sprintf("file: %s", objectKey),
+			})
+	}
+
+	return nil
+}
+
+//GetObject returns object content by the key relative to the configured folder
+func (a *S3) GetObject(key string) ([]byte, error) {
+	if a.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	objectKey := a.config.ObjectsPrefix(key)
+	output, err := a.client.GetObject(&s3.GetObjectInput{Bucket: aws.String(a.config.Bucket), Key: aws.String(objectKey)})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrObjectNotExist
+		}
+		return nil, fmt.Errorf("failed to read file %s from s3: %v", objectKey, err)
+	}
+	defer output.Body.Close()
+
+	return ioutil.ReadAll(output.Body)
+}
+
+//ListObjects returns keys (relative to the configured folder) of objects with the prefix
+func (a *S3) ListObjects(prefix string) ([]string, error) {
+	if a.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	folderPrefix := a.config.ObjectsPrefix("")
+	var keys []string
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket), Prefix: aws.String(a.config.ObjectsPrefix(prefix))}
+	if err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(object.Key), folderPrefix))
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list files with prefix %s in s3: %v", prefix, err)
+	}
+
+	return keys, nil
+}
+
 //DeleteObject deletes object from s3 bucket by key
 func (a *S3) DeleteObject(key string) error {
 	if a.closed.Load() {
@@ -143,6 +210,64 @@ func (a *S3) DeleteObject(key string) error {
 	return nil
 }
 
+//DeleteExpired deletes objects with rule prefix which were modified before expiredBefore
+func (a *S3) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if a.closed.Load() {
+		return nil, fmt.Errorf("attempt to use closed S3 instance")
+	}
+
+	prefix := a.config.ObjectsPrefix(rule.Prefix)
+	report := &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore}
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.config.Bucket), Prefix: aws.String(prefix)}
+	if err := a.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			if object.LastModified != nil && object.LastModified.Before(expiredBefore) {
+				report.Objects = append(report.Objects, aws.StringValue(object.Key))
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errorj.DeleteFromTableError.Wrap(err, "failed to list objects in s3").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Bucket:    a.config.Bucket,
+				Statement: fmt.Sprintf("prefix: %s", prefix),
+			})
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	for start := 0; start < len(report.Objects); start += s3DeleteObjectsBatchSize {
+		end := start + s3DeleteObjectsBatchSize
+		if end > len(report.Objects) {
+			end = len(report.Objects)
+		}
+
+		identifiers := make([]*s3.ObjectIdentifier, 0, end-start)
+		for _, key := range report.Objects[start:end] {
+			identifiers = append(identifiers, &s3.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := a.client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(a.config.Bucket),
+			Delete: &s3.Delete{Objects: identifiers, Quiet: aws.Bool(true)},
+		})
+		if err == nil && len(output.Errors) > 0 {
+			err = fmt.Errorf("%s: %s", aws.StringValue(output.Errors[0].Key), aws.StringValue(output.Errors[0].Message))
+		}
+		if err != nil {
+			return nil, errorj.DeleteFromTableError.Wrap(err, "failed to delete expired objects from s3").
+				WithProperty(errorj.DBInfo, &ErrorPayload{
+					Bucket:    a.config.Bucket,
+					Statement: fmt.Sprintf("prefix: %s", prefix),
+				})
+		}
+	}
+
+	return report, nil
+}
+
 //ValidateWritePermission tries to create temporary file and remove it.
 //returns nil if file creation was successful.
 func (a *S3) ValidateWritePermission() error {
@@ -0,0 +1,297 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/uuid"
+	"github.com/pkg/sftp"
+	"go.uber.org/atomic"
+	"golang.org/x/crypto/ssh"
+)
+
+//SFTPConfig is a dto for deserialized SFTP destination configuration
+//authentication parameters are the same as in SSH tunnel configuration
+type SFTPConfig struct {
+	Host     string `mapstructure:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
+	Port     int    `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
+	User     string `mapstructure:"user,omitempty" json:"user,omitempty" yaml:"user,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	//PrivateKey is a PEM encoded private key or a path to the key file
+	PrivateKey string `mapstructure:"private_key,omitempty" json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	Passphrase string `mapstructure:"passphrase,omitempty" json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+	//KnownHostKey is SFTP server public key in authorized_keys format. Connection fails if the server presents another key
+	KnownHostKey string `mapstructure:"known_host_key,omitempty" json:"known_host_key,omitempty" yaml:"known_host_key,omitempty"`
+
+	FileDropConfig `mapstructure:",squash" yaml:"-,inline"`
+}
+
+//Validate returns err if invalid
+func (sc *SFTPConfig) Validate() error {
+	if sc == nil {
+		return errors.New("SFTP config is required")
+	}
+	if sc.Host == "" {
+		return errors.New("SFTP host is required parameter")
+	}
+	if sc.User == "" {
+		return errors.New("SFTP user is required parameter")
+	}
+	if sc.Password == "" && sc.PrivateKey == "" {
+		return errors.New("SFTP password or private_key is required parameter")
+	}
+	if sc.KnownHostKey == "" {
+		return errors.New("SFTP known_host_key is required parameter: SFTP server public key (e.g. output of 'ssh-keyscan -t ed25519 host')")
+	}
+	if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(sc.KnownHostKey)); err != nil {
+		return fmt.Errorf("malformed SFTP known_host_key: %v", err)
+	}
+
+	return sc.FileDropConfig.Validate()
+}
+
+//sshConfig returns SSH connection configuration
+func (sc *SFTPConfig) sshConfig() *SSHTunnelConfig {
+	return &SSHTunnelConfig{
+		Host:         sc.Host,
+		Port:         sc.Port,
+		User:         sc.User,
+		Password:     sc.Password,
+		PrivateKey:   sc.PrivateKey,
+		Passphrase:   sc.Passphrase,
+		KnownHostKey: sc.KnownHostKey,
+	}
+}
+
+//SFTP is an ObjectStorage which stores objects as files on the SFTP server under the configured directory
+//objects are written into temporary files and then renamed. Broken connection is re-established on the next call
+type SFTP struct {
+	config       *SFTPConfig
+	clientConfig *ssh.ClientConfig
+
+	mutex     *sync.Mutex
+	sshClient *ssh.Client
+	client    *sftp.Client
+
+	closed *atomic.Bool
+}
+
+//NewSFTP returns SFTP adapter with established connection
+func NewSFTP(config *SFTPConfig) (*SFTP, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	clientConfig, err := config.sshConfig().clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SFTP{
+		config:       config,
+		clientConfig: clientConfig,
+		mutex:        &sync.Mutex{},
+		closed:       atomic.NewBool(false),
+	}
+
+	if _, err := s.getClient(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+//PutObject atomically writes object into the file
+func (s *SFTP) PutObject(key string, data []byte) error {
+	client, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	filePath := s.path(key)
+	if err := client.MkdirAll(path.Dir(filePath)); err != nil {
+		return s.resetOnError(client, fmt.Errorf("error creating directory for %s: %v", key, err))
+	}
+
+	tmpPath := path.Join(path.Dir(filePath), "."+path.Base(filePath)+"."+uuid.New()+localTmpFileSuffix)
+	if err := s.writeFile(client, tmpPath, data); err != nil {
+		_ = client.Remove(tmpPath)
+		return s.resetOnError(client, fmt.Errorf("error writing file %s: %v", key, err))
+	}
+
+	if err := client.PosixRename(tmpPath, filePath); err != nil {
+		//server doesn't support posix-rename@openssh.com extension: SFTP rename fails if target exists
+		if renameErr := client.Rename(tmpPath, filePath); renameErr != nil {
+			_ = client.Remove(tmpPath)
+			return s.resetOnError(client, fmt.Errorf("error renaming file %s: %v", key, err))
+		}
+	}
+
+	return nil
+}
+
+func (s *SFTP) writeFile(client *sftp.Client, filePath string, data []byte) error {
+	file, err := client.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+//GetObject returns file content or ErrObjectNotExist
+func (s *SFTP) GetObject(key string) ([]byte, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := client.Open(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrObjectNotExist
+		}
+		return nil, s.resetOnError(client, fmt.Errorf("error opening file %s: %v", key, err))
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, s.resetOnError(client, fmt.Errorf("error reading file %s: %v", key, err))
+	}
+
+	return data, nil
+}
+
+//DeleteObject removes the file. Returns nil if file doesn't exist
+func (s *SFTP) DeleteObject(key string) error {
+	client, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return s.resetOnError(client, fmt.Errorf("error removing file %s: %v", key, err))
+	}
+
+	return nil
+}
+
+//ListObjects returns keys of files with the prefix. Temporary files are skipped
+func (s *SFTP) ListObjects(prefix string) ([]string, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	root := s.path("")
+	var keys []string
+	walker := client.Walk(root)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, s.resetOnError(client, fmt.Errorf("error listing files with prefix %s: %v", prefix, err))
+		}
+
+		info := walker.Stat()
+		if info.IsDir() || strings.HasSuffix(info.Name(), localTmpFileSuffix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), root), "/")
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *SFTP) path(key string) string {
+	directory := s.config.Directory
+	if directory == "" {
+		directory = "."
+	}
+
+	return path.Join(directory, key)
+}
+
+//getClient returns connected SFTP client. Connects if there is no connection
+func (s *SFTP) getClient() (*sftp.Client, error) {
+	if s.closed.Load() {
+		return nil, errors.New("attempt to use closed SFTP instance")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	sshClient, err := ssh.Dial("tcp", s.config.sshConfig().address(), s.clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to SFTP server %s: %v", s.config.sshConfig().address(), err)
+	}
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("error starting SFTP session on %s: %v", s.config.sshConfig().address(), err)
+	}
+
+	s.sshClient = sshClient
+	s.client = client
+	return client, nil
+}
+
+//resetOnError closes the broken client so the connection is re-established on the next call and returns err
+func (s *SFTP) resetOnError(broken *sftp.Client, err error) error {
+	if _, statErr := broken.Getwd(); statErr == nil {
+		//connection is alive: the error isn't related to the connection
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.client == broken {
+		s.closeClient()
+	}
+
+	return err
+}
+
+func (s *SFTP) closeClient() {
+	if s.client != nil {
+		_ = s.client.Close()
+		s.client = nil
+	}
+	if s.sshClient != nil {
+		_ = s.sshClient.Close()
+		s.sshClient = nil
+	}
+}
+
+//Close closes SFTP connection
+func (s *SFTP) Close() error {
+	s.closed.Store(true)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closeClient()
+	return nil
+}
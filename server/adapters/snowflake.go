@@ -11,6 +11,7 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -38,9 +39,14 @@ const (
 	createSFTableTemplate               = `CREATE TABLE %s.%s (%s)`
 	insertSFTemplate                    = `INSERT INTO %s.%s (%s) VALUES %s`
 	deleteSFTemplate                    = `DELETE FROM %s.%s WHERE %s`
+	countExpiredSFTemplate              = `SELECT COUNT(*) FROM %s.%s WHERE %s < ?`
+	deleteExpiredSFTemplate             = `DELETE FROM %s.%s WHERE %s < ?`
 	dropSFTableTemplate                 = `DROP TABLE %s%s.%s`
 	truncateSFTableTemplate             = `TRUNCATE TABLE IF EXISTS %s.%s`
 	updateSFTemplate                    = `UPDATE %s.%s SET %s WHERE %s = ?`
+
+	//insertFromValuesSFTemplate is used for tables with semi-structured columns because PARSE_JSON isn't allowed in VALUES clause
+	insertFromValuesSFTemplate = `INSERT INTO %s.%s (%s) SELECT %s FROM VALUES %s`
 )
 
 var (
@@ -50,8 +56,13 @@ var (
 		typing.FLOAT64:   "double precision",
 		typing.TIMESTAMP: "timestamp(6)",
 		typing.BOOL:      "boolean",
+		typing.JSON:      "variant",
+		typing.ARRAY:     "variant",
 		typing.UNKNOWN:   "text",
 	}
+
+	//snowflakeSemiStructuredTypes are types which values are parsed from JSON strings with PARSE_JSON
+	snowflakeSemiStructuredTypes = map[string]bool{"variant": true, "object": true, "array": true}
 )
 
 //SnowflakeConfig dto for deserialized datasource config for Snowflake
@@ -365,7 +376,7 @@ func (s *Snowflake) Insert(insertContext *InsertContext) error {
 
 // insertSingle inserts provided object into Snowflake
 func (s *Snowflake) insertSingle(eventContext *EventContext) error {
-	var columnNames, placeholders []string
+	var placeholders []string
 	var values []interface{}
 	columns := make([]string, 0, len(eventContext.ProcessedEvent))
 	for name, _ := range eventContext.ProcessedEvent {
@@ -374,17 +385,14 @@ func (s *Snowflake) insertSingle(eventContext *EventContext) error {
 	sort.Strings(columns)
 	for _, name := range columns {
 		value := eventContext.ProcessedEvent[name]
-		columnNames = append(columnNames, reformatValue(name))
-
 		castClause := s.getCastClause(name, eventContext.Table.Columns[name])
 		placeholders = append(placeholders, "?"+castClause)
-		values = append(values, value)
+		values = append(values, jsonValue(value))
 	}
 
-	header := strings.Join(columnNames, ", ")
 	placeholderStr := strings.Join(placeholders, ", ")
 
-	statement := fmt.Sprintf(insertSFTemplate, s.config.Schema, reformatValue(eventContext.Table.Name), header, "("+placeholderStr+")")
+	statement := s.insertStatement(eventContext.Table, reformatValue(eventContext.Table.Name), columns, "("+placeholderStr+")")
 	s.queryLogger.LogQueryWithValues(statement, values)
 
 	_, err := s.dataSource.ExecContext(s.ctx, statement, values...)
@@ -508,6 +516,43 @@ func (s *Snowflake) Truncate(tableName string) error {
 	return nil
 }
 
+//Query executes read query and returns result rows
+func (s *Snowflake) Query(query string) ([]map[string]interface{}, error) {
+	sqlParams := SqlParams{
+		dataSource:  s.dataSource,
+		queryLogger: s.queryLogger,
+		ctx:         s.ctx,
+	}
+	rows, err := sqlParams.commonQuery(query)
+	if err != nil {
+		return nil, errorj.QueryError.Wrap(err, "failed to execute query").
+			WithProperty(errorj.DBInfo, &ErrorPayload{
+				Schema:    s.config.Schema,
+				Statement: query,
+			})
+	}
+
+	return rows, nil
+}
+
+//DeleteExpired deletes rows which are older than expiredBefore
+func (s *Snowflake) DeleteExpired(rule *RetentionRule, expiredBefore time.Time, dryRun bool) (*RetentionReport, error) {
+	if err := requireRetentionTable(rule); err != nil {
+		return nil, err
+	}
+
+	sqlParams := &SqlParams{ctx: s.ctx, dataSource: s.dataSource, queryLogger: s.queryLogger}
+	rows, err := deleteExpiredRows(sqlParams,
+		fmt.Sprintf(countExpiredSFTemplate, s.config.Schema, reformatValue(rule.Table), reformatValue(rule.Field)),
+		fmt.Sprintf(deleteExpiredSFTemplate, s.config.Schema, reformatValue(rule.Table), reformatValue(rule.Field)),
+		expiredBefore.UTC(), dryRun, &ErrorPayload{Schema: s.config.Schema, Table: rule.Table})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RetentionReport{Rule: rule, DryRun: dryRun, ExpiredBefore: expiredBefore, Rows: rows}, nil
+}
+
 //Update one record in Snowflake
 func (s *Snowflake) Update(table *Table, object map[string]interface{}, whereKey string, whereValue interface{}) error {
 	columnNames := make([]string, len(object), len(object))
@@ -522,8 +567,8 @@ func (s *Snowflake) Update(table *Table, object map[string]interface{}, whereKey
 	for i, name := range columns {
 		value := object[name]
 		castClause := s.getCastClause(name, table.Columns[name])
-		columnNames[i] = reformatValue(name) + "= ?" + castClause
-		values[i] = value
+		columnNames[i] = reformatValue(name) + "= " + s.wrapSemiStructured(name, table.Columns[name], "?"+castClause)
+		values[i] = jsonValue(value)
 	}
 	values[len(values)-1] = whereValue
 
@@ -592,7 +637,7 @@ func (s *Snowflake) bulkInsertInTransaction(wrappedTx *Transaction, table *Table
 
 		for i, column := range unformattedColumnNames {
 			value, _ := row[column]
-			valueArgs = append(valueArgs, value)
+			valueArgs = append(valueArgs, jsonValue(value))
 			castClause := s.getCastClause(column, table.Columns[column])
 
 			_, _ = placeholdersBuilder.WriteString("?" + castClause)
@@ -743,12 +788,7 @@ func (s *Snowflake) renameTableInTransaction(wrappedTx *Transaction, ifExists bo
 
 //executeInsertInTransaction execute insert with insertTemplate
 func (s *Snowflake) executeInsertInTransaction(wrappedTx *Transaction, table *Table, headerWithoutQuotes []string, placeholders string, valueArgs []interface{}) error {
-	var quotedHeader []string
-	for _, columnName := range headerWithoutQuotes {
-		quotedHeader = append(quotedHeader, reformatValue(columnName))
-	}
-
-	statement := fmt.Sprintf(insertSFTemplate, s.config.Schema, table.Name, strings.Join(quotedHeader, ", "), placeholders)
+	statement := s.insertStatement(table, table.Name, headerWithoutQuotes, placeholders)
 
 	s.queryLogger.LogQueryWithValues(statement, valueArgs)
 
@@ -810,11 +850,54 @@ func (s *Snowflake) getCastClause(name string, column typing.SQLColumn) string {
 		castType = column
 		ok = true
 	}
-	if ok {
-		return "::" + castType.Type
+	if !ok {
+		castType = column
+	}
+	//semi-structured values are passed as JSON strings and parsed with PARSE_JSON
+	if s.isSemiStructured(name, column) {
+		return ""
+	}
+
+	return "::" + castType.Type
+}
+
+//isSemiStructured returns true if column has VARIANT, OBJECT or ARRAY type
+func (s *Snowflake) isSemiStructured(name string, column typing.SQLColumn) bool {
+	sqlType := column.Type
+	if overriddenSQLType, ok := s.sqlTypes[name]; ok {
+		sqlType = overriddenSQLType.Type
+	}
+
+	return snowflakeSemiStructuredTypes[strings.ToLower(strings.TrimSpace(sqlType))]
+}
+
+//wrapSemiStructured returns PARSE_JSON(expression) for semi-structured columns or expression as is
+func (s *Snowflake) wrapSemiStructured(name string, column typing.SQLColumn, expression string) string {
+	if s.isSemiStructured(name, column) {
+		return "PARSE_JSON(" + expression + ")"
+	}
+
+	return expression
+}
+
+//insertStatement returns INSERT VALUES statement or INSERT SELECT FROM VALUES statement if the table has semi-structured columns
+//placeholders is a string like (?, ?), (?, ?)
+func (s *Snowflake) insertStatement(table *Table, tableName string, headerWithoutQuotes []string, placeholders string) string {
+	var quotedHeader, selectColumns []string
+	hasSemiStructured := false
+	for i, columnName := range headerWithoutQuotes {
+		quotedHeader = append(quotedHeader, reformatValue(columnName))
+		selectColumns = append(selectColumns, s.wrapSemiStructured(columnName, table.Columns[columnName], fmt.Sprintf("column%d", i+1)))
+		if s.isSemiStructured(columnName, table.Columns[columnName]) {
+			hasSemiStructured = true
+		}
+	}
+
+	if hasSemiStructured {
+		return fmt.Sprintf(insertFromValuesSFTemplate, s.config.Schema, tableName, strings.Join(quotedHeader, ", "), strings.Join(selectColumns, ", "), placeholders)
 	}
 
-	return "::" + column.Type
+	return fmt.Sprintf(insertSFTemplate, s.config.Schema, tableName, strings.Join(quotedHeader, ", "), placeholders)
 }
 
 //columnDDL returns column DDL (column name, mapped sql type)
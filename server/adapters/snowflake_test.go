@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"github.com/jitsucom/jitsu/server/typing"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -87,3 +88,29 @@ func TestReformatToParam(t *testing.T) {
 		})
 	}
 }
+
+func TestInsertStatement(t *testing.T) {
+	s := &Snowflake{config: &SnowflakeConfig{Schema: "public"}, sqlTypes: typing.SQLTypes{}}
+	tests := []struct {
+		name     string
+		columns  Columns
+		expected string
+	}{
+		{
+			"plain columns",
+			Columns{"id": typing.SQLColumn{Type: "text"}, "value": typing.SQLColumn{Type: "bigint"}},
+			`INSERT INTO public.events (id, value) VALUES (?, ?)`,
+		},
+		{
+			"semi-structured columns",
+			Columns{"id": typing.SQLColumn{Type: "text"}, "value": typing.SQLColumn{Type: "VARIANT"}},
+			`INSERT INTO public.events (id, value) SELECT column1, PARSE_JSON(column2) FROM VALUES (?, ?)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := &Table{Name: "events", Columns: tt.columns}
+			require.Equal(t, tt.expected, s.insertStatement(table, table.Name, []string{"id", "value"}, "(?, ?)"))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	//sqsMaxBatchSize is a max entries count in one SendMessageBatch request
+	sqsMaxBatchSize = 10
+	//sqsMaxBatchBytes is a max total size of messages (bodies and attributes) in one SendMessageBatch request
+	//it is also a max size of one message
+	sqsMaxBatchBytes = 256 * 1024
+	//sqsStringAttributeType is a data type of message attributes
+	sqsStringAttributeType = "String"
+	//sqsDefaultMessageGroupID is used for FIFO queues if key template isn't configured
+	sqsDefaultMessageGroupID = "jitsu"
+)
+
+//SQSConfig is a dto for parsing AWS SQS destination configuration
+type SQSConfig struct {
+	AccessKeyID string `mapstructure:"access_key_id,omitempty" json:"access_key_id,omitempty" yaml:"access_key_id,omitempty"`
+	SecretKey   string `mapstructure:"secret_access_key,omitempty" json:"secret_access_key,omitempty" yaml:"secret_access_key,omitempty"`
+	Region      string `mapstructure:"region,omitempty" json:"region,omitempty" yaml:"region,omitempty"`
+	//Endpoint is a custom SQS endpoint (e.g. ElasticMQ http://localhost:9324)
+	Endpoint string `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	//QueueURL is a queue URL template
+	QueueURL      string `mapstructure:"queue_url,omitempty" json:"queue_url,omitempty" yaml:"queue_url,omitempty"`
+	MessageConfig `mapstructure:",squash" yaml:"-,inline"`
+}
+
+//Validate returns err if invalid
+func (sc *SQSConfig) Validate() error {
+	if sc == nil {
+		return errors.New("SQS config is required")
+	}
+	if sc.Region == "" {
+		return errors.New("SQS region is required parameter")
+	}
+	if sc.QueueURL == "" {
+		return errors.New("SQS queue_url is required parameter")
+	}
+	if (sc.AccessKeyID == "") != (sc.SecretKey == "") {
+		return errors.New("SQS access_key_id and secret_access_key must be set together")
+	}
+
+	return sc.Batch.Validate()
+}
+
+//SQS is a MessagePublisher for AWS SQS (and SQS compatible brokers)
+//messages are sent with SendMessageBatch API. Key is used as a message group ID of FIFO queues
+type SQS struct {
+	client *sqs.SQS
+}
+
+//NewSQS returns configured SQS publisher
+//credentials from the environment are used if access keys aren't configured
+func NewSQS(config *SQSConfig) (*SQS, error) {
+	awsConfig := aws.NewConfig().WithRegion(config.Region)
+	if config.AccessKeyID != "" {
+		awsConfig.WithCredentials(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretKey, ""))
+	}
+	if config.Endpoint != "" {
+		awsConfig.WithEndpoint(config.Endpoint)
+	}
+
+	sqsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %v", err)
+	}
+
+	return &SQS{client: sqs.New(sqsSession)}, nil
+}
+
+//Publish sends messages with SendMessageBatch requests. Messages are split into several requests
+//if their total size exceeds SendMessageBatch limit (256KB). Messages which exceed the limit alone are rejected
+//entries which have been failed because of the sender fault are returned as MessageRejectedError
+func (s *SQS) Publish(messages []*Message) ([]error, error) {
+	queueURL := messages[0].Topic
+	errs := make([]error, len(messages))
+
+	var requestErr error
+	requests, failedRequests, oversized := 0, 0, 0
+	var chunk []int
+	chunkBytes := 0
+	send := func() {
+		if len(chunk) == 0 {
+			return
+		}
+
+		requests++
+		if err := s.sendBatch(queueURL, messages, chunk, errs); err != nil {
+			failedRequests++
+			requestErr = err
+			for _, i := range chunk {
+				errs[i] = err
+			}
+		}
+		chunk = nil
+		chunkBytes = 0
+	}
+
+	for i, message := range messages {
+		size := sqsMessageSize(message)
+		if size > sqsMaxBatchBytes {
+			oversized++
+			errs[i] = &MessageRejectedError{Err: fmt.Errorf("SQS message size %d bytes exceeds limit %d bytes", size, sqsMaxBatchBytes)}
+			continue
+		}
+		if chunkBytes+size > sqsMaxBatchBytes {
+			send()
+		}
+		chunk = append(chunk, i)
+		chunkBytes += size
+	}
+	send()
+
+	if oversized == 0 && requests == failedRequests {
+		return nil, requestErr
+	}
+
+	return errs, nil
+}
+
+//sendBatch sends messages with indices in one SendMessageBatch request and puts entries errors into errs
+func (s *SQS) sendBatch(queueURL string, messages []*Message, indices []int, errs []error) error {
+	fifo := strings.HasSuffix(queueURL, ".fifo")
+	entries := make([]*sqs.SendMessageBatchRequestEntry, 0, len(indices))
+	for _, i := range indices {
+		message := messages[i]
+		entry := &sqs.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(i)),
+			MessageBody: aws.String(string(message.Body)),
+		}
+		if len(message.Attributes) > 0 {
+			entry.MessageAttributes = map[string]*sqs.MessageAttributeValue{}
+			for name, value := range message.Attributes {
+				entry.MessageAttributes[name] = &sqs.MessageAttributeValue{DataType: aws.String(sqsStringAttributeType), StringValue: aws.String(value)}
+			}
+		}
+		if fifo {
+			groupID := message.Key
+			if groupID == "" {
+				groupID = sqsDefaultMessageGroupID
+			}
+			entry.MessageGroupId = aws.String(groupID)
+			if message.ID != "" {
+				entry.MessageDeduplicationId = aws.String(message.ID)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	output, err := s.client.SendMessageBatch(&sqs.SendMessageBatchInput{QueueUrl: aws.String(queueURL), Entries: entries})
+	if err != nil {
+		return err
+	}
+
+	for _, failed := range output.Failed {
+		i, err := strconv.Atoi(aws.StringValue(failed.Id))
+		if err != nil || i < 0 || i >= len(messages) {
+			continue
+		}
+
+		entryErr := fmt.Errorf("SQS message has been failed [%s]: %s", aws.StringValue(failed.Code), aws.StringValue(failed.Message))
+		if aws.BoolValue(failed.SenderFault) {
+			entryErr = &MessageRejectedError{Err: entryErr}
+		}
+		errs[i] = entryErr
+	}
+
+	return nil
+}
+
+//sqsMessageSize returns message size which is counted by SQS: body and attributes names, types and values
+func sqsMessageSize(message *Message) int {
+	size := len(message.Body)
+	for name, value := range message.Attributes {
+		size += len(name) + len(sqsStringAttributeType) + len(value)
+	}
+
+	return size
+}
+
+//MaxBatchSize returns SQS SendMessageBatch limit
+func (s *SQS) MaxBatchSize() int {
+	return sqsMaxBatchSize
+}
+
+//TestAccess returns err if queue attributes can't be got (queue doesn't exist or access is denied)
+//templated queue URLs aren't checked
+func (s *SQS) TestAccess(queueURL string) error {
+	if strings.Contains(queueURL, "{{") {
+		return nil
+	}
+
+	_, err := s.client.GetQueueAttributes(&sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []*string{aws.String(sqs.QueueAttributeNameQueueArn)},
+	})
+	return err
+}
+
+//Close does nothing
+func (s *SQS) Close() error {
+	return nil
+}
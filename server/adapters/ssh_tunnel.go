@@ -0,0 +1,341 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"go.uber.org/atomic"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultSSHTunnelPort = 22
+	sshTunnelDialTimeout = 30 * time.Second
+	//sshTunnelHealthCheckInterval is an interval of keepalive requests. Broken connection is re-established
+	sshTunnelHealthCheckInterval = 30 * time.Second
+)
+
+//SSHTunnelConfig is a dto for deserialized SSH tunnel (bastion host) configuration
+//Database connections are forwarded through the SSH server so the database doesn't have to be exposed
+type SSHTunnelConfig struct {
+	Host     string `mapstructure:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
+	Port     int    `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
+	User     string `mapstructure:"user,omitempty" json:"user,omitempty" yaml:"user,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	//PrivateKey is a PEM encoded private key or a path to the key file
+	PrivateKey string `mapstructure:"private_key,omitempty" json:"private_key,omitempty" yaml:"private_key,omitempty"`
+	Passphrase string `mapstructure:"passphrase,omitempty" json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
+	//KnownHostKey is SSH server public key in authorized_keys format (e.g. 'ssh-ed25519 AAAA...'). It is required:
+	//connections to SSH servers with other keys are refused
+	KnownHostKey string `mapstructure:"known_host_key,omitempty" json:"known_host_key,omitempty" yaml:"known_host_key,omitempty"`
+}
+
+//Validate returns err if SSH tunnel configuration is invalid
+func (stc *SSHTunnelConfig) Validate() error {
+	if stc == nil {
+		return nil
+	}
+	if stc.Host == "" {
+		return errors.New("ssh_tunnel.host is required parameter")
+	}
+	if stc.User == "" {
+		return errors.New("ssh_tunnel.user is required parameter")
+	}
+	if stc.Password == "" && stc.PrivateKey == "" {
+		return errors.New("ssh_tunnel.password or ssh_tunnel.private_key is required parameter")
+	}
+	if stc.KnownHostKey == "" {
+		return errors.New("ssh_tunnel.known_host_key is required parameter: SSH server public key (e.g. output of 'ssh-keyscan -t ed25519 host')")
+	}
+	if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(stc.KnownHostKey)); err != nil {
+		return fmt.Errorf("malformed ssh_tunnel.known_host_key: %v", err)
+	}
+
+	return nil
+}
+
+//ValidateTLS returns err if TLS hostname verification is configured along with SSH tunnel:
+//the database is connected via the local tunnel address (127.0.0.1) so the server certificate doesn't match the host
+func (stc *SSHTunnelConfig) ValidateTLS(hostnameVerification string) error {
+	if stc == nil || hostnameVerification == "" {
+		return nil
+	}
+
+	return fmt.Errorf("ssh_tunnel can't be used with %s: the database is connected via 127.0.0.1 through the tunnel and the certificate hostname can't be verified", hostnameVerification)
+}
+
+//clientConfig returns ssh client configuration with configured authentication methods
+func (stc *SSHTunnelConfig) clientConfig() (*ssh.ClientConfig, error) {
+	var authMethods []ssh.AuthMethod
+	if stc.PrivateKey != "" {
+		signer, err := stc.signer()
+		if err != nil {
+			return nil, err
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	if stc.Password != "" {
+		authMethods = append(authMethods, ssh.Password(stc.Password))
+	}
+
+	knownKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(stc.KnownHostKey))
+	if err != nil {
+		return nil, fmt.Errorf("malformed ssh_tunnel.known_host_key: %v", err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            stc.User,
+		Auth:            authMethods,
+		HostKeyCallback: ssh.FixedHostKey(knownKey),
+		Timeout:         sshTunnelDialTimeout,
+	}, nil
+}
+
+//signer parses private key from PEM content or from the file
+func (stc *SSHTunnelConfig) signer() (ssh.Signer, error) {
+	key := []byte(stc.PrivateKey)
+	if !strings.Contains(stc.PrivateKey, "PRIVATE KEY") {
+		fileContent, err := ioutil.ReadFile(stc.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ssh_tunnel.private_key file: %v", err)
+		}
+		key = fileContent
+	}
+
+	var signer ssh.Signer
+	var err error
+	if stc.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(stc.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed ssh_tunnel.private_key: %v", err)
+	}
+
+	return signer, nil
+}
+
+func (stc *SSHTunnelConfig) address() string {
+	port := stc.Port
+	if port == 0 {
+		port = defaultSSHTunnelPort
+	}
+	return net.JoinHostPort(stc.Host, strconv.Itoa(port))
+}
+
+//SSHTunnel forwards connections from the local port to the remote address through the SSH server
+//SSH connection is health-checked with keepalive requests and is re-established if it is broken
+type SSHTunnel struct {
+	config       *SSHTunnelConfig
+	clientConfig *ssh.ClientConfig
+	remoteAddr   string
+	listener     net.Listener
+
+	mutex  *sync.Mutex
+	client *ssh.Client
+
+	closed *atomic.Bool
+}
+
+//NewSSHTunnel connects to the SSH server and starts listening on a random local port
+//connections to the local port are forwarded to remoteHost:remotePort
+func NewSSHTunnel(config *SSHTunnelConfig, remoteHost string, remotePort int) (*SSHTunnel, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	clientConfig, err := config.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel := &SSHTunnel{
+		config:       config,
+		clientConfig: clientConfig,
+		remoteAddr:   net.JoinHostPort(remoteHost, strconv.Itoa(remotePort)),
+		mutex:        &sync.Mutex{},
+		closed:       atomic.NewBool(false),
+	}
+
+	if _, err := tunnel.getClient(); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tunnel.closeClient()
+		return nil, fmt.Errorf("error starting SSH tunnel local listener: %v", err)
+	}
+	tunnel.listener = listener
+
+	safego.RunWithRestart(tunnel.accept)
+	safego.RunWithRestart(tunnel.healthCheck)
+
+	logging.Infof("SSH tunnel %s -> %s -> %s has been started", listener.Addr().String(), config.address(), tunnel.remoteAddr)
+	return tunnel, nil
+}
+
+//LocalHost returns local tunnel host
+func (st *SSHTunnel) LocalHost() string {
+	return st.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+//LocalPort returns local tunnel port
+func (st *SSHTunnel) LocalPort() int {
+	return st.listener.Addr().(*net.TCPAddr).Port
+}
+
+//LocalAddr returns local tunnel host:port
+func (st *SSHTunnel) LocalAddr() string {
+	return st.listener.Addr().String()
+}
+
+func (st *SSHTunnel) accept() {
+	for {
+		conn, err := st.listener.Accept()
+		if err != nil {
+			if st.closed.Load() {
+				return
+			}
+
+			logging.Errorf("SSH tunnel to %s: error accepting local connection: %v", st.remoteAddr, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		safego.Run(func() {
+			st.forward(conn)
+		})
+	}
+}
+
+//forward copies data between local connection and remote connection through SSH server
+func (st *SSHTunnel) forward(localConn net.Conn) {
+	defer localConn.Close()
+
+	remoteConn, err := st.dialRemote()
+	if err != nil {
+		logging.Errorf("SSH tunnel: error connecting to %s via %s: %v", st.remoteAddr, st.config.address(), err)
+		return
+	}
+	defer remoteConn.Close()
+
+	done := make(chan struct{}, 2)
+	safego.Run(func() {
+		_, _ = io.Copy(remoteConn, localConn)
+		done <- struct{}{}
+	})
+	safego.Run(func() {
+		_, _ = io.Copy(localConn, remoteConn)
+		done <- struct{}{}
+	})
+	<-done
+}
+
+//dialRemote opens connection to the remote address. Reconnects to SSH server once if the connection is broken
+func (st *SSHTunnel) dialRemote() (net.Conn, error) {
+	client, err := st.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.Dial("tcp", st.remoteAddr)
+	if err == nil {
+		return conn, nil
+	}
+
+	st.resetClient(client)
+	client, err = st.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Dial("tcp", st.remoteAddr)
+}
+
+//healthCheck sends keepalive requests and re-establishes broken SSH connection
+func (st *SSHTunnel) healthCheck() {
+	ticker := time.NewTicker(sshTunnelHealthCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if st.closed.Load() {
+			return
+		}
+
+		client, err := st.getClient()
+		if err != nil {
+			logging.Errorf("SSH tunnel: error reconnecting to %s: %v", st.config.address(), err)
+			continue
+		}
+
+		if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			logging.Warnf("SSH tunnel: connection to %s is broken: %v. Reconnecting..", st.config.address(), err)
+			st.resetClient(client)
+		}
+	}
+}
+
+//getClient returns current SSH client or connects a new one
+func (st *SSHTunnel) getClient() (*ssh.Client, error) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if st.closed.Load() {
+		return nil, errors.New("SSH tunnel is closed")
+	}
+
+	if st.client != nil {
+		return st.client, nil
+	}
+
+	client, err := ssh.Dial("tcp", st.config.address(), st.clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to SSH server %s: %v", st.config.address(), err)
+	}
+
+	st.client = client
+	return client, nil
+}
+
+//resetClient closes broken client (if it is still the current one) so the next getClient call reconnects
+func (st *SSHTunnel) resetClient(broken *ssh.Client) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if st.client == broken {
+		_ = st.client.Close()
+		st.client = nil
+	}
+}
+
+func (st *SSHTunnel) closeClient() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	if st.client != nil {
+		_ = st.client.Close()
+		st.client = nil
+	}
+}
+
+//Close stops local listener and closes SSH connection
+func (st *SSHTunnel) Close() error {
+	if st == nil || st.closed.Swap(true) {
+		return nil
+	}
+
+	err := st.listener.Close()
+	st.closeClient()
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/jitsucom/jitsu/server/typing"
 	"reflect"
 	"sort"
+	"time"
 )
 
 //Columns is a list of columns representation
@@ -15,6 +16,8 @@ type TableField struct {
 	Field string      `json:"field,omitempty"`
 	Type  string      `json:"type,omitempty"`
 	Value interface{} `json:"value,omitempty"`
+	//Masked is a privacy policy action which was applied to the field value
+	Masked string `json:"masked,omitempty"`
 }
 
 //Table is a dto for DWH Table representation
@@ -26,6 +29,9 @@ type Table struct {
 	PKFields       map[string]bool
 	PrimaryKeyName string
 	Partition      schema.DatePartition
+	//PartitionExpiration is a max age of partitions (if the destination supports partitions expiration)
+	PartitionExpiration time.Duration
+	ClusteringFields    []string
 
 	DeletePkFields bool
 }
@@ -62,12 +68,15 @@ func (t *Table) Clone() *Table {
 	}
 
 	return &Table{
-		Schema:         t.Schema,
-		Name:           t.Name,
-		Columns:        clonedColumns,
-		PKFields:       clonedPkFields,
-		PrimaryKeyName: t.PrimaryKeyName,
-		DeletePkFields: t.DeletePkFields,
+		Schema:              t.Schema,
+		Name:                t.Name,
+		Columns:             clonedColumns,
+		PKFields:            clonedPkFields,
+		PrimaryKeyName:      t.PrimaryKeyName,
+		Partition:           t.Partition,
+		PartitionExpiration: t.PartitionExpiration,
+		ClusteringFields:    t.ClusteringFields,
+		DeletePkFields:      t.DeletePkFields,
 	}
 }
 
@@ -13,6 +13,8 @@ var (
 		typing.FLOAT64:   "string",
 		typing.TIMESTAMP: "string",
 		typing.BOOL:      "string",
+		typing.JSON:      "string",
+		typing.ARRAY:     "string",
 		typing.UNKNOWN:   "string",
 	}
 )
@@ -23,6 +25,8 @@ type WebHookConfig struct {
 	Method  string            `mapstructure:"method,omitempty" json:"method,omitempty" yaml:"method,omitempty"`
 	Body    string            `mapstructure:"body,omitempty" json:"body,omitempty" yaml:"body,omitempty"`
 	Headers map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty" yaml:"headers,omitempty"`
+	//Batch enables sending JSON arrays of events bodies
+	Batch *HTTPBatchConfig `mapstructure:"batch,omitempty" json:"batch,omitempty" yaml:"batch,omitempty"`
 }
 
 //Validate returns err if invalid
@@ -31,7 +35,7 @@ func (whc *WebHookConfig) Validate() error {
 		return errors.New("webHook config is required")
 	}
 
-	return nil
+	return whc.Batch.Validate()
 }
 
 //WebHook is an adapter for sending HTTP requests with configurable HTTP parameters (URL, body, headers)
@@ -48,6 +52,7 @@ func NewWebHook(config *WebHookConfig, httpAdapterConfiguration *HTTPAdapterConf
 	}
 
 	httpAdapterConfiguration.HTTPReqFactory = httpReqFactory
+	httpAdapterConfiguration.Batch = config.Batch
 
 	httpAdapter, err := NewHTTPAdapter(httpAdapterConfiguration)
 	if err != nil {
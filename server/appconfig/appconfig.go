@@ -93,6 +93,9 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("server.max_columns", 100)
 	viper.SetDefault("server.max_event_size", 51200)
 	viper.SetDefault("server.configurator_urn", "/configurator")
+	viper.SetDefault("server.anonymous_id_cookie.same_site", "none")
+	viper.SetDefault("server.anonymous_id_cookie.ttl_days", 0)
+	viper.SetDefault("server.anonymous_id_cookie.http_only", false)
 	//unique IDs
 	viper.SetDefault("server.fields_configuration.unique_id_field", "/eventn_ctx/event_id||/eventn_ctx_event_id||/event_id")
 	viper.SetDefault("server.fields_configuration.user_agent_path", "/eventn_ctx/user_agent||/user_agent")
@@ -246,6 +249,7 @@ func setDefaultParams(containerized bool) {
 		viper.SetDefault("sql_debug_log.ddl.path", "/home/eventnative/data/logs")
 		viper.SetDefault("sql_debug_log.queries.path", "/home/eventnative/data/logs")
 		viper.SetDefault("server.volumes.workspace", "jitsu_workspace")
+		viper.SetDefault("server.file_destinations.base_dir", "/home/eventnative/data/files")
 	} else {
 		viper.SetDefault("server.static_files_dir", "./web")
 
@@ -256,6 +260,7 @@ func setDefaultParams(containerized bool) {
 		viper.SetDefault("server.config.path", "./config")
 		viper.SetDefault("server.plugins_cache", "./cache")
 		viper.SetDefault("singer-bridge.venv_dir", "./venv")
+		viper.SetDefault("server.file_destinations.base_dir", "./files")
 		viper.SetDefault("singer-bridge.log.path", "./logs")
 		viper.SetDefault("airbyte-bridge.log.path", "./logs")
 		workingDir, _ := os.Getwd()
@@ -0,0 +1,268 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/notifications"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//State is a circuit breaker state
+type State string
+
+const (
+	//Closed - events are consumed and sent to the destination
+	Closed State = "closed"
+	//Open - events consumption is paused because of the destination errors
+	Open State = "open"
+	//HalfOpen - limited count of probe events is consumed for checking the destination
+	HalfOpen State = "half_open"
+)
+
+const (
+	defaultErrorRate      = 0.5
+	defaultMinEvents      = 20
+	defaultWindow         = time.Minute
+	defaultOpenTimeout    = 30 * time.Second
+	defaultHalfOpenProbes = 5
+)
+
+//metricValues are values of circuit breaker state metric
+var metricValues = map[State]int{Closed: 0, HalfOpen: 1, Open: 2}
+
+//Status is a dto for circuit breaker state serialization
+type Status struct {
+	State     State  `json:"state"`
+	Successes int    `json:"successes"`
+	Failures  int    `json:"failures"`
+	OpenedAt  string `json:"opened_at,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+//CircuitBreaker tracks destination events results and opens the circuit when the error rate in the window exceeds the threshold
+//while the circuit is open events consumption is paused. After the open timeout probe events are consumed (half-open state):
+//the circuit is closed when all probes succeed and is opened again after the first failure
+//all funcs are nil-safe: nil CircuitBreaker is always closed
+type CircuitBreaker struct {
+	destinationID   string
+	destinationType string
+
+	errorRate      float64
+	minEvents      int
+	window         time.Duration
+	openTimeout    time.Duration
+	halfOpenProbes int
+
+	mutex       sync.Mutex
+	state       State
+	windowStart time.Time
+	successes   int
+	failures    int
+	openedAt    time.Time
+	lastError   string
+	//probes is a count of consumed probe events, probeSuccesses is a count of succeeded ones in the half-open state
+	probes         int
+	probeSuccesses int
+	halfOpenedAt   time.Time
+}
+
+//New returns configured CircuitBreaker with default values or nil if circuit breaker isn't configured
+func New(destinationID, destinationType string, cbConfig *config.CircuitBreaker) (*CircuitBreaker, error) {
+	if cbConfig == nil {
+		return nil, nil
+	}
+
+	if err := cbConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	cb := &CircuitBreaker{
+		destinationID:   destinationID,
+		destinationType: destinationType,
+		errorRate:       defaultErrorRate,
+		minEvents:       defaultMinEvents,
+		window:          defaultWindow,
+		openTimeout:     defaultOpenTimeout,
+		halfOpenProbes:  defaultHalfOpenProbes,
+		state:           Closed,
+		windowStart:     timestamp.Now(),
+	}
+	if cbConfig.ErrorRate > 0 {
+		cb.errorRate = cbConfig.ErrorRate
+	}
+	if cbConfig.MinEvents > 0 {
+		cb.minEvents = cbConfig.MinEvents
+	}
+	if cbConfig.Window != "" {
+		cb.window, _ = time.ParseDuration(cbConfig.Window)
+	}
+	if cbConfig.OpenTimeout != "" {
+		cb.openTimeout, _ = time.ParseDuration(cbConfig.OpenTimeout)
+	}
+	if cbConfig.HalfOpenProbes > 0 {
+		cb.halfOpenProbes = cbConfig.HalfOpenProbes
+	}
+
+	metrics.SetCircuitBreakerState(destinationType, destinationID, metricValues[Closed])
+	return cb, nil
+}
+
+//Allow returns true if an event can be consumed: the circuit is closed or a probe event is allowed in the half-open state
+func (cb *CircuitBreaker) Allow() bool {
+	if cb == nil {
+		return true
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	switch cb.currentState() {
+	case Open:
+		return false
+	case HalfOpen:
+		if cb.probes >= cb.halfOpenProbes {
+			//probes results haven't been received (e.g. events have been skipped): allow new probes after the timeout
+			if timestamp.Now().Sub(cb.halfOpenedAt) < cb.openTimeout {
+				return false
+			}
+			cb.probes = 0
+			cb.halfOpenedAt = timestamp.Now()
+		}
+		cb.probes++
+		return true
+	default:
+		return true
+	}
+}
+
+//IsOpen returns true if the circuit is open and requests to the destination shouldn't be sent
+func (cb *CircuitBreaker) IsOpen() bool {
+	if cb == nil {
+		return false
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.currentState() == Open
+}
+
+//Success accounts succeeded event. Closes the circuit if all probes have succeeded
+func (cb *CircuitBreaker) Success() {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.rotateWindow()
+	cb.successes++
+	if cb.currentState() == HalfOpen {
+		cb.probeSuccesses++
+		if cb.probeSuccesses >= cb.halfOpenProbes {
+			cb.transit(Closed, fmt.Sprintf("%d probe events have been sent successfully", cb.probeSuccesses))
+		}
+	}
+}
+
+//Failure accounts failed event. Opens the circuit if the error rate is reached or a probe has failed
+func (cb *CircuitBreaker) Failure(err error) {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.rotateWindow()
+	cb.failures++
+	if err != nil {
+		cb.lastError = err.Error()
+	}
+
+	switch cb.currentState() {
+	case HalfOpen:
+		cb.transit(Open, fmt.Sprintf("probe event has failed: %s", cb.lastError))
+	case Closed:
+		total := cb.successes + cb.failures
+		rate := float64(cb.failures) / float64(total)
+		if total >= cb.minEvents && rate >= cb.errorRate {
+			cb.transit(Open, fmt.Sprintf("%d of %d events have failed in the last %s. Last error: %s", cb.failures, total, cb.window, cb.lastError))
+		}
+	}
+}
+
+//Status returns current state and counters of the window
+func (cb *CircuitBreaker) Status() *Status {
+	if cb == nil {
+		return nil
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	cb.rotateWindow()
+	status := &Status{
+		State:     cb.currentState(),
+		Successes: cb.successes,
+		Failures:  cb.failures,
+		LastError: cb.lastError,
+	}
+	if !cb.openedAt.IsZero() {
+		status.OpenedAt = timestamp.ToISOFormat(cb.openedAt)
+	}
+	return status
+}
+
+//currentState returns state and moves open circuit to the half-open state after the open timeout
+//must be called under the lock
+func (cb *CircuitBreaker) currentState() State {
+	if cb.state == Open && timestamp.Now().Sub(cb.openedAt) >= cb.openTimeout {
+		cb.transit(HalfOpen, "")
+	}
+
+	return cb.state
+}
+
+//rotateWindow resets counters if the window has passed
+//must be called under the lock
+func (cb *CircuitBreaker) rotateWindow() {
+	if now := timestamp.Now(); now.Sub(cb.windowStart) >= cb.window {
+		cb.windowStart = now
+		cb.successes = 0
+		cb.failures = 0
+	}
+}
+
+//transit changes the state, writes logs, metrics and notifications
+//must be called under the lock
+func (cb *CircuitBreaker) transit(state State, details string) {
+	now := timestamp.Now()
+	cb.state = state
+	metrics.SetCircuitBreakerState(cb.destinationType, cb.destinationID, metricValues[state])
+
+	switch state {
+	case Open:
+		cb.openedAt = now
+		metrics.CircuitBreakerOpened(cb.destinationType, cb.destinationID)
+		logging.Warnf("[%s] Circuit breaker is open: %s. Events consumption is paused for %s", cb.destinationID, details, cb.openTimeout)
+		notifications.CircuitBreakerOpened(cb.destinationID, details)
+	case HalfOpen:
+		cb.probes = 0
+		cb.probeSuccesses = 0
+		cb.halfOpenedAt = now
+		logging.Infof("[%s] Circuit breaker is half-open: %d probe events will be sent", cb.destinationID, cb.halfOpenProbes)
+	case Closed:
+		cb.windowStart = now
+		cb.successes = 0
+		cb.failures = 0
+		cb.lastError = ""
+		logging.Infof("[%s] Circuit breaker is closed: %s. Events consumption is resumed", cb.destinationID, details)
+		notifications.CircuitBreakerClosed(cb.destinationID, details)
+	}
+}
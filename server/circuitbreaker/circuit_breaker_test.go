@@ -0,0 +1,155 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       *config.CircuitBreaker
+		expected    *CircuitBreaker
+		expectedErr string
+	}{
+		{
+			"not configured",
+			nil,
+			nil,
+			"",
+		},
+		{
+			"defaults",
+			&config.CircuitBreaker{},
+			&CircuitBreaker{errorRate: 0.5, minEvents: 20, window: time.Minute, openTimeout: 30 * time.Second, halfOpenProbes: 5},
+			"",
+		},
+		{
+			"configured",
+			&config.CircuitBreaker{ErrorRate: 0.2, MinEvents: 10, Window: "5m", OpenTimeout: "10s", HalfOpenProbes: 1},
+			&CircuitBreaker{errorRate: 0.2, minEvents: 10, window: 5 * time.Minute, openTimeout: 10 * time.Second, halfOpenProbes: 1},
+			"",
+		},
+		{
+			"wrong error rate",
+			&config.CircuitBreaker{ErrorRate: 1.5},
+			nil,
+			"circuit_breaker.error_rate must be in (0, 1] range. Got: 1.5",
+		},
+		{
+			"wrong window",
+			&config.CircuitBreaker{Window: "-1m"},
+			nil,
+			"malformed circuit_breaker.window [-1m]: positive duration is expected (e.g. 30s)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := New("dest", "webhook", tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.expected == nil {
+				require.Nil(t, actual)
+				return
+			}
+			require.Equal(t, tt.expected.errorRate, actual.errorRate)
+			require.Equal(t, tt.expected.minEvents, actual.minEvents)
+			require.Equal(t, tt.expected.window, actual.window)
+			require.Equal(t, tt.expected.openTimeout, actual.openTimeout)
+			require.Equal(t, tt.expected.halfOpenProbes, actual.halfOpenProbes)
+			require.Equal(t, Closed, actual.Status().State)
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	timestamp.FreezeTime()
+	timestamp.SetFreezeTime(now)
+	defer timestamp.UnfreezeTime()
+
+	cb, err := New("dest", "webhook", &config.CircuitBreaker{ErrorRate: 0.5, MinEvents: 4, Window: "1m", OpenTimeout: "30s", HalfOpenProbes: 2})
+	require.NoError(t, err)
+
+	//not enough events
+	cb.Failure(errors.New("timeout"))
+	cb.Failure(errors.New("timeout"))
+	cb.Failure(errors.New("timeout"))
+	require.False(t, cb.IsOpen())
+
+	//window has passed: counters are reset
+	timestamp.SetFreezeTime(now.Add(time.Minute))
+	cb.Success()
+	cb.Success()
+	cb.Failure(errors.New("timeout"))
+	require.False(t, cb.IsOpen())
+	require.Equal(t, &Status{State: Closed, Successes: 2, Failures: 1, LastError: "timeout"}, cb.Status())
+
+	//error rate is reached
+	cb.Failure(errors.New("connection refused"))
+	require.True(t, cb.IsOpen())
+	require.False(t, cb.Allow())
+	require.Equal(t, &Status{State: Open, Successes: 2, Failures: 2, OpenedAt: "2021-10-01T10:01:00.000000Z", LastError: "connection refused"}, cb.Status())
+
+	//open timeout has passed: probes are limited
+	timestamp.SetFreezeTime(now.Add(time.Minute + 30*time.Second))
+	require.False(t, cb.IsOpen())
+	require.True(t, cb.Allow())
+	require.True(t, cb.Allow())
+	require.False(t, cb.Allow())
+	require.Equal(t, HalfOpen, cb.Status().State)
+
+	//probe has failed
+	cb.Failure(errors.New("connection refused"))
+	require.True(t, cb.IsOpen())
+	require.False(t, cb.Allow())
+
+	//probes have succeeded
+	timestamp.SetFreezeTime(now.Add(2 * time.Minute))
+	require.True(t, cb.Allow())
+	require.True(t, cb.Allow())
+	cb.Success()
+	require.Equal(t, HalfOpen, cb.Status().State)
+	cb.Success()
+	require.True(t, cb.Allow())
+	require.Equal(t, &Status{State: Closed, OpenedAt: "2021-10-01T10:01:30.000000Z"}, cb.Status())
+}
+
+func TestHalfOpenProbesWithoutResults(t *testing.T) {
+	now := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+	timestamp.FreezeTime()
+	timestamp.SetFreezeTime(now)
+	defer timestamp.UnfreezeTime()
+
+	cb, err := New("dest", "webhook", &config.CircuitBreaker{MinEvents: 1, OpenTimeout: "10s", HalfOpenProbes: 1})
+	require.NoError(t, err)
+
+	cb.Failure(errors.New("timeout"))
+	require.True(t, cb.IsOpen())
+
+	timestamp.SetFreezeTime(now.Add(10 * time.Second))
+	require.True(t, cb.Allow())
+	require.False(t, cb.Allow())
+
+	//probe result hasn't been received (e.g. the event has been skipped)
+	timestamp.SetFreezeTime(now.Add(20 * time.Second))
+	require.True(t, cb.Allow())
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	var cb *CircuitBreaker
+	cb.Success()
+	cb.Failure(errors.New("timeout"))
+	require.True(t, cb.Allow())
+	require.False(t, cb.IsOpen())
+	require.Nil(t, cb.Status())
+}
@@ -2,11 +2,15 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/privacy"
 	"github.com/jitsucom/jitsu/server/utils"
 	"github.com/mitchellh/mapstructure"
 )
@@ -26,6 +30,10 @@ type DestinationConfig struct {
 	CachingConfiguration   *CachingConfiguration    `mapstructure:"caching" json:"caching,omitempty" yaml:"caching,omitempty"`
 	PostHandleDestinations []string                 `mapstructure:"post_handle_destinations,omitempty" json:"post_handle_destinations,omitempty" yaml:"post_handle_destinations,omitempty"`
 	GeoDataResolverID      string                   `mapstructure:"geo_data_resolver_id" json:"geo_data_resolver_id,omitempty" yaml:"geo_data_resolver_id,omitempty"`
+	Privacy                *privacy.Policy          `mapstructure:"privacy" json:"privacy,omitempty" yaml:"privacy,omitempty"`
+	Consent                *consent.Requirements    `mapstructure:"consent" json:"consent,omitempty" yaml:"consent,omitempty"`
+	Retention              *Retention               `mapstructure:"retention" json:"retention,omitempty" yaml:"retention,omitempty"`
+	CircuitBreaker         *CircuitBreaker          `mapstructure:"circuit_breaker" json:"circuit_breaker,omitempty" yaml:"circuit_breaker,omitempty"`
 
 	//Deprecated
 	DataSource map[string]interface{} `mapstructure:"datasource,omitempty" json:"datasource,omitempty" yaml:"datasource,omitempty"`
@@ -101,6 +109,21 @@ type DataLayout struct {
 	TableNameTemplate string   `mapstructure:"table_name_template" json:"table_name_template,omitempty" yaml:"table_name_template,omitempty"`
 	PrimaryKeyFields  []string `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
 	UniqueIDField     string   `mapstructure:"unique_id_field" json:"unique_id_field,omitempty" yaml:"unique_id_field,omitempty"`
+	//UnflattenedPaths are paths to objects and arrays which are written into JSON and ARRAY columns as is
+	UnflattenedPaths []string `mapstructure:"unflattened_paths" json:"unflattened_paths,omitempty" yaml:"unflattened_paths,omitempty"`
+	//Partitioning is a time-based tables partitioning (Postgres, BigQuery)
+	Partitioning *Partitioning `mapstructure:"partitioning" json:"partitioning,omitempty" yaml:"partitioning,omitempty"`
+}
+
+//Partitioning is a model for declarative time-based tables partitioning configuration
+type Partitioning struct {
+	//Field is a timestamp column name. Default value is _timestamp
+	Field string `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	//Granularity is one of HOUR, DAY, WEEK, MONTH, QUARTER, YEAR. Default value is DAY
+	Granularity      string   `mapstructure:"granularity" json:"granularity,omitempty" yaml:"granularity,omitempty"`
+	ClusteringFields []string `mapstructure:"clustering_fields" json:"clustering_fields,omitempty" yaml:"clustering_fields,omitempty"`
+	//RetentionDays is a max age of partitions. Older partitions are dropped. 0 means that partitions aren't dropped
+	RetentionDays int `mapstructure:"retention_days" json:"retention_days,omitempty" yaml:"retention_days,omitempty"`
 }
 
 //UsersRecognition is a model for Users recognition module configuration
@@ -114,6 +137,103 @@ type UsersRecognition struct {
 	CacheTTLMin         int      `mapstructure:"cache_ttl_min" json:"cache_ttl_min,omitempty" yaml:"cache_ttl_min,omitempty"`
 }
 
+//Retention is a model for expired data deletion configuration
+type Retention struct {
+	//Schedule is a cron expression. Default value is @daily
+	Schedule string          `mapstructure:"schedule" json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	DryRun   bool            `mapstructure:"dry_run" json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+	Rules    []RetentionRule `mapstructure:"rules" json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+//RetentionAllObjectsPrefix is a retention rule prefix which matches all objects of the destination folder (S3, GCS)
+const RetentionAllObjectsPrefix = "*"
+
+//RetentionRule is a model for a single table (SQL destinations) or objects prefix (S3, GCS) retention rule
+type RetentionRule struct {
+	Table string `mapstructure:"table" json:"table,omitempty" yaml:"table,omitempty"`
+	//Prefix is an objects key prefix relative to the destination folder. RetentionAllObjectsPrefix matches all objects
+	Prefix string `mapstructure:"prefix" json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	//Field is a timestamp column name. Default value is _timestamp
+	Field string `mapstructure:"field" json:"field,omitempty" yaml:"field,omitempty"`
+	Days  int    `mapstructure:"days" json:"days,omitempty" yaml:"days,omitempty"`
+}
+
+//Validate returns err if invalid
+//rules of object storages (S3, GCS) must have prefix, rules of SQL destinations must have table
+func (r *Retention) Validate(objectStorage bool) error {
+	if r == nil {
+		return nil
+	}
+
+	if len(r.Rules) == 0 {
+		return errors.New("retention.rules are required")
+	}
+
+	for i, rule := range r.Rules {
+		if rule.Days <= 0 {
+			return fmt.Errorf("retention.rules[%d].days must be positive", i)
+		}
+		if objectStorage {
+			if rule.Table != "" {
+				return fmt.Errorf("retention.rules[%d].table isn't supported by file destinations: use prefix", i)
+			}
+			if rule.Prefix == "" {
+				return fmt.Errorf("retention.rules[%d].prefix is required for file destinations. Use '%s' for all objects", i, RetentionAllObjectsPrefix)
+			}
+		} else {
+			if rule.Prefix != "" {
+				return fmt.Errorf("retention.rules[%d].prefix is supported only by file destinations: use table", i)
+			}
+			if rule.Table == "" {
+				return fmt.Errorf("retention.rules[%d].table is required", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+//CircuitBreaker is a model for pausing stream destination consumption while the destination fails
+type CircuitBreaker struct {
+	//ErrorRate is a share of failed events (0..1] in the window which opens the circuit. Default value is 0.5
+	ErrorRate float64 `mapstructure:"error_rate" json:"error_rate,omitempty" yaml:"error_rate,omitempty"`
+	//MinEvents is a min count of events in the window for checking the error rate. Default value is 20
+	MinEvents int `mapstructure:"min_events" json:"min_events,omitempty" yaml:"min_events,omitempty"`
+	//Window is a duration of counting events results (e.g. 1m). Default value is 1m
+	Window string `mapstructure:"window" json:"window,omitempty" yaml:"window,omitempty"`
+	//OpenTimeout is a duration of the open state before probing (e.g. 30s). Default value is 30s
+	OpenTimeout string `mapstructure:"open_timeout" json:"open_timeout,omitempty" yaml:"open_timeout,omitempty"`
+	//HalfOpenProbes is a count of probe events which must succeed for closing the circuit. Default value is 5
+	HalfOpenProbes int `mapstructure:"half_open_probes" json:"half_open_probes,omitempty" yaml:"half_open_probes,omitempty"`
+}
+
+//Validate returns err if invalid
+func (cb *CircuitBreaker) Validate() error {
+	if cb == nil {
+		return nil
+	}
+
+	if cb.ErrorRate < 0 || cb.ErrorRate > 1 {
+		return fmt.Errorf("circuit_breaker.error_rate must be in (0, 1] range. Got: %v", cb.ErrorRate)
+	}
+	if cb.MinEvents < 0 {
+		return errors.New("circuit_breaker.min_events must be positive")
+	}
+	if cb.HalfOpenProbes < 0 {
+		return errors.New("circuit_breaker.half_open_probes must be positive")
+	}
+	for name, value := range map[string]string{"window": cb.Window, "open_timeout": cb.OpenTimeout} {
+		if value == "" {
+			continue
+		}
+		if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+			return fmt.Errorf("malformed circuit_breaker.%s [%s]: positive duration is expected (e.g. 30s)", name, value)
+		}
+	}
+
+	return nil
+}
+
 //CachingConfiguration is a configuration for disabling caching
 type CachingConfiguration struct {
 	Disabled bool `mapstructure:"disabled" json:"disabled" yaml:"disabled"`
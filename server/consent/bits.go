@@ -0,0 +1,100 @@
+package consent
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var errNotEnoughBits = errors.New("unexpected end of consent string")
+
+//bitReader reads big-endian bit fields from IAB base64url encoded segments
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func newBitReader(segment string) (*bitReader, error) {
+	//IAB strings are websafe base64 without padding, but some CMPs add padding
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return &bitReader{data: data}, nil
+}
+
+//readBool reads 1 bit
+func (br *bitReader) readBool() (bool, error) {
+	if br.pos >= len(br.data)*8 {
+		return false, errNotEnoughBits
+	}
+
+	value := br.data[br.pos/8]&(1<<(7-uint(br.pos%8))) != 0
+	br.pos++
+	return value, nil
+}
+
+//readInt reads n bits integer
+func (br *bitReader) readInt(n int) (int64, error) {
+	var value int64
+	for i := 0; i < n; i++ {
+		bit, err := br.readBool()
+		if err != nil {
+			return 0, err
+		}
+
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+
+	return value, nil
+}
+
+//readBitField reads n bits into a set of 1-based ids (e.g. purposes or vendors)
+func (br *bitReader) readBitField(n int) (map[int]bool, error) {
+	result := map[int]bool{}
+	for i := 1; i <= n; i++ {
+		bit, err := br.readBool()
+		if err != nil {
+			return nil, err
+		}
+		if bit {
+			result[i] = true
+		}
+	}
+
+	return result, nil
+}
+
+//readFibonacci reads Fibonacci encoded integer (used in GPP header). The value is terminated with '11' bits
+func (br *bitReader) readFibonacci() (int, error) {
+	value := 0
+	prev, current := 1, 1
+	previousBit := false
+	for {
+		bit, err := br.readBool()
+		if err != nil {
+			return 0, err
+		}
+		if bit && previousBit {
+			return value, nil
+		}
+		if bit {
+			value += current
+		}
+		previousBit = bit
+		prev, current = current, prev+current
+	}
+}
+
+//skip skips n bits
+func (br *bitReader) skip(n int) error {
+	if br.pos+n > len(br.data)*8 {
+		return errNotEnoughBits
+	}
+	br.pos += n
+	return nil
+}
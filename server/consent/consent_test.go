@@ -0,0 +1,254 @@
+package consent
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//bitWriter is used for building test consent strings
+type bitWriter struct {
+	bits []bool
+}
+
+func (bw *bitWriter) writeInt(value int64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		bw.bits = append(bw.bits, value&(1<<uint(i)) != 0)
+	}
+}
+
+func (bw *bitWriter) writeBitField(ids map[int]bool, n int) {
+	for i := 1; i <= n; i++ {
+		bw.bits = append(bw.bits, ids[i])
+	}
+}
+
+//writeFibonacci writes Zeckendorf representation of the value with '11' terminator
+func (bw *bitWriter) writeFibonacci(value int) {
+	fibs := []int{1, 2}
+	for fibs[len(fibs)-1] <= value {
+		fibs = append(fibs, fibs[len(fibs)-1]+fibs[len(fibs)-2])
+	}
+	bits := make([]bool, len(fibs)-1)
+	for i := len(fibs) - 2; i >= 0; i-- {
+		if fibs[i] <= value {
+			bits[i] = true
+			value -= fibs[i]
+		}
+	}
+	for len(bits) > 0 && !bits[len(bits)-1] {
+		bits = bits[:len(bits)-1]
+	}
+	bw.bits = append(bw.bits, bits...)
+	bw.bits = append(bw.bits, true)
+}
+
+func (bw *bitWriter) encode() string {
+	data := make([]byte, (len(bw.bits)+7)/8)
+	for i, bit := range bw.bits {
+		if bit {
+			data[i/8] |= 1 << (7 - uint(i%8))
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+//buildTCF returns TCF v2 core segment. Vendors consents are range encoded and vendors LI are bit field encoded
+func buildTCF(purposes, purposesLI map[int]bool, vendorRanges [][2]int, vendorsLI map[int]bool) string {
+	bw := &bitWriter{}
+	bw.writeInt(2, 6)
+	bw.writeInt(0, 36+36+12+12+6+12+12+6+1+1+12)
+	bw.writeBitField(purposes, 24)
+	bw.writeBitField(purposesLI, 24)
+	bw.writeInt(0, 1+12)
+
+	maxVendorID := 0
+	for _, r := range vendorRanges {
+		if r[1] > maxVendorID {
+			maxVendorID = r[1]
+		}
+	}
+	bw.writeInt(int64(maxVendorID), 16)
+	bw.writeInt(1, 1)
+	bw.writeInt(int64(len(vendorRanges)), 12)
+	for _, r := range vendorRanges {
+		if r[0] == r[1] {
+			bw.writeInt(0, 1)
+			bw.writeInt(int64(r[0]), 16)
+		} else {
+			bw.writeInt(1, 1)
+			bw.writeInt(int64(r[0]), 16)
+			bw.writeInt(int64(r[1]), 16)
+		}
+	}
+
+	maxVendorLI := 0
+	for id := range vendorsLI {
+		if id > maxVendorLI {
+			maxVendorLI = id
+		}
+	}
+	bw.writeInt(int64(maxVendorLI), 16)
+	bw.writeInt(0, 1)
+	bw.writeBitField(vendorsLI, maxVendorLI)
+
+	return bw.encode()
+}
+
+func buildGPP(sectionIDs []int, sections []string) string {
+	bw := &bitWriter{}
+	bw.writeInt(3, 6)
+	bw.writeInt(1, 6)
+	bw.writeInt(int64(len(sectionIDs)), 12)
+	last := 0
+	for _, id := range sectionIDs {
+		bw.writeInt(0, 1)
+		bw.writeFibonacci(id - last)
+		last = id
+	}
+	result := bw.encode()
+	for _, section := range sections {
+		result += "~" + section
+	}
+	return result
+}
+
+func TestParseTCF(t *testing.T) {
+	tcString := buildTCF(map[int]bool{1: true, 3: true}, map[int]bool{2: true}, [][2]int{{10, 12}, {755, 755}}, map[int]bool{4: true})
+
+	c, err := ParseTCF(tcString + ".YAAAAAAAAAAA")
+	require.NoError(t, err)
+	require.Equal(t, map[int]bool{1: true, 3: true}, c.Purposes)
+	require.Equal(t, map[int]bool{2: true}, c.PurposesLegitimateInterest)
+	require.Equal(t, map[int]bool{10: true, 11: true, 12: true, 755: true}, c.Vendors)
+	require.Equal(t, map[int]bool{4: true}, c.VendorsLegitimateInterest)
+
+	_, err = ParseTCF("BOEFEAyOEFEAyAHABDENAI4AAAB9vABAASA")
+	require.Error(t, err, "TCF v1 strings aren't supported")
+
+	_, err = ParseTCF("CA")
+	require.Error(t, err)
+
+	_, err = ParseTCF("%%%")
+	require.Error(t, err)
+}
+
+func TestParseGPP(t *testing.T) {
+	//real GPP header with only TCF EU v2 section
+	header := buildGPP([]int{2}, nil)
+	require.Equal(t, "DBABMA", header)
+
+	tcString := buildTCF(map[int]bool{1: true}, nil, [][2]int{{755, 755}}, nil)
+	c, err := ParseGPP(buildGPP([]int{2, 7}, []string{tcString, "BVVqAAEABCA"}))
+	require.NoError(t, err)
+	require.Equal(t, map[int]bool{1: true}, c.Purposes)
+	require.Equal(t, map[int]bool{755: true}, c.Vendors)
+
+	_, err = ParseGPP(buildGPP([]int{7}, []string{"BVVqAAEABCA"}))
+	require.Equal(t, ErrNoTCFSection, err)
+
+	_, err = ParseGPP(buildGPP([]int{2, 7}, []string{tcString}))
+	require.Error(t, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	tcString := buildTCF(map[int]bool{1: true, 3: true}, map[int]bool{2: true}, [][2]int{{755, 755}}, map[int]bool{4: true})
+
+	tests := []struct {
+		name         string
+		requirements *Requirements
+		event        map[string]interface{}
+		tcfCookie    string
+		expectedErr  string
+	}{
+		{
+			"no requirements",
+			nil,
+			map[string]interface{}{},
+			"",
+			"",
+		},
+		{
+			"consent string isn't provided",
+			&Requirements{Purposes: []int{1}},
+			map[string]interface{}{},
+			"",
+			"",
+		},
+		{
+			"consent string is required",
+			&Requirements{Purposes: []int{1}, RequireConsentString: true},
+			map[string]interface{}{},
+			"",
+			"Event was skipped because consent requirements aren't met: consent string wasn't provided",
+		},
+		{
+			"consented purposes and vendors from event",
+			&Requirements{Purposes: []int{1, 3}, Vendors: []int{755}},
+			map[string]interface{}{"gdpr_consent": tcString},
+			"",
+			"",
+		},
+		{
+			"consented purposes from cookie",
+			&Requirements{Purposes: []int{1, 3}},
+			map[string]interface{}{},
+			tcString,
+			"",
+		},
+		{
+			"missing purposes and vendors",
+			&Requirements{Purposes: []int{4, 2, 1}, Vendors: []int{4, 755}},
+			map[string]interface{}{"eventn_ctx": map[string]interface{}{"gdpr_consent": tcString}},
+			"",
+			"Event was skipped because consent requirements aren't met: no consent for purposes [2, 4] and vendors [4]",
+		},
+		{
+			"legitimate interest",
+			&Requirements{Purposes: []int{2}, Vendors: []int{4}, LegitimateInterest: true},
+			map[string]interface{}{"gdpr_consent": tcString},
+			"",
+			"",
+		},
+		{
+			"malformed consent string",
+			&Requirements{Purposes: []int{1}},
+			map[string]interface{}{"gdpr_consent": "CA"},
+			"",
+			"Event was skipped because consent requirements aren't met: malformed TCF consent string: unexpected end of consent string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := Extract(tt.event, tt.tcfCookie, "")
+			var c *Consent
+			var parseErr error
+			if !cs.IsEmpty() {
+				c, parseErr = cs.Parse()
+			}
+
+			err := tt.requirements.Evaluate(cs, c, parseErr)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				require.True(t, IsDenied(err))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeniedReason(t *testing.T) {
+	object := map[string]interface{}{DeniedField: map[string]interface{}{"dest1": "no consent for purposes [1]"}}
+
+	reason, ok := DeniedReason(object, "dest1")
+	require.True(t, ok)
+	require.Equal(t, "no consent for purposes [1]", reason)
+
+	_, ok = DeniedReason(object, "dest2")
+	require.False(t, ok)
+
+	require.Error(t, (&Requirements{Purposes: []int{25}}).Validate())
+	require.NoError(t, (&Requirements{Purposes: []int{1, 24}, Vendors: []int{1}}).Validate())
+}
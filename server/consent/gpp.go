@@ -0,0 +1,95 @@
+package consent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	gppHeaderType = 3
+	//gppTCFEUSectionID is an ID of IAB TCF EU v2 section in GPP string
+	gppTCFEUSectionID = 2
+)
+
+//ErrNoTCFSection is returned when GPP string doesn't contain TCF EU v2 section
+var ErrNoTCFSection = errors.New("GPP consent string doesn't contain TCF EU v2 section")
+
+//ParseGPP parses IAB GPP string and returns consent from its TCF EU v2 section
+func ParseGPP(gppString string) (*Consent, error) {
+	parts := strings.Split(strings.TrimSpace(gppString), "~")
+	br, err := newBitReader(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed GPP consent string: %v", err)
+	}
+
+	sectionIDs, err := readGPPHeader(br)
+	if err != nil {
+		return nil, fmt.Errorf("malformed GPP consent string header: %v", err)
+	}
+
+	if len(sectionIDs) != len(parts)-1 {
+		return nil, fmt.Errorf("malformed GPP consent string: header contains %d sections but got %d", len(sectionIDs), len(parts)-1)
+	}
+
+	for i, id := range sectionIDs {
+		if id == gppTCFEUSectionID {
+			return ParseTCF(parts[i+1])
+		}
+	}
+
+	return nil, ErrNoTCFSection
+}
+
+//readGPPHeader returns sections IDs in order of appearance
+func readGPPHeader(br *bitReader) ([]int, error) {
+	headerType, err := br.readInt(6)
+	if err != nil {
+		return nil, err
+	}
+	if headerType != gppHeaderType {
+		return nil, fmt.Errorf("unexpected header type: %d", headerType)
+	}
+
+	//version
+	if err := br.skip(6); err != nil {
+		return nil, err
+	}
+
+	//Fibonacci range: each id is encoded as an offset from the previous one
+	numEntries, err := br.readInt(12)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	last := 0
+	for i := int64(0); i < numEntries; i++ {
+		isRange, err := br.readBool()
+		if err != nil {
+			return nil, err
+		}
+
+		offset, err := br.readFibonacci()
+		if err != nil {
+			return nil, err
+		}
+		start := last + offset
+		end := start
+
+		if isRange {
+			length, err := br.readFibonacci()
+			if err != nil {
+				return nil, err
+			}
+			end = start + length
+		}
+
+		for id := start; id <= end; id++ {
+			ids = append(ids, id)
+		}
+		last = end
+	}
+
+	return ids, nil
+}
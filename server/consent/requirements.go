@@ -0,0 +1,189 @@
+package consent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+const (
+	//TCFCookieName is a standard IAB TCF v2 consent cookie
+	TCFCookieName = "euconsent-v2"
+	//GPPCookieName is a standard IAB GPP consent cookie
+	GPPCookieName = "__gpp"
+)
+
+var (
+	tcfPaths = []jsonutils.JSONPath{jsonutils.NewJSONPath("/gdpr_consent"), jsonutils.NewJSONPath("/eventn_ctx/gdpr_consent")}
+	gppPaths = []jsonutils.JSONPath{jsonutils.NewJSONPath("/gpp"), jsonutils.NewJSONPath("/eventn_ctx/gpp")}
+)
+
+//Requirements is a destination consent configuration. All configured purposes and vendors must be consented
+type Requirements struct {
+	Purposes []int `mapstructure:"purposes" json:"purposes,omitempty" yaml:"purposes,omitempty"`
+	Vendors  []int `mapstructure:"vendors" json:"vendors,omitempty" yaml:"vendors,omitempty"`
+	//LegitimateInterest allows legitimate interest transparency instead of consent
+	LegitimateInterest bool `mapstructure:"legitimate_interest" json:"legitimate_interest,omitempty" yaml:"legitimate_interest,omitempty"`
+	//RequireConsentString skips events without TCF/GPP consent string. Otherwise such events are accepted
+	RequireConsentString bool `mapstructure:"require_consent_string" json:"require_consent_string,omitempty" yaml:"require_consent_string,omitempty"`
+}
+
+//Validate returns err if invalid
+func (r *Requirements) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	for _, purpose := range r.Purposes {
+		if purpose < 1 || purpose > 24 {
+			return fmt.Errorf("consent.purposes must be in [1, 24] range. Got: %d", purpose)
+		}
+	}
+
+	for _, vendor := range r.Vendors {
+		if vendor < 1 {
+			return fmt.Errorf("consent.vendors must be positive. Got: %d", vendor)
+		}
+	}
+
+	return nil
+}
+
+//IsEmpty returns true if there are no requirements
+func (r *Requirements) IsEmpty() bool {
+	return r == nil || (len(r.Purposes) == 0 && len(r.Vendors) == 0 && !r.RequireConsentString)
+}
+
+//Strings is a dto for raw consent strings from event or cookies
+type Strings struct {
+	TCF string
+	GPP string
+}
+
+//IsEmpty returns true if there are no consent strings
+func (s *Strings) IsEmpty() bool {
+	return s == nil || (s.TCF == "" && s.GPP == "")
+}
+
+//Parse returns consent from TCF string or from GPP string TCF EU section
+func (s *Strings) Parse() (*Consent, error) {
+	if s.TCF != "" {
+		return ParseTCF(s.TCF)
+	}
+
+	return ParseGPP(s.GPP)
+}
+
+//Extract returns consent strings from the event (gdpr_consent and gpp fields) or from cookies values as a fallback
+func Extract(object map[string]interface{}, tcfCookie, gppCookie string) *Strings {
+	cs := &Strings{TCF: extractFirst(object, tcfPaths), GPP: extractFirst(object, gppPaths)}
+	if cs.IsEmpty() {
+		cs.TCF = tcfCookie
+		cs.GPP = gppCookie
+	}
+
+	return cs
+}
+
+func extractFirst(object map[string]interface{}, paths []jsonutils.JSONPath) string {
+	for _, path := range paths {
+		if value, ok := path.Get(object); ok {
+			if str, ok := value.(string); ok && str != "" {
+				return str
+			}
+		}
+	}
+
+	return ""
+}
+
+//Evaluate returns nil if consent satisfies requirements or error with the reason
+//parseErr is an error of consent string parsing (consent is nil in this case)
+func (r *Requirements) Evaluate(cs *Strings, consent *Consent, parseErr error) error {
+	if r.IsEmpty() {
+		return nil
+	}
+
+	if cs.IsEmpty() {
+		if r.RequireConsentString {
+			return &DeniedError{Reason: "consent string wasn't provided"}
+		}
+		return nil
+	}
+
+	if parseErr != nil {
+		return &DeniedError{Reason: parseErr.Error()}
+	}
+
+	var missingPurposes, missingVendors []int
+	for _, purpose := range r.Purposes {
+		if !consent.Purposes[purpose] && !(r.LegitimateInterest && consent.PurposesLegitimateInterest[purpose]) {
+			missingPurposes = append(missingPurposes, purpose)
+		}
+	}
+	for _, vendor := range r.Vendors {
+		if !consent.Vendors[vendor] && !(r.LegitimateInterest && consent.VendorsLegitimateInterest[vendor]) {
+			missingVendors = append(missingVendors, vendor)
+		}
+	}
+
+	var reasons []string
+	if len(missingPurposes) > 0 {
+		reasons = append(reasons, "purposes "+joinInts(missingPurposes))
+	}
+	if len(missingVendors) > 0 {
+		reasons = append(reasons, "vendors "+joinInts(missingVendors))
+	}
+	if len(reasons) > 0 {
+		return &DeniedError{Reason: "no consent for " + strings.Join(reasons, " and ")}
+	}
+
+	return nil
+}
+
+//DeniedError is returned when event doesn't satisfy destination consent requirements
+type DeniedError struct {
+	Reason string
+}
+
+func (de *DeniedError) Error() string {
+	return "Event was skipped because consent requirements aren't met: " + de.Reason
+}
+
+//IsDenied returns true if err is DeniedError
+func IsDenied(err error) bool {
+	var de *DeniedError
+	return errors.As(err, &de)
+}
+
+func joinInts(values []int) string {
+	sort.Ints(values)
+	var parts []string
+	for _, v := range values {
+		parts = append(parts, fmt.Sprint(v))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+//DeniedField is a system event field with destination ID -> reason of consent denial.
+//It is used for batch destinations which share the same incoming events log
+const DeniedField = "__CONSENT_DENIED__"
+
+//DeniedReason returns reason if the event was marked as denied for the destination
+func DeniedReason(object map[string]interface{}, destinationID string) (string, bool) {
+	switch denied := object[DeniedField].(type) {
+	case map[string]interface{}:
+		reason, ok := denied[destinationID]
+		if ok {
+			return fmt.Sprint(reason), true
+		}
+	case map[string]string:
+		reason, ok := denied[destinationID]
+		return reason, ok
+	}
+
+	return "", false
+}
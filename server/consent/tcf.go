@@ -0,0 +1,124 @@
+package consent
+
+import (
+	"fmt"
+	"strings"
+)
+
+const tcfVersion = 2
+
+//Consent is a parsed IAB TCF v2 consent
+type Consent struct {
+	Purposes                   map[int]bool
+	PurposesLegitimateInterest map[int]bool
+	Vendors                    map[int]bool
+	VendorsLegitimateInterest  map[int]bool
+}
+
+//ParseTCF parses IAB TCF v2 consent string (core segment; other segments are ignored)
+func ParseTCF(tcString string) (*Consent, error) {
+	core := strings.Split(strings.TrimSpace(tcString), ".")[0]
+	if core == "" {
+		return nil, fmt.Errorf("empty TCF consent string")
+	}
+
+	br, err := newBitReader(core)
+	if err != nil {
+		return nil, fmt.Errorf("malformed TCF consent string: %v", err)
+	}
+
+	version, err := br.readInt(6)
+	if err != nil {
+		return nil, fmt.Errorf("malformed TCF consent string: %v", err)
+	}
+	if version != tcfVersion {
+		return nil, fmt.Errorf("unsupported TCF consent string version: %d. Only version 2 is supported", version)
+	}
+
+	c, err := parseTCFCore(br)
+	if err != nil {
+		return nil, fmt.Errorf("malformed TCF consent string: %v", err)
+	}
+
+	return c, nil
+}
+
+//parseTCFCore reads core segment after the version field
+func parseTCFCore(br *bitReader) (*Consent, error) {
+	//Created(36), LastUpdated(36), CmpId(12), CmpVersion(12), ConsentScreen(6), ConsentLanguage(12),
+	//VendorListVersion(12), TcfPolicyVersion(6), IsServiceSpecific(1), UseNonStandardTexts(1), SpecialFeatureOptIns(12)
+	if err := br.skip(36 + 36 + 12 + 12 + 6 + 12 + 12 + 6 + 1 + 1 + 12); err != nil {
+		return nil, err
+	}
+
+	c := &Consent{}
+	var err error
+	if c.Purposes, err = br.readBitField(24); err != nil {
+		return nil, err
+	}
+	if c.PurposesLegitimateInterest, err = br.readBitField(24); err != nil {
+		return nil, err
+	}
+
+	//PurposeOneTreatment(1), PublisherCC(12)
+	if err := br.skip(1 + 12); err != nil {
+		return nil, err
+	}
+
+	if c.Vendors, err = readTCFVendors(br); err != nil {
+		return nil, err
+	}
+	if c.VendorsLegitimateInterest, err = readTCFVendors(br); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+//readTCFVendors reads vendors section which is either bit field or range encoded
+func readTCFVendors(br *bitReader) (map[int]bool, error) {
+	maxVendorID, err := br.readInt(16)
+	if err != nil {
+		return nil, err
+	}
+
+	isRangeEncoding, err := br.readBool()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isRangeEncoding {
+		return br.readBitField(int(maxVendorID))
+	}
+
+	numEntries, err := br.readInt(12)
+	if err != nil {
+		return nil, err
+	}
+
+	vendors := map[int]bool{}
+	for i := int64(0); i < numEntries; i++ {
+		isRange, err := br.readBool()
+		if err != nil {
+			return nil, err
+		}
+
+		start, err := br.readInt(16)
+		if err != nil {
+			return nil, err
+		}
+
+		end := start
+		if isRange {
+			if end, err = br.readInt(16); err != nil {
+				return nil, err
+			}
+		}
+
+		for id := start; id <= end && id <= maxVendorID; id++ {
+			vendors[int(id)] = true
+		}
+	}
+
+	return vendors, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
 	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logevents"
@@ -162,6 +163,17 @@ func (s *Service) GetConsumers(tokenID string) (consumers []events.Consumer) {
 	return
 }
 
+//GetConsumersByID returns consumers by destination ID (stream mode events queues) or by token ID (batch mode incoming logger)
+func (s *Service) GetConsumersByID(tokenID string) map[string]events.Consumer {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	consumers := map[string]events.Consumer{}
+	for id, c := range s.consumersByTokenID[tokenID] {
+		consumers[id] = c
+	}
+	return consumers
+}
+
 func (s *Service) GetDestinationByID(id string) (storages.StorageProxy, bool) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -216,6 +228,19 @@ func (s *Service) GetDestinationIDs(tokenID string) map[string]bool {
 	return ids
 }
 
+//GetCircuitBreakers returns circuit breakers of all destinations by destination ID
+//value is nil if circuit breaker isn't configured
+func (s *Service) GetCircuitBreakers() map[string]*circuitbreaker.CircuitBreaker {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	circuitBreakers := make(map[string]*circuitbreaker.CircuitBreaker, len(s.unitsByID))
+	for id, unit := range s.unitsByID {
+		circuitBreakers[id] = unit.storage.GetCircuitBreaker()
+	}
+	return circuitBreakers
+}
+
 func (s *Service) GetEventsConsumerByDestinationID(destinationID string) (events.Consumer, bool) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
@@ -504,3 +529,21 @@ func (s *Service) PostHandle(id string, event events.Event) error {
 	}
 	return nil
 }
+
+//Query executes read query with the destination (e.g. for loading reference data by lookup enrichment rules)
+func (s *Service) Query(destinationID, query string) ([]map[string]interface{}, error) {
+	storageProxy, ok := s.GetDestinationByID(destinationID)
+	if !ok {
+		return nil, fmt.Errorf("Cannot find destination: %v", destinationID)
+	}
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return nil, fmt.Errorf("Destination %v is not ready", destinationID)
+	}
+	querier, ok := storage.(storages.Querier)
+	if !ok {
+		return nil, fmt.Errorf("Type %v doesn't support queries. Destination: %v", storage.Type(), destinationID)
+	}
+
+	return querier.Query(query)
+}
@@ -0,0 +1,349 @@
+package enrichment
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/safego"
+)
+
+const (
+	Lookup = "lookup"
+
+	LookupSourceCSV  = "csv"
+	LookupSourceJSON = "json"
+	LookupSourceSQL  = "sql"
+
+	defaultLookupReloadSec    = 300
+	lookupInitialRetryTimeout = 10 * time.Second
+)
+
+//LookupQueryFunc executes query against the destination with destinationID and returns result rows
+type LookupQueryFunc func(destinationID, query string) ([]map[string]interface{}, error)
+
+var lookupQuery LookupQueryFunc
+
+//InitLookupQuery sets function which is used by lookup rules with 'sql' source for loading reference data
+func InitLookupQuery(queryFunc LookupQueryFunc) {
+	lookupQuery = queryFunc
+}
+
+//LookupConfig is a configuration of reference data for lookup enrichment rule
+type LookupConfig struct {
+	Source        string   `mapstructure:"source" json:"source,omitempty" yaml:"source,omitempty"`
+	Path          string   `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	DestinationID string   `mapstructure:"destination_id" json:"destination_id,omitempty" yaml:"destination_id,omitempty"`
+	Query         string   `mapstructure:"query" json:"query,omitempty" yaml:"query,omitempty"`
+	KeyField      string   `mapstructure:"key_field" json:"key_field,omitempty" yaml:"key_field,omitempty"`
+	Fields        []string `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+	ReloadSec     int      `mapstructure:"reload_sec" json:"reload_sec,omitempty" yaml:"reload_sec,omitempty"`
+}
+
+//Validate returns err if invalid
+func (lc *LookupConfig) Validate() error {
+	if lc == nil {
+		return errors.New("'lookup' is required parameter for lookup enrichment rule")
+	}
+
+	lc.Source = strings.ToLower(lc.Source)
+	switch lc.Source {
+	case LookupSourceCSV, LookupSourceJSON:
+		if lc.Path == "" {
+			return fmt.Errorf("'lookup.path' is required parameter for %s lookup source", lc.Source)
+		}
+	case LookupSourceSQL:
+		if lc.DestinationID == "" {
+			return errors.New("'lookup.destination_id' is required parameter for sql lookup source")
+		}
+		if lc.Query == "" {
+			return errors.New("'lookup.query' is required parameter for sql lookup source")
+		}
+	default:
+		return fmt.Errorf("Unsupported lookup source: %q. Supported: [%s, %s, %s]", lc.Source, LookupSourceCSV, LookupSourceJSON, LookupSourceSQL)
+	}
+
+	if lc.KeyField == "" {
+		return errors.New("'lookup.key_field' is required parameter for lookup enrichment rule")
+	}
+
+	return nil
+}
+
+//LookupRule joins reference data loaded into memory into events by a key
+//reference data is reloaded every LookupConfig.ReloadSec seconds
+type LookupRule struct {
+	source      jsonutils.JSONPath
+	destination jsonutils.JSONPath
+	config      *LookupConfig
+
+	mutex *sync.RWMutex
+	data  map[string]map[string]interface{}
+
+	closed chan struct{}
+}
+
+//NewLookupRule returns configured LookupRule and starts reference data reloading goroutine
+//file sources must be loaded successfully on creation. SQL sources are loaded in background
+//because the destination might not be initialized yet
+func NewLookupRule(source, destination jsonutils.JSONPath, lookupConfig *LookupConfig) (*LookupRule, error) {
+	if err := lookupConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	lr := &LookupRule{
+		source:      source,
+		destination: destination,
+		config:      lookupConfig,
+		mutex:       &sync.RWMutex{},
+		data:        map[string]map[string]interface{}{},
+		closed:      make(chan struct{}),
+	}
+
+	if lookupConfig.Source != LookupSourceSQL {
+		if err := lr.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	lr.start()
+	return lr, nil
+}
+
+func (lr *LookupRule) start() {
+	reloadSec := lr.config.ReloadSec
+	if reloadSec <= 0 {
+		reloadSec = defaultLookupReloadSec
+	}
+
+	safego.RunWithRestart(func() {
+		//destination might not be ready yet: retry initial loading more often
+		if lr.config.Source == LookupSourceSQL {
+			for {
+				err := lr.reload()
+				if err == nil {
+					break
+				}
+				logging.Errorf("Error loading lookup data %s: %v. Retry in %s", lr.sourceDescription(), err, lookupInitialRetryTimeout)
+				select {
+				case <-lr.closed:
+					return
+				case <-time.After(lookupInitialRetryTimeout):
+				}
+			}
+		}
+
+		ticker := time.NewTicker(time.Duration(reloadSec) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lr.closed:
+				return
+			case <-ticker.C:
+				if err := lr.reload(); err != nil {
+					logging.Errorf("Error reloading lookup data %s: %v", lr.sourceDescription(), err)
+				}
+			}
+		}
+	}).WithRestartTimeout(1 * time.Minute)
+}
+
+//reload loads reference data and replaces the current one
+//keeps the current data if loading fails
+func (lr *LookupRule) reload() error {
+	var rows []map[string]interface{}
+	var err error
+	switch lr.config.Source {
+	case LookupSourceCSV:
+		rows, err = loadLookupCSV(lr.config.Path)
+	case LookupSourceJSON:
+		rows, err = loadLookupJSON(lr.config.Path)
+	case LookupSourceSQL:
+		if lookupQuery == nil {
+			return errors.New("sql lookup source isn't initialized")
+		}
+		rows, err = lookupQuery(lr.config.DestinationID, lr.config.Query)
+	}
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		key, ok := row[lr.config.KeyField]
+		if !ok || key == nil {
+			continue
+		}
+
+		value := map[string]interface{}{}
+		if len(lr.config.Fields) > 0 {
+			for _, field := range lr.config.Fields {
+				if v, ok := row[field]; ok {
+					value[field] = v
+				}
+			}
+		} else {
+			for field, v := range row {
+				if field != lr.config.KeyField {
+					value[field] = v
+				}
+			}
+		}
+
+		data[lookupKey(key)] = value
+	}
+
+	lr.mutex.Lock()
+	lr.data = data
+	lr.mutex.Unlock()
+
+	logging.Debugf("Loaded [%d] lookup records %s", len(data), lr.sourceDescription())
+	return nil
+}
+
+//Execute finds reference data by the value from the source path and merges it into the destination path
+func (lr *LookupRule) Execute(event map[string]interface{}) {
+	keyIface, ok := lr.source.Get(event)
+	if !ok || keyIface == nil {
+		return
+	}
+
+	lr.mutex.RLock()
+	value, ok := lr.data[lookupKey(keyIface)]
+	lr.mutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	if err := lr.destination.SetOrMergeIfExist(event, maputils.CopyMap(value)); err != nil {
+		logging.SystemErrorf("Lookup data wasn't set: %v", err)
+	}
+}
+
+func (lr *LookupRule) Name() string {
+	return Lookup
+}
+
+//Close stops reference data reloading
+func (lr *LookupRule) Close() error {
+	select {
+	case <-lr.closed:
+	default:
+		close(lr.closed)
+	}
+	return nil
+}
+
+func (lr *LookupRule) sourceDescription() string {
+	if lr.config.Source == LookupSourceSQL {
+		return fmt.Sprintf("from destination [%s]", lr.config.DestinationID)
+	}
+	return fmt.Sprintf("from %s file [%s]", lr.config.Source, lr.config.Path)
+}
+
+//lookupKey returns string representation of the key
+//numbers are formatted canonically (without exponent and trailing zeros) whatever their type is:
+//json.Number("1e+06"), float64(1000000) and int64(1000000) are equal keys "1000000"
+func lookupKey(value interface{}) string {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+//loadLookupCSV reads CSV file with a header line
+func loadLookupCSV(path string) ([]map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading CSV header: %v", err)
+	}
+
+	var rows []map[string]interface{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV line: %v", err)
+		}
+
+		row := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+//loadLookupJSON reads JSON file with array of objects
+func loadLookupJSON(path string) ([]map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return nil, fmt.Errorf("error parsing JSON lookup file (array of objects is expected): %v", err)
+	}
+
+	return rows, nil
+}
@@ -1,5 +1,7 @@
 package enrichment
 
+import "io"
+
 type LookupEnrichmentStep struct {
 	enrichmentRules []Rule
 }
@@ -13,3 +15,12 @@ func (les *LookupEnrichmentStep) Execute(object map[string]interface{}) {
 		rule.Execute(object)
 	}
 }
+
+//Close closes all rules which hold resources (e.g. lookup rules with reloading goroutines)
+func (les *LookupEnrichmentStep) Close() {
+	for _, rule := range les.enrichmentRules {
+		if closer, ok := rule.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package enrichment
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "products.csv")
+	require.NoError(t, ioutil.WriteFile(csvPath, []byte("product_id,category,price\n1,shoes,10.5\n2,hats,3\n"), 0644))
+	jsonPath := filepath.Join(dir, "products.json")
+	require.NoError(t, ioutil.WriteFile(jsonPath, []byte(`[{"product_id":1,"category":"shoes","price":10.5},{"product_id":2,"category":"hats","price":3},{"product_id":1000000,"category":"gloves","price":7}]`), 0644))
+
+	tests := []struct {
+		name     string
+		config   *LookupConfig
+		input    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			"Empty input object",
+			&LookupConfig{Source: LookupSourceCSV, Path: csvPath, KeyField: "product_id"},
+			map[string]interface{}{},
+			map[string]interface{}{},
+		},
+		{
+			"Unknown key",
+			&LookupConfig{Source: LookupSourceCSV, Path: csvPath, KeyField: "product_id"},
+			map[string]interface{}{"product_id": "3"},
+			map[string]interface{}{"product_id": "3"},
+		},
+		{
+			"CSV all fields",
+			&LookupConfig{Source: LookupSourceCSV, Path: csvPath, KeyField: "product_id"},
+			map[string]interface{}{"product_id": "1"},
+			map[string]interface{}{"product_id": "1", "product": map[string]interface{}{"category": "shoes", "price": "10.5"}},
+		},
+		{
+			"CSV chosen fields with numeric key",
+			&LookupConfig{Source: LookupSourceCSV, Path: csvPath, KeyField: "product_id", Fields: []string{"category"}},
+			map[string]interface{}{"product_id": float64(2)},
+			map[string]interface{}{"product_id": float64(2), "product": map[string]interface{}{"category": "hats"}},
+		},
+		{
+			"JSON number key from event",
+			&LookupConfig{Source: LookupSourceJSON, Path: jsonPath, KeyField: "product_id", Fields: []string{"category"}},
+			map[string]interface{}{"product_id": json.Number("1e+06")},
+			map[string]interface{}{"product_id": json.Number("1e+06"), "product": map[string]interface{}{"category": "gloves"}},
+		},
+		{
+			"JSON merges with existing",
+			&LookupConfig{Source: LookupSourceJSON, Path: jsonPath, KeyField: "product_id"},
+			map[string]interface{}{"product_id": "1", "product": map[string]interface{}{"name": "sneakers"}},
+			map[string]interface{}{"product_id": "1", "product": map[string]interface{}{"name": "sneakers", "category": "shoes", "price": 10.5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := NewLookupRule(jsonutils.NewJSONPath("/product_id"), jsonutils.NewJSONPath("/product"), tt.config)
+			require.NoError(t, err)
+			defer rule.Close()
+
+			rule.Execute(tt.input)
+			test.ObjectsEqual(t, tt.expected, tt.input, "Events aren't equal")
+		})
+	}
+}
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"String", "1e+06", "1e+06"},
+		{"JSON number with exponent", json.Number("1e+06"), "1000000"},
+		{"JSON integer", json.Number("1000000"), "1000000"},
+		{"JSON big integer", json.Number("9007199254740993"), "9007199254740993"},
+		{"JSON float", json.Number("10.50"), "10.5"},
+		{"Float", float64(1000000), "1000000"},
+		{"Float with fraction", 10.5, "10.5"},
+		{"Float32", float32(2.5), "2.5"},
+		{"Int", 1000000, "1000000"},
+		{"Int64", int64(-15), "-15"},
+		{"Uint64", uint64(15), "15"},
+		{"Bool", true, "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, lookupKey(tt.input))
+		})
+	}
+}
+
+func TestLookupSQL(t *testing.T) {
+	queried := make(chan string, 1)
+	InitLookupQuery(func(destinationID, query string) ([]map[string]interface{}, error) {
+		queried <- destinationID
+		return []map[string]interface{}{{"id": int64(7), "segment": "vip"}}, nil
+	})
+	defer InitLookupQuery(nil)
+
+	rule, err := NewLookupRule(jsonutils.NewJSONPath("/user/id"), jsonutils.NewJSONPath("/user/lookup"),
+		&LookupConfig{Source: LookupSourceSQL, DestinationID: "pg", Query: "select id, segment from users", KeyField: "id"})
+	require.NoError(t, err)
+	defer rule.Close()
+
+	require.Equal(t, "pg", <-queried)
+	require.Eventually(t, func() bool {
+		event := map[string]interface{}{"user": map[string]interface{}{"id": float64(7)}}
+		rule.Execute(event)
+		_, ok := event["user"].(map[string]interface{})["lookup"]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLookupConfigValidation(t *testing.T) {
+	_, err := NewLookupRule(jsonutils.NewJSONPath("/a"), jsonutils.NewJSONPath("/b"), nil)
+	require.Error(t, err)
+
+	_, err = NewLookupRule(jsonutils.NewJSONPath("/a"), jsonutils.NewJSONPath("/b"), &LookupConfig{Source: "xml", Path: "a.xml", KeyField: "id"})
+	require.Error(t, err)
+
+	_, err = NewLookupRule(jsonutils.NewJSONPath("/a"), jsonutils.NewJSONPath("/b"), &LookupConfig{Source: LookupSourceSQL, DestinationID: "pg", KeyField: "id"})
+	require.Error(t, err)
+
+	_, err = NewLookupRule(jsonutils.NewJSONPath("/a"), jsonutils.NewJSONPath("/b"), &LookupConfig{Source: LookupSourceCSV, Path: "/not/exist.csv", KeyField: "id"})
+	require.Error(t, err)
+}
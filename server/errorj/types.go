@@ -28,6 +28,9 @@ var (
 	TruncateError             = sqlError.NewSubtype("truncate")
 	BulkMergeError            = sqlError.NewSubtype("bulk_merge")
 	CopyError                 = sqlError.NewSubtype("copy")
+	QueryError                = sqlError.NewSubtype("query")
+	CreatePartitionError      = sqlError.NewSubtype("create_partition")
+	CreateIndexError          = sqlError.NewSubtype("create_index")
 
 	stageErr             = reportedErrors.NewType("stage")
 	SaveOnStageError     = stageErr.NewSubtype("save_on_stage")
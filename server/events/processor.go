@@ -16,6 +16,9 @@ type RequestContext struct {
 	JitsuAnonymousID    string `json:"jitsu_anonymous_id,omitempty"`
 	HashedAnonymousID   string `json:"hashed_anonymous_id,omitempty"`
 	CookiesLawCompliant bool   `json:"cookie_laws_compliant,omitempty"`
+	//TCFConsent and GPPConsent are consent strings from IAB standard cookies
+	TCFConsent string `json:"tcf_consent,omitempty"`
+	GPPConsent string `json:"gpp_consent,omitempty"`
 }
 
 // Processor is used in preprocessing and postprocessing events before and after consuming(storing)
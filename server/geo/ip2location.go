@@ -0,0 +1,273 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const ip2locationHeaderSize = 64
+
+//IP2Location BIN columns positions per database type (index is a DB type: DB1..DB26)
+//0 means that the column isn't available in the database type
+var (
+	ip2locationCountryPosition   = [27]uint8{0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+	ip2locationRegionPosition    = [27]uint8{0, 0, 0, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3}
+	ip2locationCityPosition      = [27]uint8{0, 0, 0, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4}
+	ip2locationISPPosition       = [27]uint8{0, 0, 3, 0, 5, 0, 7, 5, 7, 0, 8, 0, 9, 0, 9, 0, 9, 0, 9, 7, 9, 0, 9, 7, 9, 9, 9}
+	ip2locationLatitudePosition  = [27]uint8{0, 0, 0, 0, 0, 5, 5, 0, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5}
+	ip2locationLongitudePosition = [27]uint8{0, 0, 0, 0, 0, 6, 6, 0, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6}
+	ip2locationDomainPosition    = [27]uint8{0, 0, 0, 0, 0, 0, 0, 6, 8, 0, 9, 0, 10, 0, 10, 0, 10, 0, 10, 8, 10, 0, 10, 8, 10, 10, 10}
+	ip2locationZipPosition       = [27]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 7, 7, 7, 7, 0, 7, 7, 7, 0, 7, 0, 7, 7, 7, 0, 7, 7, 7}
+	ip2locationASNPosition       = [27]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 24}
+	ip2locationASPosition        = [27]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 25}
+
+	ErrIP2LocationIPv6NotSupported = errors.New("IP2Location database doesn't contain IPv6 data")
+)
+
+//ip2locationHeader is a BIN file header
+type ip2locationHeader struct {
+	dbType     uint8
+	dbColumn   uint8
+	ipv4Count  uint32
+	ipv4Addr   uint32
+	ipv6Count  uint32
+	ipv6Addr   uint32
+}
+
+//IP2LocationResolver is a geo location data Resolver that is based on IP2Location BIN database (DB1-DB26)
+type IP2LocationResolver struct {
+	reader io.ReaderAt
+	closer io.Closer
+	header *ip2locationHeader
+}
+
+//NewIP2LocationResolver returns IP2LocationResolver from:
+// 1. direct URL for download BIN file
+// 2. file path to BIN file
+func NewIP2LocationResolver(path string) (Resolver, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		b, err := loadFromURL(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return newIP2LocationResolver(bytes.NewReader(b), nil)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening IP2Location database: %v", err)
+	}
+
+	resolver, err := newIP2LocationResolver(file, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return resolver, nil
+}
+
+func newIP2LocationResolver(reader io.ReaderAt, closer io.Closer) (*IP2LocationResolver, error) {
+	raw := make([]byte, ip2locationHeaderSize)
+	if _, err := reader.ReadAt(raw, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error reading IP2Location database header: %v", err)
+	}
+
+	header := &ip2locationHeader{
+		dbType:     raw[0],
+		dbColumn:   raw[1],
+		ipv4Count:  binary.LittleEndian.Uint32(raw[5:9]),
+		ipv4Addr:   binary.LittleEndian.Uint32(raw[9:13]),
+		ipv6Count:  binary.LittleEndian.Uint32(raw[13:17]),
+		ipv6Addr:   binary.LittleEndian.Uint32(raw[17:21]),
+	}
+
+	if header.dbType == 0 || int(header.dbType) >= len(ip2locationCountryPosition) || header.dbColumn == 0 || header.ipv4Count == 0 {
+		return nil, fmt.Errorf("malformed IP2Location database: unknown database type [%d] or empty data", header.dbType)
+	}
+
+	return &IP2LocationResolver{reader: reader, closer: closer, header: header}, nil
+}
+
+//Resolve returns location geo data (country, city, isp, domain, asn) parsed from client ip address
+func (ilr *IP2LocationResolver) Resolve(ip string) (*Data, error) {
+	if ip == "" {
+		return nil, EmptyIP
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return nil, fmt.Errorf("Error parsing IP from string: %s", ip)
+	}
+
+	row, err := ilr.findRow(parsedIP)
+	if err != nil {
+		return nil, fmt.Errorf("Error looking up IP2Location geo from ip %s: %v", ip, err)
+	}
+
+	data := &Data{}
+	if row == nil {
+		return data, nil
+	}
+
+	dbType := ilr.header.dbType
+	if pos := ip2locationCountryPosition[dbType]; pos > 0 {
+		pointer := ilr.rowUint32(row, pos)
+		if data.Country, err = ilr.readString(pointer); err != nil {
+			return nil, err
+		}
+		if data.CountryName, err = ilr.readString(pointer + 3); err != nil {
+			return nil, err
+		}
+		//IP2Location uses '-' for unknown values
+		if data.Country == "-" {
+			data.Country, data.CountryName = "", ""
+		}
+	}
+
+	stringFields := []struct {
+		position [27]uint8
+		value    *string
+	}{
+		{ip2locationRegionPosition, &data.Region},
+		{ip2locationCityPosition, &data.City},
+		{ip2locationISPPosition, &data.ISP},
+		{ip2locationDomainPosition, &data.Domain},
+		{ip2locationZipPosition, &data.Zip},
+		{ip2locationASPosition, &data.ASO},
+	}
+	for _, field := range stringFields {
+		if pos := field.position[dbType]; pos > 0 {
+			value, err := ilr.readString(ilr.rowUint32(row, pos))
+			if err != nil {
+				return nil, err
+			}
+			if value != "-" {
+				*field.value = value
+			}
+		}
+	}
+
+	if pos := ip2locationASNPosition[dbType]; pos > 0 {
+		value, err := ilr.readString(ilr.rowUint32(row, pos))
+		if err != nil {
+			return nil, err
+		}
+		if asn, err := strconv.ParseUint(value, 10, 32); err == nil {
+			data.ASN = uint(asn)
+		}
+	}
+
+	if pos := ip2locationLatitudePosition[dbType]; pos > 0 {
+		data.Lat = float64(math.Float32frombits(ilr.rowUint32(row, pos)))
+	}
+	if pos := ip2locationLongitudePosition[dbType]; pos > 0 {
+		data.Lon = float64(math.Float32frombits(ilr.rowUint32(row, pos)))
+	}
+
+	return data, nil
+}
+
+//findRow does binary search of IP range and returns row columns (without ip_from) or nil if not found
+func (ilr *IP2LocationResolver) findRow(ip net.IP) ([]byte, error) {
+	var ipNumber *big.Int
+	var count, baseAddr uint32
+	var ipSize, columnsSize uint32
+
+	columnsSize = uint32(ilr.header.dbColumn) * 4
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ipNumber = new(big.Int).SetBytes(ipv4)
+		count, baseAddr, ipSize = ilr.header.ipv4Count, ilr.header.ipv4Addr, 4
+	} else {
+		if ilr.header.ipv6Count == 0 {
+			return nil, ErrIP2LocationIPv6NotSupported
+		}
+		ipNumber = new(big.Int).SetBytes(ip.To16())
+		count, baseAddr, ipSize = ilr.header.ipv6Count, ilr.header.ipv6Addr, 16
+		//ip_from in IPv6 section takes 16 bytes instead of 4
+		columnsSize += 12
+	}
+
+	//the last row is a sentinel with the upper bound of the last range
+	low, high := int64(0), int64(count)-1
+	for low <= high {
+		mid := (low + high) / 2
+		rowOffset := int64(baseAddr) + mid*int64(columnsSize)
+
+		raw := make([]byte, columnsSize+ipSize)
+		if _, err := ilr.reader.ReadAt(raw, rowOffset-1); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		ipFrom := ip2locationNumber(raw[:ipSize])
+		ipTo := ip2locationNumber(raw[columnsSize : columnsSize+ipSize])
+
+		if ipNumber.Cmp(ipFrom) >= 0 && ipNumber.Cmp(ipTo) < 0 {
+			return raw[ipSize:columnsSize], nil
+		}
+
+		if ipNumber.Cmp(ipFrom) < 0 {
+			high = mid - 1
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return nil, nil
+}
+
+//rowUint32 returns uint32 value of the column by position (columns are numbered from 2 since 1 is ip_from)
+func (ilr *IP2LocationResolver) rowUint32(row []byte, position uint8) uint32 {
+	offset := (int(position) - 2) * 4
+	if offset < 0 || offset+4 > len(row) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(row[offset : offset+4])
+}
+
+//readString reads length-prefixed string by the pointer
+func (ilr *IP2LocationResolver) readString(pointer uint32) (string, error) {
+	length := make([]byte, 1)
+	if _, err := ilr.reader.ReadAt(length, int64(pointer)); err != nil {
+		return "", fmt.Errorf("error reading IP2Location database: %v", err)
+	}
+
+	value := make([]byte, length[0])
+	if _, err := ilr.reader.ReadAt(value, int64(pointer)+1); err != nil && err != io.EOF {
+		return "", fmt.Errorf("error reading IP2Location database: %v", err)
+	}
+
+	return string(value), nil
+}
+
+func (ilr *IP2LocationResolver) Type() string {
+	return IP2LocationType
+}
+
+//Close closes underlying file if any
+func (ilr *IP2LocationResolver) Close() error {
+	if ilr.closer != nil {
+		return ilr.closer.Close()
+	}
+
+	return nil
+}
+
+//ip2locationNumber converts little endian bytes into big.Int
+func ip2locationNumber(b []byte) *big.Int {
+	reversed := make([]byte, len(b))
+	for i := range b {
+		reversed[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(reversed)
+}
@@ -0,0 +1,379 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const (
+	CSVFormat  = "csv"
+	MMDBFormat = "mmdb"
+)
+
+var (
+	ipRangeStartColumns = map[string]bool{"start_ip": true, "ip_from": true, "range_start": true, "first_ip": true}
+	ipRangeEndColumns   = map[string]bool{"end_ip": true, "ip_to": true, "range_end": true, "last_ip": true}
+	ipRangeCIDRColumns  = map[string]bool{"network": true, "cidr": true}
+
+	//ipRangeFieldAliases is a mapping of common IP ranges databases column names into Data field names
+	ipRangeFieldAliases = map[string]string{
+		"country_code":   "country",
+		"country_long":   "country_name",
+		"region_name":    "region",
+		"city_name":      "city",
+		"lat":            "latitude",
+		"lng":            "longitude",
+		"lon":            "longitude",
+		"postal_code":    "zip",
+		"zip_code":       "zip",
+		"asn":            "autonomous_system_number",
+		"as":             "autonomous_system_organization",
+		"as_name":        "autonomous_system_organization",
+		"as_domain":      "domain",
+		"isp_name":       "isp",
+		"continent_name": "continent",
+	}
+)
+
+//ipRange is an IP range [start, end] with geo data. IPs are stored in 16-bytes form
+type ipRange struct {
+	start net.IP
+	end   net.IP
+	data  *Data
+}
+
+//IPRangeResolver is a geo location data Resolver that is based on generic IP ranges database:
+//CSV file (start/end IP or CIDR columns + geo data columns) or MMDB-compatible file (e.g. DB-IP, IPinfo)
+type IPRangeResolver struct {
+	//csv
+	ranges []*ipRange
+	//mmdb
+	mmdbReader *maxminddb.Reader
+}
+
+//NewIPRangeResolver returns IPRangeResolver from file path or direct URL according to the configuration
+func NewIPRangeResolver(path string, config *IPRangeConfig) (Resolver, error) {
+	format := config.Format
+	if format == "" {
+		format = CSVFormat
+		if strings.HasSuffix(path, mmdbSuffix) {
+			format = MMDBFormat
+		}
+	}
+
+	var payload []byte
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		b, err := loadFromURL(path)
+		if err != nil {
+			return nil, err
+		}
+		payload = b
+	}
+
+	switch format {
+	case MMDBFormat:
+		var reader *maxminddb.Reader
+		var err error
+		if payload != nil {
+			reader, err = maxminddb.FromBytes(payload)
+		} else {
+			reader, err = maxminddb.Open(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error opening MMDB IP ranges database: %v", err)
+		}
+
+		return &IPRangeResolver{mmdbReader: reader}, nil
+	case CSVFormat:
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		} else {
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("error opening CSV IP ranges database: %v", err)
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		ranges, err := parseIPRangesCSV(reader, config)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing CSV IP ranges database: %v", err)
+		}
+
+		return &IPRangeResolver{ranges: ranges}, nil
+	default:
+		return nil, fmt.Errorf("unknown IP ranges database format: %s. Available: [%s, %s]", format, CSVFormat, MMDBFormat)
+	}
+}
+
+//parseIPRangesCSV reads all rows into sorted ranges. Header is taken from the first line if config.Columns isn't set
+func parseIPRangesCSV(reader io.Reader, config *IPRangeConfig) ([]*ipRange, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	csvReader.ReuseRecord = true
+
+	columns := config.Columns
+	if len(columns) == 0 {
+		header, err := csvReader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading header: %v", err)
+		}
+		columns = make([]string, len(header))
+		copy(columns, header)
+	}
+
+	startIdx, endIdx, cidrIdx := -1, -1, -1
+	fieldNames := make([]string, len(columns))
+	for i, column := range columns {
+		name := strings.ToLower(strings.TrimSpace(column))
+		switch {
+		case ipRangeStartColumns[name]:
+			startIdx = i
+		case ipRangeEndColumns[name]:
+			endIdx = i
+		case ipRangeCIDRColumns[name]:
+			cidrIdx = i
+		default:
+			if mapped, ok := config.Mapping[column]; ok {
+				fieldNames[i] = mapped
+			} else if alias, ok := ipRangeFieldAliases[name]; ok {
+				fieldNames[i] = alias
+			} else {
+				fieldNames[i] = name
+			}
+		}
+	}
+
+	if cidrIdx == -1 && (startIdx == -1 || endIdx == -1) {
+		return nil, errors.New("IP range columns weren't found. Either start_ip and end_ip (ip_from and ip_to) or network (cidr) columns are required")
+	}
+
+	var ranges []*ipRange
+	line := 0
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("error reading line %d: %v", line, err)
+		}
+
+		r := &ipRange{data: &Data{}}
+		if cidrIdx >= 0 && cidrIdx < len(record) {
+			_, network, err := net.ParseCIDR(strings.TrimSpace(record[cidrIdx]))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing network on line %d: %v", line, err)
+			}
+			r.start, r.end = networkBounds(network)
+		} else {
+			if startIdx >= len(record) || endIdx >= len(record) {
+				return nil, fmt.Errorf("malformed line %d: IP range columns are missing", line)
+			}
+			if r.start, err = parseRangeIP(record[startIdx]); err != nil {
+				return nil, fmt.Errorf("error parsing range start on line %d: %v", line, err)
+			}
+			if r.end, err = parseRangeIP(record[endIdx]); err != nil {
+				return nil, fmt.Errorf("error parsing range end on line %d: %v", line, err)
+			}
+		}
+
+		for i, value := range record {
+			if i < len(fieldNames) && fieldNames[i] != "" {
+				setDataField(r.data, fieldNames[i], strings.TrimSpace(value))
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].start, ranges[j].start) < 0
+	})
+
+	return ranges, nil
+}
+
+//Resolve returns location geo data parsed from client ip address
+func (irr *IPRangeResolver) Resolve(ip string) (*Data, error) {
+	if ip == "" {
+		return nil, EmptyIP
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return nil, fmt.Errorf("Error parsing IP from string: %s", ip)
+	}
+
+	if irr.mmdbReader != nil {
+		var record map[string]interface{}
+		if err := irr.mmdbReader.Lookup(parsedIP, &record); err != nil {
+			return nil, fmt.Errorf("Error looking up MMDB geo from ip %s: %v", ip, err)
+		}
+
+		return dataFromMMDBRecord(record), nil
+	}
+
+	ip16 := parsedIP.To16()
+	//first range which starts after the ip
+	idx := sort.Search(len(irr.ranges), func(i int) bool {
+		return bytes.Compare(irr.ranges[i].start, ip16) > 0
+	})
+	if idx > 0 && bytes.Compare(ip16, irr.ranges[idx-1].end) <= 0 {
+		data := *irr.ranges[idx-1].data
+		return &data, nil
+	}
+
+	return &Data{}, nil
+}
+
+func (irr *IPRangeResolver) Type() string {
+	return IPRangeType
+}
+
+//Close closes MMDB reader if any
+func (irr *IPRangeResolver) Close() error {
+	if irr.mmdbReader != nil {
+		return irr.mmdbReader.Close()
+	}
+
+	return nil
+}
+
+//parseRangeIP parses IP from string or decimal number (e.g. IP2Location LITE CSV databases)
+//returns IP in 16-bytes form
+func parseRangeIP(value string) (net.IP, error) {
+	value = strings.TrimSpace(value)
+	if ip := net.ParseIP(value); ip != nil {
+		return ip.To16(), nil
+	}
+
+	number, ok := new(big.Int).SetString(value, 10)
+	if !ok || number.Sign() < 0 || number.BitLen() > 128 {
+		return nil, fmt.Errorf("malformed IP: %s", value)
+	}
+
+	if number.BitLen() <= 32 {
+		ipv4 := make(net.IP, net.IPv4len)
+		number.FillBytes(ipv4)
+		return ipv4.To16(), nil
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	number.FillBytes(ip)
+	return ip, nil
+}
+
+//networkBounds returns first and last IPs of the network in 16-bytes form
+func networkBounds(network *net.IPNet) (net.IP, net.IP) {
+	start := network.IP.Mask(network.Mask)
+	end := make(net.IP, len(start))
+	for i := range start {
+		end[i] = start[i] | ^network.Mask[i]
+	}
+	return start.To16(), end.To16()
+}
+
+//setDataField sets value into Data field by its JSON name
+func setDataField(data *Data, name, value string) {
+	if value == "" || value == "-" {
+		return
+	}
+
+	switch name {
+	case "continent":
+		data.Continent = value
+	case "country":
+		data.Country = value
+	case "country_name":
+		data.CountryName = value
+	case "city":
+		data.City = value
+	case "latitude":
+		data.Lat, _ = strconv.ParseFloat(value, 64)
+	case "longitude":
+		data.Lon, _ = strconv.ParseFloat(value, 64)
+	case "zip":
+		data.Zip = value
+	case "region":
+		data.Region = value
+	case "autonomous_system_number":
+		//e.g. AS15169 or 15169
+		asn, _ := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(value), "AS"), 10, 32)
+		data.ASN = uint(asn)
+	case "autonomous_system_organization":
+		data.ASO = value
+	case "isp":
+		data.ISP = value
+	case "organization":
+		data.Organization = value
+	case "domain":
+		data.Domain = value
+	}
+}
+
+//dataFromMMDBRecord extracts Data from MaxMind-like nested records (e.g. DB-IP) or flat records (e.g. IPinfo)
+func dataFromMMDBRecord(record map[string]interface{}) *Data {
+	data := &Data{}
+	if record == nil {
+		return data
+	}
+
+	nestedFields := []struct {
+		path  string
+		field string
+	}{
+		{"/continent/names/en", "continent"},
+		{"/country/iso_code", "country"},
+		{"/country/names/en", "country_name"},
+		{"/city/names/en", "city"},
+		{"/location/latitude", "latitude"},
+		{"/location/longitude", "longitude"},
+		{"/postal/code", "zip"},
+	}
+	for _, nf := range nestedFields {
+		if value, ok := jsonutils.NewJSONPath(nf.path).Get(record); ok {
+			if _, isMap := value.(map[string]interface{}); !isMap {
+				setDataField(data, nf.field, fmt.Sprint(value))
+			}
+		}
+	}
+
+	if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+		if subdivision, ok := subdivisions[0].(map[string]interface{}); ok {
+			if isoCode, ok := subdivision["iso_code"]; ok {
+				setDataField(data, "region", fmt.Sprint(isoCode))
+			}
+		}
+	}
+
+	for key, value := range record {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		name := strings.ToLower(key)
+		if alias, ok := ipRangeFieldAliases[name]; ok {
+			name = alias
+		}
+		setDataField(data, name, fmt.Sprint(value))
+	}
+
+	return data
+}
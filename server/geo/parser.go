@@ -44,3 +44,35 @@ func ParseConfigAsLink(config *ResolverConfig) (string, error) {
 
 	return mc.MaxMindURL, nil
 }
+
+//ParseIP2LocationConfig returns IP2Location config or error
+func ParseIP2LocationConfig(config *ResolverConfig) (*IP2LocationConfig, error) {
+	ic := &IP2LocationConfig{}
+	if err := jsonutils.UnmarshalConfig(config.Config, ic); err != nil {
+		return nil, err
+	}
+
+	if ic.Path == "" {
+		return nil, errors.New("path is required field")
+	}
+
+	return ic, nil
+}
+
+//ParseIPRangeConfig returns IP ranges database config or error
+func ParseIPRangeConfig(config *ResolverConfig) (*IPRangeConfig, error) {
+	ic := &IPRangeConfig{}
+	if err := jsonutils.UnmarshalConfig(config.Config, ic); err != nil {
+		return nil, err
+	}
+
+	if ic.Path == "" {
+		return nil, errors.New("path is required field")
+	}
+
+	if ic.Format != "" && ic.Format != CSVFormat && ic.Format != MMDBFormat {
+		return nil, fmt.Errorf("unknown format: %s. Available: [%s, %s]", ic.Format, CSVFormat, MMDBFormat)
+	}
+
+	return ic, nil
+}
@@ -11,7 +11,7 @@ import (
 type UpdatableProxy struct {
 	factoryMethod func(path string) (Resolver, error)
 
-	maxmindLink string
+	link string
 
 	mutex    *sync.RWMutex
 	resolver Resolver
@@ -20,15 +20,15 @@ type UpdatableProxy struct {
 }
 
 //newResolverProxy creates Resolver immediately and starts goroutine for re-create Resolver
-func newResolverProxy(maxmindLink string, factoryMethod func(path string) (Resolver, error)) (Resolver, error) {
-	underlyingResolver, err := factoryMethod(maxmindLink)
+func newResolverProxy(link string, factoryMethod func(path string) (Resolver, error)) (Resolver, error) {
+	underlyingResolver, err := factoryMethod(link)
 	if err != nil {
 		return nil, err
 	}
 
 	up := &UpdatableProxy{
 		factoryMethod: factoryMethod,
-		maxmindLink:   maxmindLink,
+		link:          link,
 		mutex:         &sync.RWMutex{},
 		resolver:      underlyingResolver,
 		closed:        make(chan struct{}),
@@ -47,15 +47,21 @@ func (up *UpdatableProxy) start() {
 				return
 			case <-ticker.C:
 				logging.Info("running geo resolver databases update..")
-				resolver, err := up.factoryMethod(up.maxmindLink)
+				resolver, err := up.factoryMethod(up.link)
 				if err != nil {
-					logging.SystemErrorf("Error reloading geo resolver [%s]: %v", up.maxmindLink, err)
+					logging.SystemErrorf("Error reloading geo resolver [%s]: %v", up.link, err)
 					continue
 				}
 
 				up.mutex.Lock()
+				oldResolver := up.resolver
 				up.resolver = resolver
 				up.mutex.Unlock()
+
+				//release file handles of the previous database
+				if err := oldResolver.Close(); err != nil {
+					logging.Errorf("Error closing previous geo resolver [%s]: %v", up.link, err)
+				}
 			}
 		}
 	}).WithRestartTimeout(1 * time.Minute)
@@ -3,10 +3,15 @@ package geo
 import "errors"
 
 const (
-	MaxmindType = "maxmind"
-	DummyType   = "dummy"
+	MaxmindType     = "maxmind"
+	IP2LocationType = "ip2location"
+	IPRangeType     = "ip_range"
+	DummyType       = "dummy"
 
 	UKCountry = "UK"
+
+	//SelfTestIP is a well-known public IP which is used for geo resolvers testing
+	SelfTestIP = "8.8.8.8"
 )
 
 var (
@@ -78,3 +83,17 @@ type ResolverConfig struct {
 type MaxMindConfig struct {
 	MaxMindURL string `mapstructure:"maxmind_url" json:"maxmind_url,omitempty" yaml:"maxmind_url,omitempty"`
 }
+
+//IP2LocationConfig is a dto for IP2Location BIN database configuration serialization
+type IP2LocationConfig struct {
+	Path string `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+//IPRangeConfig is a dto for IP ranges database (CSV or MMDB-compatible file) configuration serialization
+//Columns is used for CSV files without header. Mapping is a CSV column name -> geo data field name
+type IPRangeConfig struct {
+	Path    string            `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	Format  string            `mapstructure:"format" json:"format,omitempty" yaml:"format,omitempty"`
+	Columns []string          `mapstructure:"columns" json:"columns,omitempty" yaml:"columns,omitempty"`
+	Mapping map[string]string `mapstructure:"mapping" json:"mapping,omitempty" yaml:"mapping,omitempty"`
+}
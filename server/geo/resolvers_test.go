@@ -0,0 +1,187 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+//buildIP2LocationDB4 returns DB4 (country, region, city, isp) BIN database with IPv4 ranges:
+//[0.0.0.0, 1.0.0.0) - unknown, [1.0.0.0, 2.0.0.0) - US, [2.0.0.0, 3.0.0.0) - sentinel
+func buildIP2LocationDB4(t *testing.T) []byte {
+	const dbColumn = 5
+	rowsCount := uint32(2)
+	rowsAddr := uint32(ip2locationHeaderSize + 1)
+	stringsOffset := ip2locationHeaderSize + int(rowsCount+1)*dbColumn*4
+
+	stringsBuf := &bytes.Buffer{}
+	writeString := func(value string) uint32 {
+		pointer := uint32(stringsOffset + stringsBuf.Len())
+		stringsBuf.WriteByte(byte(len(value)))
+		stringsBuf.WriteString(value)
+		return pointer
+	}
+	writeCountry := func(code, name string) uint32 {
+		pointer := writeString(code)
+		for i := len(code); i < 2; i++ {
+			stringsBuf.WriteByte(0)
+		}
+		writeString(name)
+		return pointer
+	}
+
+	unknown := writeString("-")
+	unknownCountry := writeCountry("-", "-")
+	usCountry := writeCountry("US", "United States")
+	region := writeString("California")
+	city := writeString("Mountain View")
+	isp := writeString("Google LLC")
+
+	buf := &bytes.Buffer{}
+	header := make([]byte, ip2locationHeaderSize)
+	header[0] = 4
+	header[1] = dbColumn
+	binary.LittleEndian.PutUint32(header[5:9], rowsCount)
+	binary.LittleEndian.PutUint32(header[9:13], rowsAddr)
+	buf.Write(header)
+
+	rows := [][]uint32{
+		{0, unknownCountry, unknown, unknown, unknown},
+		{binary.BigEndian.Uint32(net.ParseIP("1.0.0.0").To4()), usCountry, region, city, isp},
+		{binary.BigEndian.Uint32(net.ParseIP("2.0.0.0").To4()), unknownCountry, unknown, unknown, unknown},
+	}
+	for _, row := range rows {
+		for _, column := range row {
+			require.NoError(t, binary.Write(buf, binary.LittleEndian, column))
+		}
+	}
+	buf.Write(stringsBuf.Bytes())
+
+	return buf.Bytes()
+}
+
+func TestIP2LocationResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "IP2LOCATION-DB4.BIN")
+	require.NoError(t, ioutil.WriteFile(path, buildIP2LocationDB4(t), 0644))
+
+	resolver, err := NewIP2LocationResolver(path)
+	require.NoError(t, err)
+	defer resolver.Close()
+
+	require.Equal(t, IP2LocationType, resolver.Type())
+
+	data, err := resolver.Resolve("1.2.3.4")
+	require.NoError(t, err)
+	require.Equal(t, &Data{Country: "US", CountryName: "United States", Region: "California", City: "Mountain View", ISP: "Google LLC"}, data)
+
+	data, err = resolver.Resolve("0.1.2.3")
+	require.NoError(t, err)
+	require.Equal(t, &Data{}, data)
+
+	data, err = resolver.Resolve("5.6.7.8")
+	require.NoError(t, err)
+	require.Equal(t, &Data{}, data)
+
+	_, err = resolver.Resolve("2001:db8::1")
+	require.Error(t, err)
+
+	_, err = resolver.Resolve("")
+	require.Equal(t, EmptyIP, err)
+
+	_, err = newIP2LocationResolver(bytes.NewReader([]byte("not a database")), nil)
+	require.Error(t, err)
+}
+
+func TestIPRangeResolverCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		config   *IPRangeConfig
+		ip       string
+		expected *Data
+	}{
+		{
+			"start and end IPs with aliases",
+			"start_ip,end_ip,country_code,city,asn,as_name\n1.0.0.0,1.0.0.255,AU,Sydney,AS13335,Cloudflare\n8.8.8.0,8.8.8.255,US,Mountain View,15169,Google LLC\n",
+			&IPRangeConfig{},
+			"8.8.8.8",
+			&Data{Country: "US", City: "Mountain View", ASN: 15169, ASO: "Google LLC"},
+		},
+		{
+			"CIDR networks with IPv6",
+			"network,country,isp\n2001:db8::/32,DE,Example ISP\n10.0.0.0/8,US,Private\n",
+			&IPRangeConfig{},
+			"2001:db8::1",
+			&Data{Country: "DE", ISP: "Example ISP"},
+		},
+		{
+			"decimal IPs without header and custom mapping",
+			"\"16777216\",\"16777471\",\"US\",\"Los Angeles\"\n\"16777472\",\"16778239\",\"CN\",\"Fuzhou\"\n",
+			&IPRangeConfig{Columns: []string{"ip_from", "ip_to", "cc", "town"}, Mapping: map[string]string{"cc": "country", "town": "city"}},
+			"1.0.1.5",
+			&Data{Country: "CN", City: "Fuzhou"},
+		},
+		{
+			"not found",
+			"start_ip,end_ip,country\n1.0.0.0,1.0.0.255,AU\n",
+			&IPRangeConfig{},
+			"1.0.1.0",
+			&Data{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ranges.csv")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tt.content), 0644))
+
+			resolver, err := NewIPRangeResolver(path, tt.config)
+			require.NoError(t, err)
+			defer resolver.Close()
+
+			data, err := resolver.Resolve(tt.ip)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, data)
+		})
+	}
+}
+
+func TestIPRangeResolverCSVErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.csv")
+	require.NoError(t, ioutil.WriteFile(path, []byte("ip,country\n1.0.0.0,AU\n"), 0644))
+
+	_, err := NewIPRangeResolver(path, &IPRangeConfig{})
+	require.Error(t, err)
+
+	_, err = NewIPRangeResolver(path, &IPRangeConfig{Format: "xml"})
+	require.Error(t, err)
+}
+
+func TestDataFromMMDBRecord(t *testing.T) {
+	nested := map[string]interface{}{
+		"country":      map[string]interface{}{"iso_code": "US", "names": map[string]interface{}{"en": "United States"}},
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": "New York"}},
+		"location":     map[string]interface{}{"latitude": 40.78, "longitude": -73.95},
+		"subdivisions": []interface{}{map[string]interface{}{"iso_code": "NY"}},
+	}
+	require.Equal(t, &Data{Country: "US", CountryName: "United States", City: "New York", Lat: 40.78, Lon: -73.95, Region: "NY"}, dataFromMMDBRecord(nested))
+
+	flat := map[string]interface{}{"country": "US", "asn": "AS15169", "as_name": "Google LLC", "as_domain": "google.com"}
+	require.Equal(t, &Data{Country: "US", ASN: 15169, ASO: "Google LLC", Domain: "google.com"}, dataFromMMDBRecord(flat))
+}
+
+func TestParseResolverConfigs(t *testing.T) {
+	_, err := ParseIP2LocationConfig(&ResolverConfig{Type: IP2LocationType, Config: map[string]interface{}{}})
+	require.EqualError(t, err, "path is required field")
+
+	ipRangeConfig, err := ParseIPRangeConfig(&ResolverConfig{Type: IPRangeType, Config: map[string]interface{}{"path": "/data/ranges.mmdb", "format": "mmdb"}})
+	require.NoError(t, err)
+	require.Equal(t, &IPRangeConfig{Path: "/data/ranges.mmdb", Format: MMDBFormat}, ipRangeConfig)
+
+	_, err = ParseIPRangeConfig(&ResolverConfig{Type: IPRangeType, Config: map[string]interface{}{"path": "ranges.xml", "format": "xml"}})
+	require.Error(t, err)
+}
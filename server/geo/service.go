@@ -2,6 +2,7 @@ package geo
 
 import (
 	"context"
+	"fmt"
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/resources"
@@ -160,13 +161,7 @@ func (s *Service) init(rc map[string]*ResolverConfig) {
 			s.resolversMutex.Unlock()
 		}
 
-		maxmindlink, err := ParseConfigAsLink(config)
-		if err != nil {
-			logging.Errorf("[%s] Error initializing geo resolver of type %s: %v", id, config.Type, err)
-			continue
-		}
-
-		resolverProxy, err := newResolverProxy(maxmindlink, s.factory.Create)
+		resolverProxy, err := s.createResolver(config)
 		if err != nil {
 			logging.Errorf("[%s] Error initializing geo resolver of type %s: %v", id, config.Type, err)
 			continue
@@ -180,7 +175,56 @@ func (s *Service) init(rc map[string]*ResolverConfig) {
 		s.resolversMutex.Unlock()
 
 		logging.Infof("📍 [%s] geo resolver has been initialized!", id)
+
+		selfTest(id, resolverProxy)
+	}
+}
+
+//createResolver creates auto updatable Resolver according to the config type
+func (s *Service) createResolver(config *ResolverConfig) (Resolver, error) {
+	switch config.Type {
+	case MaxmindType:
+		maxmindlink, err := ParseConfigAsLink(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return newResolverProxy(maxmindlink, s.factory.Create)
+	case IP2LocationType:
+		ip2locationConfig, err := ParseIP2LocationConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return newResolverProxy(ip2locationConfig.Path, NewIP2LocationResolver)
+	case IPRangeType:
+		ipRangeConfig, err := ParseIPRangeConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return newResolverProxy(ipRangeConfig.Path, func(path string) (Resolver, error) {
+			return NewIPRangeResolver(path, ipRangeConfig)
+		})
+	default:
+		return nil, fmt.Errorf("unsupported geo resolver type: %s", config.Type)
+	}
+}
+
+//selfTest resolves SelfTestIP and writes result into the log
+func selfTest(id string, resolver Resolver) {
+	data, err := resolver.Resolve(SelfTestIP)
+	if err != nil {
+		logging.Warnf("❌ [%s] geo resolver self-test failed: error resolving %s: %v", id, SelfTestIP, err)
+		return
 	}
+
+	if data == nil || data.Country == "" {
+		logging.Warnf("❌ [%s] geo resolver self-test failed: country of %s wasn't resolved. Please check the database", id, SelfTestIP)
+		return
+	}
+
+	logging.Infof("✅ [%s] geo resolver self-test passed: %s resolved as country: %s city: %s asn: %d isp: %s", id, SelfTestIP, data.Country, data.City, data.ASN, data.ISP)
 }
 
 func (s *Service) GetGeoResolver(id string) Resolver {
@@ -208,6 +252,41 @@ func (s *Service) TestGeoResolver(url string) ([]*EditionRule, error) {
 	return s.factory.Test(url)
 }
 
+//TestResolver creates Resolver from the config, resolves the ip and closes the Resolver
+//It is used for testing non-MaxMind geo resolvers configuration
+func (s *Service) TestResolver(config *ResolverConfig, ip string) (*Data, error) {
+	if ip == "" {
+		ip = SelfTestIP
+	}
+
+	var resolver Resolver
+	switch config.Type {
+	case IP2LocationType:
+		ip2locationConfig, err := ParseIP2LocationConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if resolver, err = NewIP2LocationResolver(ip2locationConfig.Path); err != nil {
+			return nil, err
+		}
+	case IPRangeType:
+		ipRangeConfig, err := ParseIPRangeConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if resolver, err = NewIPRangeResolver(ipRangeConfig.Path, ipRangeConfig); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geo resolver type for testing: %s", config.Type)
+	}
+	defer resolver.Close()
+
+	return resolver.Resolve(ip)
+}
+
 //GetPaidEditions returns paidEditions
 func (s *Service) GetPaidEditions() []Edition {
 	return paidEditions
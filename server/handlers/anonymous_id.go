@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/cors"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/multiplexing"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const userIdentifyEventType = "user_identify"
+
+//AnonymousIDCookie issues and refreshes first-party anonymous ID cookie (middleware.JitsuAnonymIDCookie)
+//It is used by all handlers which set the cookie so JS SDK and tracking pixel share the same identity
+type AnonymousIDCookie struct {
+	domain   string
+	sameSite http.SameSite
+	ttl      time.Duration
+	httpOnly bool
+}
+
+//NewAnonymousIDCookie returns configured AnonymousIDCookie
+//domain: if empty the top level domain of the request host is used
+//sameSite: none, lax or strict
+//ttlDays: if 0 the cookie practically never expires
+func NewAnonymousIDCookie(domain, sameSite string, ttlDays int, httpOnly bool) *AnonymousIDCookie {
+	var sameSiteMode http.SameSite
+	switch strings.ToLower(sameSite) {
+	case "", "none":
+		sameSiteMode = http.SameSiteNoneMode
+	case "lax":
+		sameSiteMode = http.SameSiteLaxMode
+	case "strict":
+		sameSiteMode = http.SameSiteStrictMode
+	default:
+		logging.SystemErrorf("Unknown anonymous ID cookie same_site value: %q. Supported: none, lax, strict. 'none' will be used", sameSite)
+		sameSiteMode = http.SameSiteNoneMode
+	}
+
+	return &AnonymousIDCookie{
+		domain:   domain,
+		sameSite: sameSiteMode,
+		ttl:      time.Duration(ttlDays) * 24 * time.Hour,
+		httpOnly: httpOnly,
+	}
+}
+
+//ExtractOrIssue returns anonymous ID from the request cookie or generates a new one
+//The cookie is written into the response only when the ID is issued (the cookie doesn't exist or is empty).
+//Server-set cookies from a custom (first-party) domain aren't capped by browsers ITP like JS-set ones
+//domain overrides configured cookie domain if not empty
+func (aic *AnonymousIDCookie) ExtractOrIssue(c *gin.Context, domain string) string {
+	anonymID, err := c.Cookie(middleware.JitsuAnonymIDCookie)
+	if err != nil && err != http.ErrNoCookie {
+		logging.Errorf("Error extracting cookie %q: %v", middleware.JitsuAnonymIDCookie, err)
+		return ""
+	}
+	if anonymID != "" {
+		return anonymID
+	}
+
+	anonymID = strings.ReplaceAll(uuid.New(), "-", "")[:10]
+
+	if domain == "" {
+		domain = aic.domain
+	}
+	if domain == "" {
+		domain, _ = cors.ExtractTopLevelAndDomain(c.Request.Host)
+	}
+
+	expires := timestamp.Now().AddDate(1000, 12, 31)
+	if aic.ttl > 0 {
+		expires = timestamp.Now().Add(aic.ttl)
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     middleware.JitsuAnonymIDCookie,
+		Value:    url.QueryEscape(anonymID),
+		Expires:  expires,
+		Path:     "/",
+		Domain:   domain,
+		SameSite: aic.sameSite,
+		Secure:   true,
+		HttpOnly: aic.httpOnly,
+	})
+
+	return anonymID
+}
+
+//AnonymousIDResponse is a dto for anonymous ID endpoint response
+type AnonymousIDResponse struct {
+	AnonymousID string `json:"anonymous_id"`
+	//Cookieless is true if the cookie wasn't set because of cookie policy. AnonymousID is a hashed one in this case
+	Cookieless bool `json:"cookieless,omitempty"`
+}
+
+//AnonymousIDHandler issues and refreshes first-party anonymous ID cookie
+//and links it with user identifiers in users recognition pipeline
+type AnonymousIDHandler struct {
+	cookie              *AnonymousIDCookie
+	multiplexingService *multiplexing.Service
+	processor           events.Processor
+	destinationService  *destinations.Service
+	geoService          *geo.Service
+}
+
+//NewAnonymousIDHandler returns configured AnonymousIDHandler instance
+func NewAnonymousIDHandler(cookie *AnonymousIDCookie, multiplexingService *multiplexing.Service, processor events.Processor,
+	destinationService *destinations.Service, geoService *geo.Service) *AnonymousIDHandler {
+	return &AnonymousIDHandler{
+		cookie:              cookie,
+		multiplexingService: multiplexingService,
+		processor:           processor,
+		destinationService:  destinationService,
+		geoService:          geoService,
+	}
+}
+
+//Handler returns anonymous ID from the cookie (or issues a new one and sets the cookie)
+//if the request body contains 'user' object with identifiers, passes internal user_identify event
+//to users recognition of destinations with enabled recognition so it links the anonymous ID with them
+func (aih *AnonymousIDHandler) Handler(c *gin.Context) {
+	var identification map[string]interface{}
+	if c.Request.Method == http.MethodPost && c.Request.Body != nil {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error reading request body", err))
+			return
+		}
+
+		if len(body) > 0 {
+			payload := map[string]interface{}{}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				c.JSON(http.StatusBadRequest, middleware.ErrResponse("Error parsing request body", err))
+				return
+			}
+
+			if user, ok := payload["user"]; ok {
+				if identification, ok = user.(map[string]interface{}); !ok {
+					c.JSON(http.StatusBadRequest, middleware.ErrResponse(fmt.Sprintf("'user' must be an object. Got: %T", user), nil))
+					return
+				}
+			}
+		}
+	}
+
+	token := c.GetString(middleware.TokenName)
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
+
+	geoResolver := aih.geoService.GetGlobalGeoResolver()
+	destinationStorages := aih.destinationService.GetDestinations(tokenID)
+	if len(destinationStorages) > 0 {
+		geoResolver = aih.geoService.GetGeoResolver(destinationStorages[0].GetGeoResolverID())
+	}
+
+	reqContext := getRequestContext(c, geoResolver)
+	response := AnonymousIDResponse{AnonymousID: reqContext.JitsuAnonymousID, Cookieless: !reqContext.CookiesLawCompliant}
+	if reqContext.CookiesLawCompliant {
+		response.AnonymousID = aih.cookie.ExtractOrIssue(c, c.Query(cookieDomainField))
+		reqContext.JitsuAnonymousID = response.AnonymousID
+	}
+
+	if len(identification) > 0 && response.AnonymousID != "" {
+		user := map[string]interface{}{}
+		for k, v := range identification {
+			user[k] = v
+		}
+		user["anonymous_id"] = response.AnonymousID
+
+		event := events.Event{"event_type": userIdentifyEventType, "user": user}
+		if err := aih.multiplexingService.AcceptIdentification(aih.processor, reqContext, token, event); err != nil {
+			code := http.StatusBadRequest
+			if err == multiplexing.ErrNoDestinations {
+				code = http.StatusUnprocessableEntity
+				err = fmt.Errorf(noDestinationsErrTemplate, token)
+			}
+
+			logging.Errorf("%v. Anonymous ID identification event: %s", err, event.DebugString())
+			c.JSON(code, middleware.ErrResponse(err.Error(), nil))
+			return
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
+}
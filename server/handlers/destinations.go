@@ -134,6 +134,13 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 			return err
 		}
 		return nil
+	case storages.GA4Type:
+		cfg := &adapters.GA4Config{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		ga4Adapter := adapters.NewTestGA4(cfg)
+		return ga4Adapter.TestAccess()
 	case storages.FacebookType:
 		cfg := &adapters.FacebookConversionAPIConfig{}
 		if err := config.GetDestConfig(config.Facebook, cfg); err != nil {
@@ -155,6 +162,16 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 		}
 		_, err := adapters.NewTag(cfg, identifier)
 		return err
+	case storages.PersonalizationType:
+		cfg := &adapters.PersonalizationConfig{}
+		if err := config.GetDestConfig(map[string]interface{}{}, cfg); err != nil {
+			return err
+		}
+		personalization, err := adapters.NewPersonalization(cfg, identifier)
+		if err != nil {
+			return err
+		}
+		return personalization.Close()
 	case storages.AmplitudeType:
 		cfg := &adapters.AmplitudeConfig{}
 		if err := config.GetDestConfig(config.Amplitude, cfg); err != nil {
@@ -169,6 +186,41 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 		}
 		hubspotAdapter := adapters.NewTestHubSpot(cfg)
 		return hubspotAdapter.TestAccess()
+	case storages.MixpanelType:
+		cfg := &adapters.MixpanelConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		mixpanelAdapter := adapters.NewTestMixpanel(cfg)
+		return mixpanelAdapter.TestAccess()
+	case storages.PostHogType:
+		cfg := &adapters.PostHogConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		postHogAdapter := adapters.NewTestPostHog(cfg)
+		return postHogAdapter.TestAccess()
+	case storages.BrazeType:
+		cfg := &adapters.BrazeConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		brazeAdapter := adapters.NewTestBraze(cfg)
+		return brazeAdapter.TestAccess()
+	case storages.CustomerIOType:
+		cfg := &adapters.CustomerIOConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		customerIOAdapter := adapters.NewTestCustomerIO(cfg)
+		return customerIOAdapter.TestAccess()
+	case storages.IntercomType:
+		cfg := &adapters.IntercomConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		intercomAdapter := adapters.NewTestIntercom(cfg)
+		return intercomAdapter.TestAccess()
 	case storages.DbtCloudType:
 		cfg := &adapters.DbtCloudConfig{}
 		if err := config.GetDestConfig(config.DbtCloud, cfg); err != nil {
@@ -204,6 +256,77 @@ func testDestinationConnection(config *config.DestinationConfig, globalConfigura
 		}
 		defer gcsAdapter.Close()
 		return gcsAdapter.ValidateWritePermission()
+	case storages.FileType:
+		fileDropConfig := &adapters.FileDropConfig{}
+		if err := config.GetDestConfig(nil, fileDropConfig); err != nil {
+			return err
+		}
+		if err := fileDropConfig.Validate(); err != nil {
+			return err
+		}
+		return adapters.ValidateLocalDirectory(fileDropConfig, storages.FileDestinationsBaseDir())
+	case storages.SFTPType:
+		sftpConfig := &adapters.SFTPConfig{}
+		if err := config.GetDestConfig(nil, sftpConfig); err != nil {
+			return err
+		}
+		fileDrop, err := adapters.NewSFTPFileDrop(sftpConfig)
+		if err != nil {
+			return err
+		}
+		defer fileDrop.Close()
+		return fileDrop.ValidateWritePermission()
+	case storages.ElasticsearchType:
+		eventContext.Table.Columns = adapters.Columns{
+			uniqueIDField: typing.SQLColumn{Type: "keyword"},
+			timestamp.Key: typing.SQLColumn{Type: "date"},
+		}
+		return testElasticsearch(config, eventContext, uniqueIDField)
+	case storages.MongoDBType:
+		return testMongoDB(config, eventContext)
+	case storages.SQSType:
+		cfg := &adapters.SQSConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		sqs, err := adapters.NewSQS(cfg)
+		if err != nil {
+			return err
+		}
+		defer sqs.Close()
+		return sqs.TestAccess(cfg.QueueURL)
+	case storages.PubSubType:
+		cfg := &adapters.PubSubConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		pubSub, err := adapters.NewPubSub(context.Background(), cfg)
+		if err != nil {
+			return err
+		}
+		defer pubSub.Close()
+		return pubSub.TestAccess(cfg.Topic)
+	case storages.NATSType:
+		cfg := &adapters.NATSConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		nats, err := adapters.NewNATS(identifier, cfg)
+		if err != nil {
+			return err
+		}
+		defer nats.Close()
+		return nats.TestAccess()
+	case storages.RedisType:
+		cfg := &adapters.RedisConfig{}
+		if err := config.GetDestConfig(nil, cfg); err != nil {
+			return err
+		}
+		redis, err := adapters.NewRedis(identifier, cfg, &logging.QueryLogger{})
+		if err != nil {
+			return err
+		}
+		return redis.Close()
 	case storages.NpmType:
 		plugin := &templates.DestinationPlugin{
 			Package: config.Package,
@@ -346,7 +469,7 @@ func testClickHouse(config *config.DestinationConfig, eventContext *adapters.Eve
 		dsnURL.RawQuery = dsnQuery.Encode()
 
 		ch, err := adapters.NewClickHouse(context.Background(), dsnURL.String(),
-			clickHouseConfig.Database, clickHouseConfig.Cluster, clickHouseConfig.TLS, tableStatementFactory,
+			clickHouseConfig.Database, clickHouseConfig.Cluster, clickHouseConfig.TLS, clickHouseConfig.SSHTunnel, tableStatementFactory,
 			map[string]bool{}, &logging.QueryLogger{}, typing.SQLTypes{})
 		if err != nil {
 			return err
@@ -473,19 +596,13 @@ func testRedshift(config *config.DestinationConfig, eventContext *adapters.Event
 
 // testClickHouse depends on the destination mode:
 // stream: connects to BigQuery, creates table, writes 1 test record, deletes table
-// batch: connects to BigQuery, Google Cloud Storage, creates table, writes 1 test file with 1 test record, copies it to BigQuery, deletes table
+// batch: connects to BigQuery, Google Cloud Storage (if configured), creates table, writes 1 test file with 1 test record, loads it to BigQuery, deletes table
 // returns err if has occurred
 func testBigQuery(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
 	google := &adapters.GoogleConfig{}
 	if err := config.GetDestConfig(config.Google, google); err != nil {
 		return err
 	}
-	if config.Mode == storages.BatchMode {
-		if err := google.ValidateBatchMode(); err != nil {
-			return err
-		}
-	}
-
 	if google.Project == "" {
 		return errors.New("BigQuery project 'bq_project' is required parameter")
 	}
@@ -519,7 +636,7 @@ func testBigQuery(config *config.DestinationConfig, eventContext *adapters.Event
 		bq.Close()
 	}()
 
-	if config.Mode == storages.BatchMode {
+	if config.Mode == storages.BatchMode && google.Bucket != "" {
 		googleStorage, err := adapters.NewGoogleCloudStorage(context.Background(), google)
 		if err != nil {
 			return err
@@ -534,6 +651,10 @@ func testBigQuery(config *config.DestinationConfig, eventContext *adapters.Event
 		if err = bq.Copy(eventContext.Table.Name, eventContext.Table.Name); err != nil {
 			return err
 		}
+	} else if config.Mode == storages.BatchMode {
+		if err = bq.Insert(adapters.NewBatchInsertContext(eventContext.Table, []map[string]interface{}{eventContext.ProcessedEvent}, false, nil)); err != nil {
+			return err
+		}
 	} else {
 		if err = bq.Insert(adapters.NewSingleInsertContext(eventContext)); err != nil {
 			return err
@@ -641,6 +762,59 @@ func testSnowflake(config *config.DestinationConfig, eventContext *adapters.Even
 	return nil
 }
 
+// testElasticsearch connects to Elasticsearch, creates index, writes 1 test document, deletes index
+// returns err if has occurred
+func testElasticsearch(config *config.DestinationConfig, eventContext *adapters.EventContext, uniqueIDField string) error {
+	esConfig := &adapters.ElasticsearchConfig{}
+	if err := config.GetDestConfig(nil, esConfig); err != nil {
+		return err
+	}
+
+	elasticsearch, err := adapters.NewElasticsearch(context.Background(), esConfig, identifiers.NewUniqueID(uniqueIDField), &logging.QueryLogger{}, typing.SQLTypes{})
+	if err != nil {
+		return err
+	}
+
+	if err = elasticsearch.CreateTable(eventContext.Table); err != nil {
+		elasticsearch.Close()
+		return err
+	}
+
+	defer func() {
+		if err := elasticsearch.DropTable(eventContext.Table); err != nil {
+			logging.Errorf("Error deleting index in test connection: %v", err)
+		}
+
+		elasticsearch.Close()
+	}()
+
+	return elasticsearch.Insert(adapters.NewSingleInsertContext(eventContext))
+}
+
+// testMongoDB connects to MongoDB, writes 1 test document, drops the collection
+// returns err if has occurred
+func testMongoDB(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
+	mongoConfig := &adapters.MongoDBConfig{}
+	if err := config.GetDestConfig(nil, mongoConfig); err != nil {
+		return err
+	}
+
+	mongoDB, err := adapters.NewMongoDB(context.Background(), mongoConfig, nil, nil, &logging.QueryLogger{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := mongoDB.DropCollection(eventContext.Table.Name); err != nil {
+			logging.Errorf("Error dropping collection in test connection: %v", err)
+		}
+
+		mongoDB.Close()
+	}()
+
+	return mongoDB.Insert(adapters.NewSingleInsertContext(eventContext))
+}
+
 // testMySQL connects to MySQL, creates table, write 1 test record, deletes table
 // returns err if has occurred
 func testMySQL(config *config.DestinationConfig, eventContext *adapters.EventContext) error {
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
+	"github.com/jitsucom/jitsu/server/destinations"
+	"net/http"
+)
+
+//DestinationsStatusResponse is a dto for destinations status response
+type DestinationsStatusResponse struct {
+	Destinations map[string]*DestinationStatus `json:"destinations"`
+}
+
+//DestinationStatus is a dto for destination status
+//CircuitBreaker is empty if circuit breaker isn't configured
+type DestinationStatus struct {
+	CircuitBreaker *circuitbreaker.Status `json:"circuit_breaker,omitempty"`
+}
+
+//DestinationsStatusHandler handles destinations status requests
+type DestinationsStatusHandler struct {
+	destinations *destinations.Service
+}
+
+//NewDestinationsStatusHandler returns configured DestinationsStatusHandler instance
+func NewDestinationsStatusHandler(destinations *destinations.Service) *DestinationsStatusHandler {
+	return &DestinationsStatusHandler{destinations: destinations}
+}
+
+//Handler returns circuit breaker states of all destinations
+func (dsh *DestinationsStatusHandler) Handler(c *gin.Context) {
+	response := DestinationsStatusResponse{Destinations: map[string]*DestinationStatus{}}
+	for destinationID, circuitBreaker := range dsh.destinations.GetCircuitBreakers() {
+		response.Destinations[destinationID] = &DestinationStatus{CircuitBreaker: circuitBreaker.Status()}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -9,13 +9,16 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/appstatus"
 	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/geo"
+	"github.com/jitsucom/jitsu/server/jsonutils"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/meta"
 	"github.com/jitsucom/jitsu/server/middleware"
@@ -34,6 +37,8 @@ type EventResponse struct {
 	Status       string                   `json:"status"`
 	DeleteCookie bool                     `json:"delete_cookie,omitempty"`
 	SdkExtras    []map[string]interface{} `json:"jitsu_sdk_extras,omitempty"`
+	//Personalization contains results of personalization destinations: key -> result
+	Personalization map[string]interface{} `json:"personalization,omitempty"`
 }
 
 //CachedEvent is a dto for events cache
@@ -151,7 +156,36 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 		eh.CacheRawEvents(eventsArray, cachingDisabled, tokenID, nil, nil)
 	}
 
-	c.JSON(http.StatusOK, EventResponse{Status: "ok", DeleteCookie: !reqContext.CookiesLawCompliant, SdkExtras: extras})
+	response := EventResponse{Status: "ok", DeleteCookie: !reqContext.CookiesLawCompliant}
+	response.SdkExtras, response.Personalization = splitSynchronousResults(extras)
+	c.JSON(http.StatusOK, response)
+}
+
+//splitSynchronousResults returns JS SDK extras (e.g. tags) and personalization object with results under destinations keys
+//results with the same key (several events in the request or several objects from a transform) are merged
+func splitSynchronousResults(results []map[string]interface{}) ([]map[string]interface{}, map[string]interface{}) {
+	var sdkExtras []map[string]interface{}
+	var personalization map[string]interface{}
+	for _, result := range results {
+		if result["type"] != adapters.PersonalizationResultType {
+			sdkExtras = append(sdkExtras, result)
+			continue
+		}
+
+		if personalization == nil {
+			personalization = map[string]interface{}{}
+		}
+		key := fmt.Sprint(result["id"])
+		value := result["value"]
+		currentObject, currentIsObject := personalization[key].(map[string]interface{})
+		valueObject, valueIsObject := value.(map[string]interface{})
+		if currentIsObject && valueIsObject {
+			value = jsonutils.Merge(currentObject, valueObject)
+		}
+		personalization[key] = value
+	}
+
+	return sdkExtras, personalization
 }
 
 //GetHandler returns cached events by destination_ids
@@ -305,6 +339,10 @@ func getRequestContext(c *gin.Context, geoResolver geo.Resolver, eventPayloads .
 		}
 	}
 
+	//consent cookies
+	tcfConsent, _ := c.Cookie(consent.TCFCookieName)
+	gppConsent, _ := c.Cookie(consent.GPPCookieName)
+
 	return &events.RequestContext{
 		UserAgent:           c.Request.UserAgent(),
 		ClientIP:            clientIP,
@@ -312,6 +350,8 @@ func getRequestContext(c *gin.Context, geoResolver geo.Resolver, eventPayloads .
 		JitsuAnonymousID:    jitsuAnonymousID,
 		HashedAnonymousID:   hashedAnonymousID,
 		CookiesLawCompliant: cookiesLawCompliant,
+		TCFConsent:          tcfConsent,
+		GPPConsent:          gppConsent,
 	}
 }
 
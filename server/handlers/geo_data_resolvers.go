@@ -9,8 +9,12 @@ import (
 )
 
 //GeoDataResolverTestRequest is a dto for test endpoint request
+//Type and Config are used for testing non-MaxMind geo data resolvers
 type GeoDataResolverTestRequest struct {
-	MaxMindURL string `json:"maxmind_url"`
+	MaxMindURL string      `json:"maxmind_url"`
+	Type       string      `json:"type,omitempty"`
+	Config     interface{} `json:"config,omitempty"`
+	IP         string      `json:"ip,omitempty"`
 }
 
 //GeoDataResolverTestResponse is a dto for test endpoint response
@@ -18,6 +22,7 @@ type GeoDataResolverTestResponse struct {
 	middleware.StatusResponse
 
 	Editions []*geo.EditionRule `json:"editions"`
+	Data     *geo.Data          `json:"data,omitempty"`
 }
 
 //GeoDataResolverHandler is responsible for testing maxmind connection
@@ -41,6 +46,20 @@ func (gdrh *GeoDataResolverHandler) TestHandler(c *gin.Context) {
 		return
 	}
 
+	if geoTestRequest.Type != "" && geoTestRequest.Type != geo.MaxmindType {
+		data, err := gdrh.service.TestResolver(&geo.ResolverConfig{Type: geoTestRequest.Type, Config: geoTestRequest.Config}, geoTestRequest.IP)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrResponse(err.Error(), nil))
+			return
+		}
+
+		c.JSON(http.StatusOK, &GeoDataResolverTestResponse{
+			StatusResponse: middleware.OKResponse(),
+			Data:           data,
+		})
+		return
+	}
+
 	if geoTestRequest.MaxMindURL == "" {
 		c.JSON(http.StatusBadRequest, middleware.ErrResponse("maxmind_url is required parameter in JSON body", nil))
 		return
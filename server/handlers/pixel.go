@@ -5,12 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/server/appconfig"
-	"github.com/jitsucom/jitsu/server/cors"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
@@ -19,8 +17,6 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"github.com/jitsucom/jitsu/server/multiplexing"
-	"github.com/jitsucom/jitsu/server/timestamp"
-	"github.com/jitsucom/jitsu/server/uuid"
 )
 
 const (
@@ -34,6 +30,7 @@ const (
 type PixelHandler struct {
 	emptyGIF            []byte
 	anonymIDPath        jsonutils.JSONPath
+	anonymIDCookie      *AnonymousIDCookie
 	multiplexingService *multiplexing.Service
 	processor           events.Processor
 	destinationService  *destinations.Service
@@ -41,11 +38,12 @@ type PixelHandler struct {
 }
 
 //NewPixelHandler returns configured PixelHandler instance
-func NewPixelHandler(multiplexingService *multiplexing.Service, processor events.Processor,
+func NewPixelHandler(anonymIDCookie *AnonymousIDCookie, multiplexingService *multiplexing.Service, processor events.Processor,
 	destinationService *destinations.Service, geoService *geo.Service) *PixelHandler {
 	return &PixelHandler{
 		emptyGIF:            appconfig.Instance.EmptyGIFPixelOnexOne,
 		anonymIDPath:        jsonutils.NewJSONPath(anonymIDJSONPath),
+		anonymIDCookie:      anonymIDCookie,
 		multiplexingService: multiplexingService,
 		processor:           processor,
 		destinationService:  destinationService,
@@ -85,7 +83,7 @@ func (ph *PixelHandler) Handle(c *gin.Context) {
 
 	reqContext := getRequestContext(c, geoResolver)
 	if reqContext.CookiesLawCompliant {
-		reqContext.JitsuAnonymousID = ph.extractOrSetAnonymIDCookie(c, event, reqContext)
+		reqContext.JitsuAnonymousID = ph.extractOrSetAnonymIDCookie(c, event)
 	}
 
 	enrichment.HTTPContextEnrichmentStep(c, event)
@@ -142,40 +140,17 @@ func (ph *PixelHandler) parseEvent(c *gin.Context) (events.Event, error) {
 	return event, nil
 }
 
-//extractOrSetAnonymIDCookie if no anoymous id found:
-// 1. gets cookie value (anonym ID)
-// 2. generates and set it if doesn't exist
-//returns anonymous id
-func (ph *PixelHandler) extractOrSetAnonymIDCookie(c *gin.Context, event events.Event, reqContext *events.RequestContext) string {
+//extractOrSetAnonymIDCookie returns anonymous id from the event if exists
+//otherwise gets it from the cookie (or generates a new one and sets the cookie)
+func (ph *PixelHandler) extractOrSetAnonymIDCookie(c *gin.Context, event events.Event) string {
 	if anonymID, ok := ph.anonymIDPath.Get(event); ok {
 		return fmt.Sprint(anonymID)
 	}
 
-	anonymID, err := c.Cookie(middleware.JitsuAnonymIDCookie)
-	if err != nil {
-		if err == http.ErrNoCookie {
-			anonymID = strings.ReplaceAll(uuid.New(), "-", "")[:10]
-
-			topLevelDomain, ok := event[cookieDomainField]
-			if !ok {
-				topLevelDomain, _ = cors.ExtractTopLevelAndDomain(c.Request.Host)
-			}
-
-			http.SetCookie(c.Writer, &http.Cookie{
-				Name:     middleware.JitsuAnonymIDCookie,
-				Value:    url.QueryEscape(anonymID),
-				Expires:  timestamp.Now().AddDate(1000, 12, 31),
-				Path:     "/",
-				Domain:   fmt.Sprint(topLevelDomain),
-				SameSite: http.SameSiteNoneMode,
-				Secure:   true,
-				HttpOnly: false,
-			})
-		} else {
-			logging.Errorf("Error extracting cookie %q: %v", middleware.JitsuAnonymIDCookie, err)
-			return ""
-		}
+	var cookieDomain string
+	if domain, ok := event[cookieDomainField]; ok {
+		cookieDomain = fmt.Sprint(domain)
 	}
 
-	return anonymID
+	return ph.anonymIDCookie.ExtractOrIssue(c, cookieDomain)
 }
@@ -180,7 +180,7 @@ func TestDestinationAdapterInsert(t *testing.T) {
 
 			defer testsuit.Close()
 
-			tableHelperWithoutPK := storages.NewTableHelper(testsuit.Schema, testsuit.adapter, coordination.NewInMemoryService(""), map[string]bool{}, tt.types, 0, tt.destinationType)
+			tableHelperWithoutPK := storages.NewTableHelper(testsuit.Schema, testsuit.adapter, coordination.NewInMemoryService(""), map[string]bool{}, nil, tt.types, 0, tt.destinationType)
 
 			processor, err := schema.NewProcessor("test", &config.DestinationConfig{}, true, "replace_me", schema.DummyMapper{}, nil, schema.NewFlattener(), schema.NewTypeResolver(), appconfig.Instance.GlobalUniqueIDField, 0, "new", false)
 			require.NoError(t, err)
@@ -213,7 +213,7 @@ func TestDestinationAdapterInsert(t *testing.T) {
 			require.Equal(t, tt.generateBatchObjects+tt.generateStreamObjects, rowsUnique)
 
 			tableHelperWitPK := storages.NewTableHelper(testsuit.Schema, testsuit.adapter, coordination.NewInMemoryService(""),
-				map[string]bool{appconfig.Instance.GlobalUniqueIDField.GetFlatFieldName(): true}, tt.types, 0, tt.destinationType)
+				map[string]bool{appconfig.Instance.GlobalUniqueIDField.GetFlatFieldName(): true}, nil, tt.types, 0, tt.destinationType)
 
 			// -- test batch with PK --
 			data, err = test.NewRandomGenerator(appconfig.Instance.GlobalUniqueIDField).GenerateDataWithUniqueIDs(10, tt.generateBatchPKObjects, tt.generateBatchPKUniqueIDs)
@@ -399,7 +399,7 @@ func initializeTestSuite(t *testing.T, destinationType string) (*InsertTestSuite
 			return nil, fmt.Errorf("failed to initialize table statement factory: %v", err)
 		}
 
-		adapter, err := adapters.NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, tsf, map[string]bool{},
+		adapter, err := adapters.NewClickHouse(ctx, container.Dsns[0], container.Database, "", nil, nil, tsf, map[string]bool{},
 			&logging.QueryLogger{}, typing.SQLTypes{})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create adapter: %v", err)
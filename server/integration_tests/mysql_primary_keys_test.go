@@ -48,7 +48,7 @@ func TestMySQLPrimaryKeyNotRemoved(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, mySQL)
 
-	tableHelperWithPk := storages.NewTableHelper(container.Database, mySQL, coordination.NewInMemoryService(""), map[string]bool{"email": true}, adapters.SchemaToMySQL, 0, storages.MySQLType)
+	tableHelperWithPk := storages.NewTableHelper(container.Database, mySQL, coordination.NewInMemoryService(""), map[string]bool{"email": true}, nil, adapters.SchemaToMySQL, 0, storages.MySQLType)
 
 	// all events should be merged as have the same PK value
 	tableWithMerge := tableHelperWithPk.MapTableSchema(&schema.BatchHeader{
@@ -71,7 +71,7 @@ func TestMySQLPrimaryKeyNotRemoved(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, rowsUnique)
 
-	tableHelperWithoutPk := storages.NewTableHelper(container.Database, mySQL, coordination.NewInMemoryService(""), map[string]bool{}, adapters.SchemaToMySQL, 0, storages.MySQLType)
+	tableHelperWithoutPk := storages.NewTableHelper(container.Database, mySQL, coordination.NewInMemoryService(""), map[string]bool{}, nil, adapters.SchemaToMySQL, 0, storages.MySQLType)
 	// all events should be merged as have the same PK value
 	table := tableHelperWithoutPk.MapTableSchema(&schema.BatchHeader{
 		TableName: "users",
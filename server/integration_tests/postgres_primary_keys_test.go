@@ -41,7 +41,7 @@ func TestPostgresPrimaryKeyRemoval(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, pg)
 
-	tableHelperWithPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{"email": true}, adapters.SchemaToPostgres, 0, storages.PostgresType)
+	tableHelperWithPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{"email": true}, nil, adapters.SchemaToPostgres, 0, storages.PostgresType)
 
 	// all events should be merged as have the same PK value
 	tableWithMerge := tableHelperWithPk.MapTableSchema(&schema.BatchHeader{
@@ -64,7 +64,7 @@ func TestPostgresPrimaryKeyRemoval(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 1, rowsUnique)
 
-	tableHelperWithoutPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{}, adapters.SchemaToPostgres, 0, storages.PostgresType)
+	tableHelperWithoutPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{}, nil, adapters.SchemaToPostgres, 0, storages.PostgresType)
 	// all events should be merged as have the same PK value
 	table := tableHelperWithoutPk.MapTableSchema(&schema.BatchHeader{
 		TableName: "users",
@@ -109,7 +109,7 @@ func TestPostgresNotManagedPrimaryKeyRemoval(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, pg)
 
-	tableHelperWithPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{"email": true}, adapters.SchemaToPostgres, 0, storages.PostgresType)
+	tableHelperWithPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{"email": true}, nil, adapters.SchemaToPostgres, 0, storages.PostgresType)
 
 	// users table
 	tableBatchHeader := &schema.BatchHeader{
@@ -144,7 +144,7 @@ func TestPostgresNotManagedPrimaryKeyRemoval(t *testing.T) {
 	require.Equal(t, 5, rowsUnique)
 
 	//check that Jitsu mustn't delete primary key
-	tableHelperWithoutPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{}, adapters.SchemaToPostgres, 0, storages.PostgresType)
+	tableHelperWithoutPk := storages.NewTableHelper(container.Schema, pg, coordination.NewInMemoryService(""), map[string]bool{}, nil, adapters.SchemaToPostgres, 0, storages.PostgresType)
 	// all events should be merged as have the same PK value
 	table := tableHelperWithoutPk.MapTableSchema(&schema.BatchHeader{
 		TableName: "users",
@@ -23,7 +23,7 @@ func Merge(left map[string]interface{}, right map[string]interface{}) map[string
 					lvObj = Merge(lvObj, rvObj)
 					left[rk] = lvObj
 				} else {
-					lvObj[rk] = rv
+					left[rk] = rv
 				}
 			} else {
 				left[rk] = rv
@@ -181,6 +181,22 @@ func TestMerge(t *testing.T) {
 				},
 			},
 		},
+		{
+			"overwrite value with object",
+			map[string]interface{}{
+				"field1": "subfield1",
+			},
+			map[string]interface{}{
+				"field1": map[string]interface{}{
+					"subfield1": 123,
+				},
+			},
+			map[string]interface{}{
+				"field1": map[string]interface{}{
+					"subfield1": 123,
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
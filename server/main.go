@@ -380,6 +380,7 @@ func main() {
 		logging.Fatal(err)
 	}
 	appconfig.Instance.ScheduleClosing(destinationsService)
+	enrichment.InitLookupQuery(destinationsService.Query)
 
 	userRecognitionStorage, err := users.InitializeStorage(globalRecognitionConfiguration.Enabled, metaStorageConfiguration)
 	if err != nil {
@@ -517,7 +518,7 @@ func main() {
 	segmentProcessor := events.NewSegmentProcessor(usersRecognitionService)
 	processorHolder := events.NewProcessorHolder(apiProcessor, jsProcessor, pixelProcessor, segmentProcessor, bulkProcessor)
 
-	multiplexingService := multiplexing.NewService(destinationsService)
+	multiplexingService := multiplexing.NewService(destinationsService, eventsCache)
 	walService := wal.NewService(logEventPath, loggerFactory.CreateWriteAheadLogger(), multiplexingService, processorHolder)
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
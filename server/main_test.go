@@ -690,6 +690,137 @@ func TestPixelEndpoint(t *testing.T) {
 	}
 }
 
+func TestAnonymousIDEndpoint(t *testing.T) {
+	uuid.InitMock()
+	binding.EnableDecoderUseNumber = true
+
+	SetTestDefaultParams()
+	tests := []struct {
+		Name                 string
+		Method               string
+		ReqURN               string
+		Body                 string
+		CookieAnonymIDValue  string
+		ExpectedCode         int
+		ExpectedAnonymID     string
+		ExpectedCookie       bool
+		ExpectedCookieDomain string
+		ExpectedUser         map[string]interface{}
+	}{
+		{
+			Name:         "Wrong token",
+			Method:       http.MethodGet,
+			ReqURN:       "/api/v1/id?token=wrongtoken",
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		{
+			Name:                 "New anonymous id",
+			Method:               http.MethodGet,
+			ReqURN:               "/api/v1/id?token=c2stoken",
+			ExpectedCode:         http.StatusOK,
+			ExpectedAnonymID:     "mockeduuid",
+			ExpectedCookie:       true,
+			ExpectedCookieDomain: "jitsu.com",
+		},
+		{
+			Name:                 "Existing anonymous id is refreshed on custom domain",
+			Method:               http.MethodGet,
+			ReqURN:               "/api/v1/id?token=c2stoken&cookie_domain=example.com",
+			CookieAnonymIDValue:  "dan3o12ndnsd",
+			ExpectedCode:         http.StatusOK,
+			ExpectedAnonymID:     "dan3o12ndnsd",
+			ExpectedCookie:       true,
+			ExpectedCookieDomain: "example.com",
+		},
+		{
+			Name:             "Cookie policy strict",
+			Method:           http.MethodGet,
+			ReqURN:           "/api/v1/id?token=c2stoken&cookie_policy=strict",
+			ExpectedCode:     http.StatusOK,
+			ExpectedAnonymID: "bd802ebfaa2fc535520c79cf72edc313",
+		},
+		{
+			Name:                 "Identification",
+			Method:               http.MethodPost,
+			ReqURN:               "/api/v1/id?token=c2stoken",
+			Body:                 `{"user":{"id":"user1","email":"user@jitsu.com"}}`,
+			CookieAnonymIDValue:  "dan3o12ndnsd",
+			ExpectedCode:         http.StatusOK,
+			ExpectedAnonymID:     "dan3o12ndnsd",
+			ExpectedCookie:       true,
+			ExpectedCookieDomain: "jitsu.com",
+			ExpectedUser:         map[string]interface{}{"id": "user1", "email": "user@jitsu.com", "anonymous_id": "dan3o12ndnsd"},
+		},
+		{
+			Name:         "Malformed identification",
+			Method:       http.MethodPost,
+			ReqURN:       "/api/v1/id?token=c2stoken",
+			Body:         `{"user":"user1"}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			testSuite := testsuit.NewSuiteBuilder(t).Build(t)
+			defer testSuite.Close()
+
+			req, err := http.NewRequest(tt.Method, "http://"+testSuite.HTTPAuthority()+tt.ReqURN, strings.NewReader(tt.Body))
+			require.NoError(t, err)
+
+			req.Host = "app.jitsu.com"
+			req.Header.Add("user-agent", "Mozilla/5.0 (iPod; CPU iPhone OS 12_0 like macOS) AppleWebKit/602.1.50 (KHTML, like Gecko) Version/12.0 Mobile/14A5335d Safari/602.1.50")
+			if tt.CookieAnonymIDValue != "" {
+				req.AddCookie(&http.Cookie{Name: middleware.JitsuAnonymIDCookie, Value: tt.CookieAnonymIDValue})
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			b, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			require.Equal(t, tt.ExpectedCode, resp.StatusCode, string(b))
+			if tt.ExpectedCode != http.StatusOK {
+				return
+			}
+
+			response := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(b, &response))
+			require.Equal(t, tt.ExpectedAnonymID, response["anonymous_id"])
+
+			var cookie *http.Cookie
+			for _, c := range resp.Cookies() {
+				if c.Name == middleware.JitsuAnonymIDCookie {
+					cookie = c
+				}
+			}
+			if tt.ExpectedCookie {
+				require.NotNil(t, cookie, "Expected Jitsu cookie doesn't exist in the response")
+				require.Equal(t, tt.ExpectedAnonymID, cookie.Value)
+				require.Equal(t, tt.ExpectedCookieDomain, cookie.Domain)
+				require.Equal(t, http.SameSiteNoneMode, cookie.SameSite)
+				require.True(t, cookie.Secure)
+			} else {
+				require.Nil(t, cookie, "Jitsu cookie must not be set")
+			}
+
+			if tt.ExpectedUser != nil {
+				time.Sleep(200 * time.Millisecond)
+				require.NotEmpty(t, logging.InstanceMock.Data)
+
+				actual := map[string]interface{}{}
+				require.NoError(t, json.Unmarshal(logging.InstanceMock.Data[len(logging.InstanceMock.Data)-1], &actual))
+				require.Equal(t, "user_identify", actual["event_type"])
+				user, ok := actual["user"].(map[string]interface{})
+				require.True(t, ok)
+				for k, v := range tt.ExpectedUser {
+					require.Equal(t, v, user[k], k)
+				}
+			}
+		})
+	}
+}
+
 func TestIPCookiePolicyComply(t *testing.T) {
 	uuid.InitMock()
 	binding.EnableDecoderUseNumber = true
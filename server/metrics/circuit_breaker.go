@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var circuitBreakerLabels = []string{"project_id", "destination_type", "destination_id"}
+
+var (
+	circuitBreakerState *prometheus.GaugeVec
+	circuitBreakerOpens *prometheus.CounterVec
+)
+
+func initCircuitBreaker() {
+	circuitBreakerState = NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "circuit_breaker_state",
+	}, circuitBreakerLabels)
+	circuitBreakerOpens = NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "circuit_breaker_opens",
+	}, circuitBreakerLabels)
+}
+
+//SetCircuitBreakerState sets destination circuit breaker state: 0 - closed, 1 - half-open, 2 - open
+func SetCircuitBreakerState(destinationType, destinationName string, value int) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		circuitBreakerState.WithLabelValues(projectID, destinationType, destinationID).Set(float64(value))
+	}
+}
+
+//CircuitBreakerOpened increments count of destination circuit breaker openings
+func CircuitBreakerOpened(destinationType, destinationName string) {
+	if Enabled() {
+		projectID, destinationID := extractLabels(destinationName)
+		circuitBreakerOpens.WithLabelValues(projectID, destinationType, destinationID).Inc()
+	}
+}
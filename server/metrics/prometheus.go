@@ -56,6 +56,7 @@ func Init(exported bool) {
 	initUsersRecognitionQueue()
 	initUsersRecognitionRedis()
 	initStreamEventsQueue()
+	initCircuitBreaker()
 }
 
 func InitRelay(clusterID string, viper *viper.Viper) *Relay {
@@ -9,12 +9,12 @@ import (
 	"github.com/jitsucom/jitsu/server/cors"
 )
 
-//Cors handles OPTIONS requests and check if request /event or dynamic event endpoint or static endpoint (/t /s /p)
+//Cors handles OPTIONS requests and check if request /event, /id or dynamic event endpoint or static endpoint (/t /s /p)
 //if token ok => check origins - if matched write origin to acao header otherwise don't write it
 //if not returns 401
 func Cors(h http.Handler, isAllowedOriginsFunc func(string) ([]string, bool)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/api/v1/event" || r.URL.Path == "/api/v1/events" || strings.Contains(r.URL.Path, "/api.") {
+		if r.URL.Path == "/api/v1/event" || r.URL.Path == "/api/v1/events" || r.URL.Path == "/api/v1/id" || strings.Contains(r.URL.Path, "/api.") {
 			writeDefaultCorsHeaders(w)
 
 			token := extractToken(r)
@@ -3,11 +3,16 @@ package multiplexing
 import (
 	"errors"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/counters"
 	"github.com/jitsucom/jitsu/server/destinations"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/storages"
+	"sync"
 )
 
 var (
@@ -17,12 +22,14 @@ var (
 //Service is a service for accepting, multiplexing events and sending to consumers
 type Service struct {
 	destinationService *destinations.Service
+	eventsCache        *caching.EventsCache
 }
 
 //NewService returns configured Service instance
-func NewService(destinationService *destinations.Service) *Service {
+func NewService(destinationService *destinations.Service, eventsCache *caching.EventsCache) *Service {
 	return &Service{
 		destinationService: destinationService,
+		eventsCache:        eventsCache,
 	}
 }
 
@@ -47,30 +54,54 @@ func (s *Service) AcceptRequest(processor events.Processor, reqContext *events.R
 			logging.SystemErrorf("[%s] Empty extracted unique identifier in: %s", destinationStorages[0].ID(), payload.DebugString())
 		}
 
+		//** Consent **
+		deniedDestinations := s.evaluateConsent(payload, reqContext, destinationStorages)
+		if len(deniedDestinations) > 0 {
+			//batch destinations share the same incoming log so the decision is kept in the event and applied by the destination processor
+			denied := map[string]interface{}{}
+			for destinationID, err := range deniedDestinations {
+				denied[destinationID] = err.Reason
+			}
+			payload[consent.DeniedField] = denied
+		}
+
 		//** Multiplexing **
-		consumers := s.destinationService.GetConsumers(tokenID)
+		consumers := s.destinationService.GetConsumersByID(tokenID)
 		synchronousStorages := s.destinationService.GetSynchronousStorages(tokenID)
 		if len(consumers) == 0 && len(synchronousStorages) == 0 {
 			counters.SkipPushSourceEvents(tokenID, 1)
 			return nil, ErrNoDestinations
 		}
 
-		for _, consumer := range consumers {
+		for id, consumer := range consumers {
+			if err, ok := deniedDestinations[id]; ok {
+				s.skipEvent(id, payload, err)
+				continue
+			}
 			consumer.Consume(payload, tokenID)
 		}
 
+		var syncWorkers []*storages.SyncWorker
 		for _, sc := range synchronousStorages {
+			if err, ok := deniedDestinations[sc.ID()]; ok {
+				s.skipEvent(sc.ID(), payload, err)
+				continue
+			}
 			synchronousStorage, ok := sc.Get()
 			if ok {
 				syncWorker := synchronousStorage.GetSyncWorker()
 				if syncWorker != nil {
-					extras = append(extras, syncWorker.ProcessEvent(payload, tokenID)...)
+					syncWorkers = append(syncWorkers, syncWorker)
 				}
 			}
 		}
+		extras = append(extras, processSynchronously(syncWorkers, payload, tokenID)...)
 
 		var destinationIDs []string
 		for _, destinationProxy := range destinationStorages {
+			if _, ok := deniedDestinations[destinationProxy.ID()]; ok {
+				continue
+			}
 			destinationIDs = append(destinationIDs, destinationProxy.ID())
 		}
 		//Retroactive users recognition
@@ -81,3 +112,104 @@ func (s *Service) AcceptRequest(processor events.Processor, reqContext *events.R
 
 	return extras, nil
 }
+
+//AcceptIdentification enriches the internal identification event and passes it only to users recognition
+//of destinations with enabled recognition. The event isn't sent to destinations because it has been synthesized by Jitsu
+func (s *Service) AcceptIdentification(processor events.Processor, reqContext *events.RequestContext, token string, event events.Event) error {
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
+	destinationStorages := s.destinationService.GetDestinations(tokenID)
+	if len(destinationStorages) == 0 {
+		return ErrNoDestinations
+	}
+
+	enrichment.ContextEnrichmentStep(event, token, reqContext, processor, destinationStorages[0].GetUniqueIDField())
+	eventID := destinationStorages[0].GetUniqueIDField().Extract(event)
+
+	deniedDestinations := s.evaluateConsent(event, reqContext, destinationStorages)
+	var destinationIDs []string
+	for _, destinationProxy := range destinationStorages {
+		if _, ok := deniedDestinations[destinationProxy.ID()]; ok {
+			continue
+		}
+		storage, ok := destinationProxy.Get()
+		if !ok || storage.IsStaging() || !storage.GetUsersRecognition().IsEnabled() {
+			continue
+		}
+		destinationIDs = append(destinationIDs, destinationProxy.ID())
+	}
+	if len(destinationIDs) > 0 {
+		processor.Postprocess(event, eventID, destinationIDs, tokenID)
+	}
+
+	return nil
+}
+
+//processSynchronously returns results of synchronous destinations in the order of the workers
+//destinations process the event in parallel so the response waits only for the slowest one
+func processSynchronously(syncWorkers []*storages.SyncWorker, payload events.Event, tokenID string) []map[string]interface{} {
+	if len(syncWorkers) == 1 {
+		return syncWorkers[0].ProcessEvent(payload, tokenID)
+	}
+
+	results := make([][]map[string]interface{}, len(syncWorkers))
+	var wg sync.WaitGroup
+	for i, syncWorker := range syncWorkers {
+		i, syncWorker := i, syncWorker
+		wg.Add(1)
+		safego.Run(func() {
+			defer wg.Done()
+			results[i] = syncWorker.ProcessEvent(payload, tokenID)
+		})
+	}
+	wg.Wait()
+
+	var extras []map[string]interface{}
+	for _, result := range results {
+		extras = append(extras, result...)
+	}
+	return extras
+}
+
+//evaluateConsent returns destination ID -> error for destinations which consent requirements aren't met
+//consent strings are parsed only if at least one destination has requirements
+func (s *Service) evaluateConsent(payload events.Event, reqContext *events.RequestContext, destinationStorages []storages.StorageProxy) map[string]*consent.DeniedError {
+	var consentStrings *consent.Strings
+	var parsed *consent.Consent
+	var parseErr error
+	denied := map[string]*consent.DeniedError{}
+	for _, destinationProxy := range destinationStorages {
+		requirements := destinationProxy.GetConsentRequirements()
+		if requirements.IsEmpty() {
+			continue
+		}
+
+		if consentStrings == nil {
+			consentStrings = consent.Extract(payload, reqContext.TCFConsent, reqContext.GPPConsent)
+			if !consentStrings.IsEmpty() {
+				parsed, parseErr = consentStrings.Parse()
+			}
+		}
+
+		if err := requirements.Evaluate(consentStrings, parsed, parseErr); err != nil {
+			if deniedErr, ok := err.(*consent.DeniedError); ok {
+				denied[destinationProxy.ID()] = deniedErr
+			}
+		}
+	}
+
+	return denied
+}
+
+//skipEvent writes skip to counters and the reason to events cache
+func (s *Service) skipEvent(destinationID string, payload events.Event, err error) {
+	if !appconfig.Instance.DisableSkipEventsWarn {
+		logging.Debugf("[%s] Event [%s]: %v", destinationID, payload.DebugString(), err)
+	}
+	counters.SkipPushDestinationEvents(destinationID, 1)
+
+	cacheDisabled := false
+	if destinationProxy, ok := s.destinationService.GetDestinationByID(destinationID); ok {
+		cacheDisabled = destinationProxy.IsCachingDisabled()
+	}
+	s.eventsCache.Skip(cacheDisabled, destinationID, payload.Serialize(), err.Error())
+}
@@ -75,6 +75,26 @@ const (
 			]
 		}
 	]
+}`
+	circuitBreakerTemplate = `{
+    "text": "*%s %s* [%s]: Destination [%s] circuit breaker is %s",
+	"attachments": [
+		{
+			"color": "%s",
+			"blocks": [
+				{
+					"type": "divider"
+				},
+				{
+					"type": "section",
+					"text": {
+						"type": "mrkdwn",
+						"text": "%s"
+					}
+				}
+			]
+		}
+	]
 }`
 )
 
@@ -278,6 +298,26 @@ func SystemError(msg ...interface{}) {
 	}
 }
 
+//CircuitBreakerOpened sends notification about paused destination
+func CircuitBreakerOpened(destinationID, details string) {
+	if instance != nil {
+		enqueueMessage(fmt.Sprintf(circuitBreakerTemplate, instance.serviceName, instance.version, instance.serverName, destinationID, "open", "#d9534f", escapeJSON(details)))
+	}
+}
+
+//CircuitBreakerClosed sends notification about resumed destination
+func CircuitBreakerClosed(destinationID, details string) {
+	if instance != nil {
+		enqueueMessage(fmt.Sprintf(circuitBreakerTemplate, instance.serviceName, instance.version, instance.serverName, destinationID, "closed", "#5cb85c", escapeJSON(details)))
+	}
+}
+
+//escapeJSON returns the value escaped for putting into JSON string
+func escapeJSON(value string) string {
+	b, _ := json.Marshal(value)
+	return string(b[1 : len(b)-1])
+}
+
 func enqueueMessage(message string) {
 	if instance != nil {
 		select {
@@ -0,0 +1,71 @@
+package privacy
+
+import (
+	"regexp"
+	"sort"
+)
+
+const (
+	EmailDetector      = "email"
+	PhoneDetector      = "phone"
+	CreditCardDetector = "credit_card"
+)
+
+//detector finds PII substrings in string values
+type detector struct {
+	name   string
+	regexp *regexp.Regexp
+	//validate is an optional additional check of a matched substring (e.g. Luhn checksum)
+	validate func(match string) bool
+}
+
+var detectors = map[string]*detector{
+	EmailDetector: {
+		name:   EmailDetector,
+		regexp: regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+	},
+	//only international (+1 555 123 4567) or US-style ((555) 123-4567) formats to avoid matching dates and ids
+	PhoneDetector: {
+		name:   PhoneDetector,
+		regexp: regexp.MustCompile(`\+\d{1,3}[\s\-.]?\(?\d{1,4}\)?(?:[\s\-.]?\d{2,4}){2,4}|\(\d{3}\)\s?\d{3}[\s\-.]\d{4}`),
+	},
+	CreditCardDetector: {
+		name:     CreditCardDetector,
+		regexp:   regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		validate: luhnValid,
+	},
+}
+
+func detectorTypes() []string {
+	var types []string
+	for name := range detectors {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+//luhnValid returns true if digits of the input string pass Luhn checksum
+func luhnValid(value string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+
+	return digits >= 13 && sum%10 == 0
+}
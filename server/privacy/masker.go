@@ -0,0 +1,233 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+//MaskedField is a report item about a masked value
+type MaskedField struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	//Detector is set when value was masked by a detector
+	Detector string `json:"detector,omitempty"`
+}
+
+//Masker applies Policy to events
+type Masker struct {
+	ipFields   []jsonutils.JSONPath
+	ipv4Mask   net.IPMask
+	ipv6Mask   net.IPMask
+	salt       []byte
+	hashFields []jsonutils.JSONPath
+	redact     []jsonutils.JSONPath
+
+	detectors       []*detector
+	detectorFields  []jsonutils.JSONPath
+	detectorsAction string
+}
+
+//NewMasker returns configured Masker or nil if policy is empty
+func NewMasker(policy *Policy) (*Masker, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &Masker{redact: newJSONPaths(policy.Redact)}
+	if policy.IP != nil {
+		m.ipFields = newJSONPaths(policy.IP.Fields)
+		m.ipv4Mask = net.CIDRMask(policy.IP.IPv4Prefix, 32)
+		m.ipv6Mask = net.CIDRMask(policy.IP.IPv6Prefix, 128)
+	}
+	if policy.Hash != nil {
+		m.salt = []byte(policy.Hash.Salt)
+		m.hashFields = newJSONPaths(policy.Hash.Fields)
+	}
+	if policy.Detectors != nil {
+		for _, detectorType := range policy.Detectors.Types {
+			m.detectors = append(m.detectors, detectors[strings.ToLower(detectorType)])
+		}
+		for i, expression := range policy.Detectors.Custom {
+			re, err := regexp.Compile(expression)
+			if err != nil {
+				return nil, fmt.Errorf("Error compiling privacy.detectors.custom[%d] regular expression: %v", i, err)
+			}
+			m.detectors = append(m.detectors, &detector{name: fmt.Sprintf("custom_%d", i), regexp: re})
+		}
+		m.detectorFields = newJSONPaths(policy.Detectors.Fields)
+		m.detectorsAction = policy.Detectors.Action
+	}
+
+	return m, nil
+}
+
+//Mask applies policy to the object in place: truncates IPs, hashes and redacts configured fields,
+//then masks detected PII. Returns report about all masked values
+func (m *Masker) Mask(object map[string]interface{}) []MaskedField {
+	if m == nil || object == nil {
+		return nil
+	}
+
+	var masked []MaskedField
+	for _, path := range m.ipFields {
+		value, ok := path.Get(object)
+		if !ok {
+			continue
+		}
+		ipStr, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if truncated, ok := m.truncateIPs(ipStr); ok {
+			path.Set(object, truncated)
+			masked = append(masked, MaskedField{Path: path.String(), Action: TruncateIPAction})
+		}
+	}
+
+	for _, path := range m.hashFields {
+		value, ok := path.Get(object)
+		if !ok || value == nil {
+			continue
+		}
+		path.Set(object, m.hash(fmt.Sprint(value)))
+		masked = append(masked, MaskedField{Path: path.String(), Action: HashAction})
+	}
+
+	for _, path := range m.redact {
+		if _, ok := path.GetAndRemove(object); ok {
+			masked = append(masked, MaskedField{Path: path.String(), Action: RedactAction})
+		}
+	}
+
+	if len(m.detectors) > 0 {
+		if len(m.detectorFields) == 0 {
+			_, detected := m.detect("", object)
+			masked = append(masked, detected...)
+		} else {
+			for _, path := range m.detectorFields {
+				value, ok := path.Get(object)
+				if !ok {
+					continue
+				}
+				newValue, detected := m.detect(path.String(), value)
+				if len(detected) > 0 {
+					path.Set(object, newValue)
+					masked = append(masked, detected...)
+				}
+			}
+		}
+	}
+
+	return masked
+}
+
+//detect recursively scans all string values and masks detected PII
+//returns the value with masked strings. Arrays are copied because they might be shared between events copies
+func (m *Masker) detect(path string, value interface{}) (interface{}, []MaskedField) {
+	var masked []MaskedField
+	switch v := value.(type) {
+	case string:
+		newValue, detected := m.maskString(v)
+		for _, name := range detected {
+			masked = append(masked, MaskedField{Path: path, Action: m.detectorsAction, Detector: name})
+		}
+		return newValue, masked
+	case map[string]interface{}:
+		for key, inner := range v {
+			newInner, innerMasked := m.detect(path+"/"+key, inner)
+			if len(innerMasked) > 0 {
+				v[key] = newInner
+				masked = append(masked, innerMasked...)
+			}
+		}
+		return v, masked
+	case []interface{}:
+		var copied []interface{}
+		for i, inner := range v {
+			newInner, innerMasked := m.detect(fmt.Sprintf("%s/%d", path, i), inner)
+			if len(innerMasked) > 0 {
+				if copied == nil {
+					copied = make([]interface{}, len(v))
+					copy(copied, v)
+				}
+				copied[i] = newInner
+				masked = append(masked, innerMasked...)
+			}
+		}
+		if copied != nil {
+			return copied, masked
+		}
+		return v, masked
+	default:
+		return value, nil
+	}
+}
+
+//maskString replaces all detected substrings and returns result string and names of detectors which matched
+func (m *Masker) maskString(value string) (string, []string) {
+	var detected []string
+	for _, d := range m.detectors {
+		found := false
+		value = d.regexp.ReplaceAllStringFunc(value, func(match string) string {
+			if d.validate != nil && !d.validate(match) {
+				return match
+			}
+			found = true
+			if m.detectorsAction == HashAction {
+				return m.hash(match)
+			}
+			return RedactedValue
+		})
+		if found {
+			detected = append(detected, d.name)
+		}
+	}
+
+	return value, detected
+}
+
+//truncateIPs truncates plain IP or comma separated IPs (e.g. from X-Forwarded-For)
+//returns false if there are no valid IPs
+func (m *Masker) truncateIPs(value string) (string, bool) {
+	parts := strings.Split(value, ",")
+	truncatedAny := false
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+		ip := net.ParseIP(parts[i])
+		if ip == nil {
+			continue
+		}
+		if ipv4 := ip.To4(); ipv4 != nil {
+			parts[i] = ipv4.Mask(m.ipv4Mask).String()
+		} else {
+			parts[i] = ip.Mask(m.ipv6Mask).String()
+		}
+		truncatedAny = true
+	}
+
+	return strings.Join(parts, ", "), truncatedAny
+}
+
+//hash returns hex encoded HMAC-SHA256 of the value with the configured salt
+func (m *Masker) hash(value string) string {
+	mac := hmac.New(sha256.New, m.salt)
+	mac.Write([]byte(value))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+func newJSONPaths(paths []string) []jsonutils.JSONPath {
+	var result []jsonutils.JSONPath
+	for _, path := range paths {
+		result = append(result, jsonutils.NewJSONPath(path))
+	}
+	return result
+}
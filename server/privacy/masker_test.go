@@ -0,0 +1,101 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         *Policy
+		input          map[string]interface{}
+		expected       map[string]interface{}
+		expectedMasked []MaskedField
+	}{
+		{
+			"Nil policy",
+			nil,
+			map[string]interface{}{"source_ip": "1.2.3.4"},
+			map[string]interface{}{"source_ip": "1.2.3.4"},
+			nil,
+		},
+		{
+			"IP truncation with default fields",
+			&Policy{IP: &IPConfig{}},
+			map[string]interface{}{"source_ip": "1.2.3.4", "eventn_ctx": map[string]interface{}{"source_ip": "2001:db8:85a3:1:2:8a2e:370:7334"}},
+			map[string]interface{}{"source_ip": "1.2.3.0", "eventn_ctx": map[string]interface{}{"source_ip": "2001:db8:85a3::"}},
+			[]MaskedField{{Path: "/source_ip", Action: TruncateIPAction}, {Path: "/eventn_ctx/source_ip", Action: TruncateIPAction}},
+		},
+		{
+			"IP truncation of forwarded IPs with custom prefix",
+			&Policy{IP: &IPConfig{Fields: []string{"/ip"}, IPv4Prefix: 16}},
+			map[string]interface{}{"ip": "10.20.30.40, 50.60.70.80", "source_ip": "1.2.3.4"},
+			map[string]interface{}{"ip": "10.20.0.0, 50.60.0.0", "source_ip": "1.2.3.4"},
+			[]MaskedField{{Path: "/ip", Action: TruncateIPAction}},
+		},
+		{
+			"Hash and redact",
+			&Policy{Hash: &HashConfig{Salt: "salt", Fields: []string{"/user/email"}}, Redact: []string{"/user/phone", "/not_exist"}},
+			map[string]interface{}{"user": map[string]interface{}{"email": "a@b.com", "phone": "123", "id": 1}},
+			map[string]interface{}{"user": map[string]interface{}{"email": "d42dc20132e799e856e1c88a927f1a6f7218d30ddbe0dc4ef8fc260a084b1c7b", "id": 1}},
+			[]MaskedField{{Path: "/user/email", Action: HashAction}, {Path: "/user/phone", Action: RedactAction}},
+		},
+		{
+			"Detectors",
+			&Policy{Detectors: &DetectorsConfig{Types: []string{"email", "phone", "credit_card"}}},
+			map[string]interface{}{
+				"url":     "https://site.com/?email=john@doe.com",
+				"comment": "call me +1 555 123 4567 or pay with 4111 1111 1111 1111",
+				"date":    "2021-12-31 23:59:59",
+				"ids":     []interface{}{"123456789012", "jane@doe.com"},
+			},
+			map[string]interface{}{
+				"url":     "https://site.com/?email=[redacted]",
+				"comment": "call me [redacted] or pay with [redacted]",
+				"date":    "2021-12-31 23:59:59",
+				"ids":     []interface{}{"123456789012", "[redacted]"},
+			},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masker, err := NewMasker(tt.policy)
+			require.NoError(t, err)
+
+			masked := masker.Mask(tt.input)
+			test.ObjectsEqual(t, tt.expected, tt.input, "Events aren't equal")
+			if tt.expectedMasked != nil {
+				require.Equal(t, tt.expectedMasked, masked)
+			}
+		})
+	}
+}
+
+func TestMaskDetectorsHashAction(t *testing.T) {
+	masker, err := NewMasker(&Policy{Hash: &HashConfig{Salt: "s"}, Detectors: &DetectorsConfig{Types: []string{"email"}, Action: "hash", Fields: []string{"/text"}}})
+	require.NoError(t, err)
+
+	shared := []interface{}{"a@b.com"}
+	event := map[string]interface{}{"text": "write to a@b.com", "other": "a@b.com", "arr": shared}
+	masked := masker.Mask(event)
+
+	require.Equal(t, []MaskedField{{Path: "/text", Action: HashAction, Detector: EmailDetector}}, masked)
+	require.NotContains(t, event["text"], "a@b.com")
+	require.Equal(t, "a@b.com", event["other"])
+	require.Equal(t, "a@b.com", shared[0])
+}
+
+func TestPolicyValidation(t *testing.T) {
+	require.Error(t, (&Policy{IP: &IPConfig{IPv4Prefix: 33}}).Validate())
+	require.Error(t, (&Policy{Hash: &HashConfig{Fields: []string{"/email"}}}).Validate())
+	require.Error(t, (&Policy{Detectors: &DetectorsConfig{Types: []string{"ssn"}}}).Validate())
+	require.Error(t, (&Policy{Detectors: &DetectorsConfig{Types: []string{"email"}, Action: "hash"}}).Validate())
+	require.NoError(t, (&Policy{Detectors: &DetectorsConfig{Types: []string{"EMAIL"}}}).Validate())
+
+	_, err := NewMasker(&Policy{Detectors: &DetectorsConfig{Custom: []string{"("}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	//HashAction replaces value with salted SHA-256 hash
+	HashAction = "hash"
+	//RedactAction removes field or replaces matched substring with RedactedValue
+	RedactAction = "redact"
+	//TruncateIPAction zeroes trailing bits of IP address
+	TruncateIPAction = "ip_truncate"
+
+	RedactedValue = "[redacted]"
+
+	defaultIPv4Prefix = 24
+	defaultIPv6Prefix = 48
+)
+
+var defaultIPFields = []string{"/source_ip", "/eventn_ctx/source_ip"}
+
+//Policy is a declarative destination privacy configuration. It is applied to every event before storing
+type Policy struct {
+	IP        *IPConfig        `mapstructure:"ip" json:"ip,omitempty" yaml:"ip,omitempty"`
+	Hash      *HashConfig      `mapstructure:"hash" json:"hash,omitempty" yaml:"hash,omitempty"`
+	Redact    []string         `mapstructure:"redact" json:"redact,omitempty" yaml:"redact,omitempty"`
+	Detectors *DetectorsConfig `mapstructure:"detectors" json:"detectors,omitempty" yaml:"detectors,omitempty"`
+}
+
+//IPConfig is a configuration of IP addresses truncation
+//e.g. IPv4Prefix: 24 turns 1.2.3.4 into 1.2.3.0
+type IPConfig struct {
+	Fields     []string `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+	IPv4Prefix int      `mapstructure:"ipv4_prefix" json:"ipv4_prefix,omitempty" yaml:"ipv4_prefix,omitempty"`
+	IPv6Prefix int      `mapstructure:"ipv6_prefix" json:"ipv6_prefix,omitempty" yaml:"ipv6_prefix,omitempty"`
+}
+
+//HashConfig is a configuration of fields which values are replaced with salted hashes
+type HashConfig struct {
+	Salt   string   `mapstructure:"salt" json:"salt,omitempty" yaml:"salt,omitempty"`
+	Fields []string `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+//DetectorsConfig is a configuration of PII detectors which scan all string values (or only Fields if set)
+//and redact or hash matched substrings
+type DetectorsConfig struct {
+	Types  []string `mapstructure:"types" json:"types,omitempty" yaml:"types,omitempty"`
+	Custom []string `mapstructure:"custom" json:"custom,omitempty" yaml:"custom,omitempty"`
+	Fields []string `mapstructure:"fields" json:"fields,omitempty" yaml:"fields,omitempty"`
+	Action string   `mapstructure:"action" json:"action,omitempty" yaml:"action,omitempty"`
+}
+
+//Validate returns err if invalid. Fills default values
+func (p *Policy) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.IP != nil {
+		if len(p.IP.Fields) == 0 {
+			p.IP.Fields = defaultIPFields
+		}
+		if p.IP.IPv4Prefix == 0 {
+			p.IP.IPv4Prefix = defaultIPv4Prefix
+		}
+		if p.IP.IPv6Prefix == 0 {
+			p.IP.IPv6Prefix = defaultIPv6Prefix
+		}
+		if p.IP.IPv4Prefix < 0 || p.IP.IPv4Prefix > 32 {
+			return fmt.Errorf("privacy.ip.ipv4_prefix must be in [0, 32] range. Got: %d", p.IP.IPv4Prefix)
+		}
+		if p.IP.IPv6Prefix < 0 || p.IP.IPv6Prefix > 128 {
+			return fmt.Errorf("privacy.ip.ipv6_prefix must be in [0, 128] range. Got: %d", p.IP.IPv6Prefix)
+		}
+	}
+
+	if p.Hash != nil && len(p.Hash.Fields) > 0 && p.Hash.Salt == "" {
+		return errors.New("privacy.hash.salt is required parameter")
+	}
+
+	if p.Detectors != nil {
+		p.Detectors.Action = strings.ToLower(p.Detectors.Action)
+		switch p.Detectors.Action {
+		case "":
+			p.Detectors.Action = RedactAction
+		case RedactAction:
+		case HashAction:
+			if p.Hash == nil || p.Hash.Salt == "" {
+				return errors.New("privacy.hash.salt is required parameter for detectors with 'hash' action")
+			}
+		default:
+			return fmt.Errorf("Unknown privacy.detectors.action: %s. Available: [%s, %s]", p.Detectors.Action, RedactAction, HashAction)
+		}
+		for _, detectorType := range p.Detectors.Types {
+			if _, ok := detectors[strings.ToLower(detectorType)]; !ok {
+				return fmt.Errorf("Unknown privacy detector type: %s. Available: [%s]", detectorType, strings.Join(detectorTypes(), ", "))
+			}
+		}
+	}
+
+	return nil
+}
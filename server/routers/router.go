@@ -90,8 +90,11 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 	airbyteHandler := handlers.NewAirbyteHandler()
 	sdkSourceHandler := handlers.NewSdkSourceHandler()
 	sourcesHandler := handlers.NewSourcesHandler(sourcesService, metaStorage, destinations)
-	pixelHandler := handlers.NewPixelHandler(multiplexingService, processorHolder.GetPixelPreprocessor(), destinations, geoService)
+	anonymIDCookie := handlers.NewAnonymousIDCookie(viper.GetString("server.anonymous_id_cookie.domain"), viper.GetString("server.anonymous_id_cookie.same_site"),
+		viper.GetInt("server.anonymous_id_cookie.ttl_days"), viper.GetBool("server.anonymous_id_cookie.http_only"))
+	pixelHandler := handlers.NewPixelHandler(anonymIDCookie, multiplexingService, processorHolder.GetPixelPreprocessor(), destinations, geoService)
 
+	anonymousIDHandler := handlers.NewAnonymousIDHandler(anonymIDCookie, multiplexingService, processorHolder.GetJSPreprocessor(), destinations, geoService)
 	bulkHandler := handlers.NewBulkHandler(destinations, processorHolder.GetBulkPreprocessor())
 
 	geoDataResolverHandler := handlers.NewGeoDataResolverHandler(geoService)
@@ -114,6 +117,9 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.POST("/segment/compat", middleware.TokenFuncAuth(segmentCompatHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, ""))
 		//Tracking pixel API
 		apiV1.GET("/p.gif", pixelHandler.Handle)
+		//First-party anonymous ID API
+		apiV1.GET("/id", middleware.TokenFuncAuth(anonymousIDHandler.Handler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
+		apiV1.POST("/id", middleware.TokenFuncAuth(anonymousIDHandler.Handler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
 		//bulk endpoint
 		apiV1.POST("/events/bulk", middleware.TokenTwoFuncAuth(bulkHandler.BulkLoadingHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server token. Please use an s2s integration token"))
 
@@ -123,6 +129,7 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 		apiV1.GET("/geo_data_resolvers/editions", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.EditionsHandler))
 		apiV1.POST("/geo_data_resolvers/test", adminTokenMiddleware.AdminAuth(geoDataResolverHandler.TestHandler))
 		apiV1.POST("/destinations/test", adminTokenMiddleware.AdminAuth(handlers.NewDestinationsHandler(userRecognition).Handler))
+		apiV1.GET("/destinations/status", adminTokenMiddleware.AdminAuth(handlers.NewDestinationsStatusHandler(destinations).Handler))
 		apiV1.POST("/templates/evaluate", adminTokenMiddleware.AdminAuth(handlers.NewEventTemplateHandler(destinations.GetFactory()).Handler))
 
 		sourcesRoute := apiV1.Group("/sources")
@@ -0,0 +1,237 @@
+package schema
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	avroMagic          = "Obj\x01"
+	avroSyncLength     = 16
+	avroBlockSize      = 1000
+	avroDefaultRecord  = "event"
+	avroTimestampType  = "long.timestamp-micros"
+	avroCodecNull      = "null"
+	avroCodecDeflate   = "deflate"
+	avroCodecSnappy    = "snappy"
+	avroCodecZstandard = "zstandard"
+)
+
+//avroTypes is a mapping between typing types and avro types
+var avroTypes = map[typing.DataType]interface{}{
+	typing.STRING:    "string",
+	typing.JSON:      "string",
+	typing.INT64:     "long",
+	typing.FLOAT64:   "double",
+	typing.BOOL:      "boolean",
+	typing.TIMESTAMP: map[string]string{"type": "long", "logicalType": "timestamp-micros"},
+}
+
+//AvroMarshaller marshals objects into Avro Object Container File with the schema derived from BatchHeader
+//all fields are nullable. Objects and arrays are written as JSON strings
+type AvroMarshaller struct {
+	codec string
+}
+
+//NewAvroMarshaller returns AvroMarshaller with blocks compression codec
+//gzip is mapped to deflate codec. lz4 isn't supported by avro
+func NewAvroMarshaller(compression string) (StronglyTypedMarshaller, error) {
+	switch compression {
+	case CompressionNone:
+		return &AvroMarshaller{codec: avroCodecNull}, nil
+	case CompressionGZIP:
+		return &AvroMarshaller{codec: avroCodecDeflate}, nil
+	case CompressionSnappy:
+		return &AvroMarshaller{codec: avroCodecSnappy}, nil
+	case CompressionZSTD:
+		return &AvroMarshaller{codec: avroCodecZstandard}, nil
+	default:
+		return nil, fmt.Errorf("compression %s isn't supported by avro format", compression)
+	}
+}
+
+type avroField struct {
+	typedField
+	avroName string
+	union    string
+}
+
+//Marshal returns avro container file bytes
+func (am *AvroMarshaller) Marshal(bh *BatchHeader, data []map[string]interface{}) ([]byte, error) {
+	fields, avroSchema, err := am.avroSchema(bh)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodec(avroSchema)
+	if err != nil {
+		return nil, fmt.Errorf("can't create avro codec: %v", err)
+	}
+
+	sync := make([]byte, avroSyncLength)
+	if _, err := rand.Read(sync); err != nil {
+		return nil, fmt.Errorf("can't generate avro sync marker: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(avroMagic)
+	writeAvroMetadata(buf, map[string]string{"avro.schema": avroSchema, "avro.codec": am.codec})
+	buf.Write(sync)
+
+	for start := 0; start < len(data); start += avroBlockSize {
+		end := start + avroBlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		var block []byte
+		for _, object := range data[start:end] {
+			record, err := am.avroRecord(fields, object)
+			if err != nil {
+				return nil, err
+			}
+			block, err = codec.BinaryFromNative(block, record)
+			if err != nil {
+				return nil, fmt.Errorf("can't encode avro record: %v", err)
+			}
+		}
+
+		compressed, err := am.compressBlock(block)
+		if err != nil {
+			return nil, err
+		}
+
+		writeAvroLong(buf, int64(end-start))
+		writeAvroLong(buf, int64(len(compressed)))
+		buf.Write(compressed)
+		buf.Write(sync)
+	}
+
+	return buf.Bytes(), nil
+}
+
+//avroSchema returns sorted fields and avro record schema JSON
+func (am *AvroMarshaller) avroSchema(bh *BatchHeader) ([]avroField, string, error) {
+	typedFields, err := sortedTypedFields(bh)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fields := make([]avroField, 0, len(typedFields))
+	schemaFields := make([]map[string]interface{}, 0, len(typedFields))
+	for _, field := range typedFields {
+		avroType := avroTypes[field.dataType]
+		union, ok := avroType.(string)
+		if !ok {
+			union = avroTimestampType
+		}
+
+		name := avroName(field.name)
+		fields = append(fields, avroField{typedField: field, avroName: name, union: union})
+		schemaFields = append(schemaFields, map[string]interface{}{
+			"name":    name,
+			"type":    []interface{}{"null", avroType},
+			"default": nil,
+		})
+	}
+
+	recordName := avroDefaultRecord
+	if bh.TableName != "" {
+		recordName = avroName(bh.TableName)
+	}
+
+	b, err := json.Marshal(map[string]interface{}{"type": "record", "name": recordName, "fields": schemaFields})
+	if err != nil {
+		return nil, "", fmt.Errorf("can't marshal avro schema: %v", err)
+	}
+
+	return fields, string(b), nil
+}
+
+func (am *AvroMarshaller) avroRecord(fields []avroField, object map[string]interface{}) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, ok := object[field.name]
+		if !ok || value == nil {
+			record[field.avroName] = nil
+			continue
+		}
+
+		converted, err := typedValue(field.dataType, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", field.name, err)
+		}
+		record[field.avroName] = goavro.Union(field.union, converted)
+	}
+
+	return record, nil
+}
+
+func (am *AvroMarshaller) compressBlock(block []byte) ([]byte, error) {
+	switch am.codec {
+	case avroCodecDeflate:
+		return compressDeflate(block)
+	case avroCodecSnappy:
+		//snappy blocks are followed by CRC32 checksum of uncompressed data
+		compressed := compressSnappy(block)
+		checksum := make([]byte, 4)
+		binary.BigEndian.PutUint32(checksum, crc32.ChecksumIEEE(block))
+		return append(compressed, checksum...), nil
+	case avroCodecZstandard:
+		return compressZSTD(block)
+	default:
+		return block, nil
+	}
+}
+
+//avroName returns valid avro name: [A-Za-z_][A-Za-z0-9_]*
+func avroName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if sanitized == "" || (sanitized[0] >= '0' && sanitized[0] <= '9') {
+		sanitized = "_" + sanitized
+	}
+
+	return sanitized
+}
+
+//writeAvroMetadata writes file metadata as avro map of bytes (one block)
+func writeAvroMetadata(buf *bytes.Buffer, metadata map[string]string) {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writeAvroLong(buf, int64(len(keys)))
+	for _, key := range keys {
+		writeAvroBytes(buf, []byte(key))
+		writeAvroBytes(buf, []byte(metadata[key]))
+	}
+	writeAvroLong(buf, 0)
+}
+
+func writeAvroBytes(buf *bytes.Buffer, b []byte) {
+	writeAvroLong(buf, int64(len(b)))
+	buf.Write(b)
+}
+
+//writeAvroLong writes zig-zag encoded varint
+func writeAvroLong(buf *bytes.Buffer, v int64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(b, v)
+	buf.Write(b[:n])
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvroMarshal(t *testing.T) {
+	bh := &BatchHeader{TableName: "events", Fields: Fields{
+		"id":                  NewField(typing.STRING),
+		"count":               NewField(typing.INT64),
+		"price":               NewField(typing.FLOAT64),
+		"paid":                NewField(typing.BOOL),
+		"_timestamp":          NewField(typing.TIMESTAMP),
+		"eventn_ctx.utc_time": NewField(typing.STRING),
+		"tags":                NewField(typing.ARRAY),
+	}}
+	ts := time.Date(2022, 5, 18, 13, 45, 1, 123456000, time.UTC)
+	data := []map[string]interface{}{
+		{"id": "1", "count": 10, "price": 1.5, "paid": true, "_timestamp": ts, "eventn_ctx.utc_time": "2022", "tags": []interface{}{"a", "b"}},
+		{"id": "2", "count": float64(11)},
+	}
+
+	for _, compression := range []string{CompressionNone, CompressionGZIP, CompressionSnappy} {
+		t.Run("compression "+compression, func(t *testing.T) {
+			am, err := NewAvroMarshaller(compression)
+			require.NoError(t, err)
+
+			b, err := am.Marshal(bh, data)
+			require.NoError(t, err)
+
+			reader, err := goavro.NewOCFReader(bytes.NewReader(b))
+			require.NoError(t, err)
+
+			var records []interface{}
+			for reader.Scan() {
+				record, err := reader.Read()
+				require.NoError(t, err)
+				records = append(records, record)
+			}
+			require.NoError(t, reader.Err())
+			require.Equal(t, []interface{}{
+				map[string]interface{}{
+					"_timestamp":          map[string]interface{}{"long.timestamp-micros": ts},
+					"count":               map[string]interface{}{"long": int64(10)},
+					"eventn_ctx_utc_time": map[string]interface{}{"string": "2022"},
+					"id":                  map[string]interface{}{"string": "1"},
+					"paid":                map[string]interface{}{"boolean": true},
+					"price":               map[string]interface{}{"double": 1.5},
+					"tags":                map[string]interface{}{"string": `["a","b"]`},
+				},
+				map[string]interface{}{
+					"_timestamp":          nil,
+					"count":               map[string]interface{}{"long": int64(11)},
+					"eventn_ctx_utc_time": nil,
+					"id":                  map[string]interface{}{"string": "2"},
+					"paid":                nil,
+					"price":               nil,
+					"tags":                nil,
+				},
+			}, records)
+		})
+	}
+
+	am, err := NewAvroMarshaller(CompressionZSTD)
+	require.NoError(t, err)
+	_, err = am.Marshal(bh, data)
+	require.NoError(t, err)
+
+	_, err = NewAvroMarshaller(CompressionLZ4)
+	require.EqualError(t, err, "compression lz4 isn't supported by avro format")
+
+	_, err = am.Marshal(bh, []map[string]interface{}{{"count": "abc"}})
+	require.EqualError(t, err, "field count: can't convert [abc] into int64")
+}
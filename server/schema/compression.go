@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+//Compression codecs which are supported by strongly typed marshallers (parquet, avro, orc)
+const (
+	CompressionNone   = ""
+	CompressionGZIP   = "gzip"
+	CompressionZSTD   = "zstd"
+	CompressionSnappy = "snappy"
+	CompressionLZ4    = "lz4"
+)
+
+//compressDeflate returns raw deflate (without zlib or gzip headers) compressed data
+func compressDeflate(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+//compressZSTD returns zstd frame with compressed data
+func compressZSTD(data []byte) ([]byte, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+
+	return encoder.EncodeAll(data, nil), nil
+}
+
+//compressSnappy returns snappy block (not framed) with compressed data
+func compressSnappy(data []byte) []byte {
+	return snappy.Encode(nil, data)
+}
+
+//compressLZ4 returns lz4 block (not framed) with compressed data
+//returns nil if data isn't compressible
+func compressLZ4(data []byte) ([]byte, error) {
+	buf := make([]byte, lz4.CompressBlockBound(len(data)))
+	n, err := lz4.CompressBlock(data, buf, nil)
+	if err != nil {
+		return nil, fmt.Errorf("lz4 compression error: %v", err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	return buf[:n], nil
+}
@@ -13,11 +13,27 @@ type Flattener interface {
 
 type FlattenerImpl struct {
 	omitNilValues bool
+	//unflattenedKeys are flattened keys of objects and arrays which must be kept as is (JSON and ARRAY types)
+	unflattenedKeys map[string]bool
 }
 
 func NewFlattener() Flattener {
+	return NewFlattenerWithUnflattenedPaths(nil)
+}
+
+//NewFlattenerWithUnflattenedPaths returns Flattener which keeps objects and arrays under the paths (e.g. /user/traits) unflattened
+func NewFlattenerWithUnflattenedPaths(paths []string) Flattener {
+	unflattenedKeys := map[string]bool{}
+	for _, path := range paths {
+		key := strings.ReplaceAll(strings.Trim(strings.TrimSpace(path), "/"), "/", "_")
+		if key != "" {
+			unflattenedKeys[Reformat(key)] = true
+		}
+	}
+
 	return &FlattenerImpl{
-		omitNilValues: true,
+		omitNilValues:   true,
+		unflattenedKeys: unflattenedKeys,
 	}
 }
 
@@ -44,6 +60,11 @@ func (f *FlattenerImpl) FlattenObject(json map[string]interface{}) (map[string]i
 func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[string]interface{}) error {
 	key = Reformat(key)
 	t := reflect.ValueOf(value)
+	if f.unflattenedKeys[key] && (t.Kind() == reflect.Slice || t.Kind() == reflect.Map) {
+		destination[key] = value
+		return nil
+	}
+
 	switch t.Kind() {
 	case reflect.Slice:
 		if strings.Contains(key, SqlTypeKeyword) {
@@ -68,3 +68,24 @@ func TestFlattenObject(t *testing.T) {
 		})
 	}
 }
+
+func TestFlattenObjectWithUnflattenedPaths(t *testing.T) {
+	flattener := NewFlattenerWithUnflattenedPaths([]string{"/products", "/user/traits", "/missing"})
+	input := map[string]interface{}{
+		"products": []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}},
+		"tags":     []interface{}{"a", "b"},
+		"user": map[string]interface{}{
+			"id":     "u1",
+			"traits": map[string]interface{}{"plan": "pro", "seats": 5},
+		},
+	}
+
+	actual, err := flattener.FlattenObject(input)
+	require.NoError(t, err)
+	test.ObjectsEqual(t, map[string]interface{}{
+		"products":    []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}},
+		"tags":        `["a","b"]`,
+		"user_id":     "u1",
+		"user_traits": map[string]interface{}{"plan": "pro", "seats": 5},
+	}, actual, "Wrong flattened json")
+}
@@ -0,0 +1,513 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//ORC file format constants (see https://orc.apache.org/specification/ORCv1/)
+const (
+	orcMagic               = "ORC"
+	orcCompressionBlock    = 256 * 1024
+	orcWriterVersion       = 1
+	orcWriterTimezone      = "UTC"
+	orcTimestampBaseSecond = 1420070400 //2015-01-01 00:00:00 UTC
+
+	//CompressionKind
+	orcCompressionNone   = 0
+	orcCompressionZlib   = 1
+	orcCompressionSnappy = 2
+	orcCompressionLZ4    = 4
+	orcCompressionZSTD   = 5
+
+	//Type.Kind
+	orcKindBoolean   = 0
+	orcKindLong      = 4
+	orcKindDouble    = 6
+	orcKindString    = 7
+	orcKindTimestamp = 9
+	orcKindStruct    = 12
+
+	//Stream.Kind
+	orcStreamPresent   = 0
+	orcStreamData      = 1
+	orcStreamLength    = 2
+	orcStreamSecondary = 5
+
+	//ColumnEncoding.Kind
+	orcEncodingDirect = 0
+)
+
+var orcKinds = map[typing.DataType]uint64{
+	typing.BOOL:      orcKindBoolean,
+	typing.INT64:     orcKindLong,
+	typing.FLOAT64:   orcKindDouble,
+	typing.STRING:    orcKindString,
+	typing.JSON:      orcKindString,
+	typing.TIMESTAMP: orcKindTimestamp,
+}
+
+//ORCMarshaller marshals objects into ORC file with one stripe and the struct schema derived from BatchHeader
+//all columns are nullable and are written with DIRECT encoding (RLE v1). Objects and arrays are written as JSON strings
+type ORCMarshaller struct {
+	compression uint64
+}
+
+//NewORCMarshaller returns ORCMarshaller with the compression codec. gzip is mapped to ORC ZLIB codec
+func NewORCMarshaller(compression string) (StronglyTypedMarshaller, error) {
+	switch compression {
+	case CompressionNone:
+		return &ORCMarshaller{compression: orcCompressionNone}, nil
+	case CompressionGZIP:
+		return &ORCMarshaller{compression: orcCompressionZlib}, nil
+	case CompressionSnappy:
+		return &ORCMarshaller{compression: orcCompressionSnappy}, nil
+	case CompressionLZ4:
+		return &ORCMarshaller{compression: orcCompressionLZ4}, nil
+	case CompressionZSTD:
+		return &ORCMarshaller{compression: orcCompressionZSTD}, nil
+	default:
+		return nil, fmt.Errorf("compression %s isn't supported by orc format", compression)
+	}
+}
+
+//orcStream is an encoded column stream
+type orcStream struct {
+	kind   uint64
+	column uint64
+	data   []byte
+}
+
+//orcColumn accumulates column values and encodes them into streams
+type orcColumn struct {
+	typedField
+	present []bool
+	hasNull bool
+
+	bools   []bool
+	longs   []int64
+	doubles []float64
+	strings [][]byte
+	times   []time.Time
+}
+
+//Marshal returns ORC file bytes
+func (om *ORCMarshaller) Marshal(bh *BatchHeader, data []map[string]interface{}) ([]byte, error) {
+	fields, err := sortedTypedFields(bh)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]*orcColumn, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, &orcColumn{typedField: field, present: make([]bool, 0, len(data))})
+	}
+
+	for _, object := range data {
+		for _, column := range columns {
+			if err := column.add(object[column.name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(orcMagic)
+
+	//stripe data streams
+	var streams []orcStream
+	for i, column := range columns {
+		streams = append(streams, column.streams(uint64(i+1))...)
+	}
+
+	stripeFooter := &protoWriter{}
+	var dataLength uint64
+	for _, stream := range streams {
+		compressed, err := om.compress(stream.data)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(compressed)
+		dataLength += uint64(len(compressed))
+
+		streamMessage := &protoWriter{}
+		streamMessage.uint(1, stream.kind)
+		streamMessage.uint(2, stream.column)
+		streamMessage.uint(3, uint64(len(compressed)))
+		stripeFooter.message(1, streamMessage)
+	}
+	for i := 0; i <= len(columns); i++ {
+		encoding := &protoWriter{}
+		encoding.uint(1, orcEncodingDirect)
+		stripeFooter.message(2, encoding)
+	}
+	stripeFooter.string(3, orcWriterTimezone)
+
+	stripeFooterBytes, err := om.compress(stripeFooter.bytes())
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(stripeFooterBytes)
+
+	//file footer
+	footer := &protoWriter{}
+	footer.uint(1, uint64(len(orcMagic)))
+	footer.uint(2, uint64(buf.Len()))
+	stripeInformation := &protoWriter{}
+	stripeInformation.uint(1, uint64(len(orcMagic)))
+	stripeInformation.uint(2, 0)
+	stripeInformation.uint(3, dataLength)
+	stripeInformation.uint(4, uint64(len(stripeFooterBytes)))
+	stripeInformation.uint(5, uint64(len(data)))
+	footer.message(3, stripeInformation)
+
+	rootType := &protoWriter{}
+	rootType.uint(1, orcKindStruct)
+	subtypes := make([]uint64, 0, len(columns))
+	for i := range columns {
+		subtypes = append(subtypes, uint64(i+1))
+	}
+	rootType.packed(2, subtypes)
+	for _, column := range columns {
+		rootType.string(3, column.name)
+	}
+	footer.message(4, rootType)
+	for _, column := range columns {
+		columnType := &protoWriter{}
+		columnType.uint(1, orcKinds[column.dataType])
+		footer.message(4, columnType)
+	}
+
+	footer.uint(6, uint64(len(data)))
+	rootStatistics := &protoWriter{}
+	rootStatistics.uint(1, uint64(len(data)))
+	footer.message(7, rootStatistics)
+	for _, column := range columns {
+		statistics := &protoWriter{}
+		statistics.uint(1, uint64(len(column.present)-column.nullsCount()))
+		statistics.bool(10, column.hasNull)
+		footer.message(7, statistics)
+	}
+	footer.uint(8, 0)
+
+	footerBytes, err := om.compress(footer.bytes())
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(footerBytes)
+
+	//postscript isn't compressed
+	postscript := &protoWriter{}
+	postscript.uint(1, uint64(len(footerBytes)))
+	postscript.uint(2, om.compression)
+	postscript.uint(3, orcCompressionBlock)
+	postscript.packed(4, []uint64{0, 12})
+	postscript.uint(5, 0)
+	postscript.uint(6, orcWriterVersion)
+	postscript.string(8000, orcMagic)
+	postscriptBytes := postscript.bytes()
+	if len(postscriptBytes) > math.MaxUint8 {
+		return nil, fmt.Errorf("orc postscript is too long: %d", len(postscriptBytes))
+	}
+	buf.Write(postscriptBytes)
+	buf.WriteByte(byte(len(postscriptBytes)))
+
+	return buf.Bytes(), nil
+}
+
+//compress splits data into chunks with 3 bytes headers: chunk length * 2 + 1 if chunk is original (not compressed)
+func (om *ORCMarshaller) compress(data []byte) ([]byte, error) {
+	if om.compression == orcCompressionNone {
+		return data, nil
+	}
+
+	buf := &bytes.Buffer{}
+	for start := 0; start < len(data); start += orcCompressionBlock {
+		end := start + orcCompressionBlock
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := data[start:end]
+
+		var compressed []byte
+		var err error
+		switch om.compression {
+		case orcCompressionZlib:
+			compressed, err = compressDeflate(chunk)
+		case orcCompressionSnappy:
+			compressed = compressSnappy(chunk)
+		case orcCompressionLZ4:
+			compressed, err = compressLZ4(chunk)
+		case orcCompressionZSTD:
+			compressed, err = compressZSTD(chunk)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		header := uint32(len(compressed)) << 1
+		if len(compressed) == 0 || len(compressed) >= len(chunk) {
+			compressed = chunk
+			header = uint32(len(chunk))<<1 | 1
+		}
+		buf.Write([]byte{byte(header), byte(header >> 8), byte(header >> 16)})
+		buf.Write(compressed)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (oc *orcColumn) add(value interface{}) error {
+	if value == nil {
+		oc.present = append(oc.present, false)
+		oc.hasNull = true
+		return nil
+	}
+
+	converted, err := typedValue(oc.dataType, value)
+	if err != nil {
+		return fmt.Errorf("field %s: %v", oc.name, err)
+	}
+
+	oc.present = append(oc.present, true)
+	switch v := converted.(type) {
+	case bool:
+		oc.bools = append(oc.bools, v)
+	case int64:
+		oc.longs = append(oc.longs, v)
+	case float64:
+		oc.doubles = append(oc.doubles, v)
+	case time.Time:
+		oc.times = append(oc.times, v)
+	case string:
+		oc.strings = append(oc.strings, []byte(v))
+	default:
+		return fmt.Errorf("field %s has unsupported value type %T", oc.name, converted)
+	}
+
+	return nil
+}
+
+func (oc *orcColumn) nullsCount() int {
+	count := 0
+	for _, present := range oc.present {
+		if !present {
+			count++
+		}
+	}
+	return count
+}
+
+//streams returns encoded column streams. PRESENT stream is written only if the column has nulls
+func (oc *orcColumn) streams(column uint64) []orcStream {
+	var streams []orcStream
+	if oc.hasNull {
+		streams = append(streams, orcStream{kind: orcStreamPresent, column: column, data: orcBooleanRLE(oc.present)})
+	}
+
+	switch oc.dataType {
+	case typing.BOOL:
+		streams = append(streams, orcStream{kind: orcStreamData, column: column, data: orcBooleanRLE(oc.bools)})
+	case typing.INT64:
+		streams = append(streams, orcStream{kind: orcStreamData, column: column, data: orcIntegerRLE(oc.longs, true)})
+	case typing.FLOAT64:
+		data := make([]byte, 8*len(oc.doubles))
+		for i, v := range oc.doubles {
+			binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(v))
+		}
+		streams = append(streams, orcStream{kind: orcStreamData, column: column, data: data})
+	case typing.TIMESTAMP:
+		seconds := make([]int64, 0, len(oc.times))
+		nanos := make([]int64, 0, len(oc.times))
+		for _, t := range oc.times {
+			seconds = append(seconds, t.Unix()-orcTimestampBaseSecond)
+			nanos = append(nanos, orcNanos(t.Nanosecond()))
+		}
+		streams = append(streams,
+			orcStream{kind: orcStreamData, column: column, data: orcIntegerRLE(seconds, true)},
+			orcStream{kind: orcStreamSecondary, column: column, data: orcIntegerRLE(nanos, false)})
+	default:
+		var data []byte
+		lengths := make([]int64, 0, len(oc.strings))
+		for _, str := range oc.strings {
+			data = append(data, str...)
+			lengths = append(lengths, int64(len(str)))
+		}
+		streams = append(streams,
+			orcStream{kind: orcStreamData, column: column, data: data},
+			orcStream{kind: orcStreamLength, column: column, data: orcIntegerRLE(lengths, false)})
+	}
+
+	return streams
+}
+
+//orcNanos returns nanoseconds with trailing zeros encoded in the 3 low bits
+func orcNanos(nanos int) int64 {
+	if nanos == 0 {
+		return 0
+	}
+	if nanos%100 != 0 {
+		return int64(nanos) << 3
+	}
+
+	nanos /= 100
+	trailingZeros := 1
+	for nanos%10 == 0 && trailingZeros < 7 {
+		nanos /= 10
+		trailingZeros++
+	}
+	return int64(nanos)<<3 | int64(trailingZeros)
+}
+
+//orcBooleanRLE packs values into bits (most significant bit first) and encodes bytes with byte RLE
+func orcBooleanRLE(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+
+	return orcByteRLE(packed)
+}
+
+//orcByteRLE encodes bytes: runs of 3-130 equal bytes are written as (length - 3, value),
+//up to 128 literals are written as (-count, values...)
+func orcByteRLE(values []byte) []byte {
+	var out []byte
+	for i := 0; i < len(values); {
+		run := 1
+		for i+run < len(values) && run < 130 && values[i+run] == values[i] {
+			run++
+		}
+		if run >= 3 {
+			out = append(out, byte(run-3), values[i])
+			i += run
+			continue
+		}
+
+		start := i
+		for i < len(values) && i-start < 128 {
+			if i+2 < len(values) && values[i] == values[i+1] && values[i] == values[i+2] {
+				break
+			}
+			i++
+		}
+		out = append(out, byte(-(i - start)))
+		out = append(out, values[start:i]...)
+	}
+
+	return out
+}
+
+//orcIntegerRLE encodes integers with RLE v1: runs of 3-130 values with the same delta (-128..127)
+//are written as (length - 3, delta, base), up to 128 literals are written as (-count, varints...)
+//signed values are zigzag encoded
+func orcIntegerRLE(values []int64, signed bool) []byte {
+	var out []byte
+	writeVarint := func(v int64) {
+		u := uint64(v)
+		if signed {
+			u = uint64((v << 1) ^ (v >> 63))
+		}
+		for u >= 0x80 {
+			out = append(out, byte(u)|0x80)
+			u >>= 7
+		}
+		out = append(out, byte(u))
+	}
+	runLength := func(i int) (int, int64) {
+		if i+1 >= len(values) {
+			return 1, 0
+		}
+		delta := values[i+1] - values[i]
+		if delta < -128 || delta > 127 {
+			return 1, 0
+		}
+		run := 2
+		for i+run < len(values) && run < 130 && values[i+run]-values[i+run-1] == delta {
+			run++
+		}
+		return run, delta
+	}
+
+	for i := 0; i < len(values); {
+		if run, delta := runLength(i); run >= 3 {
+			out = append(out, byte(run-3), byte(int8(delta)))
+			writeVarint(values[i])
+			i += run
+			continue
+		}
+
+		start := i
+		for i < len(values) && i-start < 128 {
+			if run, _ := runLength(i); run >= 3 {
+				break
+			}
+			i++
+		}
+		out = append(out, byte(-(i - start)))
+		for _, v := range values[start:i] {
+			writeVarint(v)
+		}
+	}
+
+	return out
+}
+
+//protoWriter is a minimal protocol buffers encoder for ORC metadata messages
+type protoWriter struct {
+	buf []byte
+}
+
+func (pw *protoWriter) varint(v uint64) {
+	for v >= 0x80 {
+		pw.buf = append(pw.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	pw.buf = append(pw.buf, byte(v))
+}
+
+func (pw *protoWriter) uint(field int, v uint64) {
+	pw.varint(uint64(field) << 3)
+	pw.varint(v)
+}
+
+func (pw *protoWriter) bool(field int, v bool) {
+	if v {
+		pw.uint(field, 1)
+	} else {
+		pw.uint(field, 0)
+	}
+}
+
+func (pw *protoWriter) rawBytes(field int, b []byte) {
+	pw.varint(uint64(field)<<3 | 2)
+	pw.varint(uint64(len(b)))
+	pw.buf = append(pw.buf, b...)
+}
+
+func (pw *protoWriter) string(field int, s string) {
+	pw.rawBytes(field, []byte(s))
+}
+
+func (pw *protoWriter) message(field int, message *protoWriter) {
+	pw.rawBytes(field, message.buf)
+}
+
+func (pw *protoWriter) packed(field int, values []uint64) {
+	packed := &protoWriter{}
+	for _, v := range values {
+		packed.varint(v)
+	}
+	pw.rawBytes(field, packed.buf)
+}
+
+func (pw *protoWriter) bytes() []byte {
+	return pw.buf
+}
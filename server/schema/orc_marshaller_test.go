@@ -0,0 +1,379 @@
+package schema
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestORCRunLengthEncoding(t *testing.T) {
+	zeros := make([]byte, 100)
+	require.Equal(t, []byte{0x61, 0x00}, orcByteRLE(zeros))
+	require.Equal(t, []byte{0xfe, 0x44, 0x45}, orcByteRLE([]byte{0x44, 0x45}))
+
+	sevens := make([]int64, 100)
+	for i := range sevens {
+		sevens[i] = 7
+	}
+	require.Equal(t, []byte{0x61, 0x00, 0x07}, orcIntegerRLE(sevens, false))
+	require.Equal(t, []byte{0xfb, 0x02, 0x03, 0x06, 0x07, 0x0b}, orcIntegerRLE([]int64{2, 3, 6, 7, 11}, false))
+	require.Equal(t, []byte{0x00, 0xff, 0x01}, orcIntegerRLE([]int64{-1, -2, -3}, true))
+	require.Equal(t, []byte{0xfe, 0x01, 0x04}, orcIntegerRLE([]int64{-1, 2}, true))
+
+	require.Equal(t, []byte{0xff, 0x80}, orcBooleanRLE([]bool{true, false, false, false, false, false, false, false}))
+}
+
+func TestORCMarshal(t *testing.T) {
+	bh := &BatchHeader{TableName: "events", Fields: Fields{
+		"id":         NewField(typing.STRING),
+		"count":      NewField(typing.INT64),
+		"price":      NewField(typing.FLOAT64),
+		"paid":       NewField(typing.BOOL),
+		"_timestamp": NewField(typing.TIMESTAMP),
+		"payload":    NewField(typing.JSON),
+	}}
+	data := []map[string]interface{}{
+		{"id": "1", "count": 10, "price": 1.5, "paid": true, "_timestamp": "2022-05-18T13:45:01.123456Z", "payload": map[string]interface{}{"a": 1}},
+		{"id": "2"},
+		{"id": strings.Repeat("long", 100*1024), "count": -5, "price": -0.25, "paid": false, "_timestamp": "1969-12-31T23:59:59.1Z"},
+	}
+	expected := []map[string]interface{}{
+		{"id": "1", "count": int64(10), "price": 1.5, "paid": true, "_timestamp": time.Date(2022, 5, 18, 13, 45, 1, 123456000, time.UTC), "payload": `{"a":1}`},
+		{"id": "2", "count": nil, "price": nil, "paid": nil, "_timestamp": nil, "payload": nil},
+		{"id": strings.Repeat("long", 100*1024), "count": int64(-5), "price": -0.25, "paid": false, "_timestamp": time.Date(1969, 12, 31, 23, 59, 59, 100000000, time.UTC), "payload": nil},
+	}
+	//runs and literals of RLE encodings
+	for i := 0; i < 300; i++ {
+		object := map[string]interface{}{"id": fmt.Sprint(i % 7), "count": i / 3, "paid": i%5 == 0}
+		expectedObject := map[string]interface{}{"id": fmt.Sprint(i % 7), "count": int64(i / 3), "price": nil, "paid": i%5 == 0, "_timestamp": nil, "payload": nil}
+		if i%4 != 0 {
+			object["_timestamp"] = time.Date(2022, 1, 1, 0, 0, i, i*1000, time.UTC)
+			expectedObject["_timestamp"] = time.Date(2022, 1, 1, 0, 0, i, i*1000, time.UTC)
+		}
+		data = append(data, object)
+		expected = append(expected, expectedObject)
+	}
+
+	for _, compression := range []string{CompressionNone, CompressionGZIP, CompressionSnappy, CompressionLZ4, CompressionZSTD} {
+		t.Run("compression "+compression, func(t *testing.T) {
+			om, err := NewORCMarshaller(compression)
+			require.NoError(t, err)
+
+			b, err := om.Marshal(bh, data)
+			require.NoError(t, err)
+
+			names, rows := readORC(t, b)
+			require.Equal(t, []string{"_timestamp", "count", "id", "paid", "payload", "price"}, names)
+			require.Equal(t, len(expected), len(rows))
+			for i := range expected {
+				require.Equal(t, expected[i], rows[i], "row %d", i)
+			}
+		})
+	}
+
+	_, err := NewORCMarshaller("brotli")
+	require.EqualError(t, err, "compression brotli isn't supported by orc format")
+}
+
+//readORC decodes ORC file according to the specification (https://orc.apache.org/specification/ORCv1/)
+//independently of the marshaller encoders and returns column names and rows
+func readORC(t *testing.T, b []byte) ([]string, []map[string]interface{}) {
+	require.True(t, bytes.HasPrefix(b, []byte(orcMagic)), "file must start with magic")
+
+	postscriptLength := int(b[len(b)-1])
+	postscriptBytes := b[len(b)-1-postscriptLength : len(b)-1]
+	postscript := readORCProto(t, postscriptBytes)
+	require.Equal(t, [][]byte{[]byte(orcMagic)}, postscript.bytes[8000], "postscript must contain magic")
+	compression := postscript.uint(2)
+	blockSize := postscript.uint(3)
+
+	footerLength := int(postscript.uint(1))
+	footerEnd := len(b) - 1 - postscriptLength
+	footer := readORCProto(t, decompressORC(t, compression, blockSize, b[footerEnd-footerLength:footerEnd]))
+	rowsCount := int(footer.uint(6))
+
+	types := footer.bytes[4]
+	require.NotEmpty(t, types)
+	rootType := readORCProto(t, types[0])
+	require.Equal(t, uint64(orcKindStruct), rootType.uint(1))
+	var names []string
+	for _, name := range rootType.bytes[3] {
+		names = append(names, string(name))
+	}
+	require.Len(t, types, len(names)+1)
+
+	rows := make([]map[string]interface{}, 0, rowsCount)
+	for _, stripeBytes := range footer.bytes[3] {
+		stripe := readORCProto(t, stripeBytes)
+		offset := stripe.uint(1) + stripe.uint(2)
+		dataLength := stripe.uint(3)
+		stripeRows := int(stripe.uint(5))
+		stripeFooterStart := offset + dataLength
+		stripeFooter := readORCProto(t, decompressORC(t, compression, blockSize, b[stripeFooterStart:stripeFooterStart+stripe.uint(4)]))
+		for _, encoding := range stripeFooter.bytes[2] {
+			require.Equal(t, uint64(orcEncodingDirect), readORCProto(t, encoding).uint(1))
+		}
+
+		//stream kind -> column -> decompressed data
+		streams := map[uint64]map[uint64][]byte{}
+		for _, streamBytes := range stripeFooter.bytes[1] {
+			stream := readORCProto(t, streamBytes)
+			kind, column, length := stream.uint(1), stream.uint(2), stream.uint(3)
+			if streams[kind] == nil {
+				streams[kind] = map[uint64][]byte{}
+			}
+			streams[kind][column] = decompressORC(t, compression, blockSize, b[offset:offset+length])
+			offset += length
+		}
+		require.Equal(t, stripeFooterStart, offset, "streams must fill stripe data")
+
+		stripeValues := make([]map[string]interface{}, stripeRows)
+		for i := range stripeValues {
+			stripeValues[i] = map[string]interface{}{}
+		}
+		for i, name := range names {
+			column := uint64(i + 1)
+			kind := readORCProto(t, types[i+1]).uint(1)
+			for row, value := range readORCColumn(t, kind, column, streams, stripeRows) {
+				stripeValues[row][name] = value
+			}
+		}
+		rows = append(rows, stripeValues...)
+	}
+	require.Len(t, rows, rowsCount)
+
+	return names, rows
+}
+
+//readORCColumn returns column values (nil if value isn't present)
+func readORCColumn(t *testing.T, kind, column uint64, streams map[uint64]map[uint64][]byte, rowsCount int) []interface{} {
+	present := make([]bool, rowsCount)
+	if presentStream, ok := streams[orcStreamPresent][column]; ok {
+		present = decodeORCBooleans(t, presentStream, rowsCount)
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+	valuesCount := 0
+	for _, p := range present {
+		if p {
+			valuesCount++
+		}
+	}
+
+	data := streams[orcStreamData][column]
+	var values []interface{}
+	switch kind {
+	case orcKindBoolean:
+		for _, v := range decodeORCBooleans(t, data, valuesCount) {
+			values = append(values, v)
+		}
+	case orcKindLong:
+		for _, v := range decodeORCIntegers(t, data, valuesCount, true) {
+			values = append(values, v)
+		}
+	case orcKindDouble:
+		require.Len(t, data, 8*valuesCount)
+		for i := 0; i < valuesCount; i++ {
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:])))
+		}
+	case orcKindString:
+		lengths := decodeORCIntegers(t, streams[orcStreamLength][column], valuesCount, false)
+		for _, length := range lengths {
+			values = append(values, string(data[:length]))
+			data = data[length:]
+		}
+		require.Empty(t, data)
+	case orcKindTimestamp:
+		seconds := decodeORCIntegers(t, data, valuesCount, true)
+		nanos := decodeORCIntegers(t, streams[orcStreamSecondary][column], valuesCount, false)
+		for i := range seconds {
+			nano := nanos[i] >> 3
+			if zeros := nanos[i] & 7; zeros != 0 {
+				for j := int64(0); j <= zeros; j++ {
+					nano *= 10
+				}
+			}
+			values = append(values, time.Unix(seconds[i]+orcTimestampBaseSecond, nano).UTC())
+		}
+	default:
+		require.Fail(t, "unexpected type kind", "kind: %d", kind)
+	}
+
+	result := make([]interface{}, rowsCount)
+	for i, p := range present {
+		if p {
+			result[i] = values[0]
+			values = values[1:]
+		}
+	}
+	require.Empty(t, values)
+
+	return result
+}
+
+//decompressORC decodes chunks with 3 bytes headers and decompresses them with the codec
+func decompressORC(t *testing.T, compression, blockSize uint64, data []byte) []byte {
+	if compression == orcCompressionNone {
+		return data
+	}
+
+	var result []byte
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 3, "chunk header")
+		header := uint64(data[0]) | uint64(data[1])<<8 | uint64(data[2])<<16
+		length := header >> 1
+		chunk := data[3 : 3+length]
+		data = data[3+length:]
+		if header&1 == 1 {
+			result = append(result, chunk...)
+			continue
+		}
+
+		var decompressed []byte
+		var err error
+		switch compression {
+		case orcCompressionZlib:
+			decompressed, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(chunk)))
+		case orcCompressionSnappy:
+			decompressed, err = snappy.Decode(nil, chunk)
+		case orcCompressionLZ4:
+			buf := make([]byte, blockSize)
+			var n int
+			n, err = lz4.UncompressBlock(chunk, buf)
+			decompressed = buf[:n]
+		case orcCompressionZSTD:
+			var decoder *zstd.Decoder
+			decoder, err = zstd.NewReader(nil)
+			require.NoError(t, err)
+			decompressed, err = decoder.DecodeAll(chunk, nil)
+			decoder.Close()
+		default:
+			require.Fail(t, "unexpected compression", "compression: %d", compression)
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, uint64(len(decompressed)), blockSize)
+		result = append(result, decompressed...)
+	}
+
+	return result
+}
+
+func decodeORCBooleans(t *testing.T, data []byte, count int) []bool {
+	bytesValues := decodeORCBytes(t, data)
+	require.Equal(t, (count+7)/8, len(bytesValues))
+	values := make([]bool, count)
+	for i := range values {
+		values[i] = bytesValues[i/8]&(0x80>>uint(i%8)) != 0
+	}
+	return values
+}
+
+func decodeORCBytes(t *testing.T, data []byte) []byte {
+	var values []byte
+	for len(data) > 0 {
+		header := int8(data[0])
+		if header >= 0 {
+			require.GreaterOrEqual(t, len(data), 2)
+			for i := 0; i < int(header)+3; i++ {
+				values = append(values, data[1])
+			}
+			data = data[2:]
+			continue
+		}
+
+		count := -int(header)
+		require.GreaterOrEqual(t, len(data), count+1)
+		values = append(values, data[1:count+1]...)
+		data = data[count+1:]
+	}
+	return values
+}
+
+func decodeORCIntegers(t *testing.T, data []byte, count int, signed bool) []int64 {
+	readVarint := func() int64 {
+		u, n := protowire.ConsumeVarint(data)
+		require.Greater(t, n, 0, "malformed varint")
+		data = data[n:]
+		if signed {
+			return protowire.DecodeZigZag(u)
+		}
+		return int64(u)
+	}
+
+	var values []int64
+	for len(data) > 0 {
+		header := int8(data[0])
+		if header >= 0 {
+			delta := int64(int8(data[1]))
+			data = data[2:]
+			base := readVarint()
+			for i := 0; i < int(header)+3; i++ {
+				values = append(values, base+int64(i)*delta)
+			}
+			continue
+		}
+
+		data = data[1:]
+		for i := 0; i < -int(header); i++ {
+			values = append(values, readVarint())
+		}
+	}
+	require.Len(t, values, count)
+	return values
+}
+
+//orcProto is a decoded protobuf message: varint and length-delimited fields values
+type orcProto struct {
+	varints map[protowire.Number][]uint64
+	bytes   map[protowire.Number][][]byte
+}
+
+//uint returns the last varint value of the field or 0 if the field is missing
+func (op *orcProto) uint(field protowire.Number) uint64 {
+	values := op.varints[field]
+	if len(values) == 0 {
+		return 0
+	}
+	return values[len(values)-1]
+}
+
+func readORCProto(t *testing.T, b []byte) *orcProto {
+	message := &orcProto{varints: map[protowire.Number][]uint64{}, bytes: map[protowire.Number][][]byte{}}
+	for len(b) > 0 {
+		number, wireType, n := protowire.ConsumeTag(b)
+		require.Greater(t, n, 0, "malformed protobuf tag")
+		b = b[n:]
+		switch wireType {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.Greater(t, n, 0, "malformed protobuf varint")
+			message.varints[number] = append(message.varints[number], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.Greater(t, n, 0, "malformed protobuf bytes")
+			message.bytes[number] = append(message.bytes[number], v)
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(number, wireType, b)
+			require.Greater(t, n, 0, "malformed protobuf field")
+			b = b[n:]
+		}
+	}
+	return message
+}
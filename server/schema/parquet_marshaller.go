@@ -19,9 +19,22 @@ func NewParquetMarshaller(useGZIP bool) StronglyTypedMarshaller {
 	return pm
 }
 
+//NewParquetMarshallerWithCompression returns ParquetMarshaller with pages compression codec: gzip, zstd or snappy
+//parquet-go default codec (snappy) is used if compression is empty
+//lz4 isn't supported: parquet-go writes LZ4 codec pages as lz4 frames and has no LZ4_RAW codec
+func NewParquetMarshallerWithCompression(compression string) (StronglyTypedMarshaller, error) {
+	switch compression {
+	case CompressionNone, CompressionGZIP, CompressionZSTD, CompressionSnappy:
+		return &ParquetMarshaller{GoroutinesCount: 2, Compression: compression}, nil
+	default:
+		return nil, fmt.Errorf("compression %s isn't supported by parquet format", compression)
+	}
+}
+
 type ParquetMarshaller struct {
 	GoroutinesCount int64
 	UseGZIP         bool
+	Compression     string
 }
 
 type parquetMetadataItem struct {
@@ -39,11 +52,19 @@ func (pm *ParquetMarshaller) Marshal(bh *BatchHeader, data []map[string]interfac
 	fw := writerfile.NewWriterFile(buf)
 	defer fw.Close()
 	pw, err := writer.NewCSVWriter(parquetSchema, fw, pm.GoroutinesCount)
+	if err != nil {
+		return nil, fmt.Errorf("can't create parquet writer: %v", err)
+	}
 	if pm.UseGZIP {
 		pw.CompressionType = parquet.CompressionCodec_GZIP
 	}
-	if err != nil {
-		return nil, fmt.Errorf("can't create parquet writer: %v", err)
+	switch pm.Compression {
+	case CompressionGZIP:
+		pw.CompressionType = parquet.CompressionCodec_GZIP
+	case CompressionZSTD:
+		pw.CompressionType = parquet.CompressionCodec_ZSTD
+	case CompressionSnappy:
+		pw.CompressionType = parquet.CompressionCodec_SNAPPY
 	}
 	for _, obj := range data {
 		parquetRec, err := pm.parquetRecord(meta, obj)
@@ -81,6 +102,10 @@ func (pm *ParquetMarshaller) parquetMetadata(bh *BatchHeader) ([]string, map[str
 		case typing.TIMESTAMP:
 			parquetSchema = append(parquetSchema, fmt.Sprintf("name=%s, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MILLIS", field))
 			meta[field] = parquetMetadataItem{i, typing.TIMESTAMP, time.Time{}}
+		case typing.JSON, typing.ARRAY:
+			//objects and arrays are written as JSON strings
+			parquetSchema = append(parquetSchema, fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8", field))
+			meta[field] = parquetMetadataItem{i, typing.JSON, ""}
 
 		// UNKNOWN and default
 		default:
@@ -99,9 +124,20 @@ func (pm *ParquetMarshaller) parquetRecord(meta map[string]parquetMetadataItem,
 			fieldValue = metaItem.defaultValue
 		}
 		switch metaItem.dataType {
-		case typing.BOOL, typing.INT64, typing.FLOAT64, typing.STRING:
+		case typing.BOOL, typing.INT64, typing.FLOAT64:
 			str := fmt.Sprintf("%v", fieldValue)
 			rec[metaItem.index] = &str
+		case typing.STRING, typing.JSON:
+			str, ok := fieldValue.(string)
+			if !ok {
+				converted, err := typing.Convert(typing.STRING, fieldValue)
+				if err != nil {
+					str = fmt.Sprintf("%v", fieldValue)
+				} else {
+					str = fmt.Sprint(converted)
+				}
+			}
+			rec[metaItem.index] = &str
 		case typing.TIMESTAMP:
 			t, err := typing.ParseTimestamp(fieldValue)
 			if err != nil {
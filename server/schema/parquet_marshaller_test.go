@@ -30,6 +30,10 @@ func TestNonGZIPParquetMarshal(t *testing.T) {
 			name: "dateType is nil, but there are several type occurrences",
 			pte:  severalTypeOccurrences(),
 		},
+		{
+			name: "json and array fields",
+			pte:  complexTypesParquetTestEntity(),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -39,6 +43,25 @@ func TestNonGZIPParquetMarshal(t *testing.T) {
 	}
 }
 
+func TestParquetMarshalWithCompression(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionGZIP, CompressionZSTD, CompressionSnappy} {
+		t.Run("compression "+compression, func(t *testing.T) {
+			pm, err := NewParquetMarshallerWithCompression(compression)
+			require.NoError(t, err)
+
+			pte := fieldOfAllTypesValuesArePresentParquetTestEntity()
+			_, err = pm.Marshal(pte.batchHeader, []map[string]interface{}{pte.inputObj})
+			require.NoError(t, err, "parquet marshalling failed")
+		})
+	}
+
+	_, err := NewParquetMarshallerWithCompression(CompressionLZ4)
+	require.EqualError(t, err, "compression lz4 isn't supported by parquet format")
+
+	_, err = NewParquetMarshallerWithCompression("brotli")
+	require.EqualError(t, err, "compression brotli isn't supported by parquet format")
+}
+
 func TestGZIPParquetMarshal(t *testing.T) {
 	pm := NewParquetMarshaller(true)
 	tests := []struct {
@@ -61,6 +84,10 @@ func TestGZIPParquetMarshal(t *testing.T) {
 			name: "dateType is nil, but there are several type occurrences",
 			pte:  severalTypeOccurrences(),
 		},
+		{
+			name: "json and array fields",
+			pte:  complexTypesParquetTestEntity(),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -84,6 +111,10 @@ func TestParquetMetadata(t *testing.T) {
 			name: "dateType is nil, but there are several type occurrences",
 			pte:  severalTypeOccurrences(),
 		},
+		{
+			name: "json and array fields",
+			pte:  complexTypesParquetTestEntity(),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -116,6 +147,10 @@ func TestParquetRecord(t *testing.T) {
 			name: "dateType is nil, but there are several type occurrences",
 			pte:  severalTypeOccurrences(),
 		},
+		{
+			name: "json and array fields",
+			pte:  complexTypesParquetTestEntity(),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -138,6 +173,34 @@ type parquetTestEntity struct {
 	expectedRecord   []string
 }
 
+func complexTypesParquetTestEntity() *parquetTestEntity {
+	return &parquetTestEntity{
+		batchHeader: &BatchHeader{
+			TableName: "test_table",
+			Fields: Fields{
+				"field_json": Field{
+					dataType: typing.DataTypePtr(typing.JSON),
+				},
+				"field_array": Field{
+					dataType: typing.DataTypePtr(typing.ARRAY),
+				},
+			},
+		},
+		inputObj: map[string]interface{}{
+			"field_json":  map[string]interface{}{"plan": "pro"},
+			"field_array": []interface{}{"a", 1},
+		},
+		expectedMetadata: []string{
+			"name=field_json, type=BYTE_ARRAY, convertedtype=UTF8",
+			"name=field_array, type=BYTE_ARRAY, convertedtype=UTF8",
+		},
+		expectedRecord: []string{
+			`{"plan":"pro"}`,
+			`["a",1]`,
+		},
+	}
+}
+
 func fieldOfAllTypesValuesArePresentParquetTestEntity() *parquetTestEntity {
 	testDatetime := time.Date(2021, 9, 27, 14, 56, 41, 0, time.UTC)
 	return &parquetTestEntity{
@@ -322,6 +385,8 @@ func metaStub(bh *BatchHeader) map[string]parquetMetadataItem {
 			meta[field] = parquetMetadataItem{i, typing.STRING, ""}
 		case typing.TIMESTAMP:
 			meta[field] = parquetMetadataItem{i, typing.TIMESTAMP, time.Time{}}
+		case typing.JSON, typing.ARRAY:
+			meta[field] = parquetMetadataItem{i, typing.JSON, ""}
 		}
 		i++
 	}
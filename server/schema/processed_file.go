@@ -3,6 +3,7 @@ package schema
 import (
 	"bytes"
 	"fmt"
+	"github.com/jitsucom/jitsu/server/events"
 	"strings"
 )
 
@@ -32,6 +33,27 @@ func (pf *ProcessedFile) GetPayloadLen() int {
 	return len(pf.payload)
 }
 
+//RemoveObjects removes objects with the indices from the payload (e.g. objects which have been rejected by the destination)
+func (pf *ProcessedFile) RemoveObjects(indices map[int]bool) {
+	payload := make([]map[string]interface{}, 0, len(pf.payload))
+	var originalRawEvents []string
+	for i, object := range pf.payload {
+		if indices[i] {
+			if pf.eventsSrc != nil {
+				pf.eventsSrc[events.ExtractSrc(object)]--
+			}
+			continue
+		}
+		payload = append(payload, object)
+		if i < len(pf.originalRawEvents) {
+			originalRawEvents = append(originalRawEvents, pf.originalRawEvents[i])
+		}
+	}
+
+	pf.payload = payload
+	pf.originalRawEvents = originalRawEvents
+}
+
 //GetPayloadBytes returns marshaling by marshaller func, joined with \n,  bytes
 //assume that payload can't be empty
 func (pf *ProcessedFile) GetPayloadBytes(marshaller Marshaller) ([]byte, error) {
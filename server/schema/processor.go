@@ -9,11 +9,13 @@ import (
 
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/privacy"
 	"github.com/jitsucom/jitsu/server/templates"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/uuid"
@@ -41,6 +43,8 @@ type Envelope struct {
 	Header        *BatchHeader
 	Event         events.Event
 	OriginalEvent string
+	//Masked is a report about values masked by destination privacy policy
+	Masked []privacy.MaskedField
 }
 
 type Processor struct {
@@ -49,6 +53,7 @@ type Processor struct {
 	isSQLType               bool
 	tableNameExtractor      *TableNameExtractor
 	lookupEnrichmentStep    *enrichment.LookupEnrichmentStep
+	masker                  *privacy.Masker
 	transformer             templates.TemplateExecutor
 	builtinTransformer      templates.TemplateExecutor
 	fieldMapper             events.Mapper
@@ -69,11 +74,16 @@ type Processor struct {
 }
 
 func NewProcessor(destinationID string, destinationConfig *config.DestinationConfig, isSQLType bool, tableNameFuncExpression string, fieldMapper events.Mapper, enrichmentRules []enrichment.Rule, flattener Flattener, typeResolver TypeResolver, uniqueIDField *identifiers.UniqueID, maxColumnNameLen int, mappingStyle string, userRecognitionEnabled bool) (*Processor, error) {
+	masker, err := privacy.NewMasker(destinationConfig.Privacy)
+	if err != nil {
+		return nil, fmt.Errorf("error creating privacy policy: %v", err)
+	}
 	return &Processor{
 		identifier:              destinationID,
 		destinationConfig:       destinationConfig,
 		isSQLType:               isSQLType,
 		lookupEnrichmentStep:    enrichment.NewLookupEnrichmentStep(enrichmentRules),
+		masker:                  masker,
 		fieldMapper:             fieldMapper,
 		pulledEventsfieldMapper: &DummyMapper{},
 		typeResolver:            typeResolver,
@@ -120,18 +130,18 @@ func (p *Processor) ProcessEvents(fileName string, objects []map[string]interfac
 		envelops, err := p.processObject(event, alreadyUploadedTables, needCopyEvent)
 		if err != nil {
 			//handle skip object functionality
-			if err == ErrSkipObject {
+			if err == ErrSkipObject || consent.IsDenied(err) {
 				eventID := p.uniqueIDField.Extract(event)
 				if !appconfig.Instance.DisableSkipEventsWarn {
 					logging.Warnf("[%s] Event [%s]: %v", p.identifier, eventID, err)
 				}
 
-				originalEventBytes, _ := json.Marshal(event)
-				skippedEvents.Events = append(skippedEvents.Events, &events.SkippedEvent{Event: originalEventBytes, Error: ErrSkipObject.Error(), RecognizedEvent: recognizedEvent})
+				originalEventBytes := []byte(p.SerializeOriginalEvent(event))
+				skippedEvents.Events = append(skippedEvents.Events, &events.SkippedEvent{Event: originalEventBytes, Error: err.Error(), RecognizedEvent: recognizedEvent})
 			} else if p.breakOnError {
 				return nil, nil, nil, nil, err
 			} else {
-				originalEventBytes, _ := json.Marshal(event)
+				originalEventBytes := []byte(p.SerializeOriginalEvent(event))
 
 				logging.Warnf("Unable to process object %s: %v. This line will be stored in fallback.", string(originalEventBytes), err)
 
@@ -194,6 +204,11 @@ func (p *Processor) ProcessPulledEvents(tableName string, objects []map[string]i
 		if err != nil {
 			return nil, fmt.Errorf("Error mapping object: %v", err)
 		}
+		if p.masker != nil {
+			//pulled objects might be stored into several destinations
+			processedObject = maputils.CopyMap(processedObject)
+			p.masker.Mask(processedObject)
+		}
 		flatObject, err := p.flattener.FlattenObject(processedObject)
 		if err != nil {
 			return nil, err
@@ -235,6 +250,11 @@ func (p *Processor) ProcessPulledEvents(tableName string, objects []map[string]i
 // 2. execute enrichment.LookupEnrichmentStep and Mapping
 // or ErrSkipObject/another error
 func (p *Processor) processObject(object map[string]interface{}, alreadyUploadedTables map[string]bool, needCopyEvent bool) ([]Envelope, error) {
+	//consent decision is made on events accepting
+	if reason, denied := consent.DeniedReason(object, p.identifier); denied {
+		return nil, &consent.DeniedError{Reason: reason}
+	}
+
 	var workingObject map[string]interface{}
 	if needCopyEvent {
 		//we need to copy event when more that one storage can process the same event in parallel
@@ -307,7 +327,7 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 		return nil, ErrSkipObject
 	}
 	envelops := make([]Envelope, 0, len(toProcess))
-	originalEvent, _ := json.Marshal(object)
+	originalEvent := p.SerializeOriginalEvent(object)
 	for i, prObject := range toProcess {
 		newUniqueId := p.uniqueIDField.Extract(object)
 		if newUniqueId == "" {
@@ -338,11 +358,14 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 		}
 		delete(prObject, templates.TableNameParameter)
 		delete(prObject, events.HTTPContextField)
+		delete(prObject, consent.DeniedField)
 		//object has been already processed (storage:table pair might be already processed)
 		_, ok := alreadyUploadedTables[tableName]
 		if ok {
 			continue
 		}
+		//privacy policy is applied after enrichment and transform (e.g. geo data is resolved from original IP)
+		masked := p.masker.Mask(prObject)
 		flatObject, err := p.flattener.FlattenObject(prObject)
 		if err != nil {
 			return nil, err
@@ -356,12 +379,23 @@ func (p *Processor) processObject(object map[string]interface{}, alreadyUploaded
 		if err != nil {
 			return nil, fmt.Errorf("failed to process long fields: %v", err)
 		}
-		envelops = append(envelops, Envelope{Header: bh, Event: obj, OriginalEvent: string(originalEvent)})
+		envelops = append(envelops, Envelope{Header: bh, Event: obj, OriginalEvent: originalEvent, Masked: masked})
 	}
 
 	return envelops, nil
 }
 
+//SerializeOriginalEvent returns JSON of the original event with applied privacy policy
+//it is written into events cache and fallback instead of the original event
+func (p *Processor) SerializeOriginalEvent(object map[string]interface{}) string {
+	if p.masker != nil {
+		object = maputils.CopyMap(object)
+		p.masker.Mask(object)
+	}
+	b, _ := json.Marshal(object)
+	return string(b)
+}
+
 // foldLongFields replace all column names with truncated values if they exceed the limit
 // uses cutName under the hood
 func (p *Processor) foldLongFields(header *BatchHeader, object map[string]interface{}) (*BatchHeader, map[string]interface{}, error) {
@@ -508,6 +542,9 @@ func (p *Processor) DestinationType() string {
 
 func (p *Processor) Close() {
 	p.CloseJavaScriptTemplates()
+	if p.lookupEnrichmentStep != nil {
+		p.lookupEnrichmentStep.Close()
+	}
 }
 
 // cutName converts input name that exceeds maxLen to lower length string by cutting parts between '_' to 2 symbols.
@@ -16,6 +16,7 @@ import (
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/geo"
 	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/privacy"
 	"github.com/jitsucom/jitsu/server/test"
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/typing"
@@ -510,6 +511,36 @@ switch ($.event_type) {
 	}
 }
 
+func TestProcessEventsMaskOriginalEvent(t *testing.T) {
+	viper.Set("server.log.path", "")
+	viper.Set("sql_debug_log.ddl.enabled", false)
+
+	err := appconfig.Init(false, "")
+	require.NoError(t, err)
+
+	destination := &config.DestinationConfig{Type: "postgres", DataLayout: &config.DataLayout{Transform: ""},
+		Privacy: &privacy.Policy{IP: &privacy.IPConfig{Fields: []string{"/source_ip"}, IPv4Prefix: 24}, Redact: []string{"/user/email"}}}
+	p, err := NewProcessor("test", destination, false, `{{if eq .event_type "skipped"}}{{else}}events{{end}}`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), identifiers.NewUniqueID("/eventn_ctx/event_id"), 0, "new", false)
+	require.NoError(t, err)
+	require.NoError(t, p.InitJavaScriptTemplates())
+
+	objects := []map[string]interface{}{
+		{"event_type": "site_page", "source_ip": "10.1.2.3", "user": map[string]interface{}{"email": "a@b.com", "id": "u1"}},
+		{"event_type": "skipped", "source_ip": "10.1.2.3", "user": map[string]interface{}{"email": "a@b.com", "id": "u1"}},
+	}
+	flatData, _, _, skipped, err := p.ProcessEvents("testfile", objects, map[string]bool{}, true)
+	require.NoError(t, err)
+
+	expectedOriginal := `{"event_type":"site_page","source_ip":"10.1.2.0","user":{"id":"u1"}}`
+	require.Equal(t, []string{expectedOriginal}, flatData["events"].GetOriginalRawEvents(), "events cache must contain masked original event")
+	require.Equal(t, "10.1.2.3", objects[0]["source_ip"], "input event must not be changed")
+
+	require.Len(t, skipped.Events, 1)
+	require.Equal(t, `{"event_type":"skipped","source_ip":"10.1.2.0","user":{"id":"u1"}}`, string(skipped.Events[0].Event), "skipped events must contain masked original event")
+
+	require.Equal(t, expectedOriginal, p.SerializeOriginalEvent(objects[0]))
+}
+
 func TestCutName(t *testing.T) {
 	require.Equal(t, "ountry", cutName("firstnamelastnamemiddlenamecountry", 6))
 	require.Equal(t, "test", cutName("test", 12))
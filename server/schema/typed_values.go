@@ -0,0 +1,95 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//typedField is a batch header field with resolved data type
+type typedField struct {
+	name     string
+	dataType typing.DataType
+}
+
+//sortedTypedFields returns batch header fields sorted by name with resolved types
+//objects and arrays are returned with JSON type (they are written as JSON strings)
+func sortedTypedFields(bh *BatchHeader) ([]typedField, error) {
+	fields := make([]typedField, 0, len(bh.Fields))
+	for name, field := range bh.Fields {
+		dataType := field.GetType()
+		switch dataType {
+		case typing.STRING, typing.INT64, typing.FLOAT64, typing.BOOL, typing.TIMESTAMP:
+		case typing.JSON, typing.ARRAY:
+			dataType = typing.JSON
+		default:
+			return nil, fmt.Errorf("field %s has unmappable data type", name)
+		}
+
+		fields = append(fields, typedField{name: name, dataType: dataType})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+
+	return fields, nil
+}
+
+//typedValue returns value converted into Go type of dataType: int64, float64, bool, string or time.Time
+//objects and arrays (JSON type) are serialized into JSON strings
+func typedValue(dataType typing.DataType, value interface{}) (interface{}, error) {
+	switch dataType {
+	case typing.INT64:
+		str := fmt.Sprint(value)
+		if v, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return v, nil
+		}
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil || f != math.Trunc(f) {
+			return nil, fmt.Errorf("can't convert [%v] into int64", value)
+		}
+		return int64(f), nil
+	case typing.FLOAT64:
+		f, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert [%v] into float64", value)
+		}
+		return f, nil
+	case typing.BOOL:
+		b, err := strconv.ParseBool(fmt.Sprint(value))
+		if err != nil {
+			return nil, fmt.Errorf("can't convert [%v] into bool", value)
+		}
+		return b, nil
+	case typing.TIMESTAMP:
+		t, err := typing.ParseTimestamp(value)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC(), nil
+	case typing.JSON:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("can't serialize [%v] into JSON: %v", value, err)
+		}
+		return string(b), nil
+	default:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		if t, ok := value.(time.Time); ok {
+			return t.Format(time.RFC3339Nano), nil
+		}
+		converted, err := typing.Convert(typing.STRING, value)
+		if err != nil {
+			return fmt.Sprint(value), nil
+		}
+		return fmt.Sprint(converted), nil
+	}
+}
@@ -3,6 +3,7 @@ package storages
 import (
 	"fmt"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
 	"github.com/jitsucom/jitsu/server/enrichment"
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/timestamp"
@@ -43,6 +44,8 @@ type Abstract struct {
 	cachingConfiguration *config.CachingConfiguration
 
 	streamingWorker *StreamingWorker
+	retentionWorker *RetentionWorker
+	circuitBreaker  *circuitbreaker.CircuitBreaker
 
 	archiveLogger logging.ObjectLogger
 }
@@ -81,13 +84,18 @@ func (a *Abstract) ErrorEvent(fallback bool, eventCtx *adapters.EventContext, er
 	metrics.ErrorTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
 	counters.ErrorPushDestinationEvents(a.destinationID, 1)
 	telemetry.Error(eventCtx.TokenID, a.destinationID, eventCtx.Src, "", 1)
+	//only terminal destination failures are accounted: failed attempts which will be retried (fallback=false),
+	//processing errors (e.g. transform) and rejected events (e.g. 4xx responses) don't mean that the destination is unavailable
+	if fallback && eventCtx.ProcessedEvent != nil && IsDestinationFailure(err) {
+		a.circuitBreaker.Failure(err)
+	}
 
 	//cache
 	a.eventsCache.Error(eventCtx.CacheDisabled, a.ID(), eventCtx.GetSerializedOriginalEvent(), err.Error())
 
 	if fallback {
 		a.Fallback(&events.FailedEvent{
-			Event:   []byte(eventCtx.RawEvent.Serialize()),
+			Event:   []byte(eventCtx.GetSerializedOriginalEvent()),
 			Error:   err.Error(),
 			EventID: eventCtx.EventID,
 		})
@@ -99,6 +107,7 @@ func (a *Abstract) SuccessEvent(eventCtx *adapters.EventContext) {
 	counters.SuccessPushDestinationEvents(a.destinationID, 1)
 	telemetry.Event(eventCtx.TokenID, a.destinationID, eventCtx.Src, "", 1)
 	metrics.SuccessTokenEvent(eventCtx.TokenID, a.Processor().DestinationType(), a.destinationID)
+	a.circuitBreaker.Success()
 
 	//cache
 	a.eventsCache.Succeed(eventCtx)
@@ -253,6 +262,22 @@ func (a *Abstract) Update(eventContext *adapters.EventContext) error {
 	return nil
 }
 
+//Query executes read query with the destination SQL adapter
+//returns err if the destination doesn't support queries
+func (a *Abstract) Query(query string) ([]map[string]interface{}, error) {
+	if len(a.sqlAdapters) == 0 {
+		return nil, fmt.Errorf("[%s] destination doesn't support SQL queries", a.ID())
+	}
+
+	sqlAdapter, _ := a.getAdapters()
+	querier, ok := sqlAdapter.(adapters.Querier)
+	if !ok {
+		return nil, fmt.Errorf("[%s] destination adapter %T doesn't support SQL queries", a.ID(), sqlAdapter)
+	}
+
+	return querier.Query(query)
+}
+
 //retryInsert does retry if ensuring table or insert is failed
 func (a *Abstract) retryInsert(sqlAdapter adapters.SQLAdapter, tableHelper *TableHelper, eventContext *adapters.EventContext,
 	dbSchemaFromObject *adapters.Table) error {
@@ -280,6 +305,11 @@ func (a *Abstract) retryInsert(sqlAdapter adapters.SQLAdapter, tableHelper *Tabl
 func (a *Abstract) AccountResult(eventContext *adapters.EventContext, err error) {
 	if err != nil {
 		if IsConnectionError(err) {
+			//streaming worker retries such events until they are stored (there is no terminal failure),
+			//so every failed attempt is accounted by the circuit breaker
+			if IsDestinationFailure(err) {
+				a.circuitBreaker.Failure(err)
+			}
 			a.ErrorEvent(false, eventContext, err)
 		} else {
 			a.ErrorEvent(true, eventContext, err)
@@ -295,6 +325,11 @@ func (a *Abstract) Clean(tableName string) error {
 }
 
 func (a *Abstract) close() (multiErr error) {
+	if a.retentionWorker != nil {
+		if err := a.retentionWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing retention worker: %v", a.ID(), err))
+		}
+	}
 	if a.streamingWorker != nil {
 		if err := a.streamingWorker.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", a.ID(), err))
@@ -325,6 +360,7 @@ func (a *Abstract) Init(config *Config, impl Storage, preinstalledJavaScript str
 	a.uniqueIDField = config.uniqueIDField
 	a.staged = config.destination.Staged
 	a.cachingConfiguration = config.destination.CachingConfiguration
+	a.circuitBreaker = config.circuitBreaker
 	var err error
 	a.processor, a.sqlTypes, err = a.setupProcessor(config)
 	if err != nil {
@@ -344,11 +380,40 @@ func (a *Abstract) Start(config *Config) error {
 	a.archiveLogger = config.loggerFactory.CreateStreamingArchiveLogger(config.destinationID)
 
 	if a.streamingWorker != nil {
+		a.streamingWorker.circuitBreaker = a.circuitBreaker
 		a.streamingWorker.start()
 	}
+
+	if config.retention != nil {
+		retainer := a.retainer()
+		if provider, ok := a.implementation.(retainerProvider); ok {
+			retainer = provider.retainer()
+		}
+		if retainer == nil {
+			return fmt.Errorf("retention isn't supported by destination type: %s", config.destination.Type)
+		}
+
+		a.retentionWorker = newRetentionWorker(a.destinationID, retainer, config.retention, config.coordinationService)
+		a.retentionWorker.start()
+	}
 	return nil
 }
 
+//retainerProvider is implemented by storages which delete expired data with their own adapters (e.g. file storages)
+type retainerProvider interface {
+	retainer() adapters.Retainer
+}
+
+//retainer returns the first SQL adapter if it supports retention (ClickHouse nodes share the cluster) or nil
+func (a *Abstract) retainer() adapters.Retainer {
+	if len(a.sqlAdapters) == 0 {
+		return nil
+	}
+
+	retainer, _ := a.sqlAdapters[0].(adapters.Retainer)
+	return retainer
+}
+
 func (a *Abstract) setupProcessor(cfg *Config) (processor *schema.Processor, sqlTypes typing.SQLTypes, err error) {
 	destination := cfg.destination
 	destinationID := cfg.destinationID
@@ -359,12 +424,14 @@ func (a *Abstract) setupProcessor(cfg *Config) (processor *schema.Processor, sql
 	var tableName string
 	var oldStyleMappings []string
 	var newStyleMapping *config.Mapping
+	var unflattenedPaths []string
 	mappingFieldType := config.Default
 	uniqueIDField := appconfig.Instance.GlobalUniqueIDField
 	if destination.DataLayout != nil {
 		mappingFieldType = destination.DataLayout.MappingType
 		oldStyleMappings = destination.DataLayout.Mapping
 		newStyleMapping = destination.DataLayout.Mappings
+		unflattenedPaths = destination.DataLayout.UnflattenedPaths
 		if destination.DataLayout.TableNameTemplate != "" {
 			tableName = destination.DataLayout.TableNameTemplate
 		}
@@ -421,7 +488,7 @@ func (a *Abstract) setupProcessor(cfg *Config) (processor *schema.Processor, sql
 	var flattener schema.Flattener
 	var typeResolver schema.TypeResolver
 	if isSQLType {
-		flattener = schema.NewFlattener()
+		flattener = schema.NewFlattenerWithUnflattenedPaths(unflattenedPaths)
 		typeResolver = schema.NewTypeResolver()
 	} else {
 		flattener = schema.NewDummyFlattener()
@@ -51,6 +51,7 @@ func NewAmplitude(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   a.ErrorEvent,
 		SuccessHandler: a.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
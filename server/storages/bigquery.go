@@ -16,13 +16,15 @@ import (
 var disabledRecognitionConfiguration = &UserRecognitionConfiguration{Enabled: false}
 
 //BigQuery stores files to google BigQuery in two modes:
-//batch: via google cloud storage in batch mode (1 file = 1 operation)
+//batch: via google cloud storage (if gcs_bucket is configured) or in-memory payload load jobs in batch mode (1 file = 1 operation)
 //stream: via events queue in stream mode (1 object = 1 operation)
+//batches with primary keys are loaded into staging tables and merged with MERGE statement
 type BigQuery struct {
 	Abstract
 
-	gcsAdapter *adapters.GoogleCloudStorage
-	bqAdapter  *adapters.BigQuery
+	gcsAdapter                    *adapters.GoogleCloudStorage
+	bqAdapter                     *adapters.BigQuery
+	usersRecognitionConfiguration *UserRecognitionConfiguration
 }
 
 func init() {
@@ -41,15 +43,14 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 	if err = config.destination.GetDestConfig(config.destination.Google, gConfig); err != nil {
 		return
 	}
-	if !config.streamMode {
-		if err = gConfig.ValidateBatchMode(); err != nil {
-			return
-		}
-	}
 	if gConfig.Project == "" {
 		return nil, errors.New("BigQuery project(bq_project) is required parameter")
 	}
 
+	if config.partitioning != nil && len(config.partitioning.ClusteringFields) > adapters.BigQueryMaxClusteringFields {
+		return nil, fmt.Errorf("BigQuery supports at most %d clustering fields. Got: %d", adapters.BigQueryMaxClusteringFields, len(config.partitioning.ClusteringFields))
+	}
+
 	//enrich with default parameters
 	if gConfig.Dataset == "" {
 		gConfig.Dataset = "default"
@@ -57,7 +58,8 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 	}
 
 	var gcsAdapter *adapters.GoogleCloudStorage
-	if !config.streamMode {
+	//without google cloud storage bucket data is loaded with load jobs from in-memory payloads
+	if !config.streamMode && gConfig.Bucket != "" {
 		gConfig.RequireDefaultStage(BigQueryType)
 		gcsAdapter, err = adapters.NewGoogleCloudStorage(config.ctx, gConfig)
 		if err != nil {
@@ -79,6 +81,7 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 		return
 	}
 	bq.bqAdapter = bigQueryAdapter
+	bq.usersRecognitionConfiguration = config.usersRecognition
 
 	//create dataset if doesn't exist
 	err = bigQueryAdapter.CreateDataset(gConfig.Dataset)
@@ -86,7 +89,7 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper("", bigQueryAdapter, config.coordinationService, config.pkFields, adapters.SchemaToBigQueryString, config.maxColumns, BigQueryType)
+	tableHelper := NewTableHelper("", bigQueryAdapter, config.coordinationService, config.pkFields, config.partitioning, adapters.SchemaToBigQueryString, config.maxColumns, BigQueryType)
 
 	//Abstract
 	bq.tableHelpers = []*TableHelper{tableHelper}
@@ -98,7 +101,7 @@ func NewBigQuery(config *Config) (storage Storage, err error) {
 }
 
 //storeTable checks table schema
-//stores data into one table via google cloud storage (if batch BQ) or uses streaming if stream mode
+//stores data into one table via google cloud storage (if configured) or with in-memory payload load job
 func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
 	_, tableHelper := bq.getAdapters()
 	table := tableHelper.MapTableSchema(fdata.BatchHeader)
@@ -114,7 +117,7 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 		if fileName == "" {
 			fileName = dbTable.Name + "_" + uuid.NewLettersNumbers()
 		}
-		b, err := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
+		b, err := bq.bqAdapter.LoadPayload(dbTable, fdata.GetPayload(), true)
 		if err != nil {
 			return dbTable, err
 		}
@@ -122,7 +125,7 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 			return dbTable, err
 		}
 
-		if err := bq.bqAdapter.Copy(fileName, dbTable.Name); err != nil {
+		if err := bq.bqAdapter.CopyWithMerge(fileName, dbTable); err != nil {
 			return dbTable, fmt.Errorf("Error copying file [%s] from gcp to bigquery: %v", fileName, err)
 		}
 
@@ -133,13 +136,8 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, er
 		return dbTable, nil
 	}
 
-	//stream mode
-	return dbTable, bq.bqAdapter.Insert(adapters.NewBatchInsertContext(table, fdata.GetPayload(), true, nil))
-}
-
-//Update isn't supported
-func (bq *BigQuery) Update(eventContext *adapters.EventContext) error {
-	return errors.New("BigQuery doesn't support updates")
+	//load job from in-memory payload
+	return dbTable, bq.bqAdapter.Insert(adapters.NewBatchInsertContext(dbTable, fdata.GetPayload(), true, nil))
 }
 
 // SyncStore is used in storing chunk of pulled data to BigQuery with processing
@@ -188,9 +186,9 @@ func (bq *BigQuery) Clean(tableName string) error {
 	return cleanImpl(bq, tableName)
 }
 
-//GetUsersRecognition returns disabled users recognition configuration
+//GetUsersRecognition returns users recognition configuration
 func (bq *BigQuery) GetUsersRecognition() *UserRecognitionConfiguration {
-	return disabledRecognitionConfiguration
+	return bq.usersRecognitionConfiguration
 }
 
 //Type returns BigQuery type
@@ -0,0 +1,86 @@
+package storages
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//go:embed transform/braze.js
+var brazeTransform string
+
+//Braze is a destination that can send data into Braze
+type Braze struct {
+	HTTPStorage
+
+	merger *userMerger
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: BrazeType, createFunc: NewBraze, isSQL: false})
+}
+
+//NewBraze returns configured Braze destination
+func NewBraze(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if !config.streamMode {
+		return nil, fmt.Errorf("Braze destination doesn't support %s mode", BatchMode)
+	}
+	brazeConfig := &adapters.BrazeConfig{}
+	if err = config.destination.GetDestConfig(nil, brazeConfig); err != nil {
+		return
+	}
+
+	b := &Braze{merger: newUserMerger(config.usersRecognition, adapters.BrazeMergeObject)}
+	err = b.Init(config, b, brazeTransform, fmt.Sprintf(`return toBraze($, {app_id: %q})`, brazeConfig.AppID))
+	if err != nil {
+		return
+	}
+	storage = b
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	bAdapter, err := adapters.NewBraze(brazeConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   b.ErrorEvent,
+		SuccessHandler: b.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+	})
+	if err != nil {
+		return
+	}
+	//HTTPStorage
+	b.adapter = bAdapter
+
+	//streaming worker (queue reading)
+	b.streamingWorker = newStreamingWorker(config.eventQueue, b)
+	return
+}
+
+//Update merges anonymous user alias profile into the identified user on events re-sent by users recognition
+func (b *Braze) Update(eventContext *adapters.EventContext) error {
+	if mergeContext := b.merger.mergeEventContext(eventContext); mergeContext != nil {
+		return b.adapter.Insert(adapters.NewSingleInsertContext(mergeContext))
+	}
+
+	return nil
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (b *Braze) GetUsersRecognition() *UserRecognitionConfiguration {
+	return b.merger.recognition
+}
+
+//Type returns Braze type
+func (b *Braze) Type() string {
+	return BrazeType
+}
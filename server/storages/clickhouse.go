@@ -5,6 +5,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
 )
 
@@ -42,6 +43,16 @@ func NewClickHouse(config *Config) (storage Storage, err error) {
 		return
 	}
 
+	if config.usersRecognition.IsEnabled() && !tableStatementFactory.IsReplacingEngine() {
+		logging.Errorf("[%s] Retroactive Users Recognition was DISABLED: it is supported only for ReplacingMergeTree and ReplicatedReplacingMergeTree engines", config.destinationID)
+		config.usersRecognition = &UserRecognitionConfiguration{Enabled: false}
+	}
+
+	//TTL deletes data so it isn't applied in dry run mode
+	if config.retention != nil && !config.retention.DryRun {
+		tableStatementFactory.SetRetentionRules(config.retention.Rules)
+	}
+
 	nullableFields := map[string]bool{}
 	if chConfig.Engine != nil {
 		for _, fieldName := range chConfig.Engine.NullableFields {
@@ -62,14 +73,14 @@ func NewClickHouse(config *Config) (storage Storage, err error) {
 	var sqlAdapters []adapters.SQLAdapter
 	for _, dsn := range chConfig.Dsns {
 		var adapter *adapters.ClickHouse
-		adapter, err = adapters.NewClickHouse(config.ctx, dsn, chConfig.Database, chConfig.Cluster, chConfig.TLS,
+		adapter, err = adapters.NewClickHouse(config.ctx, dsn, chConfig.Database, chConfig.Cluster, chConfig.TLS, chConfig.SSHTunnel,
 			tableStatementFactory, nullableFields, queryLogger, ch.sqlTypes)
 		if err != nil {
 			return
 		}
 
 		ch.adapters = append(ch.adapters, adapter)
-		ch.chTableHelpers = append(ch.chTableHelpers, NewTableHelper("", adapter, config.coordinationService, config.pkFields, adapters.SchemaToClickhouse, config.maxColumns, ClickHouseType))
+		ch.chTableHelpers = append(ch.chTableHelpers, NewTableHelper("", adapter, config.coordinationService, config.pkFields, nil, adapters.SchemaToClickhouse, config.maxColumns, ClickHouseType))
 		sqlAdapters = append(sqlAdapters, adapter)
 	}
 
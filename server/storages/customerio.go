@@ -0,0 +1,86 @@
+package storages
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//go:embed transform/customerio.js
+var customerIOTransform string
+
+//CustomerIO is a destination that can send data into Customer.io
+type CustomerIO struct {
+	HTTPStorage
+
+	merger *userMerger
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: CustomerIOType, createFunc: NewCustomerIO, isSQL: false})
+}
+
+//NewCustomerIO returns configured CustomerIO destination
+func NewCustomerIO(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if !config.streamMode {
+		return nil, fmt.Errorf("Customer.io destination doesn't support %s mode", BatchMode)
+	}
+	customerIOConfig := &adapters.CustomerIOConfig{}
+	if err = config.destination.GetDestConfig(nil, customerIOConfig); err != nil {
+		return
+	}
+
+	c := &CustomerIO{merger: newUserMerger(config.usersRecognition, adapters.CustomerIOMergeObject)}
+	err = c.Init(config, c, customerIOTransform, `return toCustomerIO($)`)
+	if err != nil {
+		return
+	}
+	storage = c
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	cAdapter, err := adapters.NewCustomerIO(customerIOConfig, c.uniqueIDField.GetFlatFieldName(), &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   c.ErrorEvent,
+		SuccessHandler: c.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+	})
+	if err != nil {
+		return
+	}
+	//HTTPStorage
+	c.adapter = cAdapter
+
+	//streaming worker (queue reading)
+	c.streamingWorker = newStreamingWorker(config.eventQueue, c)
+	return
+}
+
+//Update merges anonymous activity into the identified person on events re-sent by users recognition
+func (c *CustomerIO) Update(eventContext *adapters.EventContext) error {
+	if mergeContext := c.merger.mergeEventContext(eventContext); mergeContext != nil {
+		return c.adapter.Insert(adapters.NewSingleInsertContext(mergeContext))
+	}
+
+	return nil
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (c *CustomerIO) GetUsersRecognition() *UserRecognitionConfiguration {
+	return c.merger.recognition
+}
+
+//Type returns CustomerIO type
+func (c *CustomerIO) Type() string {
+	return CustomerIOType
+}
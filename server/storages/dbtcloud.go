@@ -58,6 +58,7 @@ func NewDbtCloud(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   dbt.ErrorEvent,
 		SuccessHandler: dbt.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
@@ -0,0 +1,154 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//Elasticsearch stores events to Elasticsearch/OpenSearch indices (1 table = 1 index) in two modes:
+//batch: (1 file = _bulk requests per index)
+//stream: (1 object = 1 _bulk request)
+//documents rejected by the index in batch mode are sent to fallback, other documents are stored
+type Elasticsearch struct {
+	Abstract
+
+	adapter     *adapters.Elasticsearch
+	tableHelper *TableHelper
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: ElasticsearchType, createFunc: NewElasticsearch, isSQL: true})
+}
+
+//NewElasticsearch returns configured Elasticsearch instance
+func NewElasticsearch(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+
+	esConfig := &adapters.ElasticsearchConfig{}
+	if err = config.destination.GetDestConfig(nil, esConfig); err != nil {
+		return
+	}
+
+	es := &Elasticsearch{}
+	err = es.Init(config, es, "", "")
+	if err != nil {
+		return
+	}
+	storage = es
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	adapter, err := adapters.NewElasticsearch(config.ctx, esConfig, config.uniqueIDField, queryLogger, es.sqlTypes)
+	if err != nil {
+		return
+	}
+	es.adapter = adapter
+
+	es.tableHelper = NewTableHelper("", adapter, config.coordinationService, config.pkFields, nil, adapters.SchemaToElasticsearch, config.maxColumns, ElasticsearchType)
+
+	//Abstract
+	es.tableHelpers = []*TableHelper{es.tableHelper}
+	es.sqlAdapters = []adapters.SQLAdapter{adapter}
+
+	//streaming worker (queue reading)
+	es.streamingWorker = newStreamingWorker(config.eventQueue, es, es.tableHelper)
+	return
+}
+
+//storeTable ensures index mappings and writes documents with _bulk API
+//documents which have been rejected (e.g. because of mapping conflicts) are sent to fallback and removed from fdata
+//the whole batch is failed (and will be retried) only if some documents can be written on retry
+func (es *Elasticsearch) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
+	table := es.tableHelper.MapTableSchema(fdata.BatchHeader)
+	dbSchema, err := es.tableHelper.EnsureTableWithoutCaching(es.ID(), table)
+	if err != nil {
+		return table, err
+	}
+
+	start := timestamp.Now()
+	err = es.adapter.Insert(adapters.NewBatchInsertContext(dbSchema, fdata.GetPayload(), true, nil))
+	var bulkErr *adapters.ElasticsearchBulkError
+	if err != nil && (!errors.As(err, &bulkErr) || bulkErr.Retryable()) {
+		return dbSchema, err
+	}
+
+	if bulkErr != nil {
+		logging.Errorf("[%s] %v", es.ID(), bulkErr)
+		es.fallbackRejected(fdata, bulkErr)
+	}
+	logging.Debugf("[%s] Inserted [%d] documents in [%.2f] seconds", es.ID(), fdata.GetPayloadLen(), timestamp.Now().Sub(start).Seconds())
+
+	return dbSchema, nil
+}
+
+//fallbackRejected writes rejected documents to fallback, counters and events cache and removes them from fdata
+func (es *Elasticsearch) fallbackRejected(fdata *schema.ProcessedFile, bulkErr *adapters.ElasticsearchBulkError) {
+	rawEvents := fdata.GetOriginalRawEvents()
+	payload := fdata.GetPayload()
+	rejected := make(map[int]bool, len(bulkErr.Items))
+	for i, itemErr := range bulkErr.Items {
+		rejected[i] = true
+		es.eventsCache.Error(es.IsCachingDisabled(), es.ID(), rawEvents[i], itemErr.Error())
+		es.Fallback(&events.FailedEvent{
+			Event:   []byte(rawEvents[i]),
+			Error:   itemErr.Error(),
+			EventID: es.uniqueIDField.Extract(payload[i]),
+		})
+	}
+	counters.ErrorPushDestinationEvents(es.ID(), int64(len(rejected)))
+
+	fdata.RemoveObjects(rejected)
+}
+
+//SyncStore is used in storing chunk of pulled data to Elasticsearch with processing
+func (es *Elasticsearch) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, deleteConditions *base.DeleteConditions, cacheTable bool, needCopyEvent bool) error {
+	return syncStoreImpl(es, overriddenDataSchema, objects, deleteConditions, cacheTable, needCopyEvent)
+}
+
+func (es *Elasticsearch) Clean(tableName string) error {
+	return cleanImpl(es, tableName)
+}
+
+//GetUsersRecognition returns disabled users recognition configuration
+func (es *Elasticsearch) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Type returns Elasticsearch type
+func (es *Elasticsearch) Type() string {
+	return ElasticsearchType
+}
+
+//Close closes Elasticsearch adapter, fallback logger and streaming worker
+func (es *Elasticsearch) Close() (multiErr error) {
+	if es.streamingWorker != nil {
+		if err := es.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", es.ID(), err))
+		}
+	}
+
+	if es.adapter != nil {
+		if err := es.adapter.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing Elasticsearch adapter: %v", es.ID(), err))
+		}
+	}
+
+	if err := es.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+
+	return
+}
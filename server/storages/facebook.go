@@ -51,6 +51,7 @@ func NewFacebook(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   fb.ErrorEvent,
 		SuccessHandler: fb.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
 	"github.com/jitsucom/jitsu/server/config"
 	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/events"
@@ -61,6 +63,9 @@ type Config struct {
 	loggerFactory          *logevents.Factory
 	queueFactory           *events.QueueFactory
 	pkFields               map[string]bool
+	partitioning           *adapters.Partitioning
+	retention              *Retention
+	circuitBreaker         *circuitbreaker.CircuitBreaker
 	uniqueIDField          *identifiers.UniqueID
 	logEventPath           string
 	PostHandleDestinations []string
@@ -156,6 +161,10 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 	}
 	logging.Infof("[%s] destination mode: %s", destinationID, destination.Mode)
 
+	if err := destination.Consent.Validate(); err != nil {
+		return nil, nil, err
+	}
+
 	pkFields := map[string]bool{}
 	maxColumns := f.maxColumns
 	uniqueIDField := appconfig.Instance.GlobalUniqueIDField
@@ -171,6 +180,27 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 			uniqueIDField = identifiers.NewUniqueID(destination.DataLayout.UniqueIDField)
 		}
 	}
+	var partitioning *adapters.Partitioning
+	if destination.DataLayout != nil && destination.DataLayout.Partitioning != nil {
+		partitioningConfig := destination.DataLayout.Partitioning
+		var err error
+		partitioning, err = adapters.NewPartitioning(partitioningConfig.Field, partitioningConfig.Granularity, partitioningConfig.ClusteringFields, partitioningConfig.RetentionDays)
+		if err != nil {
+			return nil, nil, err
+		}
+		logging.Infof("[%s] tables are partitioned by %s with %s granularity", destinationID, partitioning.Field, partitioning.Granularity)
+	}
+	retention, err := NewRetention(destination.Retention, destination.Type == S3Type || destination.Type == GCSType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if destination.CircuitBreaker != nil && destination.Mode != StreamMode {
+		return nil, nil, fmt.Errorf("circuit_breaker is supported only in %s mode", StreamMode)
+	}
+	circuitBreaker, err := circuitbreaker.New(destinationID, destination.Type, destination.CircuitBreaker)
+	if err != nil {
+		return nil, nil, err
+	}
 	if len(pkFields) > 0 {
 		logging.Infof("[%s] has primary key fields: [%s]", destinationID, strings.Join(destination.DataLayout.PrimaryKeyFields, ", "))
 	} else {
@@ -229,6 +259,9 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 		loggerFactory:          destinationLoggerFactory,
 		queueFactory:           f.eventsQueueFactory,
 		pkFields:               pkFields,
+		partitioning:           partitioning,
+		retention:              retention,
+		circuitBreaker:         circuitBreaker,
 		uniqueIDField:          uniqueIDField,
 		logEventPath:           f.logEventPath,
 		PostHandleDestinations: destination.PostHandleDestinations,
@@ -238,7 +271,7 @@ func (f *FactoryImpl) Configure(destinationID string, destination config.Destina
 
 //initializeRetroactiveUsersRecognition initializes recognition configuration (overrides global one with destination layer)
 //skip initialization if dummy meta storage
-//disable destination configuration if Postgres or Redshift without primary keys
+//disable destination configuration if Postgres or Redshift without primary keys or BigQuery in stream mode
 func (f *FactoryImpl) initializeRetroactiveUsersRecognition(destinationID string, destination *config.DestinationConfig, pkFields map[string]bool) (*UserRecognitionConfiguration, error) {
 	if f.metaStorage.Type() == meta.DummyType {
 		if destination.UsersRecognition != nil {
@@ -282,6 +315,12 @@ func (f *FactoryImpl) initializeRetroactiveUsersRecognition(destinationID string
 		return &UserRecognitionConfiguration{Enabled: false}, nil
 	}
 
+	//check mode
+	if URSetup.BatchModeOnly && destination.Mode == StreamMode {
+		logging.Errorf("[%s] Retroactive Users Recognition was DISABLED: %s destination supports it only in batch mode", destinationID, destination.Type)
+		return &UserRecognitionConfiguration{Enabled: false}, nil
+	}
+
 	logging.Infof("[%s] configured retroactive users recognition", destinationID)
 
 	//check deprecated node
@@ -10,6 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/coordination"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/schema"
@@ -26,10 +27,36 @@ type FileAdapter interface {
 	Format() adapters.FileEncodingFormat
 }
 
+//batchFileAdapter is implemented by file adapters which write batch description next to the file (e.g. manifest)
+type batchFileAdapter interface {
+	UploadBatch(fileName string, fileBytes []byte, batch *adapters.FileBatch) error
+}
+
 type FileStorage struct {
 	Abstract
 	storageType string
 	adapter     FileAdapter
+
+	//deltaLake is used instead of adapter.UploadBytes if table_format is delta
+	deltaLake           *adapters.DeltaLake
+	coordinationService *coordination.Service
+}
+
+//initTableFormat configures table format writer if table_format is configured
+func (fs *FileStorage) initTableFormat(config *Config, fileConfig adapters.FileConfig, objectStorage adapters.ObjectStorage) {
+	if fileConfig.TableFormat != adapters.FileTableFormatDelta {
+		return
+	}
+
+	fs.deltaLake = adapters.NewDeltaLake(objectStorage, fileConfig.Compression)
+	fs.coordinationService = config.coordinationService
+	logging.Infof("[%s] writes tables in %s format", config.destinationID, fileConfig.TableFormat)
+}
+
+//retainer returns file adapter if it supports expired objects deletion or nil
+func (fs *FileStorage) retainer() adapters.Retainer {
+	retainer, _ := fs.adapter.(adapters.Retainer)
+	return retainer
 }
 
 func (fs *FileStorage) DryRun(events.Event) ([][]adapters.TableField, error) {
@@ -77,14 +104,46 @@ func (fs *FileStorage) Store(fileName string, objects []map[string]interface{},
 }
 
 func (fs *FileStorage) uploadFile(f *schema.ProcessedFile) error {
+	if fs.deltaLake != nil {
+		return fs.writeDeltaTable(f)
+	}
+
 	b, err := fs.marshall(f)
 	if err != nil {
 		return fmt.Errorf("marshalling error: %v", err)
 	}
 	fileName := fs.fileName(f)
+	if batchAdapter, ok := fs.adapter.(batchFileAdapter); ok {
+		return batchAdapter.UploadBatch(fileName, b, &adapters.FileBatch{Table: f.BatchHeader.TableName, Rows: f.GetPayloadLen()})
+	}
 	return fs.adapter.UploadBytes(fileName, b)
 }
 
+//writeDeltaTable writes file into Delta Lake table under the table lock (commits must be serialized between nodes)
+func (fs *FileStorage) writeDeltaTable(f *schema.ProcessedFile) error {
+	tableName := f.BatchHeader.TableName
+	tableLock := fs.coordinationService.CreateLock(fs.ID() + "_delta_" + tableName)
+	locked, err := tableLock.TryLock(tableLockTimeout)
+	if err != nil {
+		return fmt.Errorf("unable to lock delta table %s: %v", tableName, err)
+	}
+	if !locked {
+		return fmt.Errorf("unable to lock delta table %s. Table has been already locked: timeout after %s", tableName, tableLockTimeout.String())
+	}
+	defer tableLock.Unlock()
+
+	commit, err := fs.deltaLake.Write(f.BatchHeader, f.GetPayload())
+	if err != nil {
+		return err
+	}
+
+	if commit.SchemaChanged {
+		logging.Infof("[%s] delta table %s schema has been updated in version %d", fs.ID(), tableName, commit.Version)
+	}
+
+	return nil
+}
+
 func (fs *FileStorage) marshall(fdata *schema.ProcessedFile) ([]byte, error) {
 	encodingFormat := fs.adapter.Format()
 	switch encodingFormat {
@@ -93,8 +152,23 @@ func (fs *FileStorage) marshall(fdata *schema.ProcessedFile) ([]byte, error) {
 	case adapters.FileFormatFlatJSON, adapters.FileFormatJSON:
 		return fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
 	case adapters.FileFormatParquet:
-		pm := schema.NewParquetMarshaller(fs.adapter.Compression() == adapters.FileCompressionGZIP)
+		pm, err := schema.NewParquetMarshallerWithCompression(string(fs.adapter.Compression()))
+		if err != nil {
+			return nil, err
+		}
 		return fdata.GetPayloadUsingStronglyTypedMarshaller(pm)
+	case adapters.FileFormatAvro:
+		am, err := schema.NewAvroMarshaller(string(fs.adapter.Compression()))
+		if err != nil {
+			return nil, err
+		}
+		return fdata.GetPayloadUsingStronglyTypedMarshaller(am)
+	case adapters.FileFormatORC:
+		om, err := schema.NewORCMarshaller(string(fs.adapter.Compression()))
+		if err != nil {
+			return nil, err
+		}
+		return fdata.GetPayloadUsingStronglyTypedMarshaller(om)
 	default:
 		return nil, fmt.Errorf("unsupported %s encoding format %v", fs.storageType, encodingFormat)
 	}
@@ -103,9 +177,10 @@ func (fs *FileStorage) marshall(fdata *schema.ProcessedFile) ([]byte, error) {
 func (fs *FileStorage) fileName(fdata *schema.ProcessedFile) string {
 	start, end := findStartEndTimestamp(fdata.GetPayload())
 	var extension string
-	if fs.adapter.Format() == adapters.FileFormatParquet {
-		extension = "parquet"
-	} else {
+	switch fs.adapter.Format() {
+	case adapters.FileFormatParquet, adapters.FileFormatAvro, adapters.FileFormatORC:
+		extension = string(fs.adapter.Format())
+	default:
 		extension = "log"
 	}
 	return fmt.Sprintf("%s-%s-%s-%s.%s", fdata.BatchHeader.TableName, start.Format("2006-01-02T15:04:05"), end.Format("15:04:05"), appconfig.Instance.ServerName, extension)
@@ -0,0 +1,73 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	RegisterFileStorage(FileType, NewLocalFileDrop, func(config *config.DestinationConfig) map[string]interface{} {
+		return nil
+	})
+	RegisterFileStorage(SFTPType, NewSFTPFileDrop, func(config *config.DestinationConfig) map[string]interface{} {
+		return nil
+	})
+}
+
+//FileDestinationsBaseDir returns the directory which contains all directories of 'file' destinations
+func FileDestinationsBaseDir() string {
+	return viper.GetString("server.file_destinations.base_dir")
+}
+
+//NewLocalFileDrop returns FileStorage which writes files into the local directory
+func NewLocalFileDrop(config *Config) (Storage, error) {
+	if err := requireBatchMode(config); err != nil {
+		return nil, err
+	}
+
+	var adapterConfig adapters.FileDropConfig
+	if err := config.destination.GetDestConfig(nil, &adapterConfig); err != nil {
+		return nil, err
+	}
+
+	adapter, err := adapters.NewLocalFileDrop(&adapterConfig, FileDestinationsBaseDir())
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileDropStorage(config, FileType, adapter)
+}
+
+//NewSFTPFileDrop returns FileStorage which writes files into the directory on the SFTP server
+func NewSFTPFileDrop(config *Config) (Storage, error) {
+	if err := requireBatchMode(config); err != nil {
+		return nil, err
+	}
+
+	var adapterConfig adapters.SFTPConfig
+	if err := config.destination.GetDestConfig(nil, &adapterConfig); err != nil {
+		return nil, err
+	}
+
+	adapter, err := adapters.NewSFTPFileDrop(&adapterConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileDropStorage(config, SFTPType, adapter)
+}
+
+func newFileDropStorage(config *Config, storageType string, adapter *adapters.FileDrop) (Storage, error) {
+	fs := &FileStorage{
+		storageType: storageType,
+		adapter:     adapter,
+	}
+
+	if err := fs.Init(config, fs, "", ""); err != nil {
+		_ = fs.Close()
+		return nil, err
+	}
+
+	return fs, nil
+}
@@ -33,6 +33,8 @@ func NewGoogleCloudStorage(config *Config) (Storage, error) {
 		adapter:     adapter,
 	}
 
+	fs.initTableFormat(config, adapterConfig.FileConfig, adapter)
+
 	if err := fs.Init(config, fs, "", ""); err != nil {
 		_ = fs.Close()
 		return nil, err
@@ -52,6 +52,7 @@ func NewGoogleAnalytics(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   ga.ErrorEvent,
 		SuccessHandler: ga.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
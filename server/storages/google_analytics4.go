@@ -0,0 +1,70 @@
+package storages
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//go:embed transform/ga4.js
+var ga4Transform string
+
+//GA4 stores events to Google Analytics 4 with Measurement Protocol in stream mode
+type GA4 struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: GA4Type, createFunc: NewGA4, isSQL: false})
+}
+
+//NewGA4 returns configured GA4 destination
+func NewGA4(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if !config.streamMode {
+		return nil, fmt.Errorf("Google Analytics 4 destination doesn't support %s mode", BatchMode)
+	}
+	ga4Config := &adapters.GA4Config{}
+	if err = config.destination.GetDestConfig(nil, ga4Config); err != nil {
+		return
+	}
+
+	g := &GA4{}
+	err = g.Init(config, g, ga4Transform, `return toGA4($)`)
+	if err != nil {
+		return
+	}
+	storage = g
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	gAdapter, err := adapters.NewGA4(ga4Config, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   g.ErrorEvent,
+		SuccessHandler: g.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+	})
+	if err != nil {
+		return
+	}
+	//HTTPStorage
+	g.adapter = gAdapter
+
+	//streaming worker (queue reading)
+	g.streamingWorker = newStreamingWorker(config.eventQueue, g)
+	return
+}
+
+//Type returns GA4 type
+func (g *GA4) Type() string {
+	return GA4Type
+}
@@ -48,6 +48,7 @@ func NewHubSpot(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   h.ErrorEvent,
 		SuccessHandler: h.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
@@ -0,0 +1,83 @@
+package storages
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//go:embed transform/intercom.js
+var intercomTransform string
+
+//Intercom is a destination that can send data into Intercom
+type Intercom struct {
+	HTTPStorage
+
+	usersRecognition *UserRecognitionConfiguration
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: IntercomType, createFunc: NewIntercom, isSQL: false})
+}
+
+//NewIntercom returns configured Intercom destination
+func NewIntercom(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if !config.streamMode {
+		return nil, fmt.Errorf("Intercom destination doesn't support %s mode", BatchMode)
+	}
+	intercomConfig := &adapters.IntercomConfig{}
+	if err = config.destination.GetDestConfig(nil, intercomConfig); err != nil {
+		return
+	}
+
+	i := &Intercom{usersRecognition: config.usersRecognition}
+	err = i.Init(config, i, intercomTransform, `return toIntercom($)`)
+	if err != nil {
+		return
+	}
+	storage = i
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	iAdapter, err := adapters.NewIntercom(intercomConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   i.ErrorEvent,
+		SuccessHandler: i.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+	})
+	if err != nil {
+		return
+	}
+	//HTTPStorage
+	i.adapter = iAdapter
+
+	//streaming worker (queue reading)
+	i.streamingWorker = newStreamingWorker(config.eventQueue, i)
+	return
+}
+
+//Update sends events re-sent by users recognition. Events of anonymous users aren't sent to Intercom
+//so they are delivered after identification
+func (i *Intercom) Update(eventContext *adapters.EventContext) error {
+	return i.adapter.Insert(adapters.NewSingleInsertContext(eventContext))
+}
+
+//GetUsersRecognition returns users recognition configuration
+func (i *Intercom) GetUsersRecognition() *UserRecognitionConfiguration {
+	return i.usersRecognition
+}
+
+//Type returns Intercom type
+func (i *Intercom) Type() string {
+	return IntercomType
+}
@@ -0,0 +1,47 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//newMessageAdapter returns MessageAdapter which publishes events of the storage with the publisher
+//publisher is closed if the adapter can't be created
+func newMessageAdapter(config *Config, storage *HTTPStorage, publisher adapters.MessagePublisher, topic string, messageConfig *adapters.MessageConfig) (*adapters.MessageAdapter, error) {
+	messageFactory, err := adapters.NewMessageFactory(config.destinationID, config.destination.Type, topic, messageConfig)
+	if err != nil {
+		publisher.Close()
+		return nil, err
+	}
+
+	adapter, err := adapters.NewMessageAdapter(&adapters.MessageAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		Publisher:      publisher,
+		MessageFactory: messageFactory,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    config.loggerFactory.CreateSQLQueryLogger(config.destinationID),
+		ErrorHandler:   storage.ErrorEvent,
+		SuccessHandler: storage.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+		Batch:          messageConfig.Batch,
+	})
+	if err != nil {
+		messageFactory.Close()
+		publisher.Close()
+		return nil, err
+	}
+
+	return adapter, nil
+}
+
+//requireStreamMode returns err if the destination isn't in stream mode
+func requireStreamMode(config *Config) error {
+	if !config.streamMode {
+		return fmt.Errorf("%s destination doesn't support %s mode", config.destination.Type, BatchMode)
+	}
+
+	return nil
+}
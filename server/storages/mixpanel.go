@@ -0,0 +1,71 @@
+package storages
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//go:embed transform/mixpanel.js
+var mixpanelTransform string
+
+//Mixpanel is a destination that can send data into Mixpanel
+type Mixpanel struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: MixpanelType, createFunc: NewMixpanel, isSQL: false})
+}
+
+//NewMixpanel returns configured Mixpanel destination
+func NewMixpanel(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if !config.streamMode {
+		return nil, fmt.Errorf("Mixpanel destination doesn't support %s mode", BatchMode)
+	}
+	mixpanelConfig := &adapters.MixpanelConfig{}
+	if err = config.destination.GetDestConfig(nil, mixpanelConfig); err != nil {
+		return
+	}
+
+	m := &Mixpanel{}
+	//user profiles are updated on user_identify events only if they are enabled
+	err = m.Init(config, m, mixpanelTransform, fmt.Sprintf(`return toMixpanel($, {users_enabled: %t})`, mixpanelConfig.UsersEnabled))
+	if err != nil {
+		return
+	}
+	storage = m
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	mAdapter, err := adapters.NewMixpanel(mixpanelConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   m.ErrorEvent,
+		SuccessHandler: m.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+	})
+	if err != nil {
+		return
+	}
+	//HTTPStorage
+	m.adapter = mAdapter
+
+	//streaming worker (queue reading)
+	m.streamingWorker = newStreamingWorker(config.eventQueue, m)
+	return
+}
+
+//Type returns Mixpanel type
+func (m *Mixpanel) Type() string {
+	return MixpanelType
+}
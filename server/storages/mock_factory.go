@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
 	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 )
@@ -43,6 +45,12 @@ func (tpm *testProxyMock) GetPostHandleDestinations() []string { return nil }
 //GetGeoResolverID is a mock func
 func (tpm *testProxyMock) GetGeoResolverID() string { return "" }
 
+//GetConsentRequirements is a mock func
+func (tpm *testProxyMock) GetConsentRequirements() *consent.Requirements { return nil }
+
+//GetCircuitBreaker is a mock func
+func (tpm *testProxyMock) GetCircuitBreaker() *circuitbreaker.CircuitBreaker { return nil }
+
 //MockFactory is a Mock destinations storages factory
 type MockFactory struct{}
 
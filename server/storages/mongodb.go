@@ -0,0 +1,178 @@
+package storages
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//MongoDB stores non-flattened events (documents keep nested structure) to MongoDB collections (1 table = 1 collection) in two modes:
+//batch: (1 file = BulkWrite calls per collection)
+//stream: (1 object = 1 BulkWrite call)
+//documents are upserted by primary_key_fields values if they are configured
+type MongoDB struct {
+	Abstract
+
+	adapter     *adapters.MongoDB
+	tableHelper *TableHelper
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: MongoDBType, createFunc: NewMongoDB})
+}
+
+//NewMongoDB returns configured MongoDB instance
+func NewMongoDB(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+
+	mongoConfig := &adapters.MongoDBConfig{}
+	if err = config.destination.GetDestConfig(nil, mongoConfig); err != nil {
+		return
+	}
+
+	m := &MongoDB{}
+	err = m.Init(config, m, "", "")
+	if err != nil {
+		return
+	}
+	storage = m
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	adapter, err := adapters.NewMongoDB(config.ctx, mongoConfig, config.pkFields, config.uniqueIDField, queryLogger)
+	if err != nil {
+		return
+	}
+	m.adapter = adapter
+
+	//collections are schemaless: table helper is used only for mapping batch headers into collection names
+	m.tableHelper = NewTableHelper("", nil, config.coordinationService, config.pkFields, nil, adapters.SchemaToMongoDB, 0, MongoDBType)
+
+	//streaming worker (queue reading)
+	m.streamingWorker = newStreamingWorker(config.eventQueue, m, m.tableHelper)
+	return
+}
+
+//DryRun returns top level fields of the processed document with BSON types
+func (m *MongoDB) DryRun(payload events.Event) ([][]adapters.TableField, error) {
+	envelops, err := m.processor.ProcessEvent(payload, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([][]adapters.TableField, 0, len(envelops))
+	for _, envelop := range envelops {
+		maskedFields := maskedFlatFields(envelop.Masked)
+		names := make([]string, 0, len(envelop.Event))
+		for name := range envelop.Event {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		tableFields := make([]adapters.TableField, 0, len(names))
+		for _, name := range names {
+			value := envelop.Event[name]
+			dataType, _ := typing.TypeFromValue(typing.ReformatValue(value))
+			tableFields = append(tableFields, adapters.TableField{Field: name, Type: adapters.SchemaToMongoDB[dataType], Value: value, Masked: maskedFields[name]})
+		}
+		res = append(res, tableFields)
+	}
+
+	return res, nil
+}
+
+//Insert writes one document into the collection
+func (m *MongoDB) Insert(eventContext *adapters.EventContext) (insertErr error) {
+	defer func() {
+		//metrics/counters/cache/fallback
+		m.AccountResult(eventContext, insertErr)
+
+		//archive
+		if insertErr == nil {
+			m.archiveLogger.Consume(eventContext.RawEvent, eventContext.TokenID)
+		}
+	}()
+
+	return m.adapter.Insert(adapters.NewSingleInsertContext(eventContext))
+}
+
+//storeTable writes documents into the collection
+func (m *MongoDB) storeTable(fdata *schema.ProcessedFile) (*adapters.Table, error) {
+	table := m.tableHelper.MapTableSchema(fdata.BatchHeader)
+
+	start := timestamp.Now()
+	if err := m.adapter.Insert(adapters.NewBatchInsertContext(table, fdata.GetPayload(), true, nil)); err != nil {
+		return table, err
+	}
+	logging.Debugf("[%s] Inserted [%d] documents in [%.2f] seconds", m.ID(), fdata.GetPayloadLen(), timestamp.Now().Sub(start).Seconds())
+
+	return table, nil
+}
+
+//SyncStore isn't supported
+func (m *MongoDB) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, deleteConditions *base.DeleteConditions, cacheTable bool, needCopyEvent bool) error {
+	return fmt.Errorf("[%s] MongoDB doesn't support sync store", m.ID())
+}
+
+//Update isn't supported
+func (m *MongoDB) Update(eventContext *adapters.EventContext) error {
+	return fmt.Errorf("[%s] MongoDB doesn't support updates", m.ID())
+}
+
+//ReplaceTable renames replacement collection into the original one
+func (m *MongoDB) ReplaceTable(originalTable, replacementTable string, dropOldTable bool) error {
+	return m.adapter.RenameCollection(replacementTable, originalTable, dropOldTable)
+}
+
+//DropTable drops the collection
+func (m *MongoDB) DropTable(tableName string) error {
+	return m.adapter.DropCollection(tableName)
+}
+
+//Clean removes all documents from the collection
+func (m *MongoDB) Clean(tableName string) error {
+	return m.adapter.DeleteAll(tableName)
+}
+
+//GetUsersRecognition returns disabled users recognition configuration
+func (m *MongoDB) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Type returns MongoDB type
+func (m *MongoDB) Type() string {
+	return MongoDBType
+}
+
+//Close closes MongoDB adapter, fallback logger and streaming worker
+func (m *MongoDB) Close() (multiErr error) {
+	if m.streamingWorker != nil {
+		if err := m.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", m.ID(), err))
+		}
+	}
+
+	if m.adapter != nil {
+		if err := m.adapter.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing MongoDB adapter: %v", m.ID(), err))
+		}
+	}
+
+	if err := m.close(); err != nil {
+		multiErr = multierror.Append(multiErr, err)
+	}
+
+	return
+}
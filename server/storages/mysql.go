@@ -63,7 +63,7 @@ func NewMySQL(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(mConfig.Schema, adapter, config.coordinationService, config.pkFields, adapters.SchemaToMySQL, config.maxColumns, MySQLType)
+	tableHelper := NewTableHelper(mConfig.Schema, adapter, config.coordinationService, config.pkFields, nil, adapters.SchemaToMySQL, config.maxColumns, MySQLType)
 
 	m.adapter = adapter
 	m.usersRecognitionConfiguration = config.usersRecognition
@@ -0,0 +1,59 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//NATS is a destination that publishes events to NATS subjects (with or without JetStream)
+type NATS struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: NATSType, createFunc: NewNATS, isSQL: false})
+}
+
+//NewNATS returns configured NATS destination
+func NewNATS(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if err = requireStreamMode(config); err != nil {
+		return
+	}
+
+	natsConfig := &adapters.NATSConfig{}
+	if err = config.destination.GetDestConfig(nil, natsConfig); err != nil {
+		return
+	}
+
+	n := &NATS{}
+	err = n.Init(config, n, "", "")
+	if err != nil {
+		return
+	}
+	storage = n
+
+	publisher, err := adapters.NewNATS(config.destinationID, natsConfig)
+	if err != nil {
+		return
+	}
+
+	adapter, err := newMessageAdapter(config, &n.HTTPStorage, publisher, natsConfig.Subject, &natsConfig.MessageConfig)
+	if err != nil {
+		return
+	}
+	n.adapter = adapter
+
+	//streaming worker (queue reading)
+	n.streamingWorker = newStreamingWorker(config.eventQueue, n)
+	return
+}
+
+//Type returns NATS type
+func (n *NATS) Type() string {
+	return NATSType
+}
@@ -70,6 +70,7 @@ func NewNpmDestination(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   wh.ErrorEvent,
 		SuccessHandler: wh.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
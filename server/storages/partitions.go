@@ -0,0 +1,68 @@
+package storages
+
+import (
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"go.uber.org/atomic"
+)
+
+const partitionsMaintenanceInterval = time.Hour
+
+//PartitionsMaintainer is implemented by adapters which create and drop table partitions themselves (e.g. Postgres native partitioning)
+type PartitionsMaintainer interface {
+	MaintainPartitions(partitioning *adapters.Partitioning) error
+}
+
+//PartitionsWorker creates future partitions and drops expired partitions on startup and then every partitionsMaintenanceInterval
+//all statements are idempotent so the worker may run on every node
+type PartitionsWorker struct {
+	destinationID string
+	maintainer    PartitionsMaintainer
+	partitioning  *adapters.Partitioning
+
+	done   chan struct{}
+	closed *atomic.Bool
+}
+
+//newPartitionsWorker returns configured PartitionsWorker
+func newPartitionsWorker(destinationID string, maintainer PartitionsMaintainer, partitioning *adapters.Partitioning) *PartitionsWorker {
+	return &PartitionsWorker{
+		destinationID: destinationID,
+		maintainer:    maintainer,
+		partitioning:  partitioning,
+		done:          make(chan struct{}),
+		closed:        atomic.NewBool(false),
+	}
+}
+
+func (pw *PartitionsWorker) start() {
+	safego.RunWithRestart(func() {
+		for {
+			if pw.closed.Load() {
+				return
+			}
+
+			if err := pw.maintainer.MaintainPartitions(pw.partitioning); err != nil {
+				logging.Errorf("[%s] Error maintaining table partitions: %v", pw.destinationID, err)
+			}
+
+			select {
+			case <-pw.done:
+				return
+			case <-time.After(partitionsMaintenanceInterval):
+			}
+		}
+	})
+}
+
+//Close stops the worker
+func (pw *PartitionsWorker) Close() error {
+	if !pw.closed.Swap(true) {
+		close(pw.done)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package storages
+
+import (
+	"errors"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	enconfig "github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/drivers/base"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/utils"
+)
+
+//Personalization is a synchronous destination which returns JSON results (feature flags, segments, recommendations)
+//in /api/v1/event response
+type Personalization struct {
+	Abstract
+	adapter    *adapters.Personalization
+	syncWorker *SyncWorker
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: PersonalizationType, createFunc: NewPersonalization, isSQL: false, IsSynchronous: true})
+}
+
+//NewPersonalization returns configured Personalization destination
+func NewPersonalization(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+
+	personalizationConfig := &adapters.PersonalizationConfig{}
+	if err = config.destination.GetDestConfig(map[string]interface{}{}, personalizationConfig); err != nil {
+		return nil, err
+	}
+	if config.destination.DataLayout == nil {
+		config.destination.DataLayout = &enconfig.DataLayout{}
+	}
+	config.destination.DataLayout.TableNameTemplate = utils.NvlString(personalizationConfig.Filter, "always")
+	p := &Personalization{}
+	err = p.Init(config, p, "", "")
+	if err != nil {
+		return
+	}
+	storage = p
+
+	personalizationAdapter, err := adapters.NewPersonalization(personalizationConfig, config.destinationID)
+	if err != nil {
+		return
+	}
+	p.adapter = personalizationAdapter
+
+	p.syncWorker = newTimeoutSyncWorker(p, personalizationConfig.GetTimeout(), personalizationConfig.GetMaxConcurrency())
+	return
+}
+
+//ProcessEvent returns personalization result of the processed event
+func (p *Personalization) ProcessEvent(eventContext *adapters.EventContext) (map[string]interface{}, error) {
+	//unique ID is injected into all objects except the first one if the transform returns an array
+	delete(eventContext.ProcessedEvent, p.uniqueIDField.GetFlatFieldName())
+
+	res, err := p.adapter.ProcessEvent(eventContext.ProcessedEvent)
+	if err != nil {
+		return nil, err
+	}
+	eventContext.SynchronousResult = res
+	return res, nil
+}
+
+//Type returns PersonalizationType type
+func (p *Personalization) Type() string {
+	return PersonalizationType
+}
+
+func (p *Personalization) Close() error {
+	if p.syncWorker != nil {
+		p.syncWorker.Close()
+	}
+	if p.adapter != nil {
+		return p.adapter.Close()
+	}
+	return nil
+}
+
+func (p *Personalization) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, deleteConditions *base.DeleteConditions, cacheTable bool, needCopyEvent bool) error {
+	return errors.New("Personalization destination doesn't support sync store")
+}
+
+func (p *Personalization) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+func (p *Personalization) GetSyncWorker() *SyncWorker {
+	return p.syncWorker
+}
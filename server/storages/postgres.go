@@ -19,6 +19,7 @@ type Postgres struct {
 
 	adapter                       *adapters.Postgres
 	usersRecognitionConfiguration *UserRecognitionConfiguration
+	partitionsWorker              *PartitionsWorker
 }
 
 func init() {
@@ -51,6 +52,12 @@ func NewPostgres(config *Config) (storage Storage, err error) {
 		pgConfig.Parameters["connect_timeout"] = "600"
 	}
 
+	//Postgres requires partition column to be a part of the primary key
+	if config.partitioning != nil && len(config.pkFields) > 0 && !config.pkFields[config.partitioning.Field] {
+		err = fmt.Errorf("partitioning field %s must be one of primary_key_fields", config.partitioning.Field)
+		return
+	}
+
 	dir := adapters.SSLDir(appconfig.Instance.ConfigPath, config.destinationID)
 	if err = adapters.ProcessSSL(dir, pgConfig); err != nil {
 		return
@@ -76,7 +83,7 @@ func NewPostgres(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(pgConfig.Schema, adapter, config.coordinationService, config.pkFields, adapters.SchemaToPostgres, config.maxColumns, PostgresType)
+	tableHelper := NewTableHelper(pgConfig.Schema, adapter, config.coordinationService, config.pkFields, config.partitioning, adapters.SchemaToPostgres, config.maxColumns, PostgresType)
 
 	p.adapter = adapter
 	p.usersRecognitionConfiguration = config.usersRecognition
@@ -86,6 +93,11 @@ func NewPostgres(config *Config) (storage Storage, err error) {
 
 	//streaming worker (queue reading)
 	p.streamingWorker = newStreamingWorker(config.eventQueue, p, tableHelper)
+
+	if config.partitioning != nil {
+		p.partitionsWorker = newPartitionsWorker(config.destinationID, adapter, config.partitioning)
+		p.partitionsWorker.start()
+	}
 	return
 }
 
@@ -116,6 +128,12 @@ func (p *Postgres) Close() (multiErr error) {
 		}
 	}
 
+	if p.partitionsWorker != nil {
+		if err := p.partitionsWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing partitions worker: %v", p.ID(), err))
+		}
+	}
+
 	if p.adapter != nil {
 		if err := p.adapter.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing postgres datasource: %v", p.ID(), err))
@@ -0,0 +1,70 @@
+package storages
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//go:embed transform/posthog.js
+var postHogTransform string
+
+//PostHog is a destination that can send data into PostHog
+type PostHog struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: PostHogType, createFunc: NewPostHog, isSQL: false})
+}
+
+//NewPostHog returns configured PostHog destination
+func NewPostHog(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if !config.streamMode {
+		return nil, fmt.Errorf("PostHog destination doesn't support %s mode", BatchMode)
+	}
+	postHogConfig := &adapters.PostHogConfig{}
+	if err = config.destination.GetDestConfig(nil, postHogConfig); err != nil {
+		return
+	}
+
+	p := &PostHog{}
+	err = p.Init(config, p, postHogTransform, `return toPostHog($)`)
+	if err != nil {
+		return
+	}
+	storage = p
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.destinationID)
+	pAdapter, err := adapters.NewPostHog(postHogConfig, &adapters.HTTPAdapterConfiguration{
+		DestinationID:  config.destinationID,
+		Dir:            config.logEventPath,
+		HTTPConfig:     DefaultHTTPConfiguration,
+		QueueFactory:   config.queueFactory,
+		PoolWorkers:    defaultWorkersPoolSize,
+		DebugLogger:    requestDebugLogger,
+		ErrorHandler:   p.ErrorEvent,
+		SuccessHandler: p.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
+	})
+	if err != nil {
+		return
+	}
+	//HTTPStorage
+	p.adapter = pAdapter
+
+	//streaming worker (queue reading)
+	p.streamingWorker = newStreamingWorker(config.eventQueue, p)
+	return
+}
+
+//Type returns PostHog type
+func (p *PostHog) Type() string {
+	return PostHogType
+}
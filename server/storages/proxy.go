@@ -1,6 +1,8 @@
 package storages
 
 import (
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/safego"
@@ -124,6 +126,16 @@ func (rsp *RetryableProxy) GetGeoResolverID() string {
 	return rsp.config.destination.GeoDataResolverID
 }
 
+//GetConsentRequirements returns destination consent requirements (nil if aren't configured)
+func (rsp *RetryableProxy) GetConsentRequirements() *consent.Requirements {
+	return rsp.config.destination.Consent
+}
+
+//GetCircuitBreaker returns destination circuit breaker (nil if isn't configured)
+func (rsp *RetryableProxy) GetCircuitBreaker() *circuitbreaker.CircuitBreaker {
+	return rsp.config.circuitBreaker
+}
+
 //Close stops underlying goroutine and close the storage
 func (rsp *RetryableProxy) Close() error {
 	rsp.Lock()
@@ -0,0 +1,59 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//PubSub is a destination that publishes events to Google Pub/Sub topics
+type PubSub struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: PubSubType, createFunc: NewPubSub, isSQL: false})
+}
+
+//NewPubSub returns configured Pub/Sub destination
+func NewPubSub(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if err = requireStreamMode(config); err != nil {
+		return
+	}
+
+	pubSubConfig := &adapters.PubSubConfig{}
+	if err = config.destination.GetDestConfig(nil, pubSubConfig); err != nil {
+		return
+	}
+
+	ps := &PubSub{}
+	err = ps.Init(config, ps, "", "")
+	if err != nil {
+		return
+	}
+	storage = ps
+
+	publisher, err := adapters.NewPubSub(config.ctx, pubSubConfig)
+	if err != nil {
+		return
+	}
+
+	adapter, err := newMessageAdapter(config, &ps.HTTPStorage, publisher, pubSubConfig.Topic, &pubSubConfig.MessageConfig)
+	if err != nil {
+		return
+	}
+	ps.adapter = adapter
+
+	//streaming worker (queue reading)
+	ps.streamingWorker = newStreamingWorker(config.eventQueue, ps)
+	return
+}
+
+//Type returns Pub/Sub type
+func (ps *PubSub) Type() string {
+	return PubSubType
+}
@@ -0,0 +1,69 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//Redis is a destination that writes events into Redis structures (profiles, sorted sets, counters)
+type Redis struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: RedisType, createFunc: NewRedis, isSQL: false})
+}
+
+//NewRedis returns configured Redis destination
+func NewRedis(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if err = requireStreamMode(config); err != nil {
+		return
+	}
+
+	redisConfig := &adapters.RedisConfig{}
+	if err = config.destination.GetDestConfig(nil, redisConfig); err != nil {
+		return
+	}
+
+	r := &Redis{}
+	err = r.Init(config, r, "", "")
+	if err != nil {
+		return
+	}
+	storage = r
+
+	adapter, err := adapters.NewRedis(config.destinationID, redisConfig, config.loggerFactory.CreateSQLQueryLogger(config.destinationID))
+	if err != nil {
+		return
+	}
+	r.adapter = adapter
+
+	//streaming worker (queue reading)
+	r.streamingWorker = newStreamingWorker(config.eventQueue, r)
+	return
+}
+
+//Insert writes event into Redis synchronously
+func (r *Redis) Insert(eventContext *adapters.EventContext) (insertErr error) {
+	defer func() {
+		//metrics/counters/cache/fallback
+		r.AccountResult(eventContext, insertErr)
+
+		//archive
+		if insertErr == nil {
+			r.archiveLogger.Consume(eventContext.RawEvent, eventContext.TokenID)
+		}
+	}()
+
+	return r.adapter.Insert(adapters.NewSingleInsertContext(eventContext))
+}
+
+//Type returns Redis type
+func (r *Redis) Type() string {
+	return RedisType
+}
@@ -94,7 +94,7 @@ func NewAwsRedshift(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(redshiftConfig.Schema, redshiftAdapter, config.coordinationService, config.pkFields, adapters.SchemaToRedshift, config.maxColumns, RedshiftType)
+	tableHelper := NewTableHelper(redshiftConfig.Schema, redshiftAdapter, config.coordinationService, config.pkFields, nil, adapters.SchemaToRedshift, config.maxColumns, RedshiftType)
 
 	ar.s3Adapter = s3Adapter
 	ar.redshiftAdapter = redshiftAdapter
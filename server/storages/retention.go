@@ -0,0 +1,152 @@
+package storages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/jitsucom/jitsu/server/coordination"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultRetentionSchedule = "@daily"
+	defaultRetentionField    = "_timestamp"
+	//retentionLockMinDuration is a min time of holding a rule lock. It prevents running the same job on nodes with clock skew
+	retentionLockMinDuration = time.Minute
+)
+
+var retentionScheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+//Retention is a parsed and validated retention configuration
+type Retention struct {
+	Schedule cron.Schedule
+	DryRun   bool
+	Rules    []*adapters.RetentionRule
+}
+
+//NewRetention returns validated Retention with default values or nil if retention isn't configured
+//objectStorage is true for file destinations (S3, GCS) which delete objects by prefix
+func NewRetention(retentionConfig *config.Retention, objectStorage bool) (*Retention, error) {
+	if retentionConfig == nil {
+		return nil, nil
+	}
+
+	if err := retentionConfig.Validate(objectStorage); err != nil {
+		return nil, err
+	}
+
+	scheduleExpression := retentionConfig.Schedule
+	if scheduleExpression == "" {
+		scheduleExpression = defaultRetentionSchedule
+	}
+	schedule, err := retentionScheduleParser.Parse(scheduleExpression)
+	if err != nil {
+		return nil, fmt.Errorf("malformed retention.schedule [%s]: %v", scheduleExpression, err)
+	}
+
+	rules := make([]*adapters.RetentionRule, 0, len(retentionConfig.Rules))
+	for _, ruleConfig := range retentionConfig.Rules {
+		field := ruleConfig.Field
+		if field == "" {
+			field = defaultRetentionField
+		}
+
+		prefix := ruleConfig.Prefix
+		if prefix == config.RetentionAllObjectsPrefix {
+			prefix = ""
+		}
+
+		rules = append(rules, &adapters.RetentionRule{
+			Table:  ruleConfig.Table,
+			Prefix: prefix,
+			Field:  field,
+			TTL:    time.Duration(ruleConfig.Days) * 24 * time.Hour,
+		})
+	}
+
+	return &Retention{Schedule: schedule, DryRun: retentionConfig.DryRun, Rules: rules}, nil
+}
+
+//RetentionWorker applies retention rules on schedule
+//every rule is run under coordination lock so only one node runs it
+type RetentionWorker struct {
+	destinationID       string
+	retainer            adapters.Retainer
+	retention           *Retention
+	coordinationService *coordination.Service
+
+	cronInstance *cron.Cron
+}
+
+//newRetentionWorker returns configured RetentionWorker
+func newRetentionWorker(destinationID string, retainer adapters.Retainer, retention *Retention, coordinationService *coordination.Service) *RetentionWorker {
+	return &RetentionWorker{
+		destinationID:       destinationID,
+		retainer:            retainer,
+		retention:           retention,
+		coordinationService: coordinationService,
+		cronInstance:        cron.New(),
+	}
+}
+
+func (rw *RetentionWorker) start() {
+	rw.cronInstance.Schedule(rw.retention.Schedule, cron.FuncJob(rw.run))
+	rw.cronInstance.Start()
+}
+
+//run applies all retention rules
+func (rw *RetentionWorker) run() {
+	for _, rule := range rw.retention.Rules {
+		rw.apply(rule)
+	}
+}
+
+//apply deletes expired data by the rule if the rule lock is acquired
+func (rw *RetentionWorker) apply(rule *adapters.RetentionRule) {
+	lock := rw.coordinationService.CreateLock(rw.lockName(rule))
+	locked, err := lock.TryLock(0)
+	if err != nil {
+		logging.Errorf("[%s] Error locking retention rule %s: %v", rw.destinationID, rule.String(), err)
+		return
+	}
+	if !locked {
+		logging.Debugf("[%s] Retention rule %s is being applied on another node", rw.destinationID, rule.String())
+		return
+	}
+
+	started := time.Now()
+	defer func() {
+		//unlock later so nodes which are triggered a bit later skip the rule
+		time.AfterFunc(retentionLockMinDuration-time.Since(started), lock.Unlock)
+	}()
+
+	report, err := rw.retainer.DeleteExpired(rule, timestamp.Now().UTC().Add(-rule.TTL), rw.retention.DryRun)
+	if err != nil {
+		logging.Errorf("[%s] Error applying retention rule %s: %v", rw.destinationID, rule.String(), err)
+		return
+	}
+
+	if report.DryRun {
+		logging.Infof("[%s] Retention dry run: %s", rw.destinationID, report.String())
+	} else {
+		logging.Infof("[%s] Retention: %s", rw.destinationID, report.String())
+	}
+}
+
+func (rw *RetentionWorker) lockName(rule *adapters.RetentionRule) string {
+	if rule.Table != "" {
+		return "retention_" + rw.destinationID + "_" + rule.Table
+	}
+
+	return "retention_" + rw.destinationID + "_" + rule.Prefix
+}
+
+//Close stops the worker. Running rules aren't interrupted
+func (rw *RetentionWorker) Close() error {
+	rw.cronInstance.Stop()
+	return nil
+}
@@ -0,0 +1,89 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetention(t *testing.T) {
+	retention, err := NewRetention(nil, false)
+	require.NoError(t, err)
+	require.Nil(t, retention)
+
+	retention, err = NewRetention(&config.Retention{DryRun: true, Rules: []config.RetentionRule{{Table: "events", Days: 400}, {Table: "pageviews", Field: "ts", Days: 30}}}, false)
+	require.NoError(t, err)
+	require.True(t, retention.DryRun)
+	require.Equal(t, []*adapters.RetentionRule{
+		{Table: "events", Field: "_timestamp", TTL: 400 * 24 * time.Hour},
+		{Table: "pageviews", Field: "ts", TTL: 30 * 24 * time.Hour},
+	}, retention.Rules)
+	now := time.Date(2022, 5, 18, 13, 45, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2022, 5, 19, 0, 0, 0, 0, time.UTC), retention.Schedule.Next(now))
+
+	retention, err = NewRetention(&config.Retention{Rules: []config.RetentionRule{{Prefix: "archive/", Days: 30}, {Prefix: "*", Days: 400}}}, true)
+	require.NoError(t, err)
+	require.Equal(t, []*adapters.RetentionRule{
+		{Prefix: "archive/", Field: "_timestamp", TTL: 30 * 24 * time.Hour},
+		{Prefix: "", Field: "_timestamp", TTL: 400 * 24 * time.Hour},
+	}, retention.Rules)
+
+	tests := []struct {
+		name          string
+		config        *config.Retention
+		objectStorage bool
+		expectedError string
+	}{
+		{
+			"without rules",
+			&config.Retention{},
+			false,
+			"retention.rules are required",
+		},
+		{
+			"without days",
+			&config.Retention{Rules: []config.RetentionRule{{Table: "events"}}},
+			false,
+			"retention.rules[0].days must be positive",
+		},
+		{
+			"malformed schedule",
+			&config.Retention{Schedule: "every day", Rules: []config.RetentionRule{{Table: "events", Days: 1}}},
+			false,
+			"malformed retention.schedule [every day]: expected exactly 5 fields, found 2: [every day]",
+		},
+		{
+			"SQL rule without table",
+			&config.Retention{Rules: []config.RetentionRule{{Days: 1}}},
+			false,
+			"retention.rules[0].table is required",
+		},
+		{
+			"SQL rule with prefix",
+			&config.Retention{Rules: []config.RetentionRule{{Prefix: "archive/", Days: 1}}},
+			false,
+			"retention.rules[0].prefix is supported only by file destinations: use table",
+		},
+		{
+			"file rule without prefix",
+			&config.Retention{Rules: []config.RetentionRule{{Days: 1}}},
+			true,
+			"retention.rules[0].prefix is required for file destinations. Use '*' for all objects",
+		},
+		{
+			"file rule with table",
+			&config.Retention{Rules: []config.RetentionRule{{Prefix: "archive/", Days: 1}, {Table: "events", Days: 1}}},
+			true,
+			"retention.rules[1].table isn't supported by file destinations: use prefix",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRetention(tt.config, tt.objectStorage)
+			require.EqualError(t, err, tt.expectedError)
+		})
+	}
+}
@@ -31,6 +31,8 @@ func NewS3(config *Config) (Storage, error) {
 		adapter:     adapter,
 	}
 
+	fs.initTableFormat(config, adapterConfig.FileConfig, adapter)
+
 	if err := fs.Init(config, fs, "", ""); err != nil {
 		_ = fs.Close()
 		return nil, err
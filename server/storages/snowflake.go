@@ -106,7 +106,7 @@ func NewSnowflake(config *Config) (storage Storage, err error) {
 		return
 	}
 
-	tableHelper := NewTableHelper(snowflakeConfig.Schema, snowflakeAdapter, config.coordinationService, config.pkFields, adapters.SchemaToSnowflake, config.maxColumns, SnowflakeType)
+	tableHelper := NewTableHelper(snowflakeConfig.Schema, snowflakeAdapter, config.coordinationService, config.pkFields, nil, adapters.SchemaToSnowflake, config.maxColumns, SnowflakeType)
 
 	snowflake.snowflakeAdapter = snowflakeAdapter
 	snowflake.usersRecognitionConfiguration = config.usersRecognition
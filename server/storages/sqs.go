@@ -0,0 +1,59 @@
+package storages
+
+import (
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//SQS is a destination that publishes events to AWS SQS queues
+type SQS struct {
+	HTTPStorage
+}
+
+func init() {
+	RegisterStorage(StorageType{typeName: SQSType, createFunc: NewSQS, isSQL: false})
+}
+
+//NewSQS returns configured SQS destination
+func NewSQS(config *Config) (storage Storage, err error) {
+	defer func() {
+		if err != nil && storage != nil {
+			storage.Close()
+			storage = nil
+		}
+	}()
+	if err = requireStreamMode(config); err != nil {
+		return
+	}
+
+	sqsConfig := &adapters.SQSConfig{}
+	if err = config.destination.GetDestConfig(nil, sqsConfig); err != nil {
+		return
+	}
+
+	s := &SQS{}
+	err = s.Init(config, s, "", "")
+	if err != nil {
+		return
+	}
+	storage = s
+
+	publisher, err := adapters.NewSQS(sqsConfig)
+	if err != nil {
+		return
+	}
+
+	adapter, err := newMessageAdapter(config, &s.HTTPStorage, publisher, sqsConfig.QueueURL, &sqsConfig.MessageConfig)
+	if err != nil {
+		return
+	}
+	s.adapter = adapter
+
+	//streaming worker (queue reading)
+	s.streamingWorker = newStreamingWorker(config.eventQueue, s)
+	return
+}
+
+//Type returns SQS type
+func (s *SQS) Type() string {
+	return SQSType
+}
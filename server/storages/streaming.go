@@ -3,6 +3,7 @@ package storages
 import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -15,6 +16,9 @@ import (
 	"time"
 )
 
+//circuitBreakerPauseInterval is a delay between circuit breaker checks while it is open
+const circuitBreakerPauseInterval = time.Second
+
 //StreamingStorage supports Insert operation
 type StreamingStorage interface {
 	Storage
@@ -35,6 +39,8 @@ type StreamingWorker struct {
 	eventQueue       events.Queue
 	streamingStorage StreamingStorage
 	tableHelper      []*TableHelper
+	//circuitBreaker pauses events consuming while the destination fails (optional)
+	circuitBreaker *circuitbreaker.CircuitBreaker
 
 	closed *atomic.Bool
 }
@@ -61,6 +67,11 @@ func (sw *StreamingWorker) start() {
 			if sw.closed.Load() {
 				break
 			}
+			//events are kept in the queue while the destination is unavailable
+			if !sw.circuitBreaker.Allow() {
+				time.Sleep(circuitBreakerPauseInterval)
+				continue
+			}
 
 			fact, dequeuedTime, tokenID, err := sw.eventQueue.DequeueBlock()
 			if err != nil {
@@ -92,6 +103,8 @@ func (sw *StreamingWorker) start() {
 				Src:             events.ExtractSrc(fact),
 				RawEvent:        fact,
 				RecognizedEvent: recognizedEvent,
+
+				SerializedOriginalEvent: sw.streamingStorage.Processor().SerializeOriginalEvent(fact),
 			}
 
 			envelops, err := sw.streamingStorage.Processor().ProcessEvent(fact, true)
@@ -132,6 +145,8 @@ func (sw *StreamingWorker) start() {
 					ProcessedEvent:  flattenObject,
 					Table:           table,
 					RecognizedEvent: recognizedEvent,
+
+					SerializedOriginalEvent: envelop.OriginalEvent,
 				}
 				if recognizedEvent {
 					if updateErr := sw.streamingStorage.Update(eventContext); updateErr != nil {
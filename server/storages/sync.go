@@ -1,15 +1,19 @@
 package storages
 
 import (
+	"fmt"
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/errorj"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/safego"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/utils"
 	"go.uber.org/atomic"
 	"math/rand"
+	"time"
 )
 
 //SyncStorage supports ProcessEvent synchronous operation
@@ -29,6 +33,10 @@ type SyncStorage interface {
 type SyncWorker struct {
 	syncStorage SyncStorage
 	tableHelper []*TableHelper
+	//timeout is a max time of waiting for the result. 0 means waiting until processing is finished
+	timeout time.Duration
+	//inFlight is a semaphore which limits events processed in background when timeout is configured
+	inFlight chan struct{}
 
 	closed *atomic.Bool
 }
@@ -42,7 +50,52 @@ func newSyncWorker(syncStorage SyncStorage, tableHelper ...*TableHelper) *SyncWo
 	}
 }
 
+//newTimeoutSyncWorker returns configured sync worker which doesn't wait for results longer than timeout
+//and processes up to maxConcurrency events at the same time
+func newTimeoutSyncWorker(syncStorage SyncStorage, timeout time.Duration, maxConcurrency int, tableHelper ...*TableHelper) *SyncWorker {
+	sw := newSyncWorker(syncStorage, tableHelper...)
+	sw.timeout = timeout
+	sw.inFlight = make(chan struct{}, maxConcurrency)
+	return sw
+}
+
+//ProcessEvent returns results of the event processing. If timeout is configured and exceeded, returns nil:
+//processing is finished in background (metrics/counters/events cache are written) but results aren't returned
+//If max concurrency is reached (e.g. processing of previous events is stuck), the event is skipped
 func (sw *SyncWorker) ProcessEvent(fact events.Event, tokenID string) []map[string]interface{} {
+	if sw.timeout == 0 {
+		return sw.processEvent(fact, tokenID)
+	}
+
+	select {
+	case sw.inFlight <- struct{}{}:
+	default:
+		err := fmt.Errorf("max concurrency %d of synchronous processing is reached", cap(sw.inFlight))
+		logging.Warnf("[%s] Event [%s] is skipped: %v", sw.syncStorage.ID(), sw.syncStorage.GetUniqueIDField().Extract(fact), err)
+		sw.syncStorage.SkipEvent(sw.preliminaryEventContext(fact, tokenID), err)
+		return nil
+	}
+
+	//processing might outlive the HTTP request: the event is copied because it is used after the response
+	eventCopy := events.Event(maputils.CopyMap(fact))
+	resultCh := make(chan []map[string]interface{}, 1)
+	safego.Run(func() {
+		defer func() { <-sw.inFlight }()
+		resultCh <- sw.processEvent(eventCopy, tokenID)
+	})
+
+	timer := time.NewTimer(sw.timeout)
+	defer timer.Stop()
+	select {
+	case results := <-resultCh:
+		return results
+	case <-timer.C:
+		logging.Warnf("[%s] Event [%s]: synchronous processing exceeded timeout %s. Result is omitted in the response", sw.syncStorage.ID(), sw.syncStorage.GetUniqueIDField().Extract(fact), sw.timeout)
+		return nil
+	}
+}
+
+func (sw *SyncWorker) processEvent(fact events.Event, tokenID string) []map[string]interface{} {
 	if sw.syncStorage.IsStaging() {
 		return nil
 	}
@@ -56,16 +109,7 @@ func (sw *SyncWorker) ProcessEvent(fact events.Event, tokenID string) []map[stri
 		return nil
 	}
 
-	//is used in writing counters/metrics/events cache
-	preliminaryEventContext := &adapters.EventContext{
-		CacheDisabled:   sw.syncStorage.IsCachingDisabled(),
-		DestinationID:   sw.syncStorage.ID(),
-		EventID:         sw.syncStorage.GetUniqueIDField().Extract(fact),
-		TokenID:         tokenID,
-		Src:             events.ExtractSrc(fact),
-		RawEvent:        fact,
-		RecognizedEvent: recognizedEvent,
-	}
+	preliminaryEventContext := sw.preliminaryEventContext(fact, tokenID)
 
 	envelops, err := sw.syncStorage.Processor().ProcessEvent(fact, true)
 	if err != nil && !recognizedEvent {
@@ -106,6 +150,8 @@ func (sw *SyncWorker) ProcessEvent(fact events.Event, tokenID string) []map[stri
 			ProcessedEvent:  flattenObject,
 			Table:           table,
 			RecognizedEvent: recognizedEvent,
+
+			SerializedOriginalEvent: envelop.OriginalEvent,
 		}
 		result, err := sw.syncStorage.ProcessEvent(eventContext)
 		if err != nil {
@@ -127,6 +173,22 @@ func (sw *SyncWorker) ProcessEvent(fact events.Event, tokenID string) []map[stri
 	return results
 }
 
+//preliminaryEventContext returns event context of not processed event. It is used in writing counters/metrics/events cache
+func (sw *SyncWorker) preliminaryEventContext(fact events.Event, tokenID string) *adapters.EventContext {
+	_, recognizedEvent := fact[schema.JitsuUserRecognizedEvent]
+	return &adapters.EventContext{
+		CacheDisabled:   sw.syncStorage.IsCachingDisabled(),
+		DestinationID:   sw.syncStorage.ID(),
+		EventID:         sw.syncStorage.GetUniqueIDField().Extract(fact),
+		TokenID:         tokenID,
+		Src:             events.ExtractSrc(fact),
+		RawEvent:        fact,
+		RecognizedEvent: recognizedEvent,
+
+		SerializedOriginalEvent: sw.syncStorage.Processor().SerializeOriginalEvent(fact),
+	}
+}
+
 func (sw *SyncWorker) Close() error {
 	sw.closed.Store(true)
 
@@ -0,0 +1,52 @@
+package storages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/identifiers"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/stretchr/testify/require"
+)
+
+//skipCountingSyncStorage is a SyncStorage which records skipped events
+type skipCountingSyncStorage struct {
+	SyncStorage
+	skipped []*adapters.EventContext
+}
+
+func (s *skipCountingSyncStorage) ID() string {
+	return "personalization"
+}
+
+func (s *skipCountingSyncStorage) IsCachingDisabled() bool {
+	return false
+}
+
+func (s *skipCountingSyncStorage) GetUniqueIDField() *identifiers.UniqueID {
+	return identifiers.NewUniqueID("/eventn_ctx/event_id")
+}
+
+func (s *skipCountingSyncStorage) Processor() *schema.Processor {
+	return &schema.Processor{}
+}
+
+func (s *skipCountingSyncStorage) SkipEvent(eventCtx *adapters.EventContext, err error) {
+	s.skipped = append(s.skipped, eventCtx)
+}
+
+func TestTimeoutSyncWorkerMaxConcurrency(t *testing.T) {
+	storage := &skipCountingSyncStorage{}
+	sw := newTimeoutSyncWorker(storage, 10*time.Millisecond, 1)
+	//processing of the previous event is stuck
+	sw.inFlight <- struct{}{}
+
+	event := events.Event{"eventn_ctx": map[string]interface{}{"event_id": "e1"}, "src": "api"}
+	require.Nil(t, sw.ProcessEvent(event, "token"))
+	require.Len(t, storage.skipped, 1)
+	require.Equal(t, "e1", storage.skipped[0].EventID)
+	require.Equal(t, "token", storage.skipped[0].TokenID)
+	require.Equal(t, "personalization", storage.skipped[0].DestinationID)
+}
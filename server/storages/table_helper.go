@@ -28,6 +28,7 @@ type TableHelper struct {
 	tables              map[string]*adapters.Table
 
 	pkFields           map[string]bool
+	partitioning       *adapters.Partitioning
 	columnTypesMapping map[typing.DataType]string
 
 	dbSchema        string
@@ -39,7 +40,7 @@ type TableHelper struct {
 //NewTableHelper returns configured TableHelper instance
 //Note: columnTypesMapping must be not empty (or fields will be ignored)
 func NewTableHelper(dbSchema string, sqlAdapter adapters.SQLAdapter, coordinationService *coordination.Service, pkFields map[string]bool,
-	columnTypesMapping map[typing.DataType]string, maxColumns int, destinationType string) *TableHelper {
+	partitioning *adapters.Partitioning, columnTypesMapping map[typing.DataType]string, maxColumns int, destinationType string) *TableHelper {
 
 	return &TableHelper{
 		sqlAdapter:          sqlAdapter,
@@ -47,6 +48,7 @@ func NewTableHelper(dbSchema string, sqlAdapter adapters.SQLAdapter, coordinatio
 		tables:              map[string]*adapters.Table{},
 
 		pkFields:           pkFields,
+		partitioning:       partitioning,
 		columnTypesMapping: columnTypesMapping,
 
 		dbSchema:        dbSchema,
@@ -66,6 +68,15 @@ func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters
 		PKFields:  th.pkFields,
 	}
 
+	//partitioning from the configuration
+	if th.partitioning != nil {
+		if table.Partition.Field == "" {
+			table.Partition = th.partitioning.DatePartition()
+		}
+		table.PartitionExpiration = th.partitioning.Retention
+		table.ClusteringFields = th.partitioning.ClusteringFields
+	}
+
 	//pk fields from the configuration
 	if len(th.pkFields) > 0 {
 		table.PrimaryKeyName = adapters.BuildConstraintName(table.Schema, table.Name)
@@ -57,7 +57,7 @@ func TestMapTableSchema(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tableHelper := NewTableHelper("test", nil, nil, tt.pkFields, tt.columnTypesMapping, 0, PostgresType)
+			tableHelper := NewTableHelper("test", nil, nil, tt.pkFields, nil, tt.columnTypesMapping, 0, PostgresType)
 			actual := tableHelper.MapTableSchema(&tt.input)
 			require.Equal(t, tt.expected, *actual, "Tables aren't equal")
 		})
@@ -132,7 +132,7 @@ return $
 			} else {
 				require.NoError(t, err)
 				require.EqualValues(t, len(tt.expectedObjects), len(envelopes), "Number of expected objects doesnt match.")
-				tableHelper := NewTableHelper("test", nil, nil, map[string]bool{}, adapters.SchemaToPostgres, 0, PostgresType)
+				tableHelper := NewTableHelper("test", nil, nil, map[string]bool{}, nil, adapters.SchemaToPostgres, 0, PostgresType)
 				for i := 0; i < len(envelopes); i++ {
 					table := tableHelper.MapTableSchema(envelopes[i].Header)
 					actual := envelopes[i].Event
@@ -0,0 +1,49 @@
+function toBraze($, options) {
+  const context = $.eventn_ctx || $;
+  const user = context.user || {};
+  const utm = context.utm || {};
+  const conversion = context.conversion || {};
+  const anonymousAlias = user.anonymous_id ? { alias_name: user.anonymous_id, alias_label: "jitsu_anonymous_id" } : undefined;
+  const identifier = user.id ? { external_id: String(user.id) } : anonymousAlias ? { user_alias: anonymousAlias } : undefined;
+  if (!identifier) {
+    //events without user identifiers can't be sent
+    return null;
+  }
+
+  if ($.event_type === "user_identify") {
+    const result = [];
+    if (user.id && anonymousAlias) {
+      //merges anonymous user profile into the identified one
+      result.push({
+        aliases_to_identify: [{ external_id: String(user.id), user_alias: anonymousAlias }],
+      });
+    }
+    const { id, anonymous_id, hashed_anonymous_id, ...traits } = user;
+    result.push({
+      attributes: [{ ...traits, ...identifier, _update_existing_only: false }],
+    });
+    return result;
+  }
+
+  return {
+    events: [
+      {
+        ...identifier,
+        app_id: options?.app_id || undefined,
+        name: $.event_type,
+        time: $._timestamp ? new Date($._timestamp).toISOString() : new Date().toISOString(),
+        properties: {
+          url: context.url,
+          title: context.page_title,
+          referrer: context.referer,
+          utm_source: utm.source,
+          utm_medium: utm.medium,
+          utm_campaign: utm.campaign,
+          utm_term: utm.term,
+          utm_content: utm.content,
+          revenue: conversion.revenue || $.revenue,
+        },
+      },
+    ],
+  };
+}
@@ -0,0 +1,45 @@
+function toCustomerIO($) {
+  const context = $.eventn_ctx || $;
+  const user = context.user || {};
+  const utm = context.utm || {};
+  const conversion = context.conversion || {};
+  const identifiedId = user.id || user.email;
+  const timestamp = Math.floor(($._timestamp ? new Date($._timestamp).getTime() : Date.now()) / 1000);
+
+  if ($.event_type === "user_identify") {
+    if (!identifiedId) {
+      return null;
+    }
+    const { id, hashed_anonymous_id, ...traits } = user;
+    //anonymous_id attribute merges anonymous activity into the person
+    return { ...traits, id: identifiedId, _last_seen: timestamp };
+  }
+
+  const event = {
+    name: $.event_type,
+    timestamp: timestamp,
+    data: {
+      url: context.url,
+      title: context.page_title,
+      referrer: context.referer,
+      utm_source: utm.source,
+      utm_medium: utm.medium,
+      utm_campaign: utm.campaign,
+      utm_term: utm.term,
+      utm_content: utm.content,
+      revenue: conversion.revenue || $.revenue,
+    },
+  };
+  if ($.event_type === "pageview" && context.url) {
+    event.type = "page";
+    event.name = context.url;
+  }
+  if (identifiedId) {
+    event.id = identifiedId;
+  } else if (user.anonymous_id) {
+    event.anonymous_id = user.anonymous_id;
+  } else {
+    return null;
+  }
+  return event;
+}
@@ -0,0 +1,72 @@
+function toGA4($) {
+  const context = $.eventn_ctx || $;
+  const user = context.user || {};
+  const utm = context.utm || {};
+  const conversion = context.conversion || {};
+  if (!user.anonymous_id) {
+    //client_id is required by Measurement Protocol
+    return null;
+  }
+
+  //GA4 event and parameter names: letters, digits and underscores, starting with a letter, up to 40 characters
+  const toName = (name, maxLength) => {
+    const sanitized = String(name)
+      .replace(/[^a-zA-Z0-9_]/g, "_")
+      .replace(/^[^a-zA-Z]+/, "");
+    return sanitized.substring(0, maxLength);
+  };
+  const toValue = (value, maxLength) => (typeof value === "string" ? value.substring(0, maxLength) : value);
+  const eventNames = {
+    pageview: "page_view",
+    screenview: "screen_view",
+    user_identify: "login",
+    signup: "sign_up",
+    conversion: "purchase",
+    purchase: "purchase",
+    search: "search",
+  };
+
+  const params = {
+    page_location: toValue(context.url, 1000),
+    page_title: toValue(context.page_title, 300),
+    page_referrer: toValue(context.referer, 420),
+    campaign: toValue(utm.campaign, 100),
+    source: toValue(utm.source, 100),
+    medium: toValue(utm.medium, 100),
+    term: toValue(utm.term, 100),
+    content: toValue(utm.content, 100),
+    language: context.user_language,
+    screen_resolution: context.screen_resolution,
+    engagement_time_msec: 1,
+  };
+  const revenue = conversion.revenue || $.revenue;
+  if (revenue !== undefined) {
+    params.value = Number(revenue);
+    params.currency = conversion.currency || $.currency || "USD";
+    params.transaction_id = toValue(conversion.transaction_id, 100);
+  }
+
+  const userProperties = {};
+  if ($.event_type === "user_identify") {
+    //personally identifiable information isn't allowed by Google Analytics
+    const { id, anonymous_id, hashed_anonymous_id, email, name, phone, ...traits } = user;
+    for (const [key, value] of Object.entries(traits)) {
+      if (value !== null && value !== undefined && typeof value !== "object") {
+        userProperties[toName(key, 24)] = { value: toValue(value, 36) };
+      }
+    }
+  }
+
+  return {
+    client_id: String(user.anonymous_id),
+    user_id: user.id ? String(user.id) : undefined,
+    timestamp_micros: $._timestamp ? new Date($._timestamp).getTime() * 1000 : undefined,
+    user_properties: Object.keys(userProperties).length > 0 ? userProperties : undefined,
+    events: [
+      {
+        name: eventNames[$.event_type] || toName($.event_type || "event", 40) || "event",
+        params: params,
+      },
+    ],
+  };
+}
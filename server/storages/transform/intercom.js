@@ -0,0 +1,33 @@
+function toIntercom($) {
+  const context = $.eventn_ctx || $;
+  const user = context.user || {};
+  const timestamp = Math.floor(($._timestamp ? new Date($._timestamp).getTime() : Date.now()) / 1000);
+  if (!user.id && !user.email) {
+    //Intercom data events are sent only for users.
+    //Events of anonymous users are sent after identification if users recognition is enabled
+    return null;
+  }
+
+  if ($.event_type === "user_identify") {
+    return {
+      role: "user",
+      external_id: user.id ? String(user.id) : undefined,
+      email: user.email,
+      name: user.name,
+      phone: user.phone,
+      last_seen_at: timestamp,
+    };
+  }
+
+  return {
+    event_name: $.event_type,
+    created_at: timestamp,
+    user_id: user.id ? String(user.id) : undefined,
+    email: user.id ? undefined : user.email,
+    metadata: {
+      url: context.url,
+      title: context.page_title,
+      referrer: context.referer,
+    },
+  };
+}
@@ -0,0 +1,67 @@
+function toMixpanel($, options) {
+  const context = $.eventn_ctx || $;
+  const user = context.user || {};
+  const utm = context.utm || {};
+  const location = context.location || {};
+  const ua = context.parsed_ua || {};
+  const conversion = context.conversion || {};
+  const identifiedId = user.id || user.email;
+  const distinctId = identifiedId || user.anonymous_id;
+
+  if ($.event_type === "user_identify") {
+    const result = [];
+    if (identifiedId && user.anonymous_id && identifiedId !== user.anonymous_id) {
+      result.push({
+        event: "$create_alias",
+        properties: {
+          distinct_id: user.anonymous_id,
+          alias: identifiedId,
+        },
+      });
+    }
+    if (options?.users_enabled && distinctId) {
+      const { id, email, name, anonymous_id, hashed_anonymous_id, ...traits } = user;
+      result.push({
+        $distinct_id: distinctId,
+        $ip: $.source_ip,
+        $set: {
+          ...traits,
+          $email: email,
+          $name: name,
+        },
+      });
+    }
+    return result;
+  }
+
+  return {
+    event: $.event_type,
+    properties: {
+      time: $._timestamp ? new Date($._timestamp).getTime() : Date.now(),
+      distinct_id: distinctId,
+      $insert_id: $.eventn_ctx_event_id || context.event_id,
+      $device_id: user.anonymous_id,
+      $user_id: identifiedId,
+      ip: $.source_ip,
+      $current_url: context.url,
+      $referrer: context.referer,
+      $browser: ua.ua_family,
+      $browser_version: ua.ua_version,
+      $os: ua.os_family,
+      $device: ua.device_family,
+      $city: location.city,
+      $region: location.region,
+      mp_country_code: location.country,
+      utm_source: utm.source,
+      utm_medium: utm.medium,
+      utm_campaign: utm.campaign,
+      utm_term: utm.term,
+      utm_content: utm.content,
+      title: context.page_title,
+      host: context.doc_host,
+      path: context.doc_path,
+      Revenue: conversion.revenue || $.revenue,
+      mp_lib: "jitsu",
+    },
+  };
+}
@@ -0,0 +1,66 @@
+function toPostHog($) {
+  const context = $.eventn_ctx || $;
+  const user = context.user || {};
+  const utm = context.utm || {};
+  const location = context.location || {};
+  const ua = context.parsed_ua || {};
+  const conversion = context.conversion || {};
+  const identifiedId = user.id || user.email;
+  const distinctId = identifiedId || user.anonymous_id;
+  const timestamp = $._timestamp ? new Date($._timestamp).toISOString() : undefined;
+
+  if ($.event_type === "user_identify") {
+    const { id, anonymous_id, hashed_anonymous_id, ...traits } = user;
+    const result = [];
+    if (identifiedId && user.anonymous_id && identifiedId !== user.anonymous_id) {
+      result.push({
+        event: "$create_alias",
+        distinct_id: identifiedId,
+        timestamp: timestamp,
+        properties: {
+          alias: user.anonymous_id,
+        },
+      });
+    }
+    if (distinctId) {
+      result.push({
+        event: "$identify",
+        distinct_id: distinctId,
+        timestamp: timestamp,
+        properties: {
+          $anon_distinct_id: user.anonymous_id,
+          $set: traits,
+        },
+      });
+    }
+    return result;
+  }
+
+  return {
+    event: $.event_type === "pageview" ? "$pageview" : $.event_type,
+    distinct_id: distinctId,
+    timestamp: timestamp,
+    properties: {
+      $insert_id: $.eventn_ctx_event_id || context.event_id,
+      $ip: $.source_ip,
+      $current_url: context.url,
+      $host: context.doc_host,
+      $pathname: context.doc_path,
+      $referrer: context.referer,
+      $browser: ua.ua_family,
+      $browser_version: ua.ua_version,
+      $os: ua.os_family,
+      $device_type: ua.device_family,
+      $geoip_city_name: location.city,
+      $geoip_country_code: location.country,
+      utm_source: utm.source,
+      utm_medium: utm.medium,
+      utm_campaign: utm.campaign,
+      utm_term: utm.term,
+      utm_content: utm.content,
+      title: context.page_title,
+      revenue: conversion.revenue || $.revenue,
+      $lib: "jitsu",
+    },
+  };
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 
 	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/circuitbreaker"
+	"github.com/jitsucom/jitsu/server/consent"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/identifiers"
 	"github.com/jitsucom/jitsu/server/jsonutils"
@@ -20,27 +22,50 @@ const (
 	S3Type              = "s3"
 	SnowflakeType       = "snowflake"
 	GoogleAnalyticsType = "google_analytics"
+	GA4Type             = "ga4"
 	GCSType             = "gcs"
 	FacebookType        = "facebook"
 	WebHookType         = "webhook"
 	NpmType             = "npm"
 	TagType             = "tag"
+	PersonalizationType = "personalization"
 	AmplitudeType       = "amplitude"
 	HubSpotType         = "hubspot"
+	MixpanelType        = "mixpanel"
+	PostHogType         = "posthog"
+	BrazeType           = "braze"
+	CustomerIOType      = "customerio"
+	IntercomType        = "intercom"
 	DbtCloudType        = "dbtcloud"
+	FileType            = "file"
+	SFTPType            = "sftp"
+	ElasticsearchType   = "elasticsearch"
+	MongoDBType         = "mongodb"
+	SQSType             = "sqs"
+	PubSubType          = "pubsub"
+	NATSType            = "nats"
+	RedisType           = "redis"
 )
 
 type URSetup struct {
 	PKRequired bool
+	//BatchModeOnly is true if recognized events can't be updated in stream mode
+	BatchModeOnly bool
 }
 
 var (
 	UserRecognitionStorages = map[string]URSetup{
-		MySQLType:      {true},
-		PostgresType:   {true},
-		RedshiftType:   {true},
-		SnowflakeType:  {true},
-		ClickHouseType: {false},
+		MySQLType:      {PKRequired: true},
+		PostgresType:   {PKRequired: true},
+		RedshiftType:   {PKRequired: true},
+		SnowflakeType:  {PKRequired: true},
+		ClickHouseType: {PKRequired: false},
+		//BigQuery doesn't allow DML statements on rows which are in the streaming buffer
+		BigQueryType: {PKRequired: true, BatchModeOnly: true},
+		//anonymous-to-known merges
+		BrazeType:      {PKRequired: false},
+		CustomerIOType: {PKRequired: false},
+		IntercomType:   {PKRequired: false},
 	}
 )
 
@@ -70,6 +95,11 @@ type Storage interface {
 	Clean(tableName string) error
 }
 
+//Querier is implemented by storages which are able to execute read queries (e.g. SQL destinations)
+type Querier interface {
+	Query(query string) ([]map[string]interface{}, error)
+}
+
 //StorageProxy is a storage proxy
 type StorageProxy interface {
 	io.Closer
@@ -77,6 +107,8 @@ type StorageProxy interface {
 	GetUniqueIDField() *identifiers.UniqueID
 	GetPostHandleDestinations() []string
 	GetGeoResolverID() string
+	GetConsentRequirements() *consent.Requirements
+	GetCircuitBreaker() *circuitbreaker.CircuitBreaker
 	IsCachingDisabled() bool
 	ID() string
 	Type() string
@@ -0,0 +1,57 @@
+package storages
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+//maxMergedAnonymousIDs is a max size of merged anonymous IDs set. The set is reset when it is reached
+const maxMergedAnonymousIDs = 10_000
+
+//userMerger builds anonymous-to-known users merges from events which are re-sent by users recognition
+//a merge is built once per anonymous ID because users recognition re-sends every stored anonymous event
+type userMerger struct {
+	recognition *UserRecognitionConfiguration
+	//mergeObject returns destination merge object from anonymous ID and transformed event or nil if merge isn't possible
+	mergeObject func(anonymousID string, object map[string]interface{}) map[string]interface{}
+
+	mutex  sync.Mutex
+	merged map[string]bool
+}
+
+//newUserMerger returns configured userMerger
+func newUserMerger(recognition *UserRecognitionConfiguration, mergeObject func(anonymousID string, object map[string]interface{}) map[string]interface{}) *userMerger {
+	return &userMerger{recognition: recognition, mergeObject: mergeObject, merged: map[string]bool{}}
+}
+
+//mergeEventContext returns copy of the event context with merge object or nil if the merge isn't required
+func (um *userMerger) mergeEventContext(eventContext *adapters.EventContext) *adapters.EventContext {
+	if !um.recognition.IsEnabled() {
+		return nil
+	}
+	value, ok := um.recognition.AnonymousIDJSONPath.Get(eventContext.RawEvent)
+	if !ok || value == nil || fmt.Sprint(value) == "" {
+		return nil
+	}
+	anonymousID := fmt.Sprint(value)
+	object := um.mergeObject(anonymousID, eventContext.ProcessedEvent)
+	if object == nil {
+		return nil
+	}
+
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+	if um.merged[anonymousID] {
+		return nil
+	}
+	if len(um.merged) >= maxMergedAnonymousIDs {
+		um.merged = map[string]bool{}
+	}
+	um.merged[anonymousID] = true
+
+	mergeContext := *eventContext
+	mergeContext.ProcessedEvent = object
+	return &mergeContext
+}
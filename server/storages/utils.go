@@ -1,6 +1,7 @@
 package storages
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/hashicorp/go-multierror"
@@ -9,8 +10,11 @@ import (
 	"github.com/jitsucom/jitsu/server/drivers/base"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/privacy"
 	"github.com/jitsucom/jitsu/server/schema"
 	"github.com/jitsucom/jitsu/server/timestamp"
+	"net"
+	"net/http"
 	"strings"
 )
 
@@ -24,16 +28,30 @@ func dryRun(payload events.Event, processor *schema.Processor, tableHelper *Tabl
 		batchHeader := envelop.Header
 		event := envelop.Event
 		tableSchema := tableHelper.MapTableSchema(batchHeader)
+		maskedFields := maskedFlatFields(envelop.Masked)
 		var tableFields []adapters.TableField
 
 		for name, column := range tableSchema.Columns {
-			tableFields = append(tableFields, adapters.TableField{Field: name, Type: column.Type, Value: event[name]})
+			tableFields = append(tableFields, adapters.TableField{Field: name, Type: column.Type, Value: event[name], Masked: maskedFields[name]})
 		}
 		res = append(res, tableFields)
 	}
 	return res, nil
 }
 
+//maskedFlatFields returns flat field name -> privacy action (with detector name if any) of masked values
+func maskedFlatFields(masked []privacy.MaskedField) map[string]string {
+	result := map[string]string{}
+	for _, field := range masked {
+		action := field.Action
+		if field.Detector != "" {
+			action += ":" + field.Detector
+		}
+		result[schema.Reformat(strings.Join(strings.Split(strings.TrimPrefix(field.Path, "/"), "/"), "_"))] = action
+	}
+	return result
+}
+
 func IsConnectionError(err error) bool {
 	return strings.Contains(err.Error(), "connection refused") ||
 		strings.Contains(err.Error(), "EOF") ||
@@ -42,7 +60,45 @@ func IsConnectionError(err error) bool {
 		strings.Contains(err.Error(), "context deadline exceeded") ||
 		strings.Contains(err.Error(), "connection reset by peer") ||
 		strings.Contains(err.Error(), "timed out") ||
-		strings.Contains(err.Error(), "no such host")
+		strings.Contains(err.Error(), "no such host") ||
+		strings.Contains(err.Error(), "Too Many Requests")
+}
+
+//IsDestinationFailure returns true if err means that the destination is unavailable: transport error, timeout or 5xx response
+//errors caused by the event itself (4xx responses, rejected messages or documents) aren't destination failures
+func IsDestinationFailure(err error) bool {
+	for cause := err; cause != nil; cause = unwrapCause(cause) {
+		switch e := cause.(type) {
+		case *adapters.HTTPStatusError:
+			return e.StatusCode >= http.StatusInternalServerError
+		case *adapters.ElasticsearchItemError:
+			return e.Status >= http.StatusInternalServerError
+		case *adapters.ElasticsearchBulkError:
+			for _, itemErr := range e.Items {
+				if itemErr.Status >= http.StatusInternalServerError {
+					return true
+				}
+			}
+			return false
+		case *adapters.MessageRejectedError:
+			return false
+		case net.Error:
+			return true
+		}
+		if cause == context.DeadlineExceeded {
+			return true
+		}
+	}
+
+	return IsConnectionError(err) && !strings.Contains(err.Error(), "Too Many Requests")
+}
+
+//unwrapCause returns wrapped error (errors.Unwrap) or the cause of errorj (errorx) error
+func unwrapCause(err error) error {
+	if causer, ok := err.(interface{ Cause() error }); ok {
+		return causer.Cause()
+	}
+	return errors.Unwrap(err)
 }
 
 // syncStoreImpl implements common behaviour used to storing chunk of pulled data to any storages with processing
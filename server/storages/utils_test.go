@@ -0,0 +1,38 @@
+package storages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/errorj"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDestinationFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"5xx response", &adapters.HTTPStatusError{StatusCode: http.StatusBadGateway, Message: "HTTP Response status code: [502]"}, true},
+		{"4xx response", &adapters.HTTPStatusError{StatusCode: http.StatusBadRequest, Message: "HTTP Response status code: [400]"}, false},
+		{"429 response", &adapters.HTTPStatusError{StatusCode: http.StatusTooManyRequests, Message: "Too Many Requests"}, false},
+		{"transport error", &url.Error{Op: "Post", URL: "https://api.jitsu.com", Err: errors.New("dial tcp: lookup api.jitsu.com: no such host")}, true},
+		{"timeout", fmt.Errorf("publish: %w", context.DeadlineExceeded), true},
+		{"rejected message", &adapters.MessageRejectedError{Err: errors.New("message is too large")}, false},
+		{"rejected document", errorj.ExecuteInsertError.Wrap(&adapters.ElasticsearchItemError{Status: http.StatusBadRequest, Type: "mapper_parsing_exception"}, "failed to execute single insert"), false},
+		{"unavailable shard", errorj.ExecuteInsertError.Wrap(&adapters.ElasticsearchItemError{Status: http.StatusServiceUnavailable, Type: "unavailable_shards_exception"}, "failed to execute single insert"), true},
+		{"sql connection error", errorj.ExecuteInsertError.Wrap(errors.New("dial tcp 10.0.0.1:5432: connect: connection refused"), "failed to execute single insert"), true},
+		{"sql error", errorj.ExecuteInsertError.Wrap(errors.New(`pq: column "a" does not exist`), "failed to execute single insert"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, IsDestinationFailure(tt.err))
+		})
+	}
+}
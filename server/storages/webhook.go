@@ -72,6 +72,7 @@ func NewWebHook(config *Config) (storage Storage, err error) {
 		DebugLogger:    requestDebugLogger,
 		ErrorHandler:   wh.ErrorEvent,
 		SuccessHandler: wh.SuccessEvent,
+		CircuitBreaker: config.circuitBreaker,
 	})
 	if err != nil {
 		return
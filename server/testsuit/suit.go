@@ -216,7 +216,7 @@ func (sb *suiteBuilder) Build(t *testing.T) Suit {
 	segmentProcessor := events.NewSegmentProcessor(sb.recognitionService)
 	processorHolder := events.NewProcessorHolder(apiProcessor, jsProcessor, pixelProcessor, segmentProcessor, bulkProcessor)
 
-	multiplexingService := multiplexing.NewService(sb.destinationService)
+	multiplexingService := multiplexing.NewService(sb.destinationService, sb.eventsCache)
 	walService := wal.NewService("/tmp", &logevents.SyncLogger{}, multiplexingService, processorHolder)
 	appconfig.Instance.ScheduleWriteAheadLogClosing(walService)
 
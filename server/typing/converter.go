@@ -1,6 +1,7 @@
 package typing
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,7 +10,7 @@ import (
 	"github.com/jitsucom/jitsu/server/timestamp"
 )
 
-// Typecast tree
+// Typecast tree (JSON and ARRAY types aren't a part of it. See GetCommonAncestorType)
 //      STRING(4)
 //     /      \
 //FLOAT64(3)  TIMESTAMP(5)
@@ -53,6 +54,10 @@ var (
 
 		rule{from: STRING, to: TIMESTAMP}: stringToTimestamp,
 
+		rule{from: JSON, to: STRING}:  complexToString,
+		rule{from: ARRAY, to: STRING}: complexToString,
+		rule{from: ARRAY, to: JSON}:   complexToJSON,
+
 		// Future
 		/*rule{from: STRING, to: INT64}:     stringToInt,
 		  rule{from: STRING, to: FLOAT64}:   stringToFloat,
@@ -110,7 +115,19 @@ func Convert(toType DataType, v interface{}) (interface{}, error) {
 }
 
 //GetCommonAncestorType returns lowest common ancestor type
+//JSON and ARRAY aren't a part of typecast tree: their common type is JSON
+//and common type with any other type is STRING (serialized JSON)
 func GetCommonAncestorType(t1, t2 DataType) DataType {
+	if t1.IsComplex() || t2.IsComplex() {
+		if t1 == t2 {
+			return t1
+		}
+		if t1.IsComplex() && t2.IsComplex() {
+			return JSON
+		}
+		return STRING
+	}
+
 	return lowestCommonAncestor(typecastTree, t1, t2)
 }
 
@@ -181,6 +198,21 @@ func timestampToString(v interface{}) (interface{}, error) {
 	}
 }
 
+//complexToString returns JSON string representation of objects and arrays
+func complexToString(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Error complexToString(): %v", err)
+	}
+
+	return string(b), nil
+}
+
+//complexToJSON returns value as is because arrays are valid JSON values
+func complexToJSON(v interface{}) (interface{}, error) {
+	return v, nil
+}
+
 func numberToFloat(v interface{}) (interface{}, error) {
 	switch v.(type) {
 	case int:
@@ -50,6 +50,27 @@ func TestConvert(t *testing.T) {
 			"123.523",
 			"",
 		},
+		{
+			"object -> string",
+			map[string]interface{}{"key": "value"},
+			STRING,
+			`{"key":"value"}`,
+			"",
+		},
+		{
+			"array -> string",
+			[]interface{}{1, "a"},
+			STRING,
+			`[1,"a"]`,
+			"",
+		},
+		{
+			"array -> json",
+			[]interface{}{1, "a"},
+			JSON,
+			[]interface{}{1, "a"},
+			"",
+		},
 		{
 			"timestamp -> string",
 			time.Date(2020, 07, 20, 10, 15, 23, 22, time.UTC),
@@ -262,6 +283,30 @@ func TestGetCommonAncestorType(t *testing.T) {
 			TIMESTAMP,
 			STRING,
 		},
+		{
+			"json+json=json",
+			JSON,
+			JSON,
+			JSON,
+		},
+		{
+			"array+json=json",
+			ARRAY,
+			JSON,
+			JSON,
+		},
+		{
+			"int64+array=string",
+			INT64,
+			ARRAY,
+			STRING,
+		},
+		{
+			"json+timestamp=string",
+			JSON,
+			TIMESTAMP,
+			STRING,
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,6 +3,7 @@ package typing
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -29,6 +30,10 @@ const (
 	STRING
 	//TIMESTAMP type for string values that match timestamp pattern
 	TIMESTAMP
+	//JSON type for objects which are kept unflattened. It isn't a part of typecast tree
+	JSON
+	//ARRAY type for arrays which are kept unflattened. It isn't a part of typecast tree
+	ARRAY
 )
 
 var (
@@ -38,6 +43,8 @@ var (
 		"double":    FLOAT64,
 		"timestamp": TIMESTAMP,
 		"boolean":   BOOL,
+		"json":      JSON,
+		"array":     ARRAY,
 	}
 	typeToInputString = map[DataType]string{
 		STRING:    "string",
@@ -45,6 +52,8 @@ var (
 		FLOAT64:   "double",
 		TIMESTAMP: "timestamp",
 		BOOL:      "boolean",
+		JSON:      "json",
+		ARRAY:     "array",
 	}
 )
 
@@ -62,6 +71,10 @@ func (dt DataType) String() string {
 		return "TIMESTAMP"
 	case BOOL:
 		return "BOOL"
+	case JSON:
+		return "JSON"
+	case ARRAY:
+		return "ARRAY"
 	case UNKNOWN:
 		return "UNKNOWN"
 	}
@@ -159,11 +172,28 @@ func TypeFromValue(v interface{}) (DataType, error) {
 		return TIMESTAMP, nil
 	case bool:
 		return BOOL, nil
+	case map[string]interface{}:
+		return JSON, nil
+	case []interface{}:
+		return ARRAY, nil
 	default:
+		if v != nil {
+			switch reflect.ValueOf(v).Kind() {
+			case reflect.Map:
+				return JSON, nil
+			case reflect.Slice, reflect.Array:
+				return ARRAY, nil
+			}
+		}
 		return UNKNOWN, fmt.Errorf("Unknown DataType for value: %v type: %t", v, v)
 	}
 }
 
+//IsComplex returns true if DataType is JSON or ARRAY
+func (dt DataType) IsComplex() bool {
+	return dt == JSON || dt == ARRAY
+}
+
 func DataTypePtr(dt DataType) *DataType {
 	return &dt
 }
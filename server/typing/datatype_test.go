@@ -15,6 +15,8 @@ func TestDataTypeValues(t *testing.T) {
 	require.Equal(t, DataType(3), FLOAT64)
 	require.Equal(t, DataType(4), STRING)
 	require.Equal(t, DataType(5), TIMESTAMP)
+	require.Equal(t, DataType(6), JSON)
+	require.Equal(t, DataType(7), ARRAY)
 }
 
 func TestTypeFromString(t *testing.T) {
@@ -214,6 +216,24 @@ func TestTypeFromValue(t *testing.T) {
 			BOOL,
 			"",
 		},
+		{
+			"object ok",
+			map[string]interface{}{"key": "value"},
+			JSON,
+			"",
+		},
+		{
+			"array ok",
+			[]interface{}{1, "a"},
+			ARRAY,
+			"",
+		},
+		{
+			"typed array ok",
+			[]string{"a", "b"},
+			ARRAY,
+			"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {